@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,7 +22,13 @@ import (
 )
 
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		err = runReplay(os.Args[2:])
+	} else {
+		err = run()
+	}
+	if err != nil {
 		log.Fatalf("fatal: %v", err)
 	}
 }
@@ -77,35 +84,15 @@ func run() error {
 		"http_addr", cfg.HTTPAddr,
 		"batch_size", cfg.BatchSize,
 		"flush_timeout_ms", cfg.FlushTimeout,
+		"writers", cfg.Writers,
 		"enable_fts", cfg.EnableFTS,
 		"otel_enabled", cfg.OTelEnabled,
 	)
 
 	// Open database based on driver
-	var db repository.Database
-	switch cfg.DBDriver {
-	case config.DBDriverSQLite:
-		db, err = repository.OpenSQLite(repository.SQLiteDBConfig{
-			Path:      cfg.DBPath,
-			EnableFTS: cfg.EnableFTS,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to open sqlite database: %w", err)
-		}
-	case config.DBDriverPostgres:
-		db, err = repository.OpenPostgres(repository.PostgresDBConfig{
-			Host:     cfg.PGHost,
-			Port:     cfg.PGPort,
-			User:     cfg.PGUser,
-			Password: cfg.PGPassword,
-			Database: cfg.PGDatabase,
-			SSLMode:  cfg.PGSSLMode,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to open postgres database: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported database driver: %s", cfg.DBDriver)
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
 	}
 	defer db.Close()
 
@@ -123,6 +110,13 @@ func run() error {
 	organizationRepo := repository.NewOrganizationRepository(db)
 	eventRepo := repository.NewEventRepository(db)
 	collaborationRepo := repository.NewCollaborationRepository(db)
+	moderationRepo := repository.NewModerationRepository(db)
+	noticeRepo := repository.NewNoticeRepository(db)
+	whisperRepo := repository.NewWhisperRepository(db)
+	deadLetterRepo := repository.NewDeadLetterRepository(db)
+	channelStateRepo := repository.NewChannelStateRepository(db)
+	emoteRepo := repository.NewEmoteRepository(db)
+	bitsRepo := repository.NewBitsRepository(db)
 
 	// Register database count callbacks for OTel metrics
 	if otelProvider != nil {
@@ -144,61 +138,197 @@ func run() error {
 		userRepo,
 		channelRepo,
 		ingestion.ProcessorConfig{
-			Logger:       logger,
-			Metrics:      metrics,
-			OTelProvider: otelProvider,
+			Logger:         logger,
+			Metrics:        metrics,
+			OTelProvider:   otelProvider,
+			ModerationRepo: moderationRepo,
+			NoticeRepo:     noticeRepo,
+			RoomStateRepo:  channelStateRepo,
+			WhisperRepo:    whisperRepo,
+			DeadLetterRepo: deadLetterRepo,
 		},
 	)
 
+	// Open the spool; batches the database refuses wait there for a retry
+	var spool *ingestion.Spool
+	if cfg.SpoolDir != "" {
+		spool, err = ingestion.OpenSpool(ingestion.SpoolConfig{
+			Dir:      cfg.SpoolDir,
+			MaxBytes: int64(cfg.SpoolMaxMB) << 20,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open spool: %w", err)
+		}
+		defer spool.Close()
+
+		stats := spool.Stats()
+		logger.Info("ingestion spool opened",
+			"dir", cfg.SpoolDir,
+			"pending_records", stats.Records,
+			"pending_bytes", stats.Bytes,
+		)
+		if otelProvider != nil {
+			if err := otelProvider.RegisterSpoolCallback(func() (int64, int64, time.Time) {
+				stats := spool.Stats()
+				return stats.Records, stats.Bytes, stats.OldestAt
+			}); err != nil {
+				logger.Error("failed to register spool metrics", "error", err)
+			}
+		}
+	}
+
 	// Create ingestion pipeline
 	pipeline := ingestion.NewPipeline(
 		ingestion.PipelineConfig{
 			BatchSize:    cfg.BatchSize,
 			FlushTimeout: time.Duration(cfg.FlushTimeout) * time.Millisecond,
-			BufferSize:   10000,
+			BufferSize:   cfg.BufferSize,
+			Writers:      cfg.Writers,
+			DedupWindow:  time.Duration(cfg.DedupWindow) * time.Second,
 			Metrics:      metrics,
 			OTelProvider: otelProvider,
+			Logger:       logger,
+			Overflow:     overflowPolicy(cfg),
+			Spool:        spool,
+
+			FlushRetries:      cfg.FlushRetries,
+			FlushRetryBackoff: time.Duration(cfg.FlushRetryBackoff) * time.Millisecond,
 		},
 		processor,
 	)
 
-	// Create IRC client
-	ircClient := irc.NewClient(irc.ClientConfig{
-		AuthMode:   irc.AuthMode(cfg.TwitchAuthMode),
-		Username:   cfg.TwitchUsername,
-		OAuthToken: cfg.TwitchOAuthToken,
-		OnMessage: func(msg irc.Message) {
-			metrics.RecordIRCMessage()
-			// Record OTel metrics for IRC messages (with channel label)
-			if otelProvider != nil {
-				otelProvider.RecordIRCMessage(ctx, msg.Channel)
-			}
-			pipeline.Ingest(ingestion.Message{
-				ChannelName: msg.Channel,
-				Username:    msg.Username,
-				DisplayName: msg.DisplayName,
-				Text:        msg.Text,
-				Tags:        msg.Tags,
-				ReceivedAt:  msg.ReceivedAt,
-			})
-		},
-		OnChannelChange: func(channel string, joined bool) {
-			if joined {
-				logger.IRC("joined channel", "channel", channel)
-			} else {
-				logger.IRC("left channel", "channel", channel)
+	// Receives the error that stops IRC for good, e.g. a rejected login
+	ircErrChan := make(chan error, 1)
+
+	// Record raw IRC traffic for replay, if configured
+	var recorder *irc.Recorder
+	if cfg.IRCRecordDir != "" {
+		recorder, err = irc.NewRecorder(irc.RecorderConfig{
+			Dir:      cfg.IRCRecordDir,
+			MaxBytes: int64(cfg.IRCRecordMaxMB) << 20,
+			MaxAge:   time.Duration(cfg.IRCRecordMaxMinutes) * time.Minute,
+			OnError: func(err error) {
+				logger.Error("failed to record IRC traffic", "error", err)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create IRC recorder: %w", err)
+		}
+		logger.Info("recording IRC traffic", "dir", cfg.IRCRecordDir)
+	}
+
+	// Create IRC client config; its handlers feed the pipeline
+	clientCfg := pipelineClientConfig(pipeline)
+	clientCfg.AuthMode = irc.AuthMode(cfg.TwitchAuthMode)
+	clientCfg.Username = cfg.TwitchUsername
+	clientCfg.OAuthToken = cfg.TwitchOAuthToken
+	clientCfg.Server = cfg.IRCServer
+	clientCfg.DisableTLS = !cfg.IRCTLS
+	clientCfg.MakeBeforeBreak = cfg.IRCMakeBeforeBreak
+	clientCfg.Recorder = recorder
+
+	// Messages sent from the web UI are archived when sent; their echoes
+	// are not archived again
+	sentEchoes := services.NewSentEchoes()
+
+	ingestMessage := clientCfg.OnMessage
+	clientCfg.OnMessage = func(msg irc.Message) {
+		metrics.RecordIRCMessage()
+		// Record OTel metrics for IRC messages (with channel label)
+		if otelProvider != nil {
+			otelProvider.RecordIRCMessage(ctx, msg.Channel)
+		}
+		if sentEchoes.IsEcho(msg.Channel, msg.Username, msg.Text, msg.Tags["id"]) {
+			return
+		}
+		ingestMessage(msg)
+	}
+	clientCfg.OnServerEvent = func(evt irc.ServerEvent) {
+		metrics.RecordIRCServerEvent(string(evt.Kind))
+		if otelProvider != nil {
+			otelProvider.RecordIRCServerEvent(ctx, string(evt.Kind), evt.MsgID)
+		}
+		switch evt.Kind {
+		case irc.ServerEventAuthFailed:
+			logger.Error("twitch rejected IRC login", "notice", evt.Text)
+			select {
+			case ircErrChan <- fmt.Errorf("%w: %s", irc.ErrAuthFailed, evt.Text):
+			default:
 			}
-		},
+		case irc.ServerEventReconnect:
+			logger.IRC("twitch requested reconnect")
+		default:
+			logger.IRC("notice", "channel", evt.Channel, "msg_id", evt.MsgID, "text", evt.Text)
+		}
+	}
+	clientCfg.OnChannelChange = func(channel string, joined bool) {
+		if joined {
+			logger.IRC("queued channel join", "channel", channel)
+		} else {
+			logger.IRC("left channel", "channel", channel)
+		}
+	}
+	clientCfg.OnJoin = func(res irc.JoinResult) {
+		metrics.RecordIRCJoin(res.Latency, res.Err == nil)
+		if otelProvider != nil {
+			otelProvider.RecordIRCJoin(ctx, float64(res.Latency.Milliseconds()), res.Err == nil)
+		}
+		if res.Err != nil {
+			logger.Error("failed to join channel", "channel", res.Channel, "attempts", res.Attempts, "error", res.Err)
+		} else {
+			logger.IRC("joined channel", "channel", res.Channel, "attempts", res.Attempts, "latency_ms", res.Latency.Milliseconds())
+		}
+	}
+
+	// Create IRC connection pool; channels are sharded across connections
+	ircPool := irc.NewPool(irc.PoolConfig{
+		ChannelsPerConnection: cfg.IRCChannelsPerConnection,
+		RedundantChannels:     cfg.IRCRedundantChannels,
+		Client:                clientCfg,
 	})
 
+	if otelProvider != nil {
+		if err := otelProvider.RegisterIRCJoinQueueCallback(func() int64 {
+			return int64(ircPool.JoinQueueDepth())
+		}); err != nil {
+			logger.Error("failed to register join queue metrics", "error", err)
+		}
+	}
+
 	// Create channel service
 	channelService := services.NewChannelService(
 		channelRepo,
-		ircClient,
+		ircPool,
 		logger,
 		metrics,
 	)
 
+	// Create chat service; Twitch doesn't echo our own messages on the
+	// sending connection, so sent messages are archived straight through
+	// the pipeline
+	chatService := services.NewChatService(ircPool, func(m services.SentMessage) {
+		sentEchoes.Sent(m)
+		var tags map[string]string
+		if m.ParentMsgID != "" {
+			tags = map[string]string{"reply-parent-msg-id": m.ParentMsgID}
+		}
+		pipeline.Ingest(ingestion.Message{
+			ChannelName: m.Channel,
+			Username:    m.Username,
+			DisplayName: m.Username,
+			Text:        m.Text,
+			Tags:        tags,
+			SentAt:      m.SentAt,
+			ReceivedAt:  m.SentAt,
+			Outbound:    true,
+		})
+	}, logger)
+
+	// Create whisper service; whispers have a retention of their own
+	whisperService := services.NewWhisperService(whisperRepo,
+		time.Duration(cfg.WhisperRetentionDays)*24*time.Hour, logger)
+	deadLetterService := services.NewDeadLetterService(deadLetterRepo, processor, logger)
+
 	// Create search repository and service
 	searchRepo := search.NewSearchRepository(db, cfg.EnableFTS)
 	searchService := services.NewSearchService(searchRepo, logger, metrics, otelProvider)
@@ -223,14 +353,25 @@ func run() error {
 		EventRepo:            eventRepo,
 		CollaborationService: collaborationService,
 		CollaborationRepo:    collaborationRepo,
+		ChatService:          chatService,
+		WhisperService:       whisperService,
+		DeadLetterService:    deadLetterService,
+		OverflowStats:        pipeline,
 		ChannelRepo:          channelRepo,
 		MessageRepo:          messageRepo,
 		UserRepo:             userRepo,
+		ModerationRepo:       moderationRepo,
+		NoticeRepo:           noticeRepo,
+		ChannelStateRepo:     channelStateRepo,
+		EmoteRepo:            emoteRepo,
+		BitsRepo:             bitsRepo,
 		ProfileRepo:          profileRepo,
 		OrganizationRepo:     organizationRepo,
 		EnableSSE:            cfg.EnableSSE,
 		PrometheusBaseURL:    cfg.PrometheusBaseURL,
 		PrometheusTimeout:    time.Duration(cfg.PrometheusTimeout) * time.Millisecond,
+		EmoteURLTemplate:     cfg.EmoteURLTemplate,
+		BadgeURLTemplate:     cfg.BadgeURLTemplate,
 	})
 
 	if err != nil {
@@ -253,7 +394,7 @@ func run() error {
 	logger.Info("ingestion pipeline started")
 
 	// Connect IRC client
-	if err := ircClient.Connect(ctx); err != nil {
+	if err := ircPool.Connect(ctx); err != nil {
 		return fmt.Errorf("failed to connect to IRC: %w", err)
 	}
 	metrics.RecordIRCConnection()
@@ -262,7 +403,7 @@ func run() error {
 	}
 	logger.IRC("connected to Twitch IRC",
 		"mode", cfg.TwitchAuthMode,
-		"anonymous", ircClient.IsAnonymous(),
+		"anonymous", ircPool.IsAnonymous(),
 	)
 
 	// Initialize channel service (loads channels from DB and joins enabled ones)
@@ -297,6 +438,17 @@ func run() error {
 			logger.Info("enabled channel from config", "channel", channel)
 		}
 	}
+	logger.IRC("irc connection pool ready",
+		"connections", len(ircPool.Shards()),
+		"channels", len(ircPool.Channels()),
+		"channels_per_connection", cfg.IRCChannelsPerConnection,
+		"redundant_channels", len(cfg.IRCRedundantChannels),
+	)
+
+	// Purge expired whispers in the background
+	retentionCtx, stopRetention := context.WithCancel(ctx)
+	defer stopRetention()
+	go whisperService.RunRetention(retentionCtx, time.Hour)
 
 	// Start HTTP server in goroutine
 	errChan := make(chan error, 1)
@@ -315,11 +467,15 @@ func run() error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	var runErr error
 	select {
 	case sig := <-sigChan:
 		logger.Info("received shutdown signal", "signal", sig)
 	case err := <-errChan:
 		return fmt.Errorf("HTTP server error: %w", err)
+	case err := <-ircErrChan:
+		// Shut down cleanly so buffered messages are flushed, then fail
+		runErr = fmt.Errorf("IRC stopped: %w", err)
 	}
 
 	// Graceful shutdown
@@ -334,9 +490,14 @@ func run() error {
 	}
 
 	// Disconnect IRC
-	if err := ircClient.Disconnect(); err != nil {
+	if err := ircPool.Disconnect(); err != nil {
 		logger.Error("failed to disconnect IRC", "error", err)
 	}
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			logger.Error("failed to close IRC recorder", "error", err)
+		}
+	}
 	metrics.RecordIRCDisconnection()
 	if otelProvider != nil {
 		otelProvider.RecordIRCDisconnection(ctx)
@@ -354,11 +515,22 @@ func run() error {
 		"messages_ingested", stats.MessagesIngested,
 		"batches_processed", stats.BatchesProcessed,
 		"dropped_messages", stats.DroppedMessages,
+		"duplicate_messages", stats.DuplicateMessages,
+		"irc_joins", stats.IRCJoins,
+		"irc_join_failures", stats.IRCJoinFailures,
+		"irc_reconnect_requests", stats.IRCReconnects,
 		"http_requests", stats.HTTPRequests,
 	)
+	if spool != nil {
+		spoolStats := spool.Stats()
+		logger.Info("ingestion spool left for next run",
+			"records", spoolStats.Records,
+			"bytes", spoolStats.Bytes,
+		)
+	}
 
 	logger.Info("shutdown complete")
-	return nil
+	return runErr
 }
 
 // databaseCountProvider implements observability.DatabaseCountProvider
@@ -380,3 +552,122 @@ func (p *databaseCountProvider) GetUserCount(ctx context.Context) (int64, error)
 func (p *databaseCountProvider) GetChannelCount(ctx context.Context) (int64, error) {
 	return p.channelRepo.GetCount(ctx)
 }
+
+// openDatabase opens the database cfg selects.
+func openDatabase(cfg *config.Config) (repository.Database, error) {
+	switch cfg.DBDriver {
+	case config.DBDriverSQLite:
+		db, err := repository.OpenSQLite(repository.SQLiteDBConfig{
+			Path:      cfg.DBPath,
+			EnableFTS: cfg.EnableFTS,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+		}
+		return db, nil
+	case config.DBDriverPostgres:
+		db, err := repository.OpenPostgres(repository.PostgresDBConfig{
+			Host:     cfg.PGHost,
+			Port:     cfg.PGPort,
+			User:     cfg.PGUser,
+			Password: cfg.PGPassword,
+			Database: cfg.PGDatabase,
+			SSLMode:  cfg.PGSSLMode,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open postgres database: %w", err)
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.DBDriver)
+	}
+}
+
+// pipelineClientConfig returns an IRC client config whose handlers feed
+// chat, moderation, notices, room state and whispers into pipeline.
+func pipelineClientConfig(pipeline *ingestion.Pipeline) irc.ClientConfig {
+	return irc.ClientConfig{
+		OnMessage: func(msg irc.Message) {
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: msg.Channel,
+				Username:    msg.Username,
+				DisplayName: msg.DisplayName,
+				Text:        msg.Text,
+				Tags:        msg.Tags,
+				SentAt:      msg.SentAt,
+				ReceivedAt:  msg.ReceivedAt,
+			})
+		},
+		OnModeration: func(evt irc.ModerationEvent) {
+			pipeline.IngestModeration(ingestion.ModerationEvent{
+				ChannelName:     evt.Channel,
+				Action:          string(evt.Action),
+				TargetUsername:  evt.TargetUsername,
+				TargetMessageID: evt.TargetMessageID,
+				Duration:        evt.Duration,
+				Text:            evt.Text,
+				Tags:            evt.Tags,
+				SentAt:          evt.SentAt,
+				ReceivedAt:      evt.ReceivedAt,
+			})
+		},
+		OnNotice: func(n irc.Notice) {
+			pipeline.IngestNotice(ingestion.Notice{
+				ChannelName: n.Channel,
+				Kind:        n.Kind,
+				Username:    n.Username,
+				DisplayName: n.DisplayName,
+				SystemMsg:   n.SystemMsg,
+				Text:        n.Text,
+				Params:      n.Params,
+				Tags:        n.Tags,
+				ReceivedAt:  n.ReceivedAt,
+			})
+		},
+		OnRoomState: func(rs irc.RoomState) {
+			pipeline.IngestRoomState(ingestion.RoomState{
+				ChannelName:          rs.Channel,
+				RoomID:               rs.RoomID,
+				EmoteOnly:            rs.EmoteOnly,
+				FollowersOnlyMinutes: rs.FollowersOnly,
+				R9K:                  rs.R9K,
+				SlowSeconds:          rs.Slow,
+				SubsOnly:             rs.SubsOnly,
+				ReceivedAt:           rs.ReceivedAt,
+			})
+		},
+		OnWhisper: func(w irc.Whisper) {
+			pipeline.IngestWhisper(ingestion.Whisper{
+				From:            w.From,
+				FromDisplayName: w.FromDisplayName,
+				FromUserID:      w.FromUserID,
+				To:              w.To,
+				Text:            w.Text,
+				MessageID:       w.MessageID,
+				ThreadID:        w.ThreadID,
+				Tags:            w.Tags,
+				ReceivedAt:      w.ReceivedAt,
+			})
+		},
+	}
+}
+
+// overflowPolicy returns the ingestion overflow policy cfg configures.
+func overflowPolicy(cfg *config.Config) ingestion.OverflowPolicy {
+	wait := time.Duration(cfg.OverflowWait) * time.Millisecond
+
+	switch cfg.OverflowPolicy {
+	case config.OverflowBlock:
+		return ingestion.BlockPolicy{Timeout: wait}
+	case config.OverflowPriority:
+		priorities := make(map[string]ingestion.Priority, len(cfg.ChannelPriorities))
+		for _, entry := range cfg.ChannelPriorities {
+			channel, name, _ := strings.Cut(entry, "=")
+			// Validate has checked the priority names
+			priority, _ := ingestion.ParsePriority(name)
+			priorities[strings.TrimPrefix(channel, "#")] = priority
+		}
+		return ingestion.PriorityPolicy{Priorities: priorities, Wait: wait}
+	}
+	return ingestion.DropPolicy{}
+}
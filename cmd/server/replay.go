@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/asabla/goknut/internal/config"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/irc"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// runReplay implements "goknut replay": it feeds a recording of raw IRC
+// traffic back through the IRC client and the ingestion pipeline into the
+// configured database.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	speed := fs.Float64("speed", 1, "Replay speed: 1 is real time, N is N times as fast, 0 is as fast as possible")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goknut replay [flags] <recording file or directory>\n\n")
+		fs.PrintDefaults()
+	}
+
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one recording, got %d", fs.NArg())
+	}
+	if *speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger := observability.NewLogger("goknut")
+	metrics := observability.NewMetrics()
+
+	rec, err := irc.OpenRecording(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer rec.Close()
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	processor := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewChannelRepository(db),
+		ingestion.ProcessorConfig{
+			Logger:         logger,
+			Metrics:        metrics,
+			ModerationRepo: repository.NewModerationRepository(db),
+			NoticeRepo:     repository.NewNoticeRepository(db),
+			RoomStateRepo:  repository.NewChannelStateRepository(db),
+			WhisperRepo:    repository.NewWhisperRepository(db),
+			DeadLetterRepo: repository.NewDeadLetterRepository(db),
+		},
+	)
+	pipeline := ingestion.NewPipeline(
+		ingestion.PipelineConfig{
+			BatchSize:    cfg.BatchSize,
+			FlushTimeout: time.Duration(cfg.FlushTimeout) * time.Millisecond,
+			BufferSize:   cfg.BufferSize,
+			Writers:      cfg.Writers,
+			DedupWindow:  time.Duration(cfg.DedupWindow) * time.Second,
+			Metrics:      metrics,
+			Overflow:     overflowPolicy(cfg),
+
+			FlushRetries:      cfg.FlushRetries,
+			FlushRetryBackoff: time.Duration(cfg.FlushRetryBackoff) * time.Millisecond,
+		},
+		processor,
+	)
+	if err := pipeline.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start ingestion pipeline: %w", err)
+	}
+
+	// The client never connects; replayed lines go straight to its handlers
+	clientCfg := pipelineClientConfig(pipeline)
+	clientCfg.AuthMode = irc.AuthMode(cfg.TwitchAuthMode)
+	clientCfg.Username = cfg.TwitchUsername
+	client := irc.NewClient(clientCfg)
+
+	logger.Info("replaying IRC recording", "path", fs.Arg(0), "speed", *speed)
+	started := time.Now()
+
+	replayed, replayErr := client.ReplayRecording(ctx, rec, irc.ReplayConfig{
+		Speed: *speed,
+		// The pipeline drops what doesn't fit its buffer, and a replay
+		// at speed outruns the writer
+		Wait: func(ctx context.Context) error {
+			return waitForBuffer(ctx, pipeline, cfg.BufferSize/2)
+		},
+	})
+
+	// Let the pipeline catch up before it is stopped; queued items are not
+	// flushed by Stop
+	if err := waitForBuffer(ctx, pipeline, 1); err != nil && replayErr == nil {
+		replayErr = err
+	}
+	if err := pipeline.Stop(); err != nil {
+		logger.Error("failed to stop ingestion pipeline", "error", err)
+	}
+
+	stats := metrics.Stats()
+	logger.Info("replay finished",
+		"lines", replayed,
+		"duration_ms", time.Since(started).Milliseconds(),
+		"messages_ingested", stats.MessagesIngested,
+		"dropped_messages", stats.DroppedMessages,
+		"duplicate_messages", stats.DuplicateMessages,
+	)
+	if replayErr != nil {
+		return fmt.Errorf("replay stopped: %w", replayErr)
+	}
+	return nil
+}
+
+// waitForBuffer blocks until fewer than limit items are queued in pipeline.
+func waitForBuffer(ctx context.Context, pipeline *ingestion.Pipeline, limit int) error {
+	for pipeline.BufferLen() >= max(limit, 1) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return nil
+}
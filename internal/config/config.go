@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -30,6 +31,18 @@ const (
 	DBDriverPostgres DBDriver = "postgres"
 )
 
+// OverflowPolicy is what happens to ingested items when the buffer fills up.
+type OverflowPolicy string
+
+const (
+	// OverflowDrop spools or drops what doesn't fit the buffer at once.
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowBlock makes the IRC read loop wait a bounded time for room.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowPriority sheds low-priority channels first as the buffer fills.
+	OverflowPriority OverflowPolicy = "priority"
+)
+
 // Config holds the application configuration.
 type Config struct {
 	// Database
@@ -51,16 +64,54 @@ type Config struct {
 	PrometheusBaseURL string
 	PrometheusTimeout int // milliseconds
 
+	// Chat rendering: image URL templates; empty renders text only
+	EmoteURLTemplate string // {id}, {name}
+	BadgeURLTemplate string // {name}, {version}
+
 	// Twitch IRC
 	TwitchAuthMode   AuthMode // "authenticated" or "anonymous"
 	TwitchUsername   string   // Required for authenticated mode
 	TwitchOAuthToken string   // Required for authenticated mode (format: oauth:xxx)
 	TwitchChannels   []string
 
+	// IRC server; empty means Twitch
+	IRCServer string // host:port
+	IRCTLS    bool
+
+	// IRC connection pool
+	IRCChannelsPerConnection int      // channels joined per IRC connection
+	IRCMakeBeforeBreak       bool     // overlap connections on RECONNECT so no messages are missed; replace dropped ones without backoff
+	IRCRedundantChannels     []string // channels ingested from two connections at once
+
+	// IRC recording: raw lines written to rotated, compressed files for replay
+	IRCRecordDir        string // empty disables recording
+	IRCRecordMaxMB      int    // uncompressed megabytes per file
+	IRCRecordMaxMinutes int    // minutes per file
+
 	// Ingestion
 	BatchSize    int
 	FlushTimeout int // milliseconds
 	BufferSize   int // ingestion buffer size
+	Writers      int // batches written at once, partitioned by channel
+	DedupWindow  int // seconds message ids are remembered to drop duplicates
+
+	// Retries of a batch the database refused, before it is split to
+	// dead-letter the messages refused on their own
+	FlushRetries      int
+	FlushRetryBackoff int // milliseconds before the first retry, doubling
+
+	// Overflow: what happens to items when the ingestion buffer fills up
+	OverflowPolicy    OverflowPolicy // "drop", "block" or "priority"
+	OverflowWait      int            // milliseconds an item may wait for room (block, and high priority)
+	ChannelPriorities []string       // channel=low|normal|high for the priority policy
+
+	// Spool: batches the database refused, or that overflowed the buffer,
+	// are kept on disk and replayed
+	SpoolDir   string // empty disables the spool
+	SpoolMaxMB int    // megabytes the spool may use on disk
+
+	// Whispers
+	WhisperRetentionDays int // days whispers are kept; 0 keeps them forever
 
 	// Feature flags
 	EnableFTS bool // FTS5 full-text search (SQLite only)
@@ -97,13 +148,36 @@ func DefaultConfig() *Config {
 		PrometheusBaseURL: "http://localhost:9090",
 		PrometheusTimeout: 1500,
 
+		// Chat rendering defaults: Twitch's emote CDN. Badge images are keyed
+		// by an id only the Helix API knows, so badges default to text.
+		EmoteURLTemplate: "https://static-cdn.jtvnw.net/emoticons/v2/{id}/default/dark/1.0",
+
 		// Twitch defaults
 		TwitchAuthMode: AuthModeAuthenticated,
 
+		// IRC server defaults
+		IRCTLS: true,
+
+		// IRC connection pool defaults
+		IRCChannelsPerConnection: 50,
+
+		// IRC recording defaults
+		IRCRecordMaxMB:      64,
+		IRCRecordMaxMinutes: 60,
+
 		// Ingestion defaults
 		BatchSize:    100,
 		FlushTimeout: 100,
 		BufferSize:   10000,
+		Writers:      1,
+		DedupWindow:  300,
+		SpoolMaxMB:   1024,
+
+		OverflowPolicy: OverflowDrop,
+		OverflowWait:   250,
+
+		FlushRetries:      3,
+		FlushRetryBackoff: 100,
 
 		// Feature flags
 		EnableFTS: true,
@@ -124,19 +198,45 @@ func DefaultConfig() *Config {
 // Load reads configuration from flags and environment variables.
 // Environment variables override flags. Returns an error if required fields are missing.
 func Load() (*Config, error) {
+	return LoadFlags(flag.CommandLine, os.Args[1:])
+}
+
+// LoadFlags is Load with the configuration flags defined on fs and parsed
+// from args, so a subcommand can add flags of its own.
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
 	cfg := DefaultConfig()
 
 	// Define flags
-	flag.StringVar(&cfg.DBPath, "db-path", cfg.DBPath, "Path to SQLite database file")
-	flag.StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "HTTP server listen address")
-	flag.IntVar(&cfg.BatchSize, "batch-size", cfg.BatchSize, "Message batch size for ingestion")
-	flag.IntVar(&cfg.FlushTimeout, "flush-timeout", cfg.FlushTimeout, "Batch flush timeout in milliseconds")
-	flag.IntVar(&cfg.BufferSize, "buffer-size", cfg.BufferSize, "Ingestion buffer size")
-	flag.BoolVar(&cfg.EnableFTS, "enable-fts", cfg.EnableFTS, "Enable FTS5 full-text search")
-	flag.StringVar(&cfg.PrometheusBaseURL, "prometheus-base-url", cfg.PrometheusBaseURL, "Prometheus base URL (optional; used for dashboard diagrams)")
-	flag.IntVar(&cfg.PrometheusTimeout, "prometheus-timeout-ms", cfg.PrometheusTimeout, "Prometheus HTTP timeout in milliseconds")
-
-	flag.Parse()
+	fs.StringVar(&cfg.DBPath, "db-path", cfg.DBPath, "Path to SQLite database file")
+	fs.StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "HTTP server listen address")
+	fs.IntVar(&cfg.BatchSize, "batch-size", cfg.BatchSize, "Message batch size for ingestion")
+	fs.IntVar(&cfg.FlushTimeout, "flush-timeout", cfg.FlushTimeout, "Batch flush timeout in milliseconds")
+	fs.IntVar(&cfg.BufferSize, "buffer-size", cfg.BufferSize, "Ingestion buffer size")
+	fs.IntVar(&cfg.Writers, "writers", cfg.Writers, "Message batches written to the database at once, partitioned by channel")
+	fs.IntVar(&cfg.DedupWindow, "dedup-window", cfg.DedupWindow, "Seconds message ids are remembered to drop duplicate messages")
+	fs.IntVar(&cfg.FlushRetries, "flush-retries", cfg.FlushRetries, "Times a batch the database refused is retried before its bad messages are dead-lettered")
+	fs.IntVar(&cfg.FlushRetryBackoff, "flush-retry-backoff", cfg.FlushRetryBackoff, "Milliseconds before the first retry of a refused batch; doubles each retry")
+	fs.StringVar((*string)(&cfg.OverflowPolicy), "overflow-policy", string(cfg.OverflowPolicy), "What happens when the ingestion buffer fills up: drop, block or priority")
+	fs.IntVar(&cfg.OverflowWait, "overflow-wait", cfg.OverflowWait, "Milliseconds an item may wait for room in the ingestion buffer under the block policy, or for a high-priority channel")
+	fs.StringVar(&cfg.SpoolDir, "spool-dir", cfg.SpoolDir, "Directory failed and overflowing batches are spooled to for replay (empty disables the spool)")
+	fs.IntVar(&cfg.SpoolMaxMB, "spool-max-mb", cfg.SpoolMaxMB, "Megabytes the spool may use on disk")
+	fs.IntVar(&cfg.WhisperRetentionDays, "whisper-retention-days", cfg.WhisperRetentionDays, "Days whispers are kept (0 keeps them forever)")
+	fs.BoolVar(&cfg.EnableFTS, "enable-fts", cfg.EnableFTS, "Enable FTS5 full-text search")
+	fs.StringVar(&cfg.IRCServer, "irc-server", cfg.IRCServer, "IRC server address as host:port (empty for Twitch)")
+	fs.BoolVar(&cfg.IRCTLS, "irc-tls", cfg.IRCTLS, "Connect to the IRC server over TLS")
+	fs.IntVar(&cfg.IRCChannelsPerConnection, "irc-channels-per-connection", cfg.IRCChannelsPerConnection, "Maximum channels joined on one IRC connection")
+	fs.StringVar(&cfg.IRCRecordDir, "irc-record-dir", cfg.IRCRecordDir, "Directory raw IRC lines are recorded to for replay (empty disables recording)")
+	fs.IntVar(&cfg.IRCRecordMaxMB, "irc-record-max-mb", cfg.IRCRecordMaxMB, "Uncompressed megabytes written to a recording file before it is rotated")
+	fs.IntVar(&cfg.IRCRecordMaxMinutes, "irc-record-max-minutes", cfg.IRCRecordMaxMinutes, "Minutes a recording file is written to before it is rotated")
+	fs.BoolVar(&cfg.IRCMakeBeforeBreak, "irc-make-before-break", cfg.IRCMakeBeforeBreak, "On RECONNECT, join every channel on a new IRC connection before closing the old one; replace dropped connections without backoff")
+	fs.StringVar(&cfg.PrometheusBaseURL, "prometheus-base-url", cfg.PrometheusBaseURL, "Prometheus base URL (optional; used for dashboard diagrams)")
+	fs.IntVar(&cfg.PrometheusTimeout, "prometheus-timeout-ms", cfg.PrometheusTimeout, "Prometheus HTTP timeout in milliseconds")
+	fs.StringVar(&cfg.EmoteURLTemplate, "emote-url-template", cfg.EmoteURLTemplate, "Emote image URL template with {id} and {name} (empty for text only)")
+	fs.StringVar(&cfg.BadgeURLTemplate, "badge-url-template", cfg.BadgeURLTemplate, "Badge image URL template with {name} and {version} (empty for text only)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
 
 	// Override with environment variables
 	if v := os.Getenv("DB_PATH"); v != "" {
@@ -153,6 +253,13 @@ func Load() (*Config, error) {
 			cfg.PrometheusTimeout = timeout
 		}
 	}
+	// Set but empty disables images, so look these up rather than test for ""
+	if v, ok := os.LookupEnv("EMOTE_URL_TEMPLATE"); ok {
+		cfg.EmoteURLTemplate = v
+	}
+	if v, ok := os.LookupEnv("BADGE_URL_TEMPLATE"); ok {
+		cfg.BadgeURLTemplate = v
+	}
 	if v := os.Getenv("TWITCH_USERNAME"); v != "" {
 		cfg.TwitchUsername = v
 	}
@@ -166,6 +273,42 @@ func Load() (*Config, error) {
 		}
 		cfg.TwitchChannels = channels
 	}
+	if v := os.Getenv("IRC_SERVER"); v != "" {
+		cfg.IRCServer = v
+	}
+	if v := os.Getenv("IRC_TLS"); v != "" {
+		cfg.IRCTLS = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("IRC_CHANNELS_PER_CONNECTION"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.IRCChannelsPerConnection = n
+		}
+	}
+	if v := os.Getenv("IRC_MAKE_BEFORE_BREAK"); v != "" {
+		cfg.IRCMakeBeforeBreak = strings.ToLower(v) == "true" || v == "1"
+	}
+	if v := os.Getenv("IRC_RECORD_DIR"); v != "" {
+		cfg.IRCRecordDir = v
+	}
+	if v := os.Getenv("IRC_RECORD_MAX_MB"); v != "" {
+		var mb int
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb > 0 {
+			cfg.IRCRecordMaxMB = mb
+		}
+	}
+	if v := os.Getenv("IRC_RECORD_MAX_MINUTES"); v != "" {
+		var minutes int
+		if _, err := fmt.Sscanf(v, "%d", &minutes); err == nil && minutes > 0 {
+			cfg.IRCRecordMaxMinutes = minutes
+		}
+	}
+	if v := os.Getenv("IRC_REDUNDANT_CHANNELS"); v != "" {
+		channels := strings.Split(v, ",")
+		for i, ch := range channels {
+			channels[i] = strings.TrimSpace(strings.ToLower(ch))
+		}
+		cfg.IRCRedundantChannels = channels
+	}
 	if v := os.Getenv("BATCH_SIZE"); v != "" {
 		var size int
 		if _, err := fmt.Sscanf(v, "%d", &size); err == nil && size > 0 {
@@ -184,6 +327,61 @@ func Load() (*Config, error) {
 			cfg.BufferSize = size
 		}
 	}
+	if v := os.Getenv("WRITERS"); v != "" {
+		var writers int
+		if _, err := fmt.Sscanf(v, "%d", &writers); err == nil && writers > 0 {
+			cfg.Writers = writers
+		}
+	}
+	if v := os.Getenv("DEDUP_WINDOW"); v != "" {
+		var window int
+		if _, err := fmt.Sscanf(v, "%d", &window); err == nil && window > 0 {
+			cfg.DedupWindow = window
+		}
+	}
+	if v := os.Getenv("FLUSH_RETRIES"); v != "" {
+		var retries int
+		if _, err := fmt.Sscanf(v, "%d", &retries); err == nil && retries >= 0 {
+			cfg.FlushRetries = retries
+		}
+	}
+	if v := os.Getenv("FLUSH_RETRY_BACKOFF"); v != "" {
+		var backoff int
+		if _, err := fmt.Sscanf(v, "%d", &backoff); err == nil && backoff > 0 {
+			cfg.FlushRetryBackoff = backoff
+		}
+	}
+	if v := os.Getenv("OVERFLOW_POLICY"); v != "" {
+		cfg.OverflowPolicy = OverflowPolicy(strings.ToLower(v))
+	}
+	if v := os.Getenv("OVERFLOW_WAIT"); v != "" {
+		var wait int
+		if _, err := fmt.Sscanf(v, "%d", &wait); err == nil && wait >= 0 {
+			cfg.OverflowWait = wait
+		}
+	}
+	if v := os.Getenv("CHANNEL_PRIORITIES"); v != "" {
+		priorities := strings.Split(v, ",")
+		for i, p := range priorities {
+			priorities[i] = strings.TrimSpace(strings.ToLower(p))
+		}
+		cfg.ChannelPriorities = priorities
+	}
+	if v := os.Getenv("SPOOL_DIR"); v != "" {
+		cfg.SpoolDir = v
+	}
+	if v := os.Getenv("SPOOL_MAX_MB"); v != "" {
+		var mb int
+		if _, err := fmt.Sscanf(v, "%d", &mb); err == nil && mb > 0 {
+			cfg.SpoolMaxMB = mb
+		}
+	}
+	if v := os.Getenv("WHISPER_RETENTION_DAYS"); v != "" {
+		var days int
+		if _, err := fmt.Sscanf(v, "%d", &days); err == nil && days >= 0 {
+			cfg.WhisperRetentionDays = days
+		}
+	}
 	if v := os.Getenv("ENABLE_FTS"); v != "" {
 		cfg.EnableFTS = strings.ToLower(v) == "true" || v == "1"
 	}
@@ -336,6 +534,20 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Sprintf("invalid auth mode: %s", c.TwitchAuthMode))
 	}
 
+	if c.IRCServer != "" {
+		if _, _, err := net.SplitHostPort(c.IRCServer); err != nil {
+			errs = append(errs, fmt.Sprintf("irc-server must be host:port: %v", err))
+		}
+	}
+	if c.IRCChannelsPerConnection < 0 {
+		errs = append(errs, "irc-channels-per-connection must not be negative")
+	}
+	if c.IRCRecordDir != "" && c.IRCRecordMaxMB <= 0 {
+		errs = append(errs, "irc-record-max-mb must be positive when recording")
+	}
+	if c.IRCRecordDir != "" && c.IRCRecordMaxMinutes <= 0 {
+		errs = append(errs, "irc-record-max-minutes must be positive when recording")
+	}
 	if c.BatchSize <= 0 {
 		errs = append(errs, "batch-size must be positive")
 	}
@@ -345,6 +557,49 @@ func (c *Config) Validate() error {
 	if c.BufferSize <= 0 {
 		errs = append(errs, "buffer-size must be positive")
 	}
+	if c.Writers < 0 {
+		errs = append(errs, "writers must not be negative")
+	}
+	if c.DedupWindow < 0 {
+		errs = append(errs, "dedup-window must not be negative")
+	}
+	if c.FlushRetries < 0 {
+		errs = append(errs, "flush-retries must not be negative")
+	}
+	if c.FlushRetries > 0 && c.FlushRetryBackoff <= 0 {
+		errs = append(errs, "flush-retry-backoff must be positive when retrying")
+	}
+	switch c.OverflowPolicy {
+	case "", OverflowDrop:
+	case OverflowBlock:
+		if c.OverflowWait <= 0 {
+			errs = append(errs, "overflow-wait must be positive for the block overflow policy")
+		}
+	case OverflowPriority:
+		if c.OverflowWait < 0 {
+			errs = append(errs, "overflow-wait must not be negative")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("invalid overflow policy: %s (must be drop, block or priority)", c.OverflowPolicy))
+	}
+	for _, p := range c.ChannelPriorities {
+		channel, priority, ok := strings.Cut(p, "=")
+		if !ok || channel == "" {
+			errs = append(errs, fmt.Sprintf("CHANNEL_PRIORITIES entry %q must be channel=priority", p))
+			continue
+		}
+		switch priority {
+		case "low", "normal", "high":
+		default:
+			errs = append(errs, fmt.Sprintf("CHANNEL_PRIORITIES entry %q must have priority low, normal or high", p))
+		}
+	}
+	if c.SpoolDir != "" && c.SpoolMaxMB <= 0 {
+		errs = append(errs, "spool-max-mb must be positive when spooling")
+	}
+	if c.WhisperRetentionDays < 0 {
+		errs = append(errs, "whisper-retention-days must not be negative")
+	}
 
 	// OTel validation
 	if c.OTelEnabled {
@@ -50,15 +50,76 @@ var (
 
 // Channel represents a channel in API responses.
 type Channel struct {
-	ID                    int64      `json:"id"`
-	Name                  string     `json:"name"`
-	DisplayName           string     `json:"display_name"`
-	Enabled               bool       `json:"enabled"`
-	RetainHistoryOnDelete bool       `json:"retain_history_on_delete"`
-	CreatedAt             time.Time  `json:"created_at"`
-	UpdatedAt             time.Time  `json:"updated_at"`
-	LastMessageAt         *time.Time `json:"last_message_at,omitempty"`
-	TotalMessages         int64      `json:"total_messages"`
+	ID                    int64         `json:"id"`
+	Name                  string        `json:"name"`
+	DisplayName           string        `json:"display_name"`
+	Enabled               bool          `json:"enabled"`
+	RetainHistoryOnDelete bool          `json:"retain_history_on_delete"`
+	CreatedAt             time.Time     `json:"created_at"`
+	UpdatedAt             time.Time     `json:"updated_at"`
+	LastMessageAt         *time.Time    `json:"last_message_at,omitempty"`
+	TotalMessages         int64         `json:"total_messages"`
+	TwitchRoomID          string        `json:"twitch_room_id,omitempty"`
+	State                 *ChannelState `json:"state,omitempty"`
+	JoinState             string        `json:"join_state,omitempty"` // pending, requested, joined or failed
+	JoinError             string        `json:"join_error,omitempty"`
+	JoinAttempts          int           `json:"join_attempts,omitempty"`
+}
+
+// ChannelRename is a detected broadcaster rename: two channels sharing a
+// Twitch room-id. From is the older name and can be merged into Into.
+type ChannelRename struct {
+	RoomID string  `json:"room_id"`
+	From   Channel `json:"from"`
+	Into   Channel `json:"into"`
+}
+
+// ChannelState is a channel's chat mode settings (ROOMSTATE) over a time range.
+// Settings that have not been observed yet are omitted.
+type ChannelState struct {
+	EmoteOnly            *bool      `json:"emote_only,omitempty"`
+	FollowersOnlyMinutes *int       `json:"followers_only_minutes,omitempty"` // -1 = off
+	R9K                  *bool      `json:"r9k,omitempty"`
+	SlowSeconds          *int       `json:"slow_seconds,omitempty"` // 0 = off
+	SubsOnly             *bool      `json:"subs_only,omitempty"`
+	StartedAt            time.Time  `json:"started_at"`
+	EndedAt              *time.Time `json:"ended_at,omitempty"`
+}
+
+// EmoteStat is the usage of one emote.
+type EmoteStat struct {
+	EmoteID    string    `json:"emote_id"`
+	Name       string    `json:"name"`
+	Uses       int64     `json:"uses"`
+	Messages   int64     `json:"messages"`
+	Users      int64     `json:"users"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// BitsTotal is the sum of cheers in a channel or by a user.
+type BitsTotal struct {
+	Bits     int64      `json:"bits"`
+	Cheers   int64      `json:"cheers"`
+	Users    int64      `json:"users"`
+	Channels int64      `json:"channels"`
+	LastAt   *time.Time `json:"last_at,omitempty"`
+}
+
+// BitsLeader is a leaderboard entry: a cheering user or a cheered channel.
+type BitsLeader struct {
+	Rank        int       `json:"rank"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Bits        int64     `json:"bits"`
+	Cheers      int64     `json:"cheers"`
+	LastAt      time.Time `json:"last_at"`
+}
+
+// BitsDay is the cheering on one UTC day.
+type BitsDay struct {
+	Day    string `json:"day"` // YYYY-MM-DD
+	Bits   int64  `json:"bits"`
+	Cheers int64  `json:"cheers"`
 }
 
 // CreateChannelRequest is the request body for creating a channel.
@@ -94,6 +155,11 @@ type UpdateChannelRequest struct {
 	RetainHistoryOnDelete *bool   `json:"retain_history_on_delete,omitempty"`
 }
 
+// MergeChannelRequest is the request body for merging a channel into another.
+type MergeChannelRequest struct {
+	Into string `json:"into"`
+}
+
 // DeleteChannelRequest is the request body for deleting a channel.
 type DeleteChannelRequest struct {
 	RetainHistory bool `json:"retain_history"`
@@ -101,24 +167,110 @@ type DeleteChannelRequest struct {
 
 // User represents a user in API responses.
 type User struct {
-	ID            int64     `json:"id"`
-	Username      string    `json:"username"`
-	DisplayName   string    `json:"display_name,omitempty"`
-	FirstSeenAt   time.Time `json:"first_seen_at"`
-	LastSeenAt    time.Time `json:"last_seen_at"`
-	TotalMessages int64     `json:"total_messages"`
+	ID            int64            `json:"id"`
+	Username      string           `json:"username"`
+	DisplayName   string           `json:"display_name,omitempty"`
+	TwitchUserID  string           `json:"twitch_user_id,omitempty"`
+	FirstSeenAt   time.Time        `json:"first_seen_at"`
+	LastSeenAt    time.Time        `json:"last_seen_at"`
+	TotalMessages int64            `json:"total_messages"`
+	PreviousNames []UserNameChange `json:"previous_names,omitempty"`
+}
+
+// UserNameChange is a name a user was previously known by.
+type UserNameChange struct {
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	ChangedAt   time.Time `json:"changed_at"`
 }
 
 // Message represents a message in API responses.
 type Message struct {
+	ID          int64             `json:"id"`
+	ChannelID   int64             `json:"channel_id"`
+	ChannelName string            `json:"channel_name,omitempty"`
+	UserID      int64             `json:"user_id"`
+	Username    string            `json:"username,omitempty"`
+	DisplayName string            `json:"display_name,omitempty"`
+	Text        string            `json:"text"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Bits        int               `json:"bits,omitempty"`
+	SentAt      time.Time         `json:"sent_at"`
+	DeletedAt   *time.Time        `json:"deleted_at,omitempty"`
+	Outbound    bool              `json:"outbound,omitempty"`
+}
+
+// SendMessageRequest is the request body for sending a chat message.
+type SendMessageRequest struct {
+	Text    string `json:"text"`
+	ReplyTo string `json:"reply_to,omitempty"` // Twitch id of the message replied to
+}
+
+// SentMessage is a chat message sent from the web UI.
+type SentMessage struct {
+	Channel  string    `json:"channel"`
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	ReplyTo  string    `json:"reply_to,omitempty"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// WhisperConversation summarizes the whispers with one counterpart.
+type WhisperConversation struct {
+	Counterpart   string    `json:"counterpart"`
+	DisplayName   string    `json:"display_name,omitempty"`
+	TotalWhispers int64     `json:"total_whispers"`
+	LastText      string    `json:"last_text"`
+	LastAt        time.Time `json:"last_at"`
+}
+
+// Whisper represents a whisper to the archiver's account in API responses.
+type Whisper struct {
 	ID          int64     `json:"id"`
-	ChannelID   int64     `json:"channel_id"`
-	ChannelName string    `json:"channel_name,omitempty"`
-	UserID      int64     `json:"user_id"`
-	Username    string    `json:"username,omitempty"`
+	Counterpart string    `json:"counterpart"`
 	DisplayName string    `json:"display_name,omitempty"`
 	Text        string    `json:"text"`
-	SentAt      time.Time `json:"sent_at"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// DeadLetter represents a message the database refused, in API responses.
+type DeadLetter struct {
+	ID            int64     `json:"id"`
+	ChannelName   string    `json:"channel_name"`
+	Username      string    `json:"username"`
+	Text          string    `json:"text"`
+	Payload       string    `json:"payload"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
+
+// ModerationEvent represents a timeout, ban, chat clear or message deletion.
+type ModerationEvent struct {
+	ID              int64     `json:"id"`
+	ChannelID       int64     `json:"channel_id"`
+	ChannelName     string    `json:"channel_name"`
+	Action          string    `json:"action"`
+	TargetUsername  string    `json:"target_username,omitempty"`
+	TargetMessageID string    `json:"target_message_id,omitempty"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	Text            string    `json:"text,omitempty"`
+	OccurredAt      time.Time `json:"occurred_at"`
+}
+
+// Notice represents a USERNOTICE event (sub, resub, gift sub, raid, announcement).
+type Notice struct {
+	ID            int64             `json:"id"`
+	ChannelID     int64             `json:"channel_id"`
+	ChannelName   string            `json:"channel_name"`
+	Kind          string            `json:"kind"`
+	Username      string            `json:"username,omitempty"`
+	DisplayName   string            `json:"display_name,omitempty"`
+	SystemMessage string            `json:"system_message,omitempty"`
+	Text          string            `json:"text,omitempty"`
+	Params        map[string]string `json:"params,omitempty"`
+	OccurredAt    time.Time         `json:"occurred_at"`
 }
 
 // PaginationRequest holds pagination parameters.
@@ -163,6 +315,7 @@ type SearchMessagesRequest struct {
 	Query       string     `json:"q"`
 	ChannelName *string    `json:"channel,omitempty"`
 	Username    *string    `json:"username,omitempty"`
+	Emote       *string    `json:"emote,omitempty"`
 	StartTime   *time.Time `json:"start,omitempty"`
 	EndTime     *time.Time `json:"end,omitempty"`
 	PaginationRequest
@@ -173,7 +326,7 @@ func (r *SearchMessagesRequest) Validate() error {
 	originalQuery := r.Query
 	r.Query = strings.TrimSpace(r.Query)
 	// Query is required only if no other filters are set
-	hasFilters := r.ChannelName != nil || r.Username != nil || r.StartTime != nil || r.EndTime != nil
+	hasFilters := r.ChannelName != nil || r.Username != nil || r.Emote != nil || r.StartTime != nil || r.EndTime != nil
 	if r.Query != "" {
 		if len(r.Query) < 2 {
 			return ErrSearchQueryTooShort
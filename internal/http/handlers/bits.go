@@ -0,0 +1,211 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// BitsHandler serves cheer (bits) leaderboards for channels and users.
+type BitsHandler struct {
+	channelRepo *repository.ChannelRepository
+	userRepo    *repository.UserRepository
+	bitsRepo    *repository.BitsRepository
+	templates   *template.Template
+	logger      *observability.Logger
+}
+
+// NewBitsHandler creates a new bits handler.
+func NewBitsHandler(
+	channelRepo *repository.ChannelRepository,
+	userRepo *repository.UserRepository,
+	bitsRepo *repository.BitsRepository,
+	templates *template.Template,
+	logger *observability.Logger,
+) *BitsHandler {
+	return &BitsHandler{
+		channelRepo: channelRepo,
+		userRepo:    userRepo,
+		bitsRepo:    bitsRepo,
+		templates:   templates,
+		logger:      logger,
+	}
+}
+
+// RegisterRoutes registers bits routes on the mux.
+func (h *BitsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /channels/{name}/bits", h.handleChannelBits)
+	mux.HandleFunc("GET /users/{username}/bits", h.handleUserBits)
+}
+
+// handleChannelBits returns a channel's top cheerers and daily bits.
+func (h *BitsHandler) handleChannelBits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.channelRepo.GetByName(ctx, name)
+	if err != nil {
+		h.logger.Error("failed to get channel", "name", name, "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	if channel == nil {
+		h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	filter := repository.BitsFilter{ChannelID: &channel.ID}
+	h.renderLeaderboard(w, r, filter, "/channels/"+channel.Name+"/bits", false)
+}
+
+// handleUserBits returns the channels a user cheered in and their daily bits.
+func (h *BitsHandler) handleUserBits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	username := r.PathValue("username")
+	if username == "" {
+		h.renderError(w, r, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.ResolveUsername(ctx, username)
+	if err != nil {
+		h.logger.Error("failed to get user", "username", username, "error", err)
+		h.renderError(w, r, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		h.renderError(w, r, "User not found", http.StatusNotFound)
+		return
+	}
+
+	filter := repository.BitsFilter{UserID: &user.ID}
+	h.renderLeaderboard(w, r, filter, "/users/"+username+"/bits", true)
+}
+
+// renderLeaderboard applies the window and limit query parameters to
+// filter and renders totals, a leaderboard and daily bits as an HTML
+// fragment or JSON. The leaderboard ranks channels when byChannel is set
+// and cheering users otherwise.
+func (h *BitsHandler) renderLeaderboard(w http.ResponseWriter, r *http.Request, filter repository.BitsFilter, baseURL string, byChannel bool) {
+	ctx := r.Context()
+
+	window, since := parseStatWindow(r, "30d")
+	filter.Since = since
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	filter.Limit = limit
+
+	total, err := h.bitsRepo.Totals(ctx, filter)
+	if err != nil {
+		h.logger.Error("failed to get bits totals", "error", err)
+		h.renderError(w, r, "Failed to load bits", http.StatusInternalServerError)
+		return
+	}
+
+	var leaders []dto.BitsLeader
+	if byChannel {
+		channels, err := h.bitsRepo.TopChannels(ctx, filter)
+		if err != nil {
+			h.logger.Error("failed to get top bits channels", "error", err)
+			h.renderError(w, r, "Failed to load bits", http.StatusInternalServerError)
+			return
+		}
+		for i, c := range channels {
+			leaders = append(leaders, dto.BitsLeader{Rank: i + 1, Name: c.ChannelName, Bits: c.Bits, Cheers: c.Cheers, LastAt: c.LastAt})
+		}
+	} else {
+		cheerers, err := h.bitsRepo.TopCheerers(ctx, filter)
+		if err != nil {
+			h.logger.Error("failed to get top cheerers", "error", err)
+			h.renderError(w, r, "Failed to load bits", http.StatusInternalServerError)
+			return
+		}
+		for i, c := range cheerers {
+			leaders = append(leaders, dto.BitsLeader{
+				Rank: i + 1, Name: c.Username, DisplayName: c.DisplayName, Bits: c.Bits, Cheers: c.Cheers, LastAt: c.LastAt,
+			})
+		}
+	}
+
+	// Daily totals cover at most the 30 most recent days with cheers
+	filter.Limit = 30
+	days, err := h.bitsRepo.Daily(ctx, filter)
+	if err != nil {
+		h.logger.Error("failed to get daily bits", "error", err)
+		h.renderError(w, r, "Failed to load bits", http.StatusInternalServerError)
+		return
+	}
+
+	totalDTO := dto.BitsTotal{Bits: total.Bits, Cheers: total.Cheers, Users: total.Users, Channels: total.Channels}
+	if !total.LastAt.IsZero() {
+		totalDTO.LastAt = &total.LastAt
+	}
+	dayDTOs := make([]dto.BitsDay, len(days))
+	for i, d := range days {
+		dayDTOs[i] = dto.BitsDay{Day: d.Day.Format("2006-01-02"), Bits: d.Bits, Cheers: d.Cheers}
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"window":      window,
+			"total":       totalDTO,
+			"leaderboard": leaders,
+			"daily":       dayDTOs,
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Total":     totalDTO,
+		"Leaders":   leaders,
+		"Daily":     dayDTOs,
+		"IsEmpty":   total.Cheers == 0,
+		"ByChannel": byChannel,
+		"Window":    window,
+		"Windows":   statWindowNames,
+		"BaseURL":   baseURL,
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "bits/leaderboard.html", data); err != nil {
+		h.logger.Error("failed to render bits leaderboard template", "error", err)
+	}
+}
+
+func (h *BitsHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *BitsHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
@@ -0,0 +1,183 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// ChannelStateHandler serves a channel's chat mode (ROOMSTATE) history.
+type ChannelStateHandler struct {
+	channelRepo *repository.ChannelRepository
+	stateRepo   *repository.ChannelStateRepository
+	templates   *template.Template
+	logger      *observability.Logger
+}
+
+// NewChannelStateHandler creates a new channel state handler.
+func NewChannelStateHandler(
+	channelRepo *repository.ChannelRepository,
+	stateRepo *repository.ChannelStateRepository,
+	templates *template.Template,
+	logger *observability.Logger,
+) *ChannelStateHandler {
+	return &ChannelStateHandler{
+		channelRepo: channelRepo,
+		stateRepo:   stateRepo,
+		templates:   templates,
+		logger:      logger,
+	}
+}
+
+// RegisterRoutes registers channel state routes on the mux.
+func (h *ChannelStateHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /channels/{name}/states", h.handleChannelStates)
+}
+
+// channelStateView is a timeline entry with its modes rendered as labels.
+type channelStateView struct {
+	dto.ChannelState
+	ID      int64
+	Current bool
+	Modes   []string
+}
+
+// handleChannelStates returns the chat mode timeline for a channel as an
+// HTML fragment or JSON.
+func (h *ChannelStateHandler) handleChannelStates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.channelRepo.GetByName(ctx, name)
+	if err != nil {
+		h.logger.Error("failed to get channel", "name", name, "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	if channel == nil {
+		h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	states, err := h.stateRepo.ListByChannel(ctx, channel.ID, limit)
+	if err != nil {
+		h.logger.Error("failed to list channel states", "channel_id", channel.ID, "error", err)
+		h.renderError(w, r, "Failed to load chat modes", http.StatusInternalServerError)
+		return
+	}
+
+	stateDTOs := make([]dto.ChannelState, len(states))
+	for i := range states {
+		stateDTOs[i] = *channelStateToDTO(&states[i])
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"current": channelStateToDTO(channel.State),
+			"states":  stateDTOs,
+		})
+		return
+	}
+
+	views := make([]channelStateView, len(states))
+	for i, s := range states {
+		views[i] = channelStateView{
+			ChannelState: stateDTOs[i],
+			ID:           s.ID,
+			Current:      s.EndedAt == nil,
+			Modes:        channelStateModes(stateDTOs[i]),
+		}
+	}
+
+	data := map[string]any{
+		"States":      views,
+		"IsEmpty":     len(views) == 0,
+		"ChannelName": channel.Name,
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "channels/states.html", data); err != nil {
+		h.logger.Error("failed to render channel states template", "error", err)
+	}
+}
+
+// channelStateToDTO converts a repository channel state, returning nil for nil.
+func channelStateToDTO(s *repository.ChannelState) *dto.ChannelState {
+	if s == nil {
+		return nil
+	}
+	return &dto.ChannelState{
+		EmoteOnly:            s.EmoteOnly,
+		FollowersOnlyMinutes: s.FollowersOnlyMinutes,
+		R9K:                  s.R9K,
+		SlowSeconds:          s.SlowSeconds,
+		SubsOnly:             s.SubsOnly,
+		StartedAt:            s.StartedAt,
+		EndedAt:              s.EndedAt,
+	}
+}
+
+// channelStateModes returns a label for each restriction that is enabled.
+func channelStateModes(s dto.ChannelState) []string {
+	var modes []string
+	if s.EmoteOnly != nil && *s.EmoteOnly {
+		modes = append(modes, "emote-only")
+	}
+	if s.FollowersOnlyMinutes != nil && *s.FollowersOnlyMinutes >= 0 {
+		if *s.FollowersOnlyMinutes == 0 {
+			modes = append(modes, "followers-only")
+		} else {
+			modes = append(modes, fmt.Sprintf("followers-only %dm", *s.FollowersOnlyMinutes))
+		}
+	}
+	if s.R9K != nil && *s.R9K {
+		modes = append(modes, "unique-chat")
+	}
+	if s.SlowSeconds != nil && *s.SlowSeconds > 0 {
+		modes = append(modes, fmt.Sprintf("slow %ds", *s.SlowSeconds))
+	}
+	if s.SubsOnly != nil && *s.SubsOnly {
+		modes = append(modes, "subs-only")
+	}
+	return modes
+}
+
+func (h *ChannelStateHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *ChannelStateHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
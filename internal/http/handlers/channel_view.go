@@ -3,30 +3,37 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/irc"
 	"github.com/asabla/goknut/internal/observability"
 	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/services"
 )
 
 // ChannelViewHandler handles channel view and message stream requests.
 type ChannelViewHandler struct {
 	channelRepo *repository.ChannelRepository
 	messageRepo *repository.MessageRepository
+	chat        *services.ChatService
 	templates   *template.Template
 	logger      *observability.Logger
 	metrics     *observability.Metrics
 }
 
-// NewChannelViewHandler creates a new channel view handler.
+// NewChannelViewHandler creates a new channel view handler. chat may be
+// nil, which leaves the view read-only.
 func NewChannelViewHandler(
 	channelRepo *repository.ChannelRepository,
 	messageRepo *repository.MessageRepository,
+	chat *services.ChatService,
 	templates *template.Template,
 	logger *observability.Logger,
 	metrics *observability.Metrics,
@@ -34,6 +41,7 @@ func NewChannelViewHandler(
 	return &ChannelViewHandler{
 		channelRepo: channelRepo,
 		messageRepo: messageRepo,
+		chat:        chat,
 		templates:   templates,
 		logger:      logger,
 		metrics:     metrics,
@@ -45,6 +53,7 @@ func (h *ChannelViewHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /channels/{name}/view", h.handleChannelView)
 	mux.HandleFunc("GET /channels/{name}/messages", h.handleMessages)
 	mux.HandleFunc("GET /channels/{name}/messages/stream", h.handleMessageStream)
+	mux.HandleFunc("POST /channels/{name}/messages", h.handleSend)
 }
 
 // handleChannelView renders the main channel view page with recent messages.
@@ -95,6 +104,8 @@ func (h *ChannelViewHandler) handleChannelView(w http.ResponseWriter, r *http.Re
 		"LatestID":  latestID,
 		"IsEmpty":   len(messageDTOs) == 0,
 		"PollDelay": 1000, // 1 second polling interval
+		"CanSend":   h.chat.CanSend(),
+		"MaxLength": irc.MaxMessageLength,
 	}
 
 	if h.wantsJSON(r) {
@@ -276,6 +287,87 @@ func (h *ChannelViewHandler) handleMessageStream(w http.ResponseWriter, r *http.
 	}
 }
 
+// handleSend sends a chat message, or a reply when reply_to holds the
+// Twitch id of the parent message. The message shows up in the stream once
+// the pipeline has stored it.
+func (h *ChannelViewHandler) handleSend(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.SendMessageRequest
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.renderError(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Text = r.FormValue("text")
+		req.ReplyTo = r.FormValue("reply_to")
+	}
+
+	sent, err := h.chat.Send(ctx, name, req.ReplyTo, req.Text)
+	if err != nil {
+		var rateErr *irc.RateLimitError
+		switch {
+		case errors.Is(err, services.ErrChatUnavailable), errors.Is(err, irc.ErrReadOnly):
+			h.renderSendError(w, r, "Sending chat needs an authenticated Twitch connection", http.StatusForbidden)
+		case errors.Is(err, irc.ErrInvalidMessage), errors.Is(err, services.ErrInvalidChannelName):
+			h.renderSendError(w, r, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, irc.ErrNotJoined):
+			h.renderSendError(w, r, "Channel is not joined", http.StatusConflict)
+		case errors.As(err, &rateErr):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateErr.RetryAfter.Seconds()))))
+			h.renderSendError(w, r, err.Error(), http.StatusTooManyRequests)
+		default:
+			h.logger.Error("failed to send chat message", "channel", name, "error", err)
+			h.renderSendError(w, r, "Failed to send message", http.StatusBadGateway)
+		}
+		return
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(dto.SentMessage{
+			Channel:  sent.Channel,
+			Username: sent.Username,
+			Text:     sent.Text,
+			ReplyTo:  sent.ParentMsgID,
+			SentAt:   sent.SentAt,
+		})
+		return
+	}
+
+	// Tells the compose form to clear itself
+	w.Header().Set("HX-Trigger", "chat-sent")
+	if err := h.templates.ExecuteTemplate(w, "live/compose_status.html", map[string]any{
+		"Sent": true,
+	}); err != nil {
+		h.logger.Error("failed to render compose status template", "error", err)
+	}
+}
+
+// renderSendError renders a send failure. HTMX requests get a status
+// fragment with a 200 so it is swapped into the compose box.
+func (h *ChannelViewHandler) renderSendError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) || r.Header.Get("HX-Request") != "true" {
+		h.renderError(w, r, message, status)
+		return
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "live/compose_status.html", map[string]any{
+		"Error": message,
+	}); err != nil {
+		h.logger.Error("failed to render compose status template", "error", err)
+	}
+}
+
 func (h *ChannelViewHandler) parsePagination(r *http.Request) (page, pageSize int) {
 	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
 	pageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
@@ -301,6 +393,8 @@ func (h *ChannelViewHandler) channelToDTO(ch *repository.Channel) dto.Channel {
 		UpdatedAt:             ch.UpdatedAt,
 		LastMessageAt:         ch.LastMessageAt,
 		TotalMessages:         ch.TotalMessages,
+		TwitchRoomID:          ch.TwitchRoomID,
+		State:                 channelStateToDTO(ch.State),
 	}
 }
 
@@ -315,7 +409,11 @@ func (h *ChannelViewHandler) messagesToDTOs(messages []repository.Message) []dto
 			Username:    msg.Username,
 			DisplayName: msg.DisplayName,
 			Text:        msg.Text,
+			Tags:        msg.Tags,
+			Bits:        msg.Bits,
 			SentAt:      msg.SentAt,
+			DeletedAt:   msg.DeletedAt,
+			Outbound:    msg.Outbound,
 		}
 	}
 	return dtos
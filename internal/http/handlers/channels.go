@@ -39,6 +39,7 @@ func (h *ChannelHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /channels/{name}", h.handleGet)
 	mux.HandleFunc("POST /channels/{name}", h.handleUpdate)
 	mux.HandleFunc("POST /channels/{name}/delete", h.handleDelete)
+	mux.HandleFunc("POST /channels/{name}/merge", h.handleMerge)
 }
 
 func (h *ChannelHandler) handleList(w http.ResponseWriter, r *http.Request) {
@@ -64,13 +65,30 @@ func (h *ChannelHandler) handleList(w http.ResponseWriter, r *http.Request) {
 			UpdatedAt:             ch.UpdatedAt,
 			LastMessageAt:         ch.LastMessageAt,
 			TotalMessages:         ch.TotalMessages,
+			TwitchRoomID:          ch.TwitchRoomID,
+			State:                 channelStateToDTO(ch.State),
+		}
+		h.setJoinStatus(&channelDTOs[i])
+	}
+
+	// Renames are a hint for the admin; failing to detect them isn't fatal
+	renames, err := h.service.ListRenames(ctx)
+	if err != nil {
+		h.logger.Error("failed to list channel renames", "error", err)
+	}
+	renameDTOs := make([]dto.ChannelRename, len(renames))
+	for i, rn := range renames {
+		renameDTOs[i] = dto.ChannelRename{
+			RoomID: rn.RoomID,
+			From:   dto.Channel{ID: rn.From.ID, Name: rn.From.Name, DisplayName: rn.From.DisplayName, TotalMessages: rn.From.TotalMessages},
+			Into:   dto.Channel{ID: rn.Into.ID, Name: rn.Into.Name, DisplayName: rn.Into.DisplayName, TotalMessages: rn.Into.TotalMessages},
 		}
 	}
 
 	// Respond based on Accept header or HX-Request
 	if h.wantsJSON(r) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"channels": channelDTOs})
+		json.NewEncoder(w).Encode(map[string]any{"channels": channelDTOs, "renames": renameDTOs})
 		return
 	}
 
@@ -78,6 +96,7 @@ func (h *ChannelHandler) handleList(w http.ResponseWriter, r *http.Request) {
 	data := map[string]any{
 		"Channels": channelDTOs,
 		"IsEmpty":  len(channelDTOs) == 0,
+		"Renames":  renameDTOs,
 	}
 
 	if h.isHTMXRequest(r) {
@@ -140,6 +159,7 @@ func (h *ChannelHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:             ch.UpdatedAt,
 		TotalMessages:         ch.TotalMessages,
 	}
+	h.setJoinStatus(&channelDTO)
 
 	if h.wantsJSON(r) {
 		w.Header().Set("Content-Type", "application/json")
@@ -191,7 +211,10 @@ func (h *ChannelHandler) handleGet(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:             ch.UpdatedAt,
 		LastMessageAt:         ch.LastMessageAt,
 		TotalMessages:         ch.TotalMessages,
+		TwitchRoomID:          ch.TwitchRoomID,
+		State:                 channelStateToDTO(ch.State),
 	}
+	h.setJoinStatus(&channelDTO)
 
 	if h.wantsJSON(r) {
 		w.Header().Set("Content-Type", "application/json")
@@ -269,7 +292,10 @@ func (h *ChannelHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:             ch.UpdatedAt,
 		LastMessageAt:         ch.LastMessageAt,
 		TotalMessages:         ch.TotalMessages,
+		TwitchRoomID:          ch.TwitchRoomID,
+		State:                 channelStateToDTO(ch.State),
 	}
+	h.setJoinStatus(&channelDTO)
 
 	if h.wantsJSON(r) {
 		w.Header().Set("Content-Type", "application/json")
@@ -345,6 +371,103 @@ func (h *ChannelHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/channels", http.StatusSeeOther)
 }
 
+// handleMerge merges the channel in the path into the channel named by the
+// "into" field, e.g. after a broadcaster rename.
+func (h *ChannelHandler) handleMerge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.MergeChannelRequest
+
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.renderError(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		r.ParseForm()
+		req.Into = r.FormValue("into")
+	}
+	if req.Into == "" {
+		h.renderError(w, r, "Target channel is required", http.StatusBadRequest)
+		return
+	}
+
+	from, err := h.service.GetByName(ctx, name)
+	if err != nil {
+		h.logger.Error("failed to get channel", "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	into, err := h.service.GetByName(ctx, req.Into)
+	if err != nil {
+		h.logger.Error("failed to get channel", "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	if from == nil || into == nil {
+		h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	merged, err := h.service.Merge(ctx, from.ID, into.ID)
+	if err != nil {
+		switch err {
+		case services.ErrChannelNotFound:
+			h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		case services.ErrChannelRoomMismatch:
+			h.renderError(w, r, "Channels belong to different Twitch rooms", http.StatusConflict)
+		default:
+			h.logger.Error("failed to merge channels", "from", from.Name, "into", into.Name, "error", err)
+			h.renderError(w, r, "Failed to merge channels", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dto.Channel{
+			ID:                    merged.ID,
+			Name:                  merged.Name,
+			DisplayName:           merged.DisplayName,
+			Enabled:               merged.Enabled,
+			RetainHistoryOnDelete: merged.RetainHistoryOnDelete,
+			CreatedAt:             merged.CreatedAt,
+			UpdatedAt:             merged.UpdatedAt,
+			LastMessageAt:         merged.LastMessageAt,
+			TotalMessages:         merged.TotalMessages,
+			TwitchRoomID:          merged.TwitchRoomID,
+			State:                 channelStateToDTO(merged.State),
+		})
+		return
+	}
+
+	if h.isHTMXRequest(r) {
+		w.Header().Set("HX-Redirect", "/channels/"+merged.Name)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, "/channels/"+merged.Name, http.StatusSeeOther)
+}
+
+// setJoinStatus fills in the IRC join state of an enabled channel.
+func (h *ChannelHandler) setJoinStatus(ch *dto.Channel) {
+	if !ch.Enabled {
+		return
+	}
+	if status, ok := h.service.JoinStatus(ch.Name); ok {
+		ch.JoinState = string(status.State)
+		ch.JoinError = status.Error
+		ch.JoinAttempts = status.Attempts
+	}
+}
+
 func (h *ChannelHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
 	if h.wantsJSON(r) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,214 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/services"
+)
+
+// deadLetterPageSize is how many dead letters a page lists.
+const deadLetterPageSize = 50
+
+// DeadLetterHandler serves the operator page for messages the database
+// refused.
+type DeadLetterHandler struct {
+	service   *services.DeadLetterService
+	templates *template.Template
+	logger    *observability.Logger
+}
+
+// NewDeadLetterHandler creates a new dead letter handler.
+func NewDeadLetterHandler(
+	service *services.DeadLetterService,
+	templates *template.Template,
+	logger *observability.Logger,
+) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		service:   service,
+		templates: templates,
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes registers dead letter routes on the mux.
+func (h *DeadLetterHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/dead-letters", h.handleList)
+	mux.HandleFunc("POST /admin/dead-letters/redrive", h.handleRedriveAll)
+	mux.HandleFunc("POST /admin/dead-letters/{id}/redrive", h.handleRedrive)
+	mux.HandleFunc("POST /admin/dead-letters/{id}/delete", h.handleDelete)
+}
+
+// handleList lists dead letters oldest first, a page at a time.
+func (h *DeadLetterHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	afterID, _ := strconv.ParseInt(query.Get("after"), 10, 64)
+
+	deadLetters, err := h.service.List(ctx, afterID, deadLetterPageSize)
+	if err != nil {
+		h.logger.Error("failed to list dead letters", "error", err)
+		h.renderError(w, r, "Failed to load dead letters", http.StatusInternalServerError)
+		return
+	}
+	total, err := h.service.Count(ctx)
+	if err != nil {
+		h.logger.Error("failed to count dead letters", "error", err)
+		h.renderError(w, r, "Failed to load dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	deadLetterDTOs := make([]dto.DeadLetter, len(deadLetters))
+	for i, dl := range deadLetters {
+		deadLetterDTOs[i] = dto.DeadLetter{
+			ID:            dl.ID,
+			ChannelName:   dl.ChannelName,
+			Username:      dl.Username,
+			Text:          dl.Text,
+			Payload:       dl.Payload,
+			Error:         dl.Error,
+			Attempts:      dl.Attempts,
+			FirstFailedAt: dl.FirstFailedAt,
+			LastFailedAt:  dl.LastFailedAt,
+		}
+	}
+
+	var nextAfter int64
+	if len(deadLetterDTOs) == deadLetterPageSize {
+		nextAfter = deadLetterDTOs[len(deadLetterDTOs)-1].ID
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"dead_letters": deadLetterDTOs,
+			"total":        total,
+			"next_after":   nextAfter,
+		})
+		return
+	}
+
+	// Set by the redirect after a redrive
+	redriven, _ := strconv.Atoi(query.Get("redriven"))
+	failed, _ := strconv.Atoi(query.Get("failed"))
+
+	data := map[string]any{
+		"DeadLetters": deadLetterDTOs,
+		"Total":       total,
+		"IsEmpty":     len(deadLetterDTOs) == 0,
+		"NextAfter":   nextAfter,
+		"Paged":       afterID > 0,
+		"Redriven":    redriven,
+		"Failed":      failed,
+		"ShowResult":  query.Has("redriven") || query.Has("failed"),
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "admin/dead_letters", data); err != nil {
+		h.logger.Error("failed to render dead letters template", "error", err)
+	}
+}
+
+// handleRedrive stores one dead letter again.
+func (h *DeadLetterHandler) handleRedrive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		h.renderError(w, r, "Invalid dead letter ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.Redrive(r.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrDeadLetterNotFound):
+		h.renderError(w, r, "Dead letter not found", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrRedriveFailed):
+		h.renderRedriveResult(w, r, 0, 1)
+		return
+	case err != nil:
+		h.logger.Error("failed to redrive dead letter", "id", id, "error", err)
+		h.renderError(w, r, "Failed to redrive dead letter", http.StatusInternalServerError)
+		return
+	}
+	h.renderRedriveResult(w, r, 1, 0)
+}
+
+// handleRedriveAll stores every dead letter again.
+func (h *DeadLetterHandler) handleRedriveAll(w http.ResponseWriter, r *http.Request) {
+	redriven, failed, err := h.service.RedriveAll(r.Context())
+	if err != nil {
+		h.logger.Error("failed to redrive dead letters", "redriven", redriven, "error", err)
+		h.renderError(w, r, "Failed to redrive dead letters", http.StatusInternalServerError)
+		return
+	}
+	h.renderRedriveResult(w, r, redriven, failed)
+}
+
+// handleDelete discards a dead letter without storing it.
+func (h *DeadLetterHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		h.renderError(w, r, "Invalid dead letter ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Discard(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrDeadLetterNotFound) {
+			h.renderError(w, r, "Dead letter not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete dead letter", "id", id, "error", err)
+		h.renderError(w, r, "Failed to delete dead letter", http.StatusInternalServerError)
+		return
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+		return
+	}
+	http.Redirect(w, r, "/admin/dead-letters", http.StatusSeeOther)
+}
+
+// renderRedriveResult reports how many dead letters were stored and how
+// many were refused again; the page shows the counts after the redirect.
+func (h *DeadLetterHandler) renderRedriveResult(w http.ResponseWriter, r *http.Request, redriven, failed int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{
+			"redriven": redriven,
+			"failed":   failed,
+		})
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/admin/dead-letters?redriven=%d&failed=%d", redriven, failed), http.StatusSeeOther)
+}
+
+func (h *DeadLetterHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *DeadLetterHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
@@ -0,0 +1,203 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// statWindows maps the supported window parameter values of the stats
+// fragments to durations. A zero duration means all time.
+var statWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"all": 0,
+}
+
+// statWindowNames lists the stat windows in display order.
+var statWindowNames = []string{"24h", "7d", "30d", "all"}
+
+// EmoteHandler serves emote usage statistics for channels and users.
+type EmoteHandler struct {
+	channelRepo *repository.ChannelRepository
+	userRepo    *repository.UserRepository
+	emoteRepo   *repository.EmoteRepository
+	templates   *template.Template
+	logger      *observability.Logger
+}
+
+// NewEmoteHandler creates a new emote handler.
+func NewEmoteHandler(
+	channelRepo *repository.ChannelRepository,
+	userRepo *repository.UserRepository,
+	emoteRepo *repository.EmoteRepository,
+	templates *template.Template,
+	logger *observability.Logger,
+) *EmoteHandler {
+	return &EmoteHandler{
+		channelRepo: channelRepo,
+		userRepo:    userRepo,
+		emoteRepo:   emoteRepo,
+		templates:   templates,
+		logger:      logger,
+	}
+}
+
+// RegisterRoutes registers emote routes on the mux.
+func (h *EmoteHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /channels/{name}/emotes", h.handleChannelEmotes)
+	mux.HandleFunc("GET /users/{username}/emotes", h.handleUserEmotes)
+}
+
+// handleChannelEmotes returns the top emotes used in a channel.
+func (h *EmoteHandler) handleChannelEmotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.channelRepo.GetByName(ctx, name)
+	if err != nil {
+		h.logger.Error("failed to get channel", "name", name, "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	if channel == nil {
+		h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	filter := repository.EmoteStatsFilter{ChannelID: &channel.ID}
+	h.renderTopEmotes(w, r, filter, "/channels/"+channel.Name+"/emotes", url.Values{"channel": {channel.Name}})
+}
+
+// handleUserEmotes returns the top emotes used by a user across channels.
+func (h *EmoteHandler) handleUserEmotes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	username := r.PathValue("username")
+	if username == "" {
+		h.renderError(w, r, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.ResolveUsername(ctx, username)
+	if err != nil {
+		h.logger.Error("failed to get user", "username", username, "error", err)
+		h.renderError(w, r, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		h.renderError(w, r, "User not found", http.StatusNotFound)
+		return
+	}
+
+	filter := repository.EmoteStatsFilter{UserID: &user.ID}
+	h.renderTopEmotes(w, r, filter, "/users/"+username+"/emotes", url.Values{"username": {user.Username}})
+}
+
+// renderTopEmotes applies the window and limit query parameters to filter
+// and renders the result as an HTML fragment or JSON. searchQuery scopes
+// the links from each emote to the message search.
+func (h *EmoteHandler) renderTopEmotes(w http.ResponseWriter, r *http.Request, filter repository.EmoteStatsFilter, baseURL string, searchQuery url.Values) {
+	window, since := parseStatWindow(r, "7d")
+	filter.Since = since
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	filter.Limit = limit
+
+	stats, err := h.emoteRepo.TopEmotes(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to get top emotes", "error", err)
+		h.renderError(w, r, "Failed to load emotes", http.StatusInternalServerError)
+		return
+	}
+
+	statDTOs := make([]dto.EmoteStat, len(stats))
+	for i, s := range stats {
+		statDTOs[i] = dto.EmoteStat{
+			EmoteID:    s.EmoteID,
+			Name:       s.Name,
+			Uses:       s.Uses,
+			Messages:   s.Messages,
+			Users:      s.Users,
+			LastUsedAt: s.LastUsedAt,
+		}
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"window": window,
+			"emotes": statDTOs,
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Emotes":      statDTOs,
+		"IsEmpty":     len(statDTOs) == 0,
+		"Window":      window,
+		"Windows":     statWindowNames,
+		"BaseURL":     baseURL,
+		"SearchQuery": searchQuery.Encode(),
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "emotes/top.html", data); err != nil {
+		h.logger.Error("failed to render top emotes template", "error", err)
+	}
+}
+
+// parseStatWindow reads the window query parameter, falling back to def,
+// and returns the window name and its start (nil for all time).
+func parseStatWindow(r *http.Request, def string) (string, *time.Time) {
+	window := r.URL.Query().Get("window")
+	duration, ok := statWindows[window]
+	if !ok {
+		window = def
+		duration = statWindows[window]
+	}
+	if duration == 0 {
+		return window, nil
+	}
+	since := time.Now().UTC().Add(-duration)
+	return window, &since
+}
+
+func (h *EmoteHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *EmoteHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
@@ -14,10 +14,20 @@ import (
 	"sync"
 	"time"
 
+	"github.com/asabla/goknut/internal/ingestion"
 	"github.com/asabla/goknut/internal/observability"
 	"github.com/asabla/goknut/internal/repository"
 )
 
+// homeOverflowChannels is how many channels the summary lists overflow for.
+const homeOverflowChannels = 5
+
+// OverflowStatsSource reports the ingestion overflow policy and what it
+// turned away, e.g. *ingestion.Pipeline.
+type OverflowStatsSource interface {
+	OverflowStats() ingestion.OverflowStats
+}
+
 // HomeDashboardHandler serves the dashboard fragments embedded on the home page.
 //
 // In the foundational phase this handler only returns placeholder HTML.
@@ -30,6 +40,8 @@ type HomeDashboardHandler struct {
 	channelRepo *repository.ChannelRepository
 	userRepo    *repository.UserRepository
 
+	overflow OverflowStatsSource
+
 	httpClient *http.Client
 
 	prometheusBaseURL string
@@ -45,6 +57,7 @@ func NewHomeDashboardHandler(
 	messageRepo *repository.MessageRepository,
 	channelRepo *repository.ChannelRepository,
 	userRepo *repository.UserRepository,
+	overflow OverflowStatsSource,
 	prometheusBaseURL string,
 	prometheusTimeout time.Duration,
 ) *HomeDashboardHandler {
@@ -54,6 +67,7 @@ func NewHomeDashboardHandler(
 		messageRepo:       messageRepo,
 		channelRepo:       channelRepo,
 		userRepo:          userRepo,
+		overflow:          overflow,
 		httpClient:        &http.Client{Timeout: 30 * time.Second},
 		prometheusBaseURL: prometheusBaseURL,
 		prometheusTimeout: prometheusTimeout,
@@ -89,11 +103,22 @@ func (h *HomeDashboardHandler) logDashboardDegraded(fragment string, errors []st
 
 type homeSummaryData struct {
 	Snapshot homeKPISnapshot
+	Overflow *homeOverflowSummary // Nil without a pipeline
+}
+
+// homeOverflowSummary is the ingestion overflow policy and the channels
+// that lost the most to it.
+type homeOverflowSummary struct {
+	Policy   string
+	Dropped  int64
+	Spooled  int64
+	Channels []ingestion.ChannelOverflow
+	More     int // Channels with overflow not listed
 }
 
 func (h *HomeDashboardHandler) handleSummary(w http.ResponseWriter, r *http.Request) {
 	snapshot := h.buildKPISnapshot(r.Context())
-	data := homeSummaryData{Snapshot: snapshot}
+	data := homeSummaryData{Snapshot: snapshot, Overflow: h.buildOverflowSummary()}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := h.templates.ExecuteTemplate(w, "dashboard/home_summary", data); err != nil {
@@ -216,6 +241,25 @@ func (h *HomeDashboardHandler) buildKPISnapshot(ctx context.Context) homeKPISnap
 	return snapshot
 }
 
+func (h *HomeDashboardHandler) buildOverflowSummary() *homeOverflowSummary {
+	if h.overflow == nil {
+		return nil
+	}
+
+	stats := h.overflow.OverflowStats()
+	summary := &homeOverflowSummary{Policy: stats.Policy}
+	for _, channel := range stats.Channels {
+		summary.Dropped += channel.Dropped
+		summary.Spooled += channel.Spooled
+	}
+	summary.Channels = stats.Channels
+	if len(summary.Channels) > homeOverflowChannels {
+		summary.More = len(summary.Channels) - homeOverflowChannels
+		summary.Channels = summary.Channels[:homeOverflowChannels]
+	}
+	return summary
+}
+
 type PromPoint struct {
 	Timestamp time.Time
 	Value     float64
@@ -0,0 +1,159 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// ModerationHandler serves archived moderation events for channels and users.
+type ModerationHandler struct {
+	channelRepo    *repository.ChannelRepository
+	moderationRepo *repository.ModerationRepository
+	templates      *template.Template
+	logger         *observability.Logger
+}
+
+// NewModerationHandler creates a new moderation handler.
+func NewModerationHandler(
+	channelRepo *repository.ChannelRepository,
+	moderationRepo *repository.ModerationRepository,
+	templates *template.Template,
+	logger *observability.Logger,
+) *ModerationHandler {
+	return &ModerationHandler{
+		channelRepo:    channelRepo,
+		moderationRepo: moderationRepo,
+		templates:      templates,
+		logger:         logger,
+	}
+}
+
+// RegisterRoutes registers moderation routes on the mux.
+func (h *ModerationHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /channels/{name}/moderation", h.handleChannelModeration)
+	mux.HandleFunc("GET /users/{username}/moderation", h.handleUserModeration)
+}
+
+// handleChannelModeration returns recent moderation events for a channel.
+func (h *ModerationHandler) handleChannelModeration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.channelRepo.GetByName(ctx, name)
+	if err != nil {
+		h.logger.Error("failed to get channel", "name", name, "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	if channel == nil {
+		h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	events, err := h.moderationRepo.ListByChannel(ctx, channel.ID, h.parseLimit(r))
+	if err != nil {
+		h.logger.Error("failed to list moderation events", "channel_id", channel.ID, "error", err)
+		h.renderError(w, r, "Failed to load moderation events", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, events, "channel")
+}
+
+// handleUserModeration returns recent moderation events targeting a user.
+func (h *ModerationHandler) handleUserModeration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	username := strings.ToLower(strings.TrimSpace(r.PathValue("username")))
+	if username == "" {
+		h.renderError(w, r, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.moderationRepo.ListByUsername(ctx, username, h.parseLimit(r))
+	if err != nil {
+		h.logger.Error("failed to list moderation events", "username", username, "error", err)
+		h.renderError(w, r, "Failed to load moderation events", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, r, events, "user")
+}
+
+func (h *ModerationHandler) render(w http.ResponseWriter, r *http.Request, events []repository.ModerationEvent, scope string) {
+	eventDTOs := make([]dto.ModerationEvent, len(events))
+	for i, evt := range events {
+		eventDTOs[i] = dto.ModerationEvent{
+			ID:              evt.ID,
+			ChannelID:       evt.ChannelID,
+			ChannelName:     evt.ChannelName,
+			Action:          evt.Action,
+			TargetUsername:  evt.TargetUsername,
+			TargetMessageID: evt.TargetMessageID,
+			DurationSeconds: evt.DurationSeconds,
+			Text:            evt.Text,
+			OccurredAt:      evt.OccurredAt,
+		}
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"events": eventDTOs,
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Events":  eventDTOs,
+		"IsEmpty": len(eventDTOs) == 0,
+		"Scope":   scope,
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "moderation/list.html", data); err != nil {
+		h.logger.Error("failed to render moderation template", "error", err)
+	}
+}
+
+func (h *ModerationHandler) parseLimit(r *http.Request) int {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return limit
+}
+
+func (h *ModerationHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *ModerationHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
@@ -0,0 +1,163 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// NoticeKindCount is the number of notices of a single kind, used for filters.
+type NoticeKindCount struct {
+	Kind  string `json:"kind"`
+	Count int64  `json:"count"`
+}
+
+// NoticeHandler serves archived USERNOTICE events for a channel.
+type NoticeHandler struct {
+	channelRepo *repository.ChannelRepository
+	noticeRepo  *repository.NoticeRepository
+	templates   *template.Template
+	logger      *observability.Logger
+}
+
+// NewNoticeHandler creates a new notice handler.
+func NewNoticeHandler(
+	channelRepo *repository.ChannelRepository,
+	noticeRepo *repository.NoticeRepository,
+	templates *template.Template,
+	logger *observability.Logger,
+) *NoticeHandler {
+	return &NoticeHandler{
+		channelRepo: channelRepo,
+		noticeRepo:  noticeRepo,
+		templates:   templates,
+		logger:      logger,
+	}
+}
+
+// RegisterRoutes registers notice routes on the mux.
+func (h *NoticeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /channels/{name}/notices", h.handleChannelNotices)
+}
+
+// handleChannelNotices returns recent notices for a channel, optionally
+// filtered by ?kind=, as an HTML fragment or JSON.
+func (h *NoticeHandler) handleChannelNotices(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.PathValue("name")
+	if name == "" {
+		h.renderError(w, r, "Invalid channel name", http.StatusBadRequest)
+		return
+	}
+
+	channel, err := h.channelRepo.GetByName(ctx, name)
+	if err != nil {
+		h.logger.Error("failed to get channel", "name", name, "error", err)
+		h.renderError(w, r, "Failed to load channel", http.StatusInternalServerError)
+		return
+	}
+	if channel == nil {
+		h.renderError(w, r, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("kind")))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	notices, err := h.noticeRepo.ListByChannel(ctx, channel.ID, kind, limit)
+	if err != nil {
+		h.logger.Error("failed to list notices", "channel_id", channel.ID, "error", err)
+		h.renderError(w, r, "Failed to load notices", http.StatusInternalServerError)
+		return
+	}
+
+	counts, err := h.noticeRepo.CountByKind(ctx, channel.ID)
+	if err != nil {
+		h.logger.Error("failed to count notices", "channel_id", channel.ID, "error", err)
+		h.renderError(w, r, "Failed to load notices", http.StatusInternalServerError)
+		return
+	}
+
+	kinds := make([]NoticeKindCount, 0, len(counts))
+	for k, c := range counts {
+		kinds = append(kinds, NoticeKindCount{Kind: k, Count: c})
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if kinds[i].Count != kinds[j].Count {
+			return kinds[i].Count > kinds[j].Count
+		}
+		return kinds[i].Kind < kinds[j].Kind
+	})
+
+	noticeDTOs := make([]dto.Notice, len(notices))
+	for i, n := range notices {
+		noticeDTOs[i] = dto.Notice{
+			ID:            n.ID,
+			ChannelID:     n.ChannelID,
+			ChannelName:   n.ChannelName,
+			Kind:          n.Kind,
+			Username:      n.Username,
+			DisplayName:   n.DisplayName,
+			SystemMessage: n.SystemMessage,
+			Text:          n.Text,
+			Params:        n.Params,
+			OccurredAt:    n.OccurredAt,
+		}
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"notices": noticeDTOs,
+			"kinds":   kinds,
+			"kind":    kind,
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Notices":     noticeDTOs,
+		"Kinds":       kinds,
+		"Kind":        kind,
+		"IsEmpty":     len(noticeDTOs) == 0,
+		"ChannelName": channel.Name,
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "notices/list.html", data); err != nil {
+		h.logger.Error("failed to render notices template", "error", err)
+	}
+}
+
+func (h *NoticeHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *NoticeHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
@@ -586,6 +586,8 @@ func (h *ProfileHandler) profileDetailTemplateData(
 			UpdatedAt:             ch.UpdatedAt,
 			LastMessageAt:         ch.LastMessageAt,
 			TotalMessages:         ch.TotalMessages,
+			TwitchRoomID:          ch.TwitchRoomID,
+			State:                 channelStateToDTO(ch.State),
 		})
 	}
 
@@ -601,6 +603,8 @@ func (h *ProfileHandler) profileDetailTemplateData(
 			UpdatedAt:             ch.UpdatedAt,
 			LastMessageAt:         ch.LastMessageAt,
 			TotalMessages:         ch.TotalMessages,
+			TwitchRoomID:          ch.TwitchRoomID,
+			State:                 channelStateToDTO(ch.State),
 		})
 	}
 
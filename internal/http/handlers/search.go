@@ -139,14 +139,25 @@ func (h *SearchHandler) handleUserProfile(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	var previousNames []dto.UserNameChange
+	for _, n := range profile.PreviousNames {
+		previousNames = append(previousNames, dto.UserNameChange{
+			Username:    n.Username,
+			DisplayName: n.DisplayName,
+			ChangedAt:   n.ChangedAt,
+		})
+	}
+
 	data := map[string]any{
 		"User": dto.User{
 			ID:            profile.ID,
 			Username:      profile.Username,
 			DisplayName:   profile.DisplayName,
+			TwitchUserID:  profile.TwitchUserID,
 			FirstSeenAt:   profile.FirstSeenAt,
 			LastSeenAt:    profile.LastSeenAt,
 			TotalMessages: profile.TotalMessages,
+			PreviousNames: previousNames,
 		},
 		"Channels": profile.Channels,
 	}
@@ -198,6 +209,7 @@ func (h *SearchHandler) handleUserMessages(w http.ResponseWriter, r *http.Reques
 			Username:    m.Username,
 			DisplayName: m.DisplayName,
 			Text:        m.Text,
+			Tags:        m.Tags,
 			SentAt:      m.SentAt,
 		})
 	}
@@ -257,10 +269,10 @@ func (h *SearchHandler) handleMessages(w http.ResponseWriter, r *http.Request) {
 	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
 
 	// Parse optional filters (always parse to preserve in form)
-	var channelName, username *string
+	var channelName, username, emote *string
 	var startTime, endTime *time.Time
 	var startStr, endStr string
-	var channelStr, usernameStr string
+	var channelStr, usernameStr, emoteStr string
 
 	if ch := strings.TrimSpace(r.URL.Query().Get("channel")); ch != "" {
 		channelName = &ch
@@ -272,6 +284,11 @@ func (h *SearchHandler) handleMessages(w http.ResponseWriter, r *http.Request) {
 		usernameStr = u
 	}
 
+	if e := strings.TrimSpace(r.URL.Query().Get("emote")); e != "" {
+		emote = &e
+		emoteStr = e
+	}
+
 	if start := r.URL.Query().Get("start"); start != "" {
 		startStr = start
 		if t, err := time.Parse("2006-01-02", start); err == nil {
@@ -289,17 +306,17 @@ func (h *SearchHandler) handleMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if any filters are set (besides query)
-	hasFilters := channelName != nil || username != nil || startTime != nil || endTime != nil
+	hasFilters := channelName != nil || username != nil || emote != nil || startTime != nil || endTime != nil
 
 	// If no query and no filters, show recent messages
 	if query == "" && !hasFilters {
 		result, err := h.service.GetRecentMessages(ctx, page, pageSize)
 		if err != nil {
 			h.logger.Error("failed to get recent messages", "error", err)
-			h.renderMessagesPage(w, r, nil, query, channelStr, usernameStr, startStr, endStr, "Failed to load recent messages. Please try again.")
+			h.renderMessagesPage(w, r, nil, query, channelStr, usernameStr, emoteStr, startStr, endStr, "Failed to load recent messages. Please try again.")
 			return
 		}
-		h.renderMessagesPage(w, r, result, query, channelStr, usernameStr, startStr, endStr, "")
+		h.renderMessagesPage(w, r, result, query, channelStr, usernameStr, emoteStr, startStr, endStr, "")
 		return
 	}
 
@@ -307,6 +324,7 @@ func (h *SearchHandler) handleMessages(w http.ResponseWriter, r *http.Request) {
 		Query:       query,
 		ChannelName: channelName,
 		Username:    username,
+		Emote:       emote,
 		StartTime:   startTime,
 		EndTime:     endTime,
 		PaginationRequest: dto.PaginationRequest{
@@ -317,21 +335,21 @@ func (h *SearchHandler) handleMessages(w http.ResponseWriter, r *http.Request) {
 
 	// Validate request (includes query length and time range validation)
 	if err := req.Validate(); err != nil {
-		h.renderMessagesPage(w, r, nil, query, channelStr, usernameStr, startStr, endStr, err.Error())
+		h.renderMessagesPage(w, r, nil, query, channelStr, usernameStr, emoteStr, startStr, endStr, err.Error())
 		return
 	}
 
 	result, err := h.service.SearchMessages(ctx, req)
 	if err != nil {
 		h.logger.Error("failed to search messages", "query", query, "error", err)
-		h.renderMessagesPage(w, r, nil, query, channelStr, usernameStr, startStr, endStr, "Failed to search messages. Please try again.")
+		h.renderMessagesPage(w, r, nil, query, channelStr, usernameStr, emoteStr, startStr, endStr, "Failed to search messages. Please try again.")
 		return
 	}
 
-	h.renderMessagesPage(w, r, result, query, channelStr, usernameStr, startStr, endStr, "")
+	h.renderMessagesPage(w, r, result, query, channelStr, usernameStr, emoteStr, startStr, endStr, "")
 }
 
-func (h *SearchHandler) renderMessagesPage(w http.ResponseWriter, r *http.Request, result *services.MessageSearchResult, query string, channel, username, startStr, endStr string, errorMsg string) {
+func (h *SearchHandler) renderMessagesPage(w http.ResponseWriter, r *http.Request, result *services.MessageSearchResult, query string, channel, username, emote, startStr, endStr string, errorMsg string) {
 	var messages []MessageWithHighlight
 	if result != nil {
 		for _, m := range result.Messages {
@@ -344,6 +362,7 @@ func (h *SearchHandler) renderMessagesPage(w http.ResponseWriter, r *http.Reques
 					Username:    m.Username,
 					DisplayName: m.DisplayName,
 					Text:        m.Text,
+					Tags:        m.Tags,
 					SentAt:      m.SentAt,
 				},
 				HighlightedText: template.HTML(m.HighlightedText),
@@ -367,7 +386,7 @@ func (h *SearchHandler) renderMessagesPage(w http.ResponseWriter, r *http.Reques
 	data := map[string]any{
 		"Query":      query,
 		"Messages":   messages,
-		"IsEmpty":    len(messages) == 0 && (query != "" || channel != "" || username != "") && errorMsg == "",
+		"IsEmpty":    len(messages) == 0 && (query != "" || channel != "" || username != "" || emote != "") && errorMsg == "",
 		"HasQuery":   query != "",
 		"Page":       page,
 		"TotalPages": totalPages,
@@ -378,6 +397,7 @@ func (h *SearchHandler) renderMessagesPage(w http.ResponseWriter, r *http.Reques
 		"PrevPage":   page - 1,
 		"Channel":    channel,
 		"Username":   username,
+		"Emote":      emote,
 		"StartStr":   startStr,
 		"EndStr":     endStr,
 		"Error":      errorMsg,
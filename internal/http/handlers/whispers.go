@@ -0,0 +1,168 @@
+// Package handlers provides HTTP handlers for the web UI.
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/services"
+)
+
+// WhisperHandler serves whispers to the archiver's own account.
+type WhisperHandler struct {
+	service   *services.WhisperService
+	templates *template.Template
+	logger    *observability.Logger
+}
+
+// NewWhisperHandler creates a new whisper handler.
+func NewWhisperHandler(
+	service *services.WhisperService,
+	templates *template.Template,
+	logger *observability.Logger,
+) *WhisperHandler {
+	return &WhisperHandler{
+		service:   service,
+		templates: templates,
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes registers whisper routes on the mux.
+func (h *WhisperHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /whispers", h.handleConversations)
+	mux.HandleFunc("GET /whispers/{username}", h.handleConversation)
+}
+
+// handleConversations lists one conversation per counterpart.
+func (h *WhisperHandler) handleConversations(w http.ResponseWriter, r *http.Request) {
+	conversations, err := h.service.Conversations(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list whisper conversations", "error", err)
+		h.renderError(w, r, "Failed to load whispers", http.StatusInternalServerError)
+		return
+	}
+
+	conversationDTOs := make([]dto.WhisperConversation, len(conversations))
+	for i, c := range conversations {
+		conversationDTOs[i] = dto.WhisperConversation{
+			Counterpart:   c.Counterpart,
+			DisplayName:   c.CounterpartDisplayName,
+			TotalWhispers: c.TotalWhispers,
+			LastText:      c.LastText,
+			LastAt:        c.LastAt,
+		}
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"conversations":  conversationDTOs,
+			"retention_days": h.retentionDays(),
+		})
+		return
+	}
+
+	data := map[string]any{
+		"Conversations": conversationDTOs,
+		"IsEmpty":       len(conversationDTOs) == 0,
+		"RetentionDays": h.retentionDays(),
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "whispers/index", data); err != nil {
+		h.logger.Error("failed to render whispers template", "error", err)
+	}
+}
+
+// handleConversation shows the latest whispers with one counterpart.
+func (h *WhisperHandler) handleConversation(w http.ResponseWriter, r *http.Request) {
+	username := strings.ToLower(strings.TrimSpace(r.PathValue("username")))
+	if username == "" {
+		h.renderError(w, r, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	whispers, err := h.service.Conversation(r.Context(), username, limit)
+	if err != nil {
+		h.logger.Error("failed to list whispers", "counterpart", username, "error", err)
+		h.renderError(w, r, "Failed to load whispers", http.StatusInternalServerError)
+		return
+	}
+	if len(whispers) == 0 {
+		h.renderError(w, r, "No whispers with "+username, http.StatusNotFound)
+		return
+	}
+
+	whisperDTOs := make([]dto.Whisper, len(whispers))
+	for i, wh := range whispers {
+		whisperDTOs[i] = dto.Whisper{
+			ID:          wh.ID,
+			Counterpart: wh.Counterpart,
+			DisplayName: wh.CounterpartDisplayName,
+			Text:        wh.Text,
+			ReceivedAt:  wh.ReceivedAt,
+		}
+	}
+
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"counterpart": username,
+			"whispers":    whisperDTOs,
+		})
+		return
+	}
+
+	// The latest display name is the one to show
+	displayName := whisperDTOs[len(whisperDTOs)-1].DisplayName
+	if displayName == "" {
+		displayName = username
+	}
+	data := map[string]any{
+		"Counterpart":   username,
+		"DisplayName":   displayName,
+		"Whispers":      whisperDTOs,
+		"RetentionDays": h.retentionDays(),
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "whispers/conversation", data); err != nil {
+		h.logger.Error("failed to render whisper conversation template", "error", err)
+	}
+}
+
+// retentionDays returns the whisper retention in whole days; 0 is forever.
+func (h *WhisperHandler) retentionDays() int {
+	return int(h.service.Retention().Hours() / 24)
+}
+
+func (h *WhisperHandler) renderError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if h.wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "error.html", map[string]any{
+		"Title":   http.StatusText(status),
+		"Message": message,
+	}); err != nil {
+		h.logger.Error("failed to render error template", "error", err)
+	}
+}
+
+func (h *WhisperHandler) wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json")
+}
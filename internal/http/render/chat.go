@@ -0,0 +1,144 @@
+// Package render turns stored chat messages into HTML for the web UI.
+package render
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+// badgeLabels are short text labels for common badges, used when no badge
+// URL template is configured.
+var badgeLabels = map[string]string{
+	"broadcaster":  "BC",
+	"moderator":    "MOD",
+	"vip":          "VIP",
+	"subscriber":   "SUB",
+	"founder":      "FOUNDER",
+	"staff":        "STAFF",
+	"admin":        "ADMIN",
+	"global_mod":   "GMOD",
+	"partner":      "PARTNER",
+	"artist-badge": "ARTIST",
+}
+
+// Chat renders message text and badges from the stored IRC tags.
+//
+// EmoteURL and BadgeURL are URL templates. EmoteURL may use {id} and
+// {name}; BadgeURL may use {name} and {version}. An empty template
+// renders text instead of images, which works without network access.
+type Chat struct {
+	EmoteURL string
+	BadgeURL string
+}
+
+// NewChat creates a chat renderer with the given URL templates.
+func NewChat(emoteURL, badgeURL string) *Chat {
+	return &Chat{EmoteURL: emoteURL, BadgeURL: badgeURL}
+}
+
+// FuncMap returns the template functions backed by this renderer:
+//
+//	{{chatText .Text .Tags}}          text with emotes rendered
+//	{{chatText .Text .Tags $.Query}}  same, with the term highlighted
+//	{{chatBadges .Tags}}              the sender's badges
+func (c *Chat) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"chatText":   c.Text,
+		"chatBadges": c.Badges,
+	}
+}
+
+// Text renders message text, replacing emote ranges from the emotes tag
+// with images (or marked-up names when there is no emote URL template).
+// Any other text is escaped, and if highlight is given, case-insensitive
+// matches of it are wrapped in <mark>.
+func (c *Chat) Text(text string, tags map[string]string, highlight ...string) template.HTML {
+	var mark *regexp.Regexp
+	if len(highlight) > 0 && strings.TrimSpace(highlight[0]) != "" {
+		mark = regexp.MustCompile(`(?i)(` + regexp.QuoteMeta(html.EscapeString(highlight[0])) + `)`)
+	}
+	plain := func(s string) string {
+		escaped := html.EscapeString(s)
+		if mark == nil {
+			return escaped
+		}
+		return mark.ReplaceAllString(escaped, "<mark>$1</mark>")
+	}
+
+	type occurrence struct {
+		id         string
+		start, end int
+	}
+	var occurrences []occurrence
+	for _, emote := range irc.Tags(tags).Emotes() {
+		for _, rng := range emote.Ranges {
+			occurrences = append(occurrences, occurrence{emote.ID, rng.Start, rng.End})
+		}
+	}
+	if len(occurrences) == 0 {
+		return template.HTML(plain(text))
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].start < occurrences[j].start })
+
+	runes := []rune(text)
+	var b strings.Builder
+	pos := 0
+	for _, o := range occurrences {
+		// Ranges that overlap an earlier one or run past the text are
+		// left as plain text rather than trusted
+		if o.start < pos || o.end < o.start || o.end >= len(runes) {
+			continue
+		}
+		b.WriteString(plain(string(runes[pos:o.start])))
+		b.WriteString(c.emote(o.id, string(runes[o.start:o.end+1])))
+		pos = o.end + 1
+	}
+	b.WriteString(plain(string(runes[pos:])))
+
+	return template.HTML(b.String())
+}
+
+// Badges renders the badges tag as images, or as short text labels when
+// there is no badge URL template.
+func (c *Chat) Badges(tags map[string]string) template.HTML {
+	var b strings.Builder
+	for _, badge := range irc.Tags(tags).Badges() {
+		title := html.EscapeString(badge.Name + "/" + badge.Version)
+		if c.BadgeURL != "" {
+			src := expand(c.BadgeURL, "{name}", badge.Name, "{version}", badge.Version)
+			b.WriteString(`<img class="chat-badge" src="` + src + `" alt="` + title + `" title="` + title + `">`)
+			continue
+		}
+		label, ok := badgeLabels[badge.Name]
+		if !ok {
+			label = strings.ToUpper(badge.Name)
+		}
+		b.WriteString(`<span class="chat-badge-text" title="` + title + `">` + html.EscapeString(label) + `</span>`)
+	}
+	return template.HTML(b.String())
+}
+
+// emote renders a single emote occurrence.
+func (c *Chat) emote(id, name string) string {
+	alt := html.EscapeString(name)
+	if c.EmoteURL == "" {
+		return `<span class="chat-emote-text" title="` + alt + `">` + alt + `</span>`
+	}
+	src := expand(c.EmoteURL, "{id}", id, "{name}", name)
+	return `<img class="chat-emote" src="` + src + `" alt="` + alt + `" title="` + alt + `">`
+}
+
+// expand fills placeholder/value pairs into a URL template, path-escaping
+// each value, and returns the result escaped for an HTML attribute.
+func expand(tmpl string, pairs ...string) string {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		tmpl = strings.ReplaceAll(tmpl, pairs[i], url.PathEscape(pairs[i+1]))
+	}
+	return html.EscapeString(tmpl)
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/http/render"
 	"github.com/asabla/goknut/internal/observability"
 	"github.com/asabla/goknut/internal/repository"
 	"github.com/asabla/goknut/internal/services"
@@ -38,9 +39,18 @@ type Server struct {
 	eventRepo            *repository.EventRepository
 	collaborationService *services.CollaborationService
 	collaborationRepo    *repository.CollaborationRepository
+	chatService          *services.ChatService
+	whisperService       *services.WhisperService
+	deadLetterService    *services.DeadLetterService
+	overflowStats        handlers.OverflowStatsSource
 	channelRepo          *repository.ChannelRepository
 	messageRepo          *repository.MessageRepository
 	userRepo             *repository.UserRepository
+	moderationRepo       *repository.ModerationRepository
+	noticeRepo           *repository.NoticeRepository
+	channelStateRepo     *repository.ChannelStateRepository
+	emoteRepo            *repository.EmoteRepository
+	bitsRepo             *repository.BitsRepository
 	profileRepo          *repository.ProfileRepository
 	enableSSE            bool
 	sseHandler           *handlers.SSEHandler
@@ -64,19 +74,33 @@ type ServerConfig struct {
 	EventRepo            *repository.EventRepository
 	CollaborationService *services.CollaborationService
 	CollaborationRepo    *repository.CollaborationRepository
+	ChatService          *services.ChatService // nil leaves the live view read-only
+	WhisperService       *services.WhisperService
+	DeadLetterService    *services.DeadLetterService
+	OverflowStats        handlers.OverflowStatsSource // nil hides ingestion overflow on the dashboard
 	ChannelRepo          *repository.ChannelRepository
 	MessageRepo          *repository.MessageRepository
 	UserRepo             *repository.UserRepository
+	ModerationRepo       *repository.ModerationRepository
+	NoticeRepo           *repository.NoticeRepository
+	ChannelStateRepo     *repository.ChannelStateRepository
+	EmoteRepo            *repository.EmoteRepository
+	BitsRepo             *repository.BitsRepository
 	ProfileRepo          *repository.ProfileRepository
 	EnableSSE            bool
 
 	PrometheusBaseURL string
 	PrometheusTimeout time.Duration
+
+	// Image URL templates for chat rendering; empty renders text only
+	EmoteURLTemplate string
+	BadgeURLTemplate string
 }
 
-// templateFuncs returns the custom template functions.
-func templateFuncs() template.FuncMap {
-	return template.FuncMap{
+// templateFuncs returns the custom template functions, including the chat
+// rendering functions backed by chat.
+func templateFuncs(chat *render.Chat) template.FuncMap {
+	funcs := template.FuncMap{
 		"upper": strings.ToUpper,
 		"lower": strings.ToLower,
 		"formatNumber": func(n int64) string {
@@ -115,6 +139,10 @@ func templateFuncs() template.FuncMap {
 			return m
 		},
 	}
+	for name, fn := range chat.FuncMap() {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
 // NewServer creates a new HTTP server.
@@ -134,9 +162,18 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 		eventRepo:            cfg.EventRepo,
 		collaborationService: cfg.CollaborationService,
 		collaborationRepo:    cfg.CollaborationRepo,
+		chatService:          cfg.ChatService,
+		whisperService:       cfg.WhisperService,
+		deadLetterService:    cfg.DeadLetterService,
+		overflowStats:        cfg.OverflowStats,
 		channelRepo:          cfg.ChannelRepo,
 		messageRepo:          cfg.MessageRepo,
 		userRepo:             cfg.UserRepo,
+		moderationRepo:       cfg.ModerationRepo,
+		noticeRepo:           cfg.NoticeRepo,
+		channelStateRepo:     cfg.ChannelStateRepo,
+		emoteRepo:            cfg.EmoteRepo,
+		bitsRepo:             cfg.BitsRepo,
 		profileRepo:          cfg.ProfileRepo,
 		enableSSE:            cfg.EnableSSE,
 		prometheusBaseURL:    cfg.PrometheusBaseURL,
@@ -149,7 +186,7 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 		return nil, err
 	}
 
-	s.templates, err = template.New("").Funcs(templateFuncs()).ParseFS(tmplFS, "*.html", "*/*.html")
+	s.templates, err = template.New("").Funcs(templateFuncs(render.NewChat(cfg.EmoteURLTemplate, cfg.BadgeURLTemplate))).ParseFS(tmplFS, "*.html", "*/*.html")
 	if err != nil {
 		return nil, err
 	}
@@ -215,10 +252,52 @@ func (s *Server) registerRoutes() {
 	// Register channel view handler routes (live view)
 	if s.channelRepo != nil && s.messageRepo != nil {
 		channelViewHandler := handlers.NewChannelViewHandler(
-			s.channelRepo, s.messageRepo, s.templates, s.logger, s.metrics)
+			s.channelRepo, s.messageRepo, s.chatService, s.templates, s.logger, s.metrics)
 		channelViewHandler.RegisterRoutes(s.mux)
 	}
 
+	// Register whisper routes
+	if s.whisperService != nil {
+		whisperHandler := handlers.NewWhisperHandler(s.whisperService, s.templates, s.logger)
+		whisperHandler.RegisterRoutes(s.mux)
+	}
+
+	// Register dead letter (operator) routes
+	if s.deadLetterService != nil {
+		deadLetterHandler := handlers.NewDeadLetterHandler(s.deadLetterService, s.templates, s.logger)
+		deadLetterHandler.RegisterRoutes(s.mux)
+	}
+
+	// Register moderation event routes
+	if s.channelRepo != nil && s.moderationRepo != nil {
+		moderationHandler := handlers.NewModerationHandler(s.channelRepo, s.moderationRepo, s.templates, s.logger)
+		moderationHandler.RegisterRoutes(s.mux)
+	}
+
+	// Register user notice routes
+	if s.channelRepo != nil && s.noticeRepo != nil {
+		noticeHandler := handlers.NewNoticeHandler(s.channelRepo, s.noticeRepo, s.templates, s.logger)
+		noticeHandler.RegisterRoutes(s.mux)
+	}
+
+	// Register channel state (chat mode history) routes
+	if s.channelRepo != nil && s.channelStateRepo != nil {
+		channelStateHandler := handlers.NewChannelStateHandler(s.channelRepo, s.channelStateRepo, s.templates, s.logger)
+		channelStateHandler.RegisterRoutes(s.mux)
+	}
+
+	// Register emote usage routes
+	if s.channelRepo != nil && s.userRepo != nil && s.emoteRepo != nil {
+		emoteHandler := handlers.NewEmoteHandler(s.channelRepo, s.userRepo, s.emoteRepo, s.templates, s.logger)
+		emoteHandler.RegisterRoutes(s.mux)
+	}
+
+	// Register bits (cheer) leaderboard routes
+	if s.channelRepo != nil && s.userRepo != nil && s.bitsRepo != nil {
+		bitsHandler := handlers.NewBitsHandler(s.channelRepo, s.userRepo, s.bitsRepo, s.templates, s.logger)
+		bitsHandler.RegisterRoutes(s.mux)
+	}
+
 	// Register search handler routes
 	if s.searchService != nil {
 		searchHandler := handlers.NewSearchHandler(s.searchService, s.templates, s.logger)
@@ -257,6 +336,7 @@ func (s *Server) registerRoutes() {
 			s.messageRepo,
 			s.channelRepo,
 			s.userRepo,
+			s.overflowStats,
 			s.prometheusBaseURL,
 			s.prometheusTimeout,
 		)
@@ -0,0 +1,86 @@
+package ingestion
+
+import (
+	"strings"
+	"time"
+)
+
+// dedupWindow remembers the keys of recently ingested items so the same
+// message read on two IRC connections is stored once. Keys are forgotten
+// once they are older than the window. It is only used from the pipeline's
+// process loop and needs no lock.
+type dedupWindow struct {
+	window time.Duration
+	seen   map[string]time.Time
+	order  []dedupEntry // Oldest first
+}
+
+type dedupEntry struct {
+	key    string
+	seenAt time.Time
+}
+
+func newDedupWindow(window time.Duration) *dedupWindow {
+	return &dedupWindow{window: window, seen: make(map[string]time.Time)}
+}
+
+// duplicate records key and reports whether it was already seen within the
+// window. Empty keys are never duplicates.
+func (d *dedupWindow) duplicate(key string, now time.Time) bool {
+	if key == "" {
+		return false
+	}
+	d.expire(now)
+
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+	d.seen[key] = now
+	d.order = append(d.order, dedupEntry{key: key, seenAt: now})
+	return false
+}
+
+// expire forgets keys seen before the window.
+func (d *dedupWindow) expire(now time.Time) {
+	cutoff := now.Add(-d.window)
+	n := 0
+	for n < len(d.order) && d.order[n].seenAt.Before(cutoff) {
+		delete(d.seen, d.order[n].key)
+		n++
+	}
+	d.order = d.order[n:]
+}
+
+// messageKey identifies a chat message by its Twitch id tag.
+func messageKey(msg *Message) string {
+	return msg.Tags["id"]
+}
+
+// noticeKey identifies a user notice by its Twitch id tag.
+func noticeKey(n *Notice) string {
+	if id := n.Tags["id"]; id != "" {
+		return "notice:" + id
+	}
+	return ""
+}
+
+// whisperKey identifies a whisper by its thread and message id; every
+// connection on the account receives it.
+func whisperKey(w *Whisper) string {
+	if w.ThreadID == "" || w.MessageID == "" {
+		return ""
+	}
+	return "whisper:" + w.ThreadID + ":" + w.MessageID
+}
+
+// moderationKey identifies a moderation event. CLEARCHAT has no id tag, so
+// events are keyed by what they do and Twitch's timestamp; events without
+// one are never treated as duplicates.
+func moderationKey(evt *ModerationEvent) string {
+	ts := evt.Tags["tmi-sent-ts"]
+	if ts == "" {
+		return ""
+	}
+	return strings.Join([]string{"moderation", normalizeChannelName(evt.ChannelName), evt.Action,
+		evt.TargetUsername, evt.TargetMessageID, ts}, ":")
+}
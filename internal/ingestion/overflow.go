@@ -0,0 +1,163 @@
+package ingestion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OverflowPolicy decides what happens to an item when the ingestion buffer
+// is full, or is filling up. Ingest consults it for every item, from the
+// caller's goroutine, usually an IRC read loop.
+type OverflowPolicy interface {
+	// Name identifies the policy, e.g. on the dashboard.
+	Name() string
+
+	// Admit is given the item's channel (empty for whispers) and how full
+	// the buffer is. It returns whether to queue the item at all, and if
+	// so how long Ingest may block waiting for room before the item is
+	// spooled or dropped.
+	Admit(channel string, queued, capacity int) (wait time.Duration, ok bool)
+}
+
+// DropPolicy never waits: an item that doesn't fit the buffer is spooled
+// or dropped at once. It is the default.
+type DropPolicy struct{}
+
+// Name implements OverflowPolicy.
+func (DropPolicy) Name() string { return "drop" }
+
+// Admit implements OverflowPolicy.
+func (DropPolicy) Admit(string, int, int) (time.Duration, bool) { return 0, true }
+
+// BlockPolicy blocks the caller for up to Timeout waiting for room, which
+// slows the IRC read loop down to the pace of the writers.
+type BlockPolicy struct {
+	Timeout time.Duration
+}
+
+// Name implements OverflowPolicy.
+func (BlockPolicy) Name() string { return "block" }
+
+// Admit implements OverflowPolicy.
+func (b BlockPolicy) Admit(string, int, int) (time.Duration, bool) { return b.Timeout, true }
+
+// Priority ranks channels for PriorityPolicy.
+type Priority int
+
+const (
+	// PriorityLow channels are shed first, once the buffer is half full.
+	PriorityLow Priority = iota
+	// PriorityNormal channels are shed after low ones, at 90% full.
+	PriorityNormal
+	// PriorityHigh channels are shed last, only once the buffer has been
+	// full for longer than PriorityPolicy.Wait.
+	PriorityHigh
+)
+
+// ParsePriority parses "low", "normal" or "high".
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	return PriorityNormal, fmt.Errorf("invalid priority %q", s)
+}
+
+// String returns the priority's name.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	}
+	return "normal"
+}
+
+// PriorityPolicy sheds load by channel priority as the buffer fills:
+// low-priority channels once it is half full, normal ones at 90%, so that
+// the rest stays free for high-priority channels, which may also block for
+// up to Wait when it is full.
+type PriorityPolicy struct {
+	Priorities map[string]Priority // By channel name; others are PriorityNormal
+	Wait       time.Duration
+}
+
+// Name implements OverflowPolicy.
+func (PriorityPolicy) Name() string { return "priority" }
+
+// Admit implements OverflowPolicy.
+func (pp PriorityPolicy) Admit(channel string, queued, capacity int) (time.Duration, bool) {
+	priority, ok := pp.Priorities[normalizeChannelName(channel)]
+	if !ok {
+		priority = PriorityNormal
+	}
+
+	switch priority {
+	case PriorityLow:
+		return 0, queued < capacity/2
+	case PriorityNormal:
+		return 0, queued < capacity*9/10
+	}
+	return pp.Wait, true
+}
+
+// ChannelOverflow counts the items of one channel that didn't go through
+// the buffer.
+type ChannelOverflow struct {
+	Channel string // Empty for whispers
+	Spooled int64
+	Dropped int64
+}
+
+// OverflowStats reports the overflow policy and what it turned away.
+type OverflowStats struct {
+	Policy   string
+	Channels []ChannelOverflow // Most dropped first
+}
+
+// recordChannelOverflow counts an item of channel that was spooled or
+// dropped rather than queued.
+func (p *Pipeline) recordChannelOverflow(channel string, spooled bool) {
+	channel = normalizeChannelName(channel)
+
+	p.overflowMu.Lock()
+	defer p.overflowMu.Unlock()
+	counts := p.overflow[channel]
+	if spooled {
+		counts.Spooled++
+	} else {
+		counts.Dropped++
+	}
+	p.overflow[channel] = counts
+}
+
+// OverflowStats returns the overflow policy and, per channel, how many
+// items were spooled or dropped because of it since the pipeline was
+// created.
+func (p *Pipeline) OverflowStats() OverflowStats {
+	p.overflowMu.Lock()
+	channels := make([]ChannelOverflow, 0, len(p.overflow))
+	for channel, counts := range p.overflow {
+		counts.Channel = channel
+		channels = append(channels, counts)
+	}
+	p.overflowMu.Unlock()
+
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].Dropped != channels[j].Dropped {
+			return channels[i].Dropped > channels[j].Dropped
+		}
+		if channels[i].Spooled != channels[j].Spooled {
+			return channels[i].Spooled > channels[j].Spooled
+		}
+		return channels[i].Channel < channels[j].Channel
+	})
+	return OverflowStats{Policy: p.cfg.Overflow.Name(), Channels: channels}
+}
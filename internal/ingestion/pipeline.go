@@ -16,7 +16,61 @@ type Message struct {
 	DisplayName string
 	Text        string
 	Tags        map[string]string
+	SentAt      time.Time // Twitch server time (tmi-sent-ts); zero if unknown
 	ReceivedAt  time.Time
+	Outbound    bool // Sent by our own account rather than read from chat
+}
+
+// ModerationEvent represents a CLEARCHAT/CLEARMSG event to be ingested.
+type ModerationEvent struct {
+	ChannelName     string
+	Action          string // timeout, ban, clear or delete
+	TargetUsername  string
+	TargetMessageID string
+	Duration        time.Duration
+	Text            string
+	Tags            map[string]string
+	SentAt          time.Time // Twitch server time (tmi-sent-ts); zero if unknown
+	ReceivedAt      time.Time
+}
+
+// Notice represents a USERNOTICE (sub, resub, gift sub, raid, announcement) to be ingested.
+type Notice struct {
+	ChannelName string
+	Kind        string // USERNOTICE msg-id
+	Username    string
+	DisplayName string
+	SystemMsg   string
+	Text        string
+	Params      map[string]string // msg-param-* tags without the prefix
+	Tags        map[string]string
+	ReceivedAt  time.Time
+}
+
+// RoomState represents a (possibly partial) ROOMSTATE chat mode update to be ingested.
+// Nil fields were not present in the update.
+type RoomState struct {
+	ChannelName          string
+	RoomID               string
+	EmoteOnly            *bool
+	FollowersOnlyMinutes *int
+	R9K                  *bool
+	SlowSeconds          *int
+	SubsOnly             *bool
+	ReceivedAt           time.Time
+}
+
+// Whisper represents a whisper to our account to be ingested.
+type Whisper struct {
+	From            string
+	FromDisplayName string
+	FromUserID      string
+	To              string
+	Text            string
+	MessageID       string
+	ThreadID        string
+	Tags            map[string]string
+	ReceivedAt      time.Time
 }
 
 // MessageStore is the interface for storing messages.
@@ -25,6 +79,55 @@ type MessageStore interface {
 	StoreBatch(ctx context.Context, messages []Message) error
 }
 
+// ModerationStore is optionally implemented by a MessageStore that can
+// persist moderation events. Events are dropped if the store does not
+// implement it.
+type ModerationStore interface {
+	// StoreModerationEvent stores a moderation event and marks affected messages deleted.
+	StoreModerationEvent(ctx context.Context, evt ModerationEvent) error
+}
+
+// NoticeStore is optionally implemented by a MessageStore that can persist
+// user notices. Notices are dropped if the store does not implement it.
+type NoticeStore interface {
+	// StoreNotice stores a user notice.
+	StoreNotice(ctx context.Context, n Notice) error
+}
+
+// RoomStateStore is optionally implemented by a MessageStore that can persist
+// channel chat mode history. Updates are dropped if the store does not implement it.
+type RoomStateStore interface {
+	// StoreRoomState applies a chat mode update to the channel's state history.
+	StoreRoomState(ctx context.Context, rs RoomState) error
+}
+
+// WhisperStore is optionally implemented by a MessageStore that can
+// persist whispers. Whispers are dropped if the store does not implement it.
+type WhisperStore interface {
+	// StoreWhisper stores a whisper.
+	StoreWhisper(ctx context.Context, w Whisper) error
+}
+
+// queueItem is a single entry on the ingestion queue. Exactly one field is set.
+// Messages and events share a queue so events are applied in arrival order
+// relative to the messages they affect.
+type queueItem struct {
+	msg        *Message
+	moderation *ModerationEvent
+	notice     *Notice
+	roomState  *RoomState
+	whisper    *Whisper
+	replay     *replayTask // Spooled records for a writer, not from Ingest
+}
+
+// DeadLetterStore is optionally implemented by a MessageStore that can keep
+// the messages it refuses, with the error, for inspection. Without it a
+// batch the store refuses is spooled or dropped whole.
+type DeadLetterStore interface {
+	// StoreDeadLetter stores a message the store refused and why.
+	StoreDeadLetter(ctx context.Context, msg Message, cause error) error
+}
+
 // UserResolver is the interface for resolving user IDs.
 type UserResolver interface {
 	// GetOrCreateUser returns the user ID for a username, creating if necessary.
@@ -42,6 +145,7 @@ type Metrics interface {
 	RecordBatchSize(size int)
 	RecordBatchLatency(d time.Duration)
 	RecordDroppedMessages(count int)
+	RecordDuplicateMessages(count int)
 }
 
 // Logger provides logging for the pipeline.
@@ -55,17 +159,60 @@ type PipelineConfig struct {
 	BatchSize    int
 	FlushTimeout time.Duration
 	BufferSize   int
+	// Writers is how many batches may be written at once. Items are
+	// partitioned among writers by channel, so each channel's messages and
+	// events are still stored in the order they were received. Each
+	// writer holds up to BatchSize items handed off from the buffer on top
+	// of BufferSize.
+	Writers int
+	// DedupWindow is how long message ids are remembered to drop the
+	// same message ingested twice, e.g. from redundant IRC connections.
+	DedupWindow  time.Duration
 	Metrics      Metrics
 	OTelProvider *observability.OTelProvider
 	Logger       Logger
+
+	// FlushRetries is how often a batch the store refused is tried again,
+	// FlushRetryBackoff apart and doubling each time, before it is split
+	// to find the messages the store refuses on their own.
+	FlushRetries      int
+	FlushRetryBackoff time.Duration
+
+	// Overflow decides what happens to items when the buffer fills up:
+	// whether they may wait for room, and for how long, or are turned
+	// away to the spool or dropped. Nil means DropPolicy.
+	Overflow OverflowPolicy
+
+	// Spool, if set, takes what would otherwise be lost: batches and
+	// events the store refused, items that didn't fit the buffer, and
+	// items still queued at Stop. They are replayed, oldest first, once
+	// the store takes them again.
+	Spool *Spool
 }
 
+const (
+	// maxFlushRetryBackoff caps the delay between retries of a batch
+	maxFlushRetryBackoff = 5 * time.Second
+
+	// spoolRetryInterval is how often the spool is checked while it is
+	// empty, and the first delay after a failed replay
+	spoolRetryInterval = time.Second
+
+	// spoolMaxRetryInterval caps the delay between failed replays
+	spoolMaxRetryInterval = 30 * time.Second
+)
+
 // DefaultPipelineConfig returns default pipeline configuration.
 func DefaultPipelineConfig() PipelineConfig {
 	return PipelineConfig{
 		BatchSize:    100,
 		FlushTimeout: 100 * time.Millisecond,
 		BufferSize:   10000,
+		Writers:      1,
+		DedupWindow:  5 * time.Minute,
+
+		FlushRetries:      3,
+		FlushRetryBackoff: 100 * time.Millisecond,
 	}
 }
 
@@ -73,14 +220,23 @@ func DefaultPipelineConfig() PipelineConfig {
 type Pipeline struct {
 	cfg          PipelineConfig
 	store        MessageStore
-	messages     chan Message
+	messages     chan queueItem
 	otelProvider *observability.OTelProvider
+	dedup        *dedupWindow // Owned by processLoop
+	writers      []*writer
 
 	mu      sync.Mutex
-	batch   []Message
-	timer   *time.Timer
 	running bool
 
+	overflowMu sync.Mutex
+	overflow   map[string]ChannelOverflow // By channel
+
+	// Owned by processLoop
+	spoolTimer *time.Timer
+	spoolRetry time.Duration
+	replaying  *replayRound // Handed to the writers, not yet committed
+	stranded   *queueItem   // Taken off the queue but not handed to a writer by Stop
+
 	done chan struct{}
 	wg   sync.WaitGroup
 }
@@ -96,15 +252,36 @@ func NewPipeline(cfg PipelineConfig, store MessageStore) *Pipeline {
 	if cfg.BufferSize <= 0 {
 		cfg.BufferSize = DefaultPipelineConfig().BufferSize
 	}
+	if cfg.Writers <= 0 {
+		cfg.Writers = DefaultPipelineConfig().Writers
+	}
+	if cfg.DedupWindow <= 0 {
+		cfg.DedupWindow = DefaultPipelineConfig().DedupWindow
+	}
+	if cfg.FlushRetries < 0 {
+		cfg.FlushRetries = 0
+	}
+	if cfg.FlushRetryBackoff <= 0 {
+		cfg.FlushRetryBackoff = DefaultPipelineConfig().FlushRetryBackoff
+	}
+	if cfg.Overflow == nil {
+		cfg.Overflow = DropPolicy{}
+	}
 
-	return &Pipeline{
+	p := &Pipeline{
 		cfg:          cfg,
 		store:        store,
-		messages:     make(chan Message, cfg.BufferSize),
-		batch:        make([]Message, 0, cfg.BatchSize),
+		messages:     make(chan queueItem, cfg.BufferSize),
 		otelProvider: cfg.OTelProvider,
+		dedup:        newDedupWindow(cfg.DedupWindow),
+		overflow:     make(map[string]ChannelOverflow),
 		done:         make(chan struct{}),
 	}
+	p.writers = make([]*writer, cfg.Writers)
+	for i := range p.writers {
+		p.writers[i] = newWriter(p)
+	}
+	return p
 }
 
 // Start begins processing incoming messages.
@@ -115,10 +292,19 @@ func (p *Pipeline) Start(ctx context.Context) error {
 		return nil
 	}
 	p.running = true
-	p.timer = time.NewTimer(p.cfg.FlushTimeout)
+	for _, w := range p.writers {
+		w.timer = time.NewTimer(p.cfg.FlushTimeout)
+	}
+	if p.cfg.Spool != nil {
+		// Replay whatever a previous run left behind straight away
+		p.spoolTimer = time.NewTimer(0)
+	}
 	p.mu.Unlock()
 
-	p.wg.Add(1)
+	p.wg.Add(1 + len(p.writers))
+	for _, w := range p.writers {
+		go w.run(ctx)
+	}
 	go p.processLoop(ctx)
 
 	return nil
@@ -138,122 +324,582 @@ func (p *Pipeline) Stop() error {
 	p.wg.Wait()
 
 	// Flush any remaining messages
-	p.flush(context.Background())
+	for _, w := range p.writers {
+		w.flush(context.Background())
+	}
+
+	// Keep what is still queued for the next run
+	if p.cfg.Spool != nil {
+		p.spoolQueued()
+	}
 
 	return nil
 }
 
+// spoolQueued moves every item still queued, for a writer or on the
+// queue, to the spool, oldest first.
+func (p *Pipeline) spoolQueued() {
+	spooled := 0
+	spool := func(item queueItem) {
+		if item.replay != nil {
+			// Never committed, so still in the spool
+			return
+		}
+		if err := p.cfg.Spool.append(spoolRecordFor(item)); err != nil {
+			p.recordDropped(1, "failed to spool queued item", "error", err)
+			return
+		}
+		spooled++
+	}
+
+	for _, w := range p.writers {
+		drainQueue(w.items, spool)
+	}
+	if p.stranded != nil {
+		spool(*p.stranded)
+		p.stranded = nil
+	}
+	drainQueue(p.messages, spool)
+
+	if spooled > 0 && p.cfg.Logger != nil {
+		p.cfg.Logger.Warn("spooled items still queued at stop", "count", spooled)
+	}
+}
+
+// drainQueue calls fn for every item on queue until it is empty.
+func drainQueue(queue chan queueItem, fn func(queueItem)) {
+	for {
+		select {
+		case item := <-queue:
+			fn(item)
+		default:
+			return
+		}
+	}
+}
+
 // Ingest adds a message to the ingestion queue.
 func (p *Pipeline) Ingest(msg Message) {
-	select {
-	case p.messages <- msg:
-	default:
-		// Buffer full, drop message and record metric
-		if p.cfg.Logger != nil {
-			p.cfg.Logger.Warn("ingestion buffer full, dropping message",
-				"channel", msg.ChannelName,
-				"username", msg.Username,
-			)
+	p.enqueue(queueItem{msg: &msg}, msg.ChannelName, "ingestion buffer full, dropping message",
+		"channel", msg.ChannelName,
+		"username", msg.Username,
+	)
+}
+
+// IngestModeration adds a moderation event to the ingestion queue.
+func (p *Pipeline) IngestModeration(evt ModerationEvent) {
+	p.enqueue(queueItem{moderation: &evt}, evt.ChannelName, "ingestion buffer full, dropping moderation event",
+		"channel", evt.ChannelName,
+		"action", evt.Action,
+	)
+}
+
+// IngestNotice adds a user notice to the ingestion queue.
+func (p *Pipeline) IngestNotice(n Notice) {
+	p.enqueue(queueItem{notice: &n}, n.ChannelName, "ingestion buffer full, dropping notice",
+		"channel", n.ChannelName,
+		"kind", n.Kind,
+	)
+}
+
+// IngestRoomState adds a chat mode update to the ingestion queue.
+func (p *Pipeline) IngestRoomState(rs RoomState) {
+	p.enqueue(queueItem{roomState: &rs}, rs.ChannelName, "ingestion buffer full, dropping room state",
+		"channel", rs.ChannelName,
+	)
+}
+
+// IngestWhisper adds a whisper to the ingestion queue.
+func (p *Pipeline) IngestWhisper(w Whisper) {
+	p.enqueue(queueItem{whisper: &w}, "", "ingestion buffer full, dropping whisper",
+		"from", w.From,
+	)
+}
+
+// enqueue puts an item of channel on the queue if the overflow policy
+// admits it, waiting as long as the policy allows for room. Otherwise the
+// item is spooled or dropped.
+func (p *Pipeline) enqueue(item queueItem, channel string, msg string, keysAndValues ...any) {
+	wait, ok := p.cfg.Overflow.Admit(channel, len(p.messages), cap(p.messages))
+	if ok {
+		select {
+		case p.messages <- item:
+			return
+		default:
 		}
-		if p.cfg.Metrics != nil {
-			p.cfg.Metrics.RecordDroppedMessages(1)
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case p.messages <- item:
+				return
+			case <-timer.C:
+			case <-p.done:
+			}
 		}
-		if p.otelProvider != nil {
-			p.otelProvider.RecordDroppedMessages(context.Background(), 1)
+	}
+
+	// Buffer full or shed by the policy, spool or drop and record metric
+	p.recordOverflow(item, channel, msg, keysAndValues...)
+}
+
+// recordOverflow spools an item that didn't go on the queue, or logs and
+// counts it as dropped.
+func (p *Pipeline) recordOverflow(item queueItem, channel string, msg string, keysAndValues ...any) {
+	if p.cfg.Spool != nil {
+		err := p.cfg.Spool.append(spoolRecordFor(item))
+		if err == nil {
+			p.recordChannelOverflow(channel, true)
+			return
 		}
+		keysAndValues = append(keysAndValues, "spool_error", err)
+	}
+	p.recordChannelOverflow(channel, false)
+	p.recordDropped(1, msg, keysAndValues...)
+}
+
+// recordDropped logs and counts items that are lost.
+func (p *Pipeline) recordDropped(count int, msg string, keysAndValues ...any) {
+	if p.cfg.Logger != nil {
+		p.cfg.Logger.Warn(msg, keysAndValues...)
+	}
+	if p.cfg.Metrics != nil {
+		p.cfg.Metrics.RecordDroppedMessages(count)
+	}
+	if p.otelProvider != nil {
+		p.otelProvider.RecordDroppedMessages(context.Background(), count)
 	}
 }
 
 func (p *Pipeline) processLoop(ctx context.Context) {
 	defer p.wg.Done()
 
+	// A nil channel never fires, so without a spool there is nothing to replay
+	var spoolC <-chan time.Time
+	if p.spoolTimer != nil {
+		spoolC = p.spoolTimer.C
+		defer p.spoolTimer.Stop()
+	}
+
 	for {
+		// Nil, so never ready, unless a round of replay is in flight
+		var replayed <-chan replayResult
+		if p.replaying != nil {
+			replayed = p.replaying.results
+		}
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-p.done:
 			return
-		case msg := <-p.messages:
-			p.addToBatch(ctx, msg)
-		case <-p.timer.C:
-			p.flush(ctx)
-			p.resetTimer()
+		case item := <-p.messages:
+			if p.isDuplicate(item) {
+				p.recordDuplicate(ctx)
+				continue
+			}
+			if !p.dispatch(ctx, item) {
+				return
+			}
+		case <-spoolC:
+			p.startReplay(ctx)
+		case res := <-replayed:
+			if p.replaying.record(res) {
+				round := p.replaying
+				p.replaying = nil
+				p.finishReplay(round)
+			}
 		}
 	}
 }
 
-func (p *Pipeline) addToBatch(ctx context.Context, msg Message) {
-	p.mu.Lock()
-	p.batch = append(p.batch, msg)
-	shouldFlush := len(p.batch) >= p.cfg.BatchSize
-	p.mu.Unlock()
+// dispatch hands an item to the writer for its channel, waiting while
+// that writer is behind. It returns false if the pipeline stopped first.
+func (p *Pipeline) dispatch(ctx context.Context, item queueItem) bool {
+	w := p.writers[p.partition(item.channel())]
+	select {
+	case w.items <- item:
+		return true
+	case <-ctx.Done():
+	case <-p.done:
+	}
+	p.stranded = &item
+	return false
+}
 
-	if shouldFlush {
-		p.flush(ctx)
-		p.resetTimer()
+// isDuplicate reports whether an item was already ingested within the
+// dedup window. Room states are not deduplicated; applying one twice
+// records no change.
+func (p *Pipeline) isDuplicate(item queueItem) bool {
+	var key string
+	switch {
+	case item.msg != nil:
+		key = messageKey(item.msg)
+	case item.moderation != nil:
+		key = moderationKey(item.moderation)
+	case item.notice != nil:
+		key = noticeKey(item.notice)
+	case item.whisper != nil:
+		key = whisperKey(item.whisper)
 	}
+	return p.dedup.duplicate(key, time.Now())
 }
 
-func (p *Pipeline) flush(ctx context.Context) {
-	p.mu.Lock()
-	if len(p.batch) == 0 {
-		p.mu.Unlock()
-		return
+func (p *Pipeline) recordDuplicate(ctx context.Context) {
+	if p.cfg.Metrics != nil {
+		p.cfg.Metrics.RecordDuplicateMessages(1)
+	}
+	if p.otelProvider != nil {
+		p.otelProvider.RecordDuplicateMessages(ctx, 1)
 	}
+}
 
-	batch := p.batch
-	p.batch = make([]Message, 0, p.cfg.BatchSize)
-	p.mu.Unlock()
+func (p *Pipeline) storeRoomState(ctx context.Context, rs RoomState) error {
+	store, ok := p.store.(RoomStateStore)
+	if !ok {
+		return nil
+	}
+	return store.StoreRoomState(ctx, rs)
+}
 
-	start := time.Now()
+func (p *Pipeline) storeWhisper(ctx context.Context, w Whisper) error {
+	store, ok := p.store.(WhisperStore)
+	if !ok {
+		return nil
+	}
+	return store.StoreWhisper(ctx, w)
+}
+
+func (p *Pipeline) storeNotice(ctx context.Context, n Notice) error {
+	store, ok := p.store.(NoticeStore)
+	if !ok {
+		return nil
+	}
+	return store.StoreNotice(ctx, n)
+}
+
+func (p *Pipeline) storeModeration(ctx context.Context, evt ModerationEvent) error {
+	store, ok := p.store.(ModerationStore)
+	if !ok {
+		return nil
+	}
+	return store.StoreModerationEvent(ctx, evt)
+}
+
+// storeBatch stores batch, trying again with backoff while the store
+// refuses it. It stops retrying once the pipeline is stopping.
+func (p *Pipeline) storeBatch(ctx context.Context, batch []Message) error {
+	backoff := p.cfg.FlushRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := p.store.StoreBatch(ctx, batch)
+		if err == nil || attempt > p.cfg.FlushRetries {
+			return err
+		}
 
-	if err := p.store.StoreBatch(ctx, batch); err != nil {
-		// Log error - messages are lost
 		if p.cfg.Logger != nil {
-			p.cfg.Logger.Error("failed to store message batch",
+			p.cfg.Logger.Warn("failed to store message batch, will retry",
 				"batch_size", len(batch),
+				"attempt", attempt,
+				"retry_in", backoff.String(),
 				"error", err,
 			)
 		}
-		// Record dropped messages metric
-		if p.cfg.Metrics != nil {
-			p.cfg.Metrics.RecordDroppedMessages(len(batch))
-		}
 		if p.otelProvider != nil {
-			p.otelProvider.RecordDroppedMessages(ctx, len(batch))
+			p.otelProvider.RecordBatchRetry(ctx)
 		}
-		return
+		select {
+		case <-ctx.Done():
+			return err
+		case <-p.done:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxFlushRetryBackoff)
+	}
+}
+
+// salvage stores batch, which the store refused with cause, in halves and
+// halves of those, so that only the messages the store refuses on their own
+// are left; those are dead-lettered. It returns how many messages from the
+// start of batch were stored or dead-lettered. A message the dead letter
+// store refuses too means the database is down rather than the message
+// bad, so salvage stops there.
+func (p *Pipeline) salvage(ctx context.Context, batch []Message, cause error) (int, error) {
+	if _, ok := p.store.(DeadLetterStore); !ok {
+		return 0, cause
+	}
+	if len(batch) == 1 {
+		if err := p.deadLetter(ctx, batch[0], cause); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	done := 0
+	mid := len(batch) / 2
+	for _, part := range [][]Message{batch[:mid], batch[mid:]} {
+		if err := p.store.StoreBatch(ctx, part); err != nil {
+			n, err := p.salvage(ctx, part, err)
+			done += n
+			if err != nil {
+				return done, err
+			}
+			continue
+		}
+		done += len(part)
 	}
+	return done, nil
+}
 
+// deadLetter hands a message the store refused to the dead letter store.
+func (p *Pipeline) deadLetter(ctx context.Context, msg Message, cause error) error {
+	if err := p.store.(DeadLetterStore).StoreDeadLetter(ctx, msg, cause); err != nil {
+		return err
+	}
+
+	if p.cfg.Logger != nil {
+		p.cfg.Logger.Warn("dead-lettered message the store refused",
+			"channel", msg.ChannelName,
+			"username", msg.Username,
+			"error", cause,
+		)
+	}
+	if p.otelProvider != nil {
+		p.otelProvider.RecordDeadLetters(ctx, 1)
+	}
+	return nil
+}
+
+// spoolOrDrop keeps rec, which the store refused, in the spool for a later
+// replay. Without a spool, or if the spool can't take it, it is lost.
+func (p *Pipeline) spoolOrDrop(ctx context.Context, rec spoolRecord, msg string, keysAndValues ...any) {
+	if p.cfg.Spool != nil {
+		err := p.cfg.Spool.append(rec)
+		if err == nil {
+			if p.cfg.Logger != nil {
+				p.cfg.Logger.Warn(msg+", spooled for retry", keysAndValues...)
+			}
+			return
+		}
+		keysAndValues = append(keysAndValues, "spool_error", err)
+	}
+
+	count := max(len(rec.Messages), 1)
+	if p.cfg.Logger != nil {
+		p.cfg.Logger.Error(msg, keysAndValues...)
+	}
 	if p.cfg.Metrics != nil {
-		p.cfg.Metrics.RecordBatchSize(len(batch))
-		p.cfg.Metrics.RecordBatchLatency(time.Since(start))
+		p.cfg.Metrics.RecordDroppedMessages(count)
+	}
+	if p.otelProvider != nil {
+		p.otelProvider.RecordDroppedMessages(ctx, count)
 	}
 }
 
-func (p *Pipeline) resetTimer() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// replayTask is a writer's share of a round of replay: the records, or
+// the parts of records, of its channels, in spool order.
+type replayTask struct {
+	records []spoolRecord
+	indices []int // Of each record in the round
+	results chan<- replayResult
+}
 
-	if p.timer != nil {
-		if !p.timer.Stop() {
-			select {
-			case <-p.timer.C:
-			default:
+// replayResult reports how many of a task's records its writer stored
+// before the first failure.
+type replayResult struct {
+	task   *replayTask
+	stored int
+	err    error
+}
+
+// replayRound is a batch of the oldest spooled records being replayed by
+// the writers of their channels.
+type replayRound struct {
+	records []spoolRecord
+	results chan replayResult
+	pending int // Tasks not reported yet
+	next    int // First record not stored
+	err     error
+}
+
+// record notes a writer's result and reports whether every task is done.
+func (r *replayRound) record(res replayResult) bool {
+	if res.stored < len(res.task.records) {
+		r.next = min(r.next, res.task.indices[res.stored])
+		if r.err == nil {
+			r.err = res.err
+		}
+	}
+	r.pending--
+	return r.pending == 0
+}
+
+// startReplay hands the oldest spooled records to the writers of their
+// channels, which replay them between their live items, so each channel's
+// items are still stored one at a time and dispatch carries on meanwhile.
+// finishReplay commits the round once every writer has reported.
+func (p *Pipeline) startReplay(ctx context.Context) {
+	records, err := p.cfg.Spool.peek(p.cfg.BatchSize)
+	if err != nil {
+		p.spoolTimer.Reset(p.replayFailed(err))
+		return
+	}
+	if len(records) == 0 {
+		p.spoolRetry = 0
+		p.spoolTimer.Reset(spoolRetryInterval)
+		return
+	}
+
+	tasks := p.partitionRecords(records)
+	round := &replayRound{
+		records: records,
+		results: make(chan replayResult, len(tasks)),
+		next:    len(records),
+	}
+	for i, task := range tasks {
+		if task == nil {
+			continue
+		}
+		task.results = round.results
+		select {
+		case p.writers[i].items <- queueItem{replay: task}:
+			round.pending++
+		case <-ctx.Done():
+			// Stopping; the round is replayed again next run
+			return
+		case <-p.done:
+			return
+		}
+	}
+	if round.pending == 0 {
+		// Nothing but unreadable records, which commit steps over
+		p.finishReplay(round)
+		return
+	}
+	p.replaying = round
+}
+
+// finishReplay commits the records of a round stored before the first
+// failure and schedules the next round, backing off while the store
+// fails. Records after the failure that other writers did store are
+// replayed again; the store skips messages it already has by id.
+func (p *Pipeline) finishReplay(round *replayRound) {
+	err := round.err
+	if commitErr := p.cfg.Spool.commit(round.records[:round.next]); commitErr != nil && err == nil {
+		err = commitErr
+	}
+	if err != nil {
+		p.spoolTimer.Reset(p.replayFailed(err))
+		return
+	}
+	p.spoolRetry = 0
+	// There may be more; come straight back
+	p.spoolTimer.Reset(0)
+}
+
+// replayFailed logs a failed round of replay and returns how long to back
+// off before the next.
+func (p *Pipeline) replayFailed(err error) time.Duration {
+	p.spoolRetry = min(max(p.spoolRetry*2, spoolRetryInterval), spoolMaxRetryInterval)
+	if p.cfg.Logger != nil {
+		p.cfg.Logger.Warn("failed to replay spool, will retry",
+			"pending", p.cfg.Spool.Len(),
+			"retry_in", p.spoolRetry.String(),
+			"error", err,
+		)
+	}
+	return p.spoolRetry
+}
+
+// replaySpooled stores records in order and returns how many were stored
+// before the first failure. Messages of consecutive records are stored
+// together, up to the batch size, and salvaged like a flushed batch.
+func (p *Pipeline) replaySpooled(ctx context.Context, records []spoolRecord) (int, error) {
+	var batch []Message
+	batchStart := 0
+	// storeBatch returns, on failure, the first record not stored
+	storeBatch := func() (int, error) {
+		if len(batch) == 0 {
+			return batchStart, nil
+		}
+		if err := p.store.StoreBatch(ctx, batch); err != nil {
+			// A record only partly salvaged is replayed again whole
+			n, err := p.salvage(ctx, batch, err)
+			if err != nil {
+				return batchStart + wholeRecords(records[batchStart:], n), err
+			}
+		}
+		batch = nil
+		return batchStart, nil
+	}
+
+	for i, rec := range records {
+		if len(rec.Messages) > 0 {
+			if len(batch) == 0 {
+				batchStart = i
 			}
+			batch = append(batch, rec.Messages...)
+			if len(batch) >= p.cfg.BatchSize {
+				if next, err := storeBatch(); err != nil {
+					return next, err
+				}
+			}
+			continue
+		}
+
+		// Messages before an event are stored first, as they were received
+		if next, err := storeBatch(); err != nil {
+			return next, err
+		}
+		var err error
+		switch {
+		case rec.Moderation != nil:
+			err = p.storeModeration(ctx, *rec.Moderation)
+		case rec.Notice != nil:
+			err = p.storeNotice(ctx, *rec.Notice)
+		case rec.RoomState != nil:
+			err = p.storeRoomState(ctx, *rec.RoomState)
+		case rec.Whisper != nil:
+			err = p.storeWhisper(ctx, *rec.Whisper)
 		}
-		p.timer.Reset(p.cfg.FlushTimeout)
+		if err != nil {
+			return i, err
+		}
+	}
+	if next, err := storeBatch(); err != nil {
+		return next, err
 	}
+	return len(records), nil
 }
 
-// BufferLen returns the current buffer length.
+// wholeRecords returns how many records, from the first, are covered by
+// their first n messages.
+func wholeRecords(records []spoolRecord, n int) int {
+	for i, rec := range records {
+		if n < len(rec.Messages) {
+			return i
+		}
+		n -= len(rec.Messages)
+	}
+	return len(records)
+}
+
+// BufferLen returns how many items are queued, including those handed to
+// a writer but not yet batched.
 func (p *Pipeline) BufferLen() int {
-	return len(p.messages)
+	n := len(p.messages)
+	for _, w := range p.writers {
+		n += len(w.items)
+	}
+	return n
 }
 
-// BatchLen returns the current batch length.
+// BatchLen returns how many messages are batched for the next flushes.
 func (p *Pipeline) BatchLen() int {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	return len(p.batch)
+	n := 0
+	for _, w := range p.writers {
+		n += w.batchLen()
+	}
+	return n
 }
@@ -3,10 +3,14 @@ package ingestion
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/asabla/goknut/internal/irc"
 	"github.com/asabla/goknut/internal/observability"
 	"github.com/asabla/goknut/internal/repository"
 )
@@ -31,6 +35,21 @@ type ProcessorConfig struct {
 	CacheTTL        time.Duration                  // TTL for cache entries, defaults to 5 minutes
 	OnMessageStored func(msg StoredMessage)        // Called for each message stored (optional)
 	OnBatchStored   func(messages []StoredMessage) // Called after a batch is stored (optional)
+
+	// ModerationRepo enables storage of moderation events (optional).
+	ModerationRepo *repository.ModerationRepository
+	// NoticeRepo enables storage of user notices (optional).
+	NoticeRepo *repository.NoticeRepository
+	// RoomStateRepo enables storage of channel chat mode history (optional).
+	RoomStateRepo *repository.ChannelStateRepository
+	// WhisperRepo enables storage of whispers (optional).
+	WhisperRepo *repository.WhisperRepository
+	// DeadLetterRepo enables storage of messages the database refused
+	// (optional).
+	DeadLetterRepo *repository.DeadLetterRepository
+	// ModerationLookback bounds how far back timeouts, bans and chat clears
+	// mark messages deleted. Defaults to 10 minutes.
+	ModerationLookback time.Duration
 }
 
 // cacheEntry holds a cached value with its timestamp.
@@ -44,10 +63,16 @@ type Processor struct {
 	messageRepo  *repository.MessageRepository
 	userRepo     *repository.UserRepository
 	channelRepo  *repository.ChannelRepository
+	modRepo      *repository.ModerationRepository
+	noticeRepo   *repository.NoticeRepository
+	stateRepo    *repository.ChannelStateRepository
+	whisperRepo  *repository.WhisperRepository
+	deadRepo     *repository.DeadLetterRepository
 	logger       *observability.Logger
 	metrics      *observability.Metrics
 	otelProvider *observability.OTelProvider
 	cacheTTL     time.Duration
+	lookback     time.Duration
 
 	// Callbacks
 	onMessageStored func(msg StoredMessage)
@@ -60,6 +85,10 @@ type Processor struct {
 	// Cache for username -> user ID mapping with TTL
 	userCache   map[string]cacheEntry
 	userCacheMu sync.RWMutex
+
+	// Room-id last recorded per channel ID
+	roomIDs   map[int64]string
+	roomIDsMu sync.Mutex
 }
 
 // NewProcessor creates a new message processor.
@@ -74,18 +103,30 @@ func NewProcessor(
 		cacheTTL = 5 * time.Minute // Default 5 minute TTL
 	}
 
+	lookback := cfg.ModerationLookback
+	if lookback <= 0 {
+		lookback = 10 * time.Minute
+	}
+
 	return &Processor{
 		messageRepo:     messageRepo,
 		userRepo:        userRepo,
 		channelRepo:     channelRepo,
+		modRepo:         cfg.ModerationRepo,
+		noticeRepo:      cfg.NoticeRepo,
+		stateRepo:       cfg.RoomStateRepo,
+		whisperRepo:     cfg.WhisperRepo,
+		deadRepo:        cfg.DeadLetterRepo,
 		logger:          cfg.Logger,
 		metrics:         cfg.Metrics,
 		otelProvider:    cfg.OTelProvider,
 		cacheTTL:        cacheTTL,
+		lookback:        lookback,
 		onMessageStored: cfg.OnMessageStored,
 		onBatchStored:   cfg.OnBatchStored,
 		channelCache:    make(map[string]cacheEntry),
 		userCache:       make(map[string]cacheEntry),
+		roomIDs:         make(map[int64]string),
 	}
 }
 
@@ -126,21 +167,17 @@ func (p *Processor) StoreBatch(ctx context.Context, messages []Message) error {
 			continue
 		}
 
-		// Get or create channel ID
+		// Get or create channel ID. A lookup that fails fails the batch,
+		// so the pipeline can keep it for a retry rather than lose it
 		channelID, err := p.getChannelID(ctx, channelName)
 		if err != nil {
-			if p.logger != nil {
-				p.logger.Error("failed to get channel ID",
-					"channel", channelName,
-					"error", err,
-				)
-			}
-			continue
+			return fmt.Errorf("failed to get channel ID for %s: %w", channelName, err)
 		}
 		if channelID == 0 {
 			// Channel doesn't exist, skip
 			continue
 		}
+		p.recordRoomID(ctx, channelID, channelName, msg.Tags["room-id"])
 
 		// Normalize username
 		username := normalizeUsername(msg.Username)
@@ -149,24 +186,31 @@ func (p *Processor) StoreBatch(ctx context.Context, messages []Message) error {
 		}
 
 		// Get or create user ID
-		userID, err := p.getOrCreateUserID(ctx, username, msg.DisplayName)
+		userID, err := p.getOrCreateUserID(ctx, msg.Tags["user-id"], username, msg.DisplayName)
 		if err != nil {
-			if p.logger != nil {
-				p.logger.Error("failed to get user ID",
-					"username", username,
-					"error", err,
-				)
-			}
-			continue
+			return fmt.Errorf("failed to get user ID for %s: %w", username, err)
+		}
+
+		// Prefer Twitch's server timestamp; fall back to when we read the line
+		sentAt := msg.SentAt
+		if sentAt.IsZero() {
+			sentAt = msg.ReceivedAt
+		} else if p.otelProvider != nil && !msg.ReceivedAt.IsZero() {
+			p.otelProvider.RecordIngestionLag(ctx, float64(msg.ReceivedAt.Sub(sentAt).Milliseconds()))
 		}
 
 		// Create repository message
 		repoMsg := repository.Message{
-			ChannelID: channelID,
-			UserID:    userID,
-			Text:      msg.Text,
-			SentAt:    msg.ReceivedAt,
-			Tags:      msg.Tags,
+			ChannelID:       channelID,
+			UserID:          userID,
+			Text:            msg.Text,
+			SentAt:          sentAt,
+			ReceivedAt:      msg.ReceivedAt,
+			Tags:            msg.Tags,
+			TwitchMessageID: msg.Tags["id"],
+			Bits:            irc.Tags(msg.Tags).Bits(),
+			Emotes:          repository.ParseMessageEmotes(msg.Tags, msg.Text),
+			Outbound:        msg.Outbound,
 		}
 		repoMessages = append(repoMessages, repoMsg)
 		msgMetadata = append(msgMetadata, msgMeta{
@@ -191,6 +235,32 @@ func (p *Processor) StoreBatch(ctx context.Context, messages []Message) error {
 		return err
 	}
 
+	// Drop messages already stored, e.g. by a redundant connection before
+	// a restart; the unique index skipped them
+	stored := 0
+	for i := range repoMessages {
+		if repoMessages[i].ID == 0 {
+			continue
+		}
+		repoMessages[stored] = repoMessages[i]
+		msgMetadata[stored] = msgMetadata[i]
+		stored++
+	}
+	duplicates := len(repoMessages) - stored
+	repoMessages = repoMessages[:stored]
+	msgMetadata = msgMetadata[:stored]
+	if duplicates > 0 {
+		if p.metrics != nil {
+			p.metrics.RecordDuplicateMessages(duplicates)
+		}
+		if p.otelProvider != nil {
+			p.otelProvider.RecordDuplicateMessages(ctx, duplicates)
+		}
+	}
+	if stored == 0 {
+		return nil
+	}
+
 	// Record metrics
 	latency := time.Since(start)
 	latencyMs := float64(latency.Milliseconds())
@@ -207,7 +277,8 @@ func (p *Processor) StoreBatch(ctx context.Context, messages []Message) error {
 		p.logger.Ingestion("stored message batch",
 			"count", len(repoMessages),
 			"latency_ms", latency.Milliseconds(),
-			"dropped", len(messages)-len(repoMessages),
+			"dropped", len(messages)-len(repoMessages)-duplicates,
+			"duplicates", duplicates,
 		)
 	}
 
@@ -243,6 +314,232 @@ func (p *Processor) StoreBatch(ctx context.Context, messages []Message) error {
 	return nil
 }
 
+// StoreModerationEvent implements the ModerationStore interface for the ingestion pipeline.
+// Events for unknown channels are ignored, matching StoreBatch.
+func (p *Processor) StoreModerationEvent(ctx context.Context, evt ModerationEvent) error {
+	if p.modRepo == nil {
+		return nil
+	}
+
+	channelName := normalizeChannelName(evt.ChannelName)
+	if channelName == "" {
+		return nil
+	}
+	channelID, err := p.getChannelID(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	if channelID == 0 {
+		return nil
+	}
+
+	// Messages are stored by Twitch's clock, so the lookback is measured
+	// on it too; after a reconnect or a replay receive time lags behind
+	occurredAt := evt.SentAt
+	if occurredAt.IsZero() {
+		occurredAt = evt.ReceivedAt
+	}
+
+	repoEvt := &repository.ModerationEvent{
+		ChannelID:       channelID,
+		Action:          evt.Action,
+		TargetUsername:  normalizeUsername(evt.TargetUsername),
+		TargetMessageID: evt.TargetMessageID,
+		DurationSeconds: int(evt.Duration / time.Second),
+		Text:            evt.Text,
+		Tags:            evt.Tags,
+		OccurredAt:      occurredAt,
+	}
+	if err := p.modRepo.Record(ctx, repoEvt, occurredAt.Add(-p.lookback)); err != nil {
+		return err
+	}
+
+	if p.logger != nil {
+		p.logger.Ingestion("stored moderation event",
+			"channel", channelName,
+			"action", evt.Action,
+			"target", repoEvt.TargetUsername,
+			"messages_deleted", repoEvt.AffectedCount,
+		)
+	}
+
+	return nil
+}
+
+// StoreNotice implements the NoticeStore interface for the ingestion pipeline.
+// Notices for unknown channels are ignored, matching StoreBatch.
+func (p *Processor) StoreNotice(ctx context.Context, n Notice) error {
+	if p.noticeRepo == nil {
+		return nil
+	}
+
+	channelName := normalizeChannelName(n.ChannelName)
+	if channelName == "" {
+		return nil
+	}
+	channelID, err := p.getChannelID(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	if channelID == 0 {
+		return nil
+	}
+
+	repoNotice := &repository.UserNotice{
+		ChannelID:     channelID,
+		Kind:          n.Kind,
+		Username:      normalizeUsername(n.Username),
+		DisplayName:   n.DisplayName,
+		SystemMessage: n.SystemMsg,
+		Text:          n.Text,
+		Params:        n.Params,
+		Tags:          n.Tags,
+		OccurredAt:    n.ReceivedAt,
+	}
+	if repoNotice.Username != "" {
+		userID, err := p.getOrCreateUserID(ctx, n.Tags["user-id"], repoNotice.Username, n.DisplayName)
+		if err != nil {
+			return err
+		}
+		repoNotice.UserID = &userID
+	}
+
+	if err := p.noticeRepo.Create(ctx, repoNotice); err != nil {
+		return err
+	}
+
+	if p.logger != nil {
+		p.logger.Ingestion("stored user notice",
+			"channel", channelName,
+			"kind", n.Kind,
+			"username", repoNotice.Username,
+		)
+	}
+
+	return nil
+}
+
+// StoreWhisper implements the WhisperStore interface for the ingestion
+// pipeline. Whispers don't touch users or channels, so they stay out of
+// message counts and search.
+func (p *Processor) StoreWhisper(ctx context.Context, w Whisper) error {
+	if p.whisperRepo == nil {
+		return nil
+	}
+
+	repoWhisper := &repository.Whisper{
+		Account:                normalizeUsername(w.To),
+		Counterpart:            normalizeUsername(w.From),
+		CounterpartDisplayName: w.FromDisplayName,
+		CounterpartUserID:      w.FromUserID,
+		ThreadID:               w.ThreadID,
+		TwitchMessageID:        w.MessageID,
+		Text:                   w.Text,
+		Tags:                   w.Tags,
+		ReceivedAt:             w.ReceivedAt,
+	}
+	if repoWhisper.Counterpart == "" {
+		return nil
+	}
+	if err := p.whisperRepo.Create(ctx, repoWhisper); err != nil {
+		return err
+	}
+
+	if p.logger != nil {
+		if repoWhisper.ID == 0 {
+			p.logger.Ingestion("skipped stored whisper", "from", repoWhisper.Counterpart)
+		} else {
+			p.logger.Ingestion("stored whisper", "from", repoWhisper.Counterpart)
+		}
+	}
+
+	return nil
+}
+
+// StoreDeadLetter implements the DeadLetterStore interface for the ingestion
+// pipeline. It fails without a dead letter repository, so the pipeline
+// keeps the message some other way.
+func (p *Processor) StoreDeadLetter(ctx context.Context, msg Message, cause error) error {
+	if p.deadRepo == nil {
+		return errors.New("dead letters are not enabled")
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	dl := &repository.DeadLetter{
+		ChannelName: normalizeChannelName(msg.ChannelName),
+		Username:    normalizeUsername(msg.Username),
+		Text:        msg.Text,
+		Payload:     string(payload),
+		Error:       cause.Error(),
+	}
+	if err := p.deadRepo.Create(ctx, dl); err != nil {
+		return err
+	}
+
+	if p.logger != nil {
+		p.logger.Ingestion("stored dead letter",
+			"id", dl.ID,
+			"channel", dl.ChannelName,
+			"error", dl.Error,
+		)
+	}
+	return nil
+}
+
+// Redrive stores a dead-lettered message again from its payload, as
+// StoreBatch would have.
+func (p *Processor) Redrive(ctx context.Context, payload string) error {
+	var msg Message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return p.StoreBatch(ctx, []Message{msg})
+}
+
+// StoreRoomState implements the RoomStateStore interface for the ingestion pipeline.
+// Updates for unknown channels are ignored, matching StoreBatch.
+func (p *Processor) StoreRoomState(ctx context.Context, rs RoomState) error {
+	if p.stateRepo == nil {
+		return nil
+	}
+
+	channelName := normalizeChannelName(rs.ChannelName)
+	if channelName == "" {
+		return nil
+	}
+	channelID, err := p.getChannelID(ctx, channelName)
+	if err != nil {
+		return err
+	}
+	if channelID == 0 {
+		return nil
+	}
+
+	p.recordRoomID(ctx, channelID, channelName, rs.RoomID)
+
+	_, changed, err := p.stateRepo.Apply(ctx, channelID, repository.ChannelStateUpdate{
+		EmoteOnly:            rs.EmoteOnly,
+		FollowersOnlyMinutes: rs.FollowersOnlyMinutes,
+		R9K:                  rs.R9K,
+		SlowSeconds:          rs.SlowSeconds,
+		SubsOnly:             rs.SubsOnly,
+	}, rs.ReceivedAt)
+	if err != nil {
+		return err
+	}
+
+	if changed && p.logger != nil {
+		p.logger.Ingestion("stored channel state change",
+			"channel", channelName,
+		)
+	}
+
+	return nil
+}
+
 // getChannelID returns the channel ID for a channel name from cache or database.
 func (p *Processor) getChannelID(ctx context.Context, channelName string) (int64, error) {
 	now := time.Now()
@@ -272,27 +569,88 @@ func (p *Processor) getChannelID(ctx context.Context, channelName string) (int64
 	return channel.ID, nil
 }
 
-// getOrCreateUserID returns the user ID for a username, creating if necessary.
-func (p *Processor) getOrCreateUserID(ctx context.Context, username, displayName string) (int64, error) {
+// recordRoomID stores the channel's Twitch room-id the first time it is seen
+// (or when it changes) and logs a rename if another channel already has it.
+// Failures are logged and otherwise ignored.
+func (p *Processor) recordRoomID(ctx context.Context, channelID int64, channelName, roomID string) {
+	if roomID == "" {
+		return
+	}
+
+	p.roomIDsMu.Lock()
+	known := p.roomIDs[channelID] == roomID
+	p.roomIDsMu.Unlock()
+	if known {
+		return
+	}
+
+	channel, err := p.channelRepo.GetByID(ctx, channelID)
+	if err != nil || channel == nil {
+		return
+	}
+
+	if channel.TwitchRoomID != roomID {
+		if err := p.channelRepo.SetRoomID(ctx, channelID, roomID); err != nil {
+			if p.logger != nil {
+				p.logger.Error("failed to record channel room id", "channel", channelName, "error", err)
+			}
+			return
+		}
+
+		siblings, err := p.channelRepo.ListByRoomID(ctx, roomID)
+		if err == nil && len(siblings) > 1 && p.logger != nil {
+			names := make([]string, len(siblings))
+			for i, ch := range siblings {
+				names[i] = ch.Name
+			}
+			p.logger.Warn("channel rename detected",
+				"room_id", roomID,
+				"channel", channelName,
+				"names", names,
+			)
+		}
+	}
+
+	p.roomIDsMu.Lock()
+	p.roomIDs[channelID] = roomID
+	p.roomIDsMu.Unlock()
+}
+
+// getOrCreateUserID returns the user ID for a sender, creating if necessary.
+// When the Twitch user-id is known it is the identity, so renames keep the
+// same user; the cache key includes the names so a rename misses the cache
+// and gets recorded.
+func (p *Processor) getOrCreateUserID(ctx context.Context, twitchUserID, username, displayName string) (int64, error) {
 	now := time.Now()
 
+	key := username
+	if twitchUserID != "" {
+		key = "id:" + twitchUserID + "/" + username + "/" + displayName
+	}
+
 	// Check cache first
 	p.userCacheMu.RLock()
-	if entry, ok := p.userCache[username]; ok && now.Sub(entry.createdAt) < p.cacheTTL {
+	if entry, ok := p.userCache[key]; ok && now.Sub(entry.createdAt) < p.cacheTTL {
 		p.userCacheMu.RUnlock()
 		return entry.value, nil
 	}
 	p.userCacheMu.RUnlock()
 
 	// Get or create user
-	user, err := p.userRepo.GetOrCreate(ctx, username, displayName)
+	var user *repository.User
+	var err error
+	if twitchUserID != "" {
+		user, err = p.userRepo.GetOrCreateByTwitchID(ctx, twitchUserID, username, displayName)
+	} else {
+		user, err = p.userRepo.GetOrCreate(ctx, username, displayName)
+	}
 	if err != nil {
 		return 0, err
 	}
 
 	// Update cache
 	p.userCacheMu.Lock()
-	p.userCache[username] = cacheEntry{value: user.ID, createdAt: now}
+	p.userCache[key] = cacheEntry{value: user.ID, createdAt: now}
 	p.userCacheMu.Unlock()
 
 	return user.ID, nil
@@ -307,6 +665,10 @@ func (p *Processor) ClearCaches() {
 	p.userCacheMu.Lock()
 	p.userCache = make(map[string]cacheEntry)
 	p.userCacheMu.Unlock()
+
+	p.roomIDsMu.Lock()
+	p.roomIDs = make(map[int64]string)
+	p.roomIDsMu.Unlock()
 }
 
 // InvalidateChannelCache removes a channel from the cache.
@@ -0,0 +1,499 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSpoolSegmentBytes is the size after which a new spool segment
+	// is started.
+	DefaultSpoolSegmentBytes = 4 << 20
+
+	// DefaultSpoolMaxBytes bounds the spool on disk; records that don't
+	// fit are dropped.
+	DefaultSpoolMaxBytes = 1 << 30
+
+	spoolSegmentPrefix = "spool-"
+	spoolSegmentSuffix = ".jsonl"
+	spoolCursorFile    = "cursor"
+)
+
+// ErrSpoolFull is returned when a record would grow the spool past its
+// maximum size.
+var ErrSpoolFull = errors.New("spool is full")
+
+// SpoolConfig holds spool configuration.
+type SpoolConfig struct {
+	Dir          string // Directory segments are kept in
+	SegmentBytes int64  // Segment size; 0 means DefaultSpoolSegmentBytes
+	MaxBytes     int64  // Total size; 0 means DefaultSpoolMaxBytes
+}
+
+// SpoolStats is a point-in-time view of the spool.
+type SpoolStats struct {
+	Records  int64     // Records waiting to be replayed
+	Bytes    int64     // Bytes on disk waiting to be replayed
+	Segments int       // Segment files on disk
+	OldestAt time.Time // When the oldest waiting record was spooled; zero if empty
+	Skipped  int64     // Unreadable records stepped over since the spool was opened
+}
+
+// spoolRecord is one entry in the spool: a batch of messages or a single
+// event. Exactly one of the item fields is set.
+type spoolRecord struct {
+	SpooledAt  time.Time        `json:"spooled_at"`
+	Messages   []Message        `json:"messages,omitempty"`
+	Moderation *ModerationEvent `json:"moderation,omitempty"`
+	Notice     *Notice          `json:"notice,omitempty"`
+	RoomState  *RoomState       `json:"room_state,omitempty"`
+	Whisper    *Whisper         `json:"whisper,omitempty"`
+
+	// Set when read back: where the record ends, and whether it could
+	// not be decoded
+	end     spoolPosition
+	corrupt bool
+}
+
+// spoolRecordFor returns the spool record holding a queued item.
+func spoolRecordFor(item queueItem) spoolRecord {
+	var rec spoolRecord
+	switch {
+	case item.msg != nil:
+		rec.Messages = []Message{*item.msg}
+	case item.moderation != nil:
+		rec.Moderation = item.moderation
+	case item.notice != nil:
+		rec.Notice = item.notice
+	case item.roomState != nil:
+		rec.RoomState = item.roomState
+	case item.whisper != nil:
+		rec.Whisper = item.whisper
+	}
+	return rec
+}
+
+// spoolPosition is a byte offset within a numbered segment.
+type spoolPosition struct {
+	segment int64
+	offset  int64
+}
+
+// spoolSegment describes one segment file.
+type spoolSegment struct {
+	seq     int64
+	size    int64
+	records int64 // Records after the cursor, for the head segment
+}
+
+// Spool is a durable, append-only queue of items the store could not take,
+// kept as JSON lines in numbered segment files. Records are read back
+// oldest first and deleted, a segment at a time, once committed; the read
+// position is kept in a cursor file so a restart resumes where it left off.
+//
+// Records are written straight to the file, so they survive the process
+// crashing or restarting, though not necessarily a power loss.
+type Spool struct {
+	dir          string
+	segmentBytes int64
+	maxBytes     int64
+
+	mu       sync.Mutex
+	segments []spoolSegment // Oldest first; the last is written to
+	file     *os.File       // Last segment, open for appending
+	cursor   spoolPosition  // Start of the oldest uncommitted record
+	oldestAt time.Time
+	skipped  int64
+}
+
+// OpenSpool opens the spool in cfg.Dir, creating the directory if needed,
+// and recovers records left by a previous run. A record cut short by a
+// crash is discarded.
+func OpenSpool(cfg SpoolConfig) (*Spool, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("spool directory is required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		dir:          cfg.Dir,
+		segmentBytes: cfg.SegmentBytes,
+		maxBytes:     cfg.MaxBytes,
+	}
+	if s.segmentBytes <= 0 {
+		s.segmentBytes = DefaultSpoolSegmentBytes
+	}
+	if s.maxBytes <= 0 {
+		s.maxBytes = DefaultSpoolMaxBytes
+	}
+
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover loads the segments and cursor left on disk.
+func (s *Spool) recover() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, spoolSegmentPrefix+"*"+spoolSegmentSuffix))
+	if err != nil {
+		return fmt.Errorf("failed to list spool segments: %w", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), spoolSegmentPrefix), spoolSegmentSuffix)
+		seq, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, spoolSegment{seq: seq})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	s.cursor = s.readCursor()
+	// Segments before the cursor were committed but not yet deleted
+	for len(s.segments) > 0 && s.segments[0].seq < s.cursor.segment {
+		os.Remove(s.segmentPath(s.segments[0].seq))
+		s.segments = s.segments[1:]
+	}
+	if len(s.segments) == 0 || s.segments[0].seq != s.cursor.segment {
+		s.cursor = spoolPosition{}
+		if len(s.segments) > 0 {
+			s.cursor.segment = s.segments[0].seq
+		}
+	}
+
+	for i := range s.segments {
+		seg := &s.segments[i]
+		from := int64(0)
+		if i == 0 {
+			from = s.cursor.offset
+		}
+		size, records, err := s.scanSegment(seg.seq, from, i == len(s.segments)-1)
+		if err != nil {
+			return err
+		}
+		seg.size = size
+		seg.records = records
+	}
+	if len(s.segments) > 0 && s.cursor.offset > s.segments[0].size {
+		s.cursor.offset = s.segments[0].size
+	}
+
+	if len(s.segments) == 0 {
+		return s.startSegment(1)
+	}
+	last := s.segments[len(s.segments)-1]
+	f, err := os.OpenFile(s.segmentPath(last.seq), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	s.file = f
+	s.oldestAt = s.headTime()
+	return nil
+}
+
+// scanSegment returns a segment's size and how many records follow from.
+// A torn record at the end of the last segment is truncated away.
+func (s *Spool) scanSegment(seq, from int64, last bool) (int64, int64, error) {
+	data, err := os.ReadFile(s.segmentPath(seq))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read spool segment: %w", err)
+	}
+
+	complete := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if complete < int64(len(data)) && last {
+		if err := os.Truncate(s.segmentPath(seq), complete); err != nil {
+			return 0, 0, fmt.Errorf("failed to truncate spool segment: %w", err)
+		}
+		data = data[:complete]
+	}
+	if from > int64(len(data)) {
+		from = int64(len(data))
+	}
+	return int64(len(data)), int64(bytes.Count(data[from:], []byte{'\n'})), nil
+}
+
+func (s *Spool) segmentPath(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", spoolSegmentPrefix, seq, spoolSegmentSuffix))
+}
+
+// startSegment opens a new, empty segment to append to. The caller must
+// hold s.mu or have sole use of s.
+func (s *Spool) startSegment(seq int64) error {
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = f
+	s.segments = append(s.segments, spoolSegment{seq: seq})
+	if len(s.segments) == 1 {
+		s.cursor = spoolPosition{segment: seq}
+	}
+	return nil
+}
+
+// append writes rec at the end of the spool.
+func (s *Spool) append(rec spoolRecord) error {
+	if rec.SpooledAt.IsZero() {
+		rec.SpooledAt = time.Now().UTC()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("spool is closed")
+	}
+	if s.pendingBytes()+int64(len(line)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+
+	last := &s.segments[len(s.segments)-1]
+	if last.size > 0 && last.size+int64(len(line)) > s.segmentBytes {
+		if err := s.startSegment(last.seq + 1); err != nil {
+			return err
+		}
+		last = &s.segments[len(s.segments)-1]
+	}
+
+	n, err := s.file.Write(line)
+	last.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write spool record: %w", err)
+	}
+	last.records++
+	if s.oldestAt.IsZero() {
+		s.oldestAt = rec.SpooledAt
+	}
+	return nil
+}
+
+// peek returns up to n of the oldest records without removing them.
+// Unreadable records are skipped.
+func (s *Spool) peek(n int) ([]spoolRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []spoolRecord
+	pos := s.cursor
+	for i := 0; i < len(s.segments) && len(records) < n; i++ {
+		seg := s.segments[i]
+		if seg.seq != pos.segment {
+			pos = spoolPosition{segment: seg.seq}
+		}
+		read, err := s.readRecords(pos, seg.size, n-len(records))
+		if err != nil {
+			return records, err
+		}
+		records = append(records, read...)
+	}
+	return records, nil
+}
+
+// readRecords reads up to n records from pos up to the segment's size.
+// The caller must hold s.mu.
+func (s *Spool) readRecords(pos spoolPosition, size int64, n int) ([]spoolRecord, error) {
+	if pos.offset >= size {
+		return nil, nil
+	}
+	f, err := os.Open(s.segmentPath(pos.segment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(io.NewSectionReader(f, pos.offset, size-pos.offset))
+	var records []spoolRecord
+	offset := pos.offset
+	for len(records) < n {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return records, fmt.Errorf("failed to read spool segment: %w", err)
+		}
+		offset += int64(len(line))
+
+		var rec spoolRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// Replayed as nothing, so commit steps over it
+			rec = spoolRecord{corrupt: true}
+		}
+		rec.end = spoolPosition{segment: pos.segment, offset: offset}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// commit removes records, which must be the oldest ones returned by peek,
+// deleting segments that have been read to the end.
+func (s *Spool) commit(records []spoolRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rec := range records {
+		if rec.corrupt {
+			s.skipped++
+		}
+		for i := range s.segments {
+			if s.segments[i].seq == rec.end.segment {
+				s.segments[i].records--
+				break
+			}
+		}
+	}
+
+	end := records[len(records)-1].end
+	for len(s.segments) > 1 && s.segments[0].seq < end.segment {
+		if err := s.dropHead(); err != nil {
+			return err
+		}
+	}
+	s.cursor = end
+	if len(s.segments) > 1 && s.cursor.offset >= s.segments[0].size {
+		if err := s.dropHead(); err != nil {
+			return err
+		}
+	}
+
+	s.oldestAt = s.headTime()
+	return s.writeCursor()
+}
+
+// dropHead deletes the oldest segment and moves the cursor to the next.
+// The caller must hold s.mu.
+func (s *Spool) dropHead() error {
+	if err := os.Remove(s.segmentPath(s.segments[0].seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete spool segment: %w", err)
+	}
+	s.segments = s.segments[1:]
+	s.cursor = spoolPosition{}
+	if len(s.segments) > 0 {
+		s.cursor.segment = s.segments[0].seq
+	}
+	return nil
+}
+
+// headTime returns when the oldest waiting record was spooled. The caller
+// must hold s.mu.
+func (s *Spool) headTime() time.Time {
+	pos := s.cursor
+	for _, seg := range s.segments {
+		if seg.seq != pos.segment {
+			pos = spoolPosition{segment: seg.seq}
+		}
+		records, err := s.readRecords(pos, seg.size, 1)
+		if err == nil && len(records) > 0 {
+			return records[0].SpooledAt
+		}
+	}
+	return time.Time{}
+}
+
+// readCursor returns the position saved by writeCursor, or the start.
+func (s *Spool) readCursor() spoolPosition {
+	data, err := os.ReadFile(filepath.Join(s.dir, spoolCursorFile))
+	if err != nil {
+		return spoolPosition{}
+	}
+	var pos spoolPosition
+	if _, err := fmt.Sscanf(string(data), "%d %d", &pos.segment, &pos.offset); err != nil {
+		return spoolPosition{}
+	}
+	return pos
+}
+
+// writeCursor saves the cursor, replacing the file so a crash leaves
+// either the old or the new position. The caller must hold s.mu.
+func (s *Spool) writeCursor() error {
+	path := filepath.Join(s.dir, spoolCursorFile)
+	tmp := path + ".tmp"
+	data := fmt.Sprintf("%d %d\n", s.cursor.segment, s.cursor.offset)
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+	return nil
+}
+
+// pendingBytes returns the bytes not yet committed. The caller must hold s.mu.
+func (s *Spool) pendingBytes() int64 {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	return total - s.cursor.offset
+}
+
+// Len returns the number of records waiting to be replayed.
+func (s *Spool) Len() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records int64
+	for _, seg := range s.segments {
+		records += seg.records
+	}
+	return records
+}
+
+// Stats returns the spool's size and age.
+func (s *Spool) Stats() SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SpoolStats{
+		Bytes:    s.pendingBytes(),
+		Segments: len(s.segments),
+		Skipped:  s.skipped,
+	}
+	for _, seg := range s.segments {
+		stats.Records += seg.records
+	}
+	if stats.Records > 0 {
+		stats.OldestAt = s.oldestAt
+	}
+	return stats
+}
+
+// Close closes the segment being written. Records still waiting are
+// replayed when the spool is next opened.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,230 @@
+package ingestion
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// writer batches and stores the items of the channels in its partition.
+// Each writer has its own goroutine, so while one writes a batch the
+// others, and the dispatcher feeding them, keep going.
+type writer struct {
+	p     *Pipeline
+	items chan queueItem
+	timer *time.Timer // Owned by run
+
+	mu    sync.Mutex
+	batch []Message
+}
+
+func newWriter(p *Pipeline) *writer {
+	return &writer{
+		p: p,
+		// Only a hand-off of about a batch: the backlog stays in the
+		// buffer, where BufferSize bounds it and the overflow policy sees
+		// it, rather than piling up behind a slow writer
+		items: make(chan queueItem, p.cfg.BatchSize),
+		batch: make([]Message, 0, p.cfg.BatchSize),
+	}
+}
+
+// partition returns the index of the writer for items of channel. Every
+// item of a channel goes to the same writer, which keeps them in order.
+func (p *Pipeline) partition(channel string) int {
+	if len(p.writers) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(normalizeChannelName(channel)))
+	return int(h.Sum32() % uint32(len(p.writers)))
+}
+
+// channel returns the channel of an item; whispers have none.
+func (item queueItem) channel() string {
+	switch {
+	case item.msg != nil:
+		return item.msg.ChannelName
+	case item.moderation != nil:
+		return item.moderation.ChannelName
+	case item.notice != nil:
+		return item.notice.ChannelName
+	case item.roomState != nil:
+		return item.roomState.ChannelName
+	}
+	return ""
+}
+
+// partitionRecords splits spooled records among the writers of their
+// channels, in order. A record of several channels' messages is split into
+// a part for each of their writers. Unreadable records go to none.
+func (p *Pipeline) partitionRecords(records []spoolRecord) []*replayTask {
+	tasks := make([]*replayTask, len(p.writers))
+	add := func(i, index int, rec spoolRecord) {
+		if tasks[i] == nil {
+			tasks[i] = &replayTask{}
+		}
+		tasks[i].records = append(tasks[i].records, rec)
+		tasks[i].indices = append(tasks[i].indices, index)
+	}
+
+	for index, rec := range records {
+		if len(rec.Messages) == 0 {
+			var channel string
+			switch {
+			case rec.Moderation != nil:
+				channel = rec.Moderation.ChannelName
+			case rec.Notice != nil:
+				channel = rec.Notice.ChannelName
+			case rec.RoomState != nil:
+				channel = rec.RoomState.ChannelName
+			case rec.Whisper == nil:
+				continue
+			}
+			add(p.partition(channel), index, rec)
+			continue
+		}
+
+		parts := make(map[int][]Message)
+		for _, msg := range rec.Messages {
+			i := p.partition(msg.ChannelName)
+			parts[i] = append(parts[i], msg)
+		}
+		for i, messages := range parts {
+			part := rec
+			part.Messages = messages
+			add(i, index, part)
+		}
+	}
+	return tasks
+}
+
+func (w *writer) run(ctx context.Context) {
+	defer w.p.wg.Done()
+	defer w.timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.p.done:
+			return
+		case item := <-w.items:
+			w.handleItem(ctx, item)
+		case <-w.timer.C:
+			w.flush(ctx)
+			w.resetTimer()
+		}
+	}
+}
+
+func (w *writer) handleItem(ctx context.Context, item queueItem) {
+	p := w.p
+
+	switch {
+	case item.msg != nil:
+		w.addToBatch(ctx, *item.msg)
+	case item.moderation != nil:
+		// Flush first so the event sees every message received before it
+		w.flush(ctx)
+		w.resetTimer()
+		if err := p.storeModeration(ctx, *item.moderation); err != nil {
+			p.spoolOrDrop(ctx, spoolRecordFor(item), "failed to store moderation event",
+				"channel", item.moderation.ChannelName,
+				"action", item.moderation.Action,
+				"error", err,
+			)
+		}
+	case item.notice != nil:
+		if err := p.storeNotice(ctx, *item.notice); err != nil {
+			p.spoolOrDrop(ctx, spoolRecordFor(item), "failed to store notice",
+				"channel", item.notice.ChannelName,
+				"kind", item.notice.Kind,
+				"error", err,
+			)
+		}
+	case item.roomState != nil:
+		if err := p.storeRoomState(ctx, *item.roomState); err != nil {
+			p.spoolOrDrop(ctx, spoolRecordFor(item), "failed to store room state",
+				"channel", item.roomState.ChannelName,
+				"error", err,
+			)
+		}
+	case item.replay != nil:
+		// Like an event, after the messages batched before it
+		w.flush(ctx)
+		w.resetTimer()
+		stored, err := p.replaySpooled(ctx, item.replay.records)
+		item.replay.results <- replayResult{task: item.replay, stored: stored, err: err}
+	case item.whisper != nil:
+		if err := p.storeWhisper(ctx, *item.whisper); err != nil {
+			p.spoolOrDrop(ctx, spoolRecordFor(item), "failed to store whisper",
+				"from", item.whisper.From,
+				"error", err,
+			)
+		}
+	}
+}
+
+func (w *writer) addToBatch(ctx context.Context, msg Message) {
+	w.mu.Lock()
+	w.batch = append(w.batch, msg)
+	shouldFlush := len(w.batch) >= w.p.cfg.BatchSize
+	w.mu.Unlock()
+
+	if shouldFlush {
+		w.flush(ctx)
+		w.resetTimer()
+	}
+}
+
+func (w *writer) flush(ctx context.Context) {
+	p := w.p
+
+	w.mu.Lock()
+	if len(w.batch) == 0 {
+		w.mu.Unlock()
+		return
+	}
+
+	batch := w.batch
+	w.batch = make([]Message, 0, p.cfg.BatchSize)
+	w.mu.Unlock()
+
+	start := time.Now()
+
+	if err := p.storeBatch(ctx, batch); err != nil {
+		// Don't let a few bad messages cost the whole batch
+		stored, err := p.salvage(ctx, batch, err)
+		if stored < len(batch) {
+			p.spoolOrDrop(ctx, spoolRecord{Messages: batch[stored:]}, "failed to store message batch",
+				"batch_size", len(batch)-stored,
+				"error", err,
+			)
+		}
+		return
+	}
+
+	if p.cfg.Metrics != nil {
+		p.cfg.Metrics.RecordBatchSize(len(batch))
+		p.cfg.Metrics.RecordBatchLatency(time.Since(start))
+	}
+}
+
+func (w *writer) resetTimer() {
+	if !w.timer.Stop() {
+		select {
+		case <-w.timer.C:
+		default:
+		}
+	}
+	w.timer.Reset(w.p.cfg.FlushTimeout)
+}
+
+// batchLen returns how many messages are waiting for the next flush.
+func (w *writer) batchLen() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.batch)
+}
@@ -2,14 +2,14 @@
 package irc
 
 import (
-	"bufio"
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"math/rand/v2"
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,6 +27,13 @@ const (
 	reconnectBackoffMult  = 2
 	maxReconnectAttempts  = 10  // Maximum attempts before giving up temporarily
 	reconnectJitterFactor = 0.2 // 20% jitter to prevent thundering herd
+
+	// registrationTimeout bounds the wait for the welcome (001) or an
+	// auth failure NOTICE after logging in
+	registrationTimeout = 10 * time.Second
+
+	// dialTimeout bounds opening a connection, including the TLS handshake
+	dialTimeout = 10 * time.Second
 )
 
 // AuthMode represents the IRC authentication mode.
@@ -46,6 +53,7 @@ type Message struct {
 	DisplayName string
 	Text        string
 	Tags        map[string]string
+	SentAt      time.Time // From tmi-sent-ts; zero if the tag is missing
 	ReceivedAt  time.Time
 }
 
@@ -55,23 +63,66 @@ type MessageHandler func(msg Message)
 // ChannelChangeHandler is called when channel join/part is requested.
 type ChannelChangeHandler func(channel string, joined bool)
 
+// Dialer opens the network connection to the IRC server. *net.Dialer
+// satisfies it.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// DialerFunc adapts a function to a Dialer.
+type DialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialContext calls f.
+func (f DialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
 // Client is a Twitch IRC client.
 type Client struct {
 	authMode   AuthMode
 	username   string
 	oauthToken string
-
-	conn   net.Conn
-	reader *bufio.Reader
+	server     string
+	useTLS     bool
+	dialer     Dialer
 
 	mu             sync.RWMutex
+	sess           *session // Current connection
 	channels       map[string]bool
 	connected      bool
 	reconnecting   bool
 	reconnectDelay time.Duration
+	err            error // Set when the client stopped for good, e.g. ErrAuthFailed
+
+	// Make-before-break: on RECONNECT, or when sess drops, a standby
+	// session joins every channel before it replaces sess; while both are
+	// open, lines seen on both are delivered once
+	makeBeforeBreak bool
+	replacing       bool
+	standby         *session
+	overlap         atomic.Pointer[recentIDs]
 
 	onMessage       MessageHandler
+	onModeration    ModerationHandler
+	onNotice        NoticeHandler
+	onRoomState     RoomStateHandler
+	onWhisper       WhisperHandler
+	onServerEvent   ServerEventHandler
 	onChannelChange ChannelChangeHandler
+	onJoin          JoinHandler
+
+	// JOINs are queued and sent by joinLoop as joinLimiter allows
+	joinLimiter   *RateLimiter
+	joinTimeout   time.Duration
+	joinQueue     []string
+	joinStates    map[string]JoinStatus
+	joinWake      chan struct{}
+	joinLoopStart sync.Once
+
+	// Chat messages are refused once sendLimiter runs out
+	sendLimiter *RateLimiter
+
+	recorder *Recorder
 
 	done chan struct{}
 	wg   sync.WaitGroup
@@ -83,7 +134,49 @@ type ClientConfig struct {
 	Username        string   // Required for authenticated, optional for anonymous
 	OAuthToken      string   // Required for authenticated, must be empty for anonymous
 	OnMessage       MessageHandler
+	OnModeration    ModerationHandler  // Optional: called for CLEARCHAT/CLEARMSG
+	OnNotice        NoticeHandler      // Optional: called for USERNOTICE
+	OnRoomState     RoomStateHandler   // Optional: called for ROOMSTATE
+	OnWhisper       WhisperHandler     // Optional: called for WHISPER (authenticated only)
+	OnServerEvent   ServerEventHandler // Optional: called for NOTICE and RECONNECT
 	OnChannelChange ChannelChangeHandler
+	OnJoin          JoinHandler // Optional: called as joins are confirmed or fail
+
+	// Server is the IRC server as host:port. Empty means Twitch:
+	// TwitchIRCServerTLS, or TwitchIRCServer with DisableTLS.
+	Server string
+
+	// DisableTLS connects in plain text, e.g. to a local test server or a
+	// bouncer.
+	DisableTLS bool
+
+	// Dialer opens the connection; TLS, unless disabled, is layered on top.
+	// Nil means a net.Dialer.
+	Dialer Dialer
+
+	// MakeBeforeBreak makes a RECONNECT open a second connection and join
+	// every channel on it before the old connection is closed, so nothing
+	// is missed. Messages seen on both are delivered once, by their id tag.
+	// A dropped connection is replaced the same way, straight away rather
+	// than after the reconnect backoff.
+	MakeBeforeBreak bool
+
+	// JoinLimiter rate limits JOINs. Nil means a limiter of its own using
+	// DefaultJoinLimit(AuthMode); share one between connections on the same
+	// account.
+	JoinLimiter *RateLimiter
+
+	// JoinTimeout is how long a JOIN may go unconfirmed before it is sent
+	// again. Zero means 15 seconds.
+	JoinTimeout time.Duration
+
+	// SendLimiter rate limits chat messages. Nil means a limiter of its own
+	// using SendLimit; share one between connections on the same account.
+	SendLimiter *RateLimiter
+
+	// Recorder, if set, records every line read from the server so it can
+	// be replayed later; share one between connections.
+	Recorder *Recorder
 }
 
 // NewClient creates a new IRC client.
@@ -95,75 +188,143 @@ func NewClient(cfg ClientConfig) *Client {
 		username = fmt.Sprintf("justinfan%d", rand.IntN(99999)+1)
 	}
 
+	joinLimiter := cfg.JoinLimiter
+	if joinLimiter == nil {
+		joinLimiter = NewRateLimiter(DefaultJoinLimit(cfg.AuthMode))
+	}
+
+	sendLimiter := cfg.SendLimiter
+	if sendLimiter == nil {
+		sendLimiter = NewRateLimiter(SendLimit)
+	}
+
+	joinTimeout := cfg.JoinTimeout
+	if joinTimeout <= 0 {
+		joinTimeout = defaultJoinTimeout
+	}
+
+	server := cfg.Server
+	if server == "" {
+		server = TwitchIRCServerTLS
+		if cfg.DisableTLS {
+			server = TwitchIRCServer
+		}
+	}
+
+	var dialer Dialer = &net.Dialer{}
+	if cfg.Dialer != nil {
+		dialer = cfg.Dialer
+	}
+
 	return &Client{
 		authMode:        cfg.AuthMode,
 		username:        username,
 		oauthToken:      cfg.OAuthToken,
+		server:          server,
+		useTLS:          !cfg.DisableTLS,
+		dialer:          dialer,
 		channels:        make(map[string]bool),
 		reconnectDelay:  initialReconnectDelay,
 		onMessage:       cfg.OnMessage,
+		onModeration:    cfg.OnModeration,
+		onNotice:        cfg.OnNotice,
+		onRoomState:     cfg.OnRoomState,
+		onWhisper:       cfg.OnWhisper,
+		onServerEvent:   cfg.OnServerEvent,
 		onChannelChange: cfg.OnChannelChange,
+		onJoin:          cfg.OnJoin,
+		makeBeforeBreak: cfg.MakeBeforeBreak,
+		joinLimiter:     joinLimiter,
+		joinTimeout:     joinTimeout,
+		sendLimiter:     sendLimiter,
+		recorder:        cfg.Recorder,
+		joinStates:      make(map[string]JoinStatus),
+		joinWake:        make(chan struct{}, 1),
 		done:            make(chan struct{}),
 	}
 }
 
-// Connect establishes a TLS connection to Twitch IRC.
+// Connect connects to the IRC server, over TLS unless disabled, and logs
+// in. It returns once the server welcomes the client, or an error wrapping
+// ErrAuthFailed if the login is rejected.
 // The context is used for cancellation during the connection process.
 func (c *Client) Connect(ctx context.Context) error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
 	if c.connected {
+		c.mu.Unlock()
 		return nil
 	}
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
 
 	// Check for context cancellation before connecting
 	select {
 	case <-ctx.Done():
+		c.mu.Unlock()
 		return ctx.Err()
 	default:
 	}
 
-	// Use TLS for secure connection (port 6697)
-	dialer := &net.Dialer{Timeout: 10 * time.Second}
-	conn, err := tls.DialWithDialer(dialer, "tcp", TwitchIRCServerTLS, &tls.Config{
-		MinVersion: tls.VersionTLS12,
-	})
+	sess, err := c.dial(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to connect to Twitch IRC (TLS): %w", err)
+		c.mu.Unlock()
+		return err
 	}
+	c.sess = sess
 
-	c.conn = conn
-	c.reader = bufio.NewReader(conn)
+	// Start read loop; it marks the client connected on the welcome
+	c.wg.Add(1)
+	go c.readLoop(sess)
+	c.mu.Unlock()
 
-	// Request capabilities (works for both auth modes)
-	if err := c.send("CAP REQ :twitch.tv/tags twitch.tv/commands"); err != nil {
-		c.conn.Close()
-		return fmt.Errorf("failed to request capabilities: %w", err)
-	}
-
-	// Authenticate based on mode
-	if c.authMode == AuthModeAuthenticated {
-		// Authenticated mode: send PASS with OAuth token, then NICK
-		if err := c.send("PASS " + c.oauthToken); err != nil {
-			c.conn.Close()
-			return fmt.Errorf("failed to send password: %w", err)
-		}
-	}
-	// For both modes, send NICK (anonymous uses justinfan nick)
-	if err := c.send("NICK " + c.username); err != nil {
-		c.conn.Close()
-		return fmt.Errorf("failed to send nick: %w", err)
+	if err := c.awaitWelcome(ctx, sess); err != nil {
+		// Unblocks the read loop, which exits without reconnecting since
+		// the client never connected
+		sess.conn.Close()
+		return err
 	}
+	return nil
+}
 
+// handleWelcome marks the client connected once Twitch accepts the login
+// and queues JOINs for every channel; on a reconnect this rejoins them.
+func (c *Client) handleWelcome() {
+	c.mu.Lock()
 	c.connected = true
 	c.reconnectDelay = initialReconnectDelay
 
-	// Start read loop
-	c.wg.Add(1)
-	go c.readLoop()
+	c.joinLoopStart.Do(func() {
+		c.wg.Add(1)
+		go c.joinLoop()
+	})
+	c.requeueJoins()
 
-	return nil
+	sess := c.sess
+	c.mu.Unlock()
+
+	sess.signalRegistered(nil)
+}
+
+// Err returns the error that stopped the client for good, such as
+// ErrAuthFailed, or nil.
+func (c *Client) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+// stopped reports whether the client was disconnected or failed for good.
+// The caller must hold c.mu.
+func (c *Client) stopped() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return c.err != nil
+	}
 }
 
 // Disconnect closes the IRC connection.
@@ -183,9 +344,12 @@ func (c *Client) Disconnect() error {
 	close(c.done)
 	c.connected = false
 
-	// Close connection to unblock any pending reads
-	if c.conn != nil {
-		c.conn.Close()
+	// Close connections to unblock any pending reads
+	if c.sess != nil {
+		c.sess.conn.Close()
+	}
+	if c.standby != nil {
+		c.standby.conn.Close()
 	}
 	c.mu.Unlock()
 
@@ -194,7 +358,9 @@ func (c *Client) Disconnect() error {
 	return nil
 }
 
-// Join joins a channel.
+// Join queues a JOIN for a channel. The JOIN is sent once the client is
+// connected and the join rate limit allows it, and the channel counts as
+// joined once the server confirms it; JoinStatus reports progress.
 func (c *Client) Join(channel string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -205,14 +371,13 @@ func (c *Client) Join(channel string) error {
 		return nil // Already joined
 	}
 
+	c.channels[channel] = true
 	if c.connected {
-		if err := c.send("JOIN " + channel); err != nil {
-			return fmt.Errorf("failed to join channel %s: %w", channel, err)
-		}
+		c.enqueueJoin(channel)
+	} else {
+		c.setJoinState(channel, JoinStatePending, "")
 	}
 
-	c.channels[channel] = true
-
 	if c.onChannelChange != nil {
 		go c.onChannelChange(channel, true)
 	}
@@ -231,13 +396,16 @@ func (c *Client) Part(channel string) error {
 		return nil // Not in channel
 	}
 
-	if c.connected {
+	// A channel still waiting in the queue was never joined
+	c.dequeueJoin(channel)
+	if state := c.joinStates[channel].State; c.connected && (state == JoinStateRequested || state == JoinStateJoined) {
 		if err := c.send("PART " + channel); err != nil {
 			return fmt.Errorf("failed to part channel %s: %w", channel, err)
 		}
 	}
 
 	delete(c.channels, channel)
+	delete(c.joinStates, channel)
 
 	if c.onChannelChange != nil {
 		go c.onChannelChange(channel, false)
@@ -276,16 +444,18 @@ func (c *Client) AuthMode() AuthMode {
 	return c.authMode
 }
 
+// send writes a line to the current connection. The caller must hold c.mu.
 func (c *Client) send(msg string) error {
-	if c.conn == nil {
+	if c.sess == nil {
 		return fmt.Errorf("not connected")
 	}
-	_, err := c.conn.Write([]byte(msg + "\r\n"))
-	return err
+	return c.sess.send(msg)
 }
 
-func (c *Client) readLoop() {
+// readLoop reads lines from one session until its connection fails.
+func (c *Client) readLoop(sess *session) {
 	defer c.wg.Done()
+	defer close(sess.closed)
 
 	for {
 		select {
@@ -294,106 +464,199 @@ func (c *Client) readLoop() {
 		default:
 		}
 
-		c.conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
-		line, err := c.reader.ReadString('\n')
+		sess.conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		line, err := sess.reader.ReadString('\n')
 		if err != nil {
-			c.handleDisconnect()
+			c.handleDisconnect(sess)
 			return
 		}
 
 		line = strings.TrimSpace(line)
-		c.handleLine(line)
+		receivedAt := time.Now().UTC()
+		if c.recorder != nil {
+			c.recorder.Record(receivedAt, line)
+		}
+		c.handleLine(sess, line, receivedAt)
 	}
 }
 
-func (c *Client) handleLine(line string) {
-	// Handle PING
-	if strings.HasPrefix(line, "PING") {
-		c.mu.Lock()
-		c.send("PONG" + line[4:])
-		c.mu.Unlock()
+// handleLine handles a line read from sess at receivedAt, or replayed when
+// sess is nil.
+func (c *Client) handleLine(sess *session, line string, receivedAt time.Time) {
+	m := ParseRawMessage(line)
+	if m == nil {
 		return
 	}
 
-	// Handle NOTICE (may contain auth failures or rate limit warnings)
-	if strings.Contains(line, "NOTICE") {
-		// Common auth failure messages:
-		// - "Login authentication failed"
-		// - "Improperly formatted auth"
-		// Rate limit messages typically contain "You are sending"
-		// For now, we log these internally but don't take action
-		// (could add callback for error handling in future)
+	// A nil session is a replayed line, which has no connection to act on
+	c.mu.RLock()
+	current := sess == nil || sess == c.sess
+	c.mu.RUnlock()
+
+	// A standby session only logs in and joins until it replaces the
+	// current one; its chat is delivered alongside, deduplicated
+	if !current {
+		if c.handleStandbyLine(sess, m) {
+			return
+		}
+	}
+	if c.isOverlapDuplicate(m, line) {
+		return
 	}
 
-	// Parse PRIVMSG
-	if strings.Contains(line, "PRIVMSG") {
-		msg := c.parseMessage(line)
+	switch m.Command {
+	case "001":
+		if sess != nil {
+			c.handleWelcome()
+		}
+	case "PING":
+		if sess != nil {
+			sess.send("PONG :" + m.Param(0))
+		}
+	case "JOIN":
+		// Twitch echoes our own JOIN once the channel is joined
+		if strings.EqualFold(m.Nick(), c.username) {
+			c.confirmJoin(m.Channel())
+		}
+	case "NOTICE", "RECONNECT":
+		evt := serverEventFromRaw(m, receivedAt)
+		if evt == nil {
+			return
+		}
+		if c.onServerEvent != nil {
+			c.onServerEvent(*evt)
+		}
+		switch {
+		case sess == nil:
+			// Replayed; there is no connection to act on
+		case evt.Kind == ServerEventAuthFailed:
+			c.handleAuthFailure(evt.Text)
+		case evt.Kind == ServerEventReconnect:
+			c.handleReconnectRequest(sess)
+		default:
+			// NOTICEs such as msg_channel_suspended fail a pending join
+			c.handleJoinNotice(m)
+		}
+	case "PRIVMSG":
+		msg := messageFromRaw(m, receivedAt)
 		if msg != nil && c.onMessage != nil {
 			c.onMessage(*msg)
 		}
+	case "CLEARCHAT", "CLEARMSG":
+		evt := moderationEventFromRaw(m, receivedAt)
+		if evt != nil && c.onModeration != nil {
+			c.onModeration(*evt)
+		}
+	case "USERNOTICE":
+		n := noticeFromRaw(m, receivedAt)
+		if n != nil && c.onNotice != nil {
+			c.onNotice(*n)
+		}
+	case "ROOMSTATE":
+		c.confirmJoin(m.Channel())
+		rs := roomStateFromRaw(m, receivedAt)
+		if rs != nil && c.onRoomState != nil {
+			c.onRoomState(*rs)
+		}
+	case "WHISPER":
+		w := whisperFromRaw(m, receivedAt)
+		if w != nil && c.onWhisper != nil {
+			c.onWhisper(*w)
+		}
 	}
 }
 
-func (c *Client) parseMessage(line string) *Message {
-	msg := &Message{
-		Tags:       make(map[string]string),
-		ReceivedAt: time.Now().UTC(),
+// ParseMessage parses a raw PRIVMSG line.
+// It returns nil if the line is not a well-formed PRIVMSG.
+//
+// Example:
+//
+//	@display-name=User;id=abc;tmi-sent-ts=1700000000000 :user!user@user.tmi.twitch.tv PRIVMSG #channel :hello
+func ParseMessage(line string) *Message {
+	return messageFromRaw(ParseRawMessage(line), time.Now().UTC())
+}
+
+func messageFromRaw(m *RawMessage, receivedAt time.Time) *Message {
+	// :user!user@user.tmi.twitch.tv PRIVMSG #channel :message
+	if m == nil || m.Command != "PRIVMSG" || len(m.Params) < 2 {
+		return nil
 	}
 
-	// Parse tags if present
-	if strings.HasPrefix(line, "@") {
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 {
-			return nil
-		}
-		tagStr := parts[0][1:]
-		line = parts[1]
+	msg := &Message{
+		Channel:     m.Channel(),
+		Username:    m.Nick(),
+		DisplayName: m.Tags.DisplayName(),
+		Text:        m.Param(1),
+		Tags:        m.Tags,
+		ReceivedAt:  receivedAt,
+	}
+	if sentAt, ok := m.Tags.SentAt(); ok {
+		msg.SentAt = sentAt
+	}
+	return msg
+}
 
-		for _, tag := range strings.Split(tagStr, ";") {
-			kv := strings.SplitN(tag, "=", 2)
-			if len(kv) == 2 {
-				msg.Tags[kv[0]] = kv[1]
-			}
-		}
+// handleAuthFailure stops the client: retrying a rejected login only gets
+// the account rate limited.
+func (c *Client) handleAuthFailure(text string) {
+	err := fmt.Errorf("%w: %s", ErrAuthFailed, text)
 
-		if dn, ok := msg.Tags["display-name"]; ok {
-			msg.DisplayName = dn
-		}
+	c.mu.Lock()
+	c.err = err
+	c.connected = false
+	sess := c.sess
+	if sess != nil {
+		sess.conn.Close()
 	}
+	c.mu.Unlock()
 
-	// Parse :user!user@user.tmi.twitch.tv PRIVMSG #channel :message
-	parts := strings.SplitN(line, " ", 4)
-	if len(parts) < 4 {
-		return nil
+	if sess != nil {
+		sess.signalRegistered(err)
 	}
+}
 
-	// Extract username
-	if strings.HasPrefix(parts[0], ":") {
-		userParts := strings.SplitN(parts[0][1:], "!", 2)
-		if len(userParts) >= 1 {
-			msg.Username = strings.ToLower(userParts[0])
-		}
-	}
+// handleReconnectRequest honors a RECONNECT. With make-before-break a
+// standby connection takes over; otherwise the connection is dropped and
+// reconnected right away instead of after the usual backoff.
+func (c *Client) handleReconnectRequest(sess *session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Extract channel
-	if strings.HasPrefix(parts[2], "#") {
-		msg.Channel = strings.ToLower(parts[2])
+	if sess != c.sess || c.replacing {
+		return
 	}
-
-	// Extract message text
-	if strings.HasPrefix(parts[3], ":") {
-		msg.Text = parts[3][1:]
-	} else {
-		msg.Text = parts[3]
+	if c.makeBeforeBreak {
+		c.replacing = true
+		c.wg.Add(1)
+		go c.replaceSession()
+		return
 	}
 
-	return msg
+	c.reconnectDelay = 0
+	sess.conn.Close()
 }
 
-func (c *Client) handleDisconnect() {
+func (c *Client) handleDisconnect(sess *session) {
 	c.mu.Lock()
-	c.connected = false
-	c.reconnecting = true
+	// A login that never completed (or was rejected) is reported by
+	// Connect, and a connection being replaced is handled by
+	// replaceSession; only the current, established connection is
+	// reconnected here
+	if sess != c.sess || !c.connected || c.err != nil || c.replacing {
+		c.mu.Unlock()
+		return
+	}
+	c.beginReconnect()
+
+	if c.makeBeforeBreak {
+		// There is nothing left to overlap with, but a standby logs in
+		// and joins every channel at once instead of after the backoff
+		c.replacing = true
+		c.mu.Unlock()
+		c.wg.Add(1)
+		go c.replaceSession()
+		return
+	}
 	c.mu.Unlock()
 
 	// Attempt reconnection with exponential backoff
@@ -402,6 +665,16 @@ func (c *Client) handleDisconnect() {
 	go c.reconnect()
 }
 
+// beginReconnect marks the client disconnected and its channels pending
+// ahead of a reconnect. The caller must hold c.mu.
+func (c *Client) beginReconnect() {
+	c.connected = false
+	c.reconnecting = true
+	for ch := range c.channels {
+		c.setJoinState(ch, JoinStatePending, "")
+	}
+}
+
 func (c *Client) reconnect() {
 	defer c.wg.Done()
 
@@ -444,26 +717,19 @@ func (c *Client) reconnect() {
 		}
 
 		if err := c.Connect(context.Background()); err != nil {
+			if errors.Is(err, ErrAuthFailed) {
+				c.mu.Lock()
+				c.reconnecting = false
+				c.mu.Unlock()
+				return
+			}
 			c.mu.Lock()
-			c.reconnectDelay = min(c.reconnectDelay*reconnectBackoffMult, maxReconnectDelay)
+			c.reconnectDelay = min(max(c.reconnectDelay*reconnectBackoffMult, initialReconnectDelay), maxReconnectDelay)
 			c.mu.Unlock()
 			continue
 		}
 
-		// Rejoin channels
-		c.mu.RLock()
-		channels := make([]string, 0, len(c.channels))
-		for ch := range c.channels {
-			channels = append(channels, ch)
-		}
-		c.mu.RUnlock()
-
-		for _, ch := range channels {
-			c.mu.Lock()
-			c.send("JOIN " + ch)
-			c.mu.Unlock()
-		}
-
+		// Connect has queued JOINs for every channel
 		c.mu.Lock()
 		c.reconnecting = false
 		c.mu.Unlock()
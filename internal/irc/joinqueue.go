@@ -0,0 +1,303 @@
+// Package irc provides a Twitch IRC client for chat message ingestion.
+package irc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// AuthenticatedJoinLimit is Twitch's JOIN limit for a regular
+	// (unverified) account.
+	AuthenticatedJoinLimit = RateLimit{Count: 20, Per: 10 * time.Second}
+
+	// AnonymousJoinLimit is used for justinfan connections. Twitch doesn't
+	// publish a limit for them and anonymous joins are counted per IP, so
+	// this stays well below the authenticated one.
+	AnonymousJoinLimit = RateLimit{Count: 10, Per: 10 * time.Second}
+)
+
+// DefaultJoinLimit returns the JOIN limit for an auth mode.
+func DefaultJoinLimit(mode AuthMode) RateLimit {
+	if mode == AuthModeAnonymous {
+		return AnonymousJoinLimit
+	}
+	return AuthenticatedJoinLimit
+}
+
+// JoinState is where a channel is in joining: queued, requested from the
+// server, confirmed, or failed.
+//
+//	pending -> requested -> joined
+//	   ^           |
+//	   +-timeout---+-> failed (NOTICE, send error or out of attempts)
+type JoinState string
+
+const (
+	// JoinStatePending means the JOIN is queued waiting for a rate limit
+	// token or for the connection to come up.
+	JoinStatePending JoinState = "pending"
+	// JoinStateRequested means the JOIN was sent and the server hasn't
+	// confirmed it yet.
+	JoinStateRequested JoinState = "requested"
+	// JoinStateJoined means the server echoed the JOIN or sent the
+	// channel's ROOMSTATE.
+	JoinStateJoined JoinState = "joined"
+	// JoinStateFailed means the server refused the JOIN, it could not be
+	// sent, or it went unconfirmed for maxJoinAttempts attempts.
+	JoinStateFailed JoinState = "failed"
+)
+
+const (
+	// defaultJoinTimeout is how long a requested JOIN may go unconfirmed
+	// before it is queued again, unless ClientConfig.JoinTimeout says.
+	defaultJoinTimeout = 15 * time.Second
+
+	// maxJoinAttempts is how many times a JOIN is sent before the channel
+	// is marked failed. Failed channels are tried again on reconnect.
+	maxJoinAttempts = 3
+)
+
+// joinFailureNotices are NOTICE msg-ids that mean a channel can't be joined.
+var joinFailureNotices = map[string]bool{
+	"msg_channel_suspended": true,
+	"msg_banned":            true,
+	"msg_room_not_found":    true,
+	"tos_ban":               true,
+}
+
+// JoinStatus is a channel's join state.
+type JoinStatus struct {
+	Channel   string
+	State     JoinState
+	Error     string    // Why the join failed; set when State is JoinStateFailed
+	Attempts  int       // JOINs sent since the channel was queued
+	QueuedAt  time.Time // When the channel was queued to be joined
+	UpdatedAt time.Time
+}
+
+// JoinResult reports a join that was confirmed or failed.
+type JoinResult struct {
+	Channel  string
+	Latency  time.Duration // Time from queueing to the outcome
+	Attempts int
+	Err      error
+}
+
+// JoinHandler is called when a queued join is confirmed or fails.
+type JoinHandler func(result JoinResult)
+
+// JoinStatus returns a channel's join state. It returns false if the
+// channel was never joined on this client.
+func (c *Client) JoinStatus(channel string) (JoinStatus, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status, ok := c.joinStates[normalizeChannel(channel)]
+	return status, ok
+}
+
+// JoinQueueDepth returns the number of channels waiting to be joined.
+func (c *Client) JoinQueueDepth() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.joinQueue)
+}
+
+// enqueueJoin marks channel pending and queues its JOIN. Attempts carry
+// over so a retried channel eventually fails. The caller must hold c.mu.
+func (c *Client) enqueueJoin(channel string) {
+	c.setJoinState(channel, JoinStatePending, "")
+	for _, ch := range c.joinQueue {
+		if ch == channel {
+			return
+		}
+	}
+	c.joinQueue = append(c.joinQueue, channel)
+
+	select {
+	case c.joinWake <- struct{}{}:
+	default:
+	}
+}
+
+// requeueJoins queues a fresh JOIN for every channel, e.g. after
+// (re)connecting. The caller must hold c.mu.
+func (c *Client) requeueJoins() {
+	channels := make([]string, 0, len(c.channels))
+	for ch := range c.channels {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+
+	c.joinQueue = c.joinQueue[:0]
+	for _, ch := range channels {
+		delete(c.joinStates, ch)
+		c.enqueueJoin(ch)
+	}
+}
+
+// dequeueJoin drops channel from the join queue. The caller must hold c.mu.
+func (c *Client) dequeueJoin(channel string) {
+	for i, ch := range c.joinQueue {
+		if ch == channel {
+			c.joinQueue = append(c.joinQueue[:i], c.joinQueue[i+1:]...)
+			return
+		}
+	}
+}
+
+// setJoinState moves a channel to state, keeping its attempts and queue
+// time. The caller must hold c.mu.
+func (c *Client) setJoinState(channel string, state JoinState, reason string) JoinStatus {
+	now := time.Now().UTC()
+	status, ok := c.joinStates[channel]
+	if !ok {
+		status = JoinStatus{Channel: channel, QueuedAt: now}
+	}
+	status.State = state
+	status.Error = reason
+	status.UpdatedAt = now
+	c.joinStates[channel] = status
+	return status
+}
+
+// joinLoop sends queued JOINs as the rate limiter allows. Channels still
+// queued when the connection drops stay pending and are queued again by
+// Connect.
+func (c *Client) joinLoop() {
+	defer c.wg.Done()
+
+	for {
+		c.mu.RLock()
+		empty := len(c.joinQueue) == 0
+		c.mu.RUnlock()
+
+		if empty {
+			select {
+			case <-c.done:
+				return
+			case <-c.joinWake:
+			}
+			continue
+		}
+
+		if !c.joinLimiter.Wait(c.done) {
+			return
+		}
+
+		c.mu.Lock()
+		if len(c.joinQueue) == 0 {
+			c.mu.Unlock()
+			continue
+		}
+		channel := c.joinQueue[0]
+		c.joinQueue = c.joinQueue[1:]
+		if !c.connected || !c.channels[channel] {
+			c.mu.Unlock()
+			continue
+		}
+
+		if err := c.send("JOIN " + channel); err != nil {
+			result := c.finishJoin(channel, err)
+			c.mu.Unlock()
+			c.reportJoin(result)
+			continue
+		}
+
+		status := c.joinStates[channel]
+		status.Attempts++
+		c.joinStates[channel] = status
+		requested := c.setJoinState(channel, JoinStateRequested, "")
+		c.mu.Unlock()
+
+		time.AfterFunc(c.joinTimeout, func() { c.joinTimedOut(channel, requested.UpdatedAt) })
+	}
+}
+
+// joinTimedOut queues a JOIN again if it is still unconfirmed since
+// requestedAt, or fails it after maxJoinAttempts.
+func (c *Client) joinTimedOut(channel string, requestedAt time.Time) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	c.mu.Lock()
+	status, ok := c.joinStates[channel]
+	if !ok || status.State != JoinStateRequested || !status.UpdatedAt.Equal(requestedAt) {
+		c.mu.Unlock()
+		return
+	}
+	if status.Attempts < maxJoinAttempts {
+		c.enqueueJoin(channel)
+		c.mu.Unlock()
+		return
+	}
+	result := c.finishJoin(channel, fmt.Errorf("no confirmation after %d attempts", status.Attempts))
+	c.mu.Unlock()
+	c.reportJoin(result)
+}
+
+// confirmJoin marks a channel joined once the server echoes our JOIN or
+// sends the channel's ROOMSTATE.
+func (c *Client) confirmJoin(channel string) {
+	c.mu.Lock()
+	status, ok := c.joinStates[channel]
+	if !ok || !c.channels[channel] || status.State == JoinStateJoined {
+		c.mu.Unlock()
+		return
+	}
+	c.dequeueJoin(channel)
+	result := c.finishJoin(channel, nil)
+	c.mu.Unlock()
+	c.reportJoin(result)
+}
+
+// handleJoinNotice fails a channel's join when a NOTICE says the channel
+// can't be joined.
+func (c *Client) handleJoinNotice(m *RawMessage) {
+	msgID := m.Tags["msg-id"]
+	if !joinFailureNotices[msgID] {
+		return
+	}
+
+	channel := m.Channel()
+	c.mu.Lock()
+	status, ok := c.joinStates[channel]
+	if !ok || !c.channels[channel] || status.State == JoinStateFailed {
+		c.mu.Unlock()
+		return
+	}
+	c.dequeueJoin(channel)
+	result := c.finishJoin(channel, fmt.Errorf("%s: %s", msgID, m.Param(1)))
+	c.mu.Unlock()
+	c.reportJoin(result)
+}
+
+// finishJoin moves a channel to joined, or failed if err is set, and
+// returns the result to report. The caller must hold c.mu.
+func (c *Client) finishJoin(channel string, err error) JoinResult {
+	var status JoinStatus
+	if err != nil {
+		status = c.setJoinState(channel, JoinStateFailed, err.Error())
+	} else {
+		status = c.setJoinState(channel, JoinStateJoined, "")
+	}
+	return JoinResult{
+		Channel:  channel,
+		Latency:  status.UpdatedAt.Sub(status.QueuedAt),
+		Attempts: status.Attempts,
+		Err:      err,
+	}
+}
+
+// reportJoin passes a join result to the OnJoin handler. It must be called
+// without c.mu held.
+func (c *Client) reportJoin(result JoinResult) {
+	if c.onJoin != nil {
+		c.onJoin(result)
+	}
+}
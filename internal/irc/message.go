@@ -0,0 +1,350 @@
+package irc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RawMessage is a single IRC line parsed per the IRCv3 message format:
+//
+//	[@tags] [:prefix] <command> [params...] [:trailing]
+//
+// Tag values are unescaped. The trailing parameter, if any, is the last
+// element of Params.
+type RawMessage struct {
+	Tags    Tags
+	Prefix  string // Without the leading ':'
+	Command string // Uppercased, e.g. "PRIVMSG" or "001"
+	Params  []string
+}
+
+// ParseRawMessage parses a raw IRC line (without the trailing CRLF).
+// It returns nil if the line has no command.
+func ParseRawMessage(line string) *RawMessage {
+	msg := &RawMessage{Tags: Tags{}}
+
+	if strings.HasPrefix(line, "@") {
+		tagStr, rest, _ := strings.Cut(line[1:], " ")
+		msg.Tags = parseTags(tagStr)
+		line = rest
+	}
+	line = strings.TrimLeft(line, " ")
+
+	if strings.HasPrefix(line, ":") {
+		prefix, rest, _ := strings.Cut(line[1:], " ")
+		msg.Prefix = prefix
+		line = strings.TrimLeft(rest, " ")
+	}
+
+	command, rest, _ := strings.Cut(line, " ")
+	if command == "" || command[0] == '@' || command[0] == ':' {
+		return nil
+	}
+	msg.Command = strings.ToUpper(command)
+	line = rest
+
+	for {
+		line = strings.TrimLeft(line, " ")
+		if line == "" {
+			break
+		}
+		if line[0] == ':' {
+			msg.Params = append(msg.Params, line[1:])
+			break
+		}
+		var param string
+		param, line, _ = strings.Cut(line, " ")
+		msg.Params = append(msg.Params, param)
+	}
+
+	return msg
+}
+
+// Nick returns the nickname part of the prefix (nick!user@host), lowercased.
+func (m *RawMessage) Nick() string {
+	nick, _, _ := strings.Cut(m.Prefix, "!")
+	if strings.Contains(nick, ".") {
+		return "" // server prefix such as tmi.twitch.tv
+	}
+	return strings.ToLower(nick)
+}
+
+// Param returns the i-th parameter or "" if it is missing.
+func (m *RawMessage) Param(i int) string {
+	if i < 0 || i >= len(m.Params) {
+		return ""
+	}
+	return m.Params[i]
+}
+
+// Channel returns the first parameter lowercased if it is a channel (#name).
+func (m *RawMessage) Channel() string {
+	if p := m.Param(0); strings.HasPrefix(p, "#") {
+		return strings.ToLower(p)
+	}
+	return ""
+}
+
+// String serializes the message back to a raw IRC line, escaping tag values.
+// Tags are written in sorted key order.
+func (m *RawMessage) String() string {
+	var b strings.Builder
+
+	if len(m.Tags) > 0 {
+		keys := make([]string, 0, len(m.Tags))
+		for k := range m.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteByte('@')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(';')
+			}
+			b.WriteString(k)
+			if v := m.Tags[k]; v != "" {
+				b.WriteByte('=')
+				b.WriteString(escapeTagValue(v))
+			}
+		}
+		b.WriteByte(' ')
+	}
+
+	if m.Prefix != "" {
+		b.WriteByte(':')
+		b.WriteString(m.Prefix)
+		b.WriteByte(' ')
+	}
+
+	b.WriteString(m.Command)
+
+	for i, p := range m.Params {
+		b.WriteByte(' ')
+		last := i == len(m.Params)-1
+		if last && (p == "" || strings.HasPrefix(p, ":") || strings.Contains(p, " ")) {
+			b.WriteByte(':')
+		}
+		b.WriteString(p)
+	}
+
+	return b.String()
+}
+
+// parseTags parses an IRCv3 tag string (without the leading @) into a map,
+// unescaping values. Keys without a value map to "". Later duplicates win.
+func parseTags(tagStr string) Tags {
+	tags := make(Tags)
+	for _, tag := range strings.Split(tagStr, ";") {
+		if tag == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(tag, "=")
+		if key == "" {
+			continue
+		}
+		tags[key] = unescapeTagValue(value)
+	}
+	return tags
+}
+
+// unescapeTagValue reverses IRCv3 tag value escaping (\s, \:, \\, \r, \n).
+// Unknown escapes drop the backslash and a trailing backslash is removed.
+func unescapeTagValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+
+	var b strings.Builder
+	b.Grow(len(v))
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' {
+			b.WriteByte(v[i])
+			continue
+		}
+		if i+1 >= len(v) {
+			break // trailing backslash is dropped
+		}
+		i++
+		switch v[i] {
+		case 's':
+			b.WriteByte(' ')
+		case ':':
+			b.WriteByte(';')
+		case 'r':
+			b.WriteByte('\r')
+		case 'n':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeTagValue applies IRCv3 tag value escaping.
+func escapeTagValue(v string) string {
+	if !strings.ContainsAny(v, "; \\\r\n") {
+		return v
+	}
+
+	var b strings.Builder
+	b.Grow(len(v) + 8)
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case ';':
+			b.WriteString(`\:`)
+		case ' ':
+			b.WriteString(`\s`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// Tags holds unescaped IRCv3 message tags. It is assignable to and from
+// map[string]string, so typed accessors can be used on any tag map:
+//
+//	irc.Tags(msg.Tags).UserID()
+type Tags map[string]string
+
+// Badge is a single chat badge, e.g. subscriber/12.
+type Badge struct {
+	Name    string
+	Version string
+}
+
+// EmoteRange is an inclusive range of rune offsets in the message text.
+type EmoteRange struct {
+	Start int
+	End   int
+}
+
+// Text returns the part of the message text the range covers (the emote's
+// name), or "" if the range is outside the text.
+func (r EmoteRange) Text(text string) string {
+	runes := []rune(text)
+	if r.Start < 0 || r.End < r.Start || r.End >= len(runes) {
+		return ""
+	}
+	return string(runes[r.Start : r.End+1])
+}
+
+// Emote is an emote used in a message along with every place it occurs.
+type Emote struct {
+	ID     string
+	Ranges []EmoteRange
+}
+
+// Badges parses the badges tag (e.g. "broadcaster/1,subscriber/12").
+func (t Tags) Badges() []Badge {
+	return parseBadgeList(t["badges"])
+}
+
+// BadgeInfo parses the badge-info tag (e.g. "subscriber/14").
+func (t Tags) BadgeInfo() []Badge {
+	return parseBadgeList(t["badge-info"])
+}
+
+// Emotes parses the emotes tag (e.g. "25:0-4,12-16/1902:6-10").
+// Malformed entries are skipped.
+func (t Tags) Emotes() []Emote {
+	raw := t["emotes"]
+	if raw == "" {
+		return nil
+	}
+
+	var emotes []Emote
+	for _, entry := range strings.Split(raw, "/") {
+		id, ranges, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			continue
+		}
+		emote := Emote{ID: id}
+		for _, r := range strings.Split(ranges, ",") {
+			startStr, endStr, ok := strings.Cut(r, "-")
+			if !ok {
+				continue
+			}
+			start, err1 := strconv.Atoi(startStr)
+			end, err2 := strconv.Atoi(endStr)
+			if err1 != nil || err2 != nil || start < 0 || end < start {
+				continue
+			}
+			emote.Ranges = append(emote.Ranges, EmoteRange{Start: start, End: end})
+		}
+		if len(emote.Ranges) > 0 {
+			emotes = append(emotes, emote)
+		}
+	}
+	return emotes
+}
+
+// Color returns the user's chat color (e.g. "#1E90FF"), or "" if unset.
+func (t Tags) Color() string {
+	return t["color"]
+}
+
+// DisplayName returns the display-name tag.
+func (t Tags) DisplayName() string {
+	return t["display-name"]
+}
+
+// UserID returns the Twitch user ID of the sender.
+func (t Tags) UserID() string {
+	return t["user-id"]
+}
+
+// RoomID returns the Twitch user ID of the channel.
+func (t Tags) RoomID() string {
+	return t["room-id"]
+}
+
+// MessageID returns the id tag (the Twitch message ID).
+func (t Tags) MessageID() string {
+	return t["id"]
+}
+
+// SentAt returns the server timestamp from tmi-sent-ts (Unix milliseconds).
+// ok is false if the tag is missing or malformed.
+func (t Tags) SentAt() (sentAt time.Time, ok bool) {
+	ms, err := strconv.ParseInt(t["tmi-sent-ts"], 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms).UTC(), true
+}
+
+// Bits returns the number of bits cheered in the message, or 0.
+func (t Tags) Bits() int {
+	n, err := strconv.Atoi(t["bits"])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func parseBadgeList(raw string) []Badge {
+	if raw == "" {
+		return nil
+	}
+
+	var badges []Badge
+	for _, entry := range strings.Split(raw, ",") {
+		name, version, _ := strings.Cut(entry, "/")
+		if name == "" {
+			continue
+		}
+		badges = append(badges, Badge{Name: name, Version: version})
+	}
+	return badges
+}
@@ -0,0 +1,97 @@
+package irc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ModerationAction identifies the kind of moderation event.
+type ModerationAction string
+
+const (
+	// ModerationTimeout is a CLEARCHAT for a single user with a ban-duration.
+	ModerationTimeout ModerationAction = "timeout"
+	// ModerationBan is a CLEARCHAT for a single user without a ban-duration.
+	ModerationBan ModerationAction = "ban"
+	// ModerationClear is a CLEARCHAT without a target user (entire chat cleared).
+	ModerationClear ModerationAction = "clear"
+	// ModerationDelete is a CLEARMSG removing a single message.
+	ModerationDelete ModerationAction = "delete"
+)
+
+// ModerationEvent represents a parsed CLEARCHAT or CLEARMSG command.
+type ModerationEvent struct {
+	Channel         string
+	Action          ModerationAction
+	TargetUsername  string        // Empty for ModerationClear
+	TargetMessageID string        // Only set for ModerationDelete
+	Duration        time.Duration // Only set for ModerationTimeout
+	Text            string        // Deleted message text (ModerationDelete only)
+	Tags            map[string]string
+	SentAt          time.Time // From tmi-sent-ts; zero if the tag is missing
+	ReceivedAt      time.Time
+}
+
+// ModerationHandler is called for each incoming moderation event.
+type ModerationHandler func(evt ModerationEvent)
+
+// ParseModerationEvent parses a raw CLEARCHAT or CLEARMSG line.
+// It returns nil if the line is not a well-formed moderation command.
+//
+// Examples:
+//
+//	@ban-duration=600;room-id=1;target-user-id=2 :tmi.twitch.tv CLEARCHAT #channel :user
+//	@login=user;room-id=1;target-msg-id=abc :tmi.twitch.tv CLEARMSG #channel :text
+func ParseModerationEvent(line string) *ModerationEvent {
+	return moderationEventFromRaw(ParseRawMessage(line), time.Now().UTC())
+}
+
+func moderationEventFromRaw(m *RawMessage, receivedAt time.Time) *ModerationEvent {
+	if m == nil {
+		return nil
+	}
+
+	// :tmi.twitch.tv CLEARCHAT #channel [:target]
+	channel := m.Channel()
+	if channel == "" {
+		return nil
+	}
+	evt := &ModerationEvent{
+		Channel:    channel,
+		Tags:       m.Tags,
+		ReceivedAt: receivedAt,
+	}
+	if sentAt, ok := m.Tags.SentAt(); ok {
+		evt.SentAt = sentAt
+	}
+	trailing := m.Param(1)
+
+	switch m.Command {
+	case "CLEARCHAT":
+		if trailing == "" {
+			evt.Action = ModerationClear
+			return evt
+		}
+		evt.TargetUsername = strings.ToLower(trailing)
+		evt.Action = ModerationBan
+		if v, ok := evt.Tags["ban-duration"]; ok {
+			if secs, err := strconv.Atoi(v); err == nil {
+				evt.Action = ModerationTimeout
+				evt.Duration = time.Duration(secs) * time.Second
+			}
+		}
+	case "CLEARMSG":
+		evt.Action = ModerationDelete
+		evt.TargetUsername = strings.ToLower(evt.Tags["login"])
+		evt.TargetMessageID = evt.Tags["target-msg-id"]
+		evt.Text = trailing
+		if evt.TargetMessageID == "" {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	return evt
+}
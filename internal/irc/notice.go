@@ -0,0 +1,73 @@
+package irc
+
+import (
+	"strings"
+	"time"
+)
+
+// Common USERNOTICE msg-id values.
+const (
+	NoticeSub            = "sub"
+	NoticeResub          = "resub"
+	NoticeSubGift        = "subgift"
+	NoticeSubMysteryGift = "submysterygift"
+	NoticeRaid           = "raid"
+	NoticeAnnouncement   = "announcement"
+)
+
+// Notice represents a parsed USERNOTICE (subs, gift subs, raids, announcements, ...).
+type Notice struct {
+	Channel     string
+	Kind        string            // msg-id tag, e.g. "resub" or "raid"
+	Username    string            // login tag of the user who triggered the notice
+	DisplayName string            // display-name tag
+	SystemMsg   string            // system-msg tag
+	Text        string            // Optional message the user attached
+	Params      map[string]string // msg-param-* tags with the prefix stripped
+	Tags        map[string]string
+	ReceivedAt  time.Time
+}
+
+// NoticeHandler is called for each incoming USERNOTICE.
+type NoticeHandler func(n Notice)
+
+// ParseNotice parses a raw USERNOTICE line.
+// It returns nil if the line is not a well-formed USERNOTICE.
+//
+// Example:
+//
+//	@login=user;msg-id=resub;msg-param-cumulative-months=6;system-msg=user\ssubscribed :tmi.twitch.tv USERNOTICE #channel :Great stream!
+func ParseNotice(line string) *Notice {
+	return noticeFromRaw(ParseRawMessage(line), time.Now().UTC())
+}
+
+func noticeFromRaw(m *RawMessage, receivedAt time.Time) *Notice {
+	// :tmi.twitch.tv USERNOTICE #channel [:message]
+	if m == nil || m.Command != "USERNOTICE" || m.Channel() == "" {
+		return nil
+	}
+
+	n := &Notice{
+		Channel:    m.Channel(),
+		Text:       m.Param(1),
+		Tags:       m.Tags,
+		Params:     make(map[string]string),
+		ReceivedAt: receivedAt,
+	}
+
+	n.Kind = n.Tags["msg-id"]
+	if n.Kind == "" {
+		return nil
+	}
+	n.Username = strings.ToLower(n.Tags["login"])
+	n.DisplayName = n.Tags["display-name"]
+	n.SystemMsg = n.Tags["system-msg"]
+
+	for k, v := range n.Tags {
+		if param, ok := strings.CutPrefix(k, "msg-param-"); ok {
+			n.Params[param] = v
+		}
+	}
+
+	return n
+}
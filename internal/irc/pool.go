@@ -0,0 +1,487 @@
+// Package irc provides a Twitch IRC client for chat message ingestion.
+package irc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultChannelsPerConnection is the channel cap per pooled connection
+// when PoolConfig leaves it unset.
+const DefaultChannelsPerConnection = 50
+
+// PoolConn is a single IRC connection managed by a Pool. *Client
+// satisfies it.
+type PoolConn interface {
+	Connect(ctx context.Context) error
+	Disconnect() error
+	Join(channel string) error
+	Part(channel string) error
+	IsConnected() bool
+}
+
+// joinQueueConn is a PoolConn that reports its join queue, as *Client does.
+type joinQueueConn interface {
+	JoinStatus(channel string) (JoinStatus, bool)
+	JoinQueueDepth() int
+}
+
+// chatConn is a PoolConn that can send chat, as *Client does.
+type chatConn interface {
+	Say(channel, text string) error
+	Reply(channel, parentMsgID, text string) error
+}
+
+// PoolConfig holds connection pool configuration.
+type PoolConfig struct {
+	// Client configures every pooled connection; the handlers are shared.
+	// A nil Client.JoinLimiter or Client.SendLimiter is replaced by a
+	// limiter shared by all connections, since Twitch counts joins and
+	// messages per account; the two are separate buckets.
+	Client ClientConfig
+
+	// ChannelsPerConnection caps the channels joined on one connection.
+	// Zero means DefaultChannelsPerConnection.
+	ChannelsPerConnection int
+
+	// RedundantChannels are each joined on two connections, so a single
+	// dropped connection loses none of their messages. Both connections
+	// deliver every message; the ingestion pipeline drops the duplicates.
+	RedundantChannels []string
+
+	// NewConn creates a connection. Nil means NewClient(Client).
+	NewConn func(cfg ClientConfig) PoolConn
+}
+
+// ShardInfo describes one pooled connection.
+type ShardInfo struct {
+	Index     int
+	Connected bool
+	Channels  []string
+}
+
+// shard is one pooled connection and the channels assigned to it.
+type shard struct {
+	conn     PoolConn
+	channels map[string]bool
+}
+
+// Pool shards channels across several IRC connections so no single
+// connection carries more than ChannelsPerConnection channels. New
+// connections are opened as channels are joined, and when parts leave
+// enough room the least loaded connection is drained into the others and
+// closed. Redundant channels are joined on two different connections.
+//
+// Pool satisfies the same Join/Part/IsConnected contract as Client.
+type Pool struct {
+	cfg       PoolConfig
+	capacity  int
+	newConn   func(cfg ClientConfig) PoolConn
+	redundant map[string]bool
+
+	mu        sync.Mutex
+	shards    []*shard
+	assigned  map[string][]*shard // channel -> shards joined on it
+	connected bool
+	ctx       context.Context
+}
+
+// NewPool creates a new connection pool. No connection is opened until
+// Connect is called.
+func NewPool(cfg PoolConfig) *Pool {
+	capacity := cfg.ChannelsPerConnection
+	if capacity <= 0 {
+		capacity = DefaultChannelsPerConnection
+	}
+
+	if cfg.Client.JoinLimiter == nil {
+		cfg.Client.JoinLimiter = NewRateLimiter(DefaultJoinLimit(cfg.Client.AuthMode))
+	}
+	if cfg.Client.SendLimiter == nil {
+		cfg.Client.SendLimiter = NewRateLimiter(SendLimit)
+	}
+
+	newConn := cfg.NewConn
+	if newConn == nil {
+		newConn = func(c ClientConfig) PoolConn { return NewClient(c) }
+	}
+
+	redundant := make(map[string]bool, len(cfg.RedundantChannels))
+	for _, ch := range cfg.RedundantChannels {
+		redundant[normalizeChannel(ch)] = true
+	}
+
+	return &Pool{
+		cfg:       cfg,
+		capacity:  capacity,
+		newConn:   newConn,
+		redundant: redundant,
+		assigned:  make(map[string][]*shard),
+	}
+}
+
+// Connect opens a connection for every shard, or a first one if no
+// channel has been joined yet. Connections opened later for new channels
+// use ctx without its cancellation. It fails only if no connection could
+// be opened.
+func (p *Pool) Connect(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.connected {
+		return nil
+	}
+	p.ctx = context.WithoutCancel(ctx)
+
+	if len(p.shards) == 0 {
+		p.shards = append(p.shards, p.openShard())
+	}
+
+	// Channels joined before Connect were only assigned to a shard; join
+	// them now that their connection is up
+	var errs []error
+	for _, s := range p.shards {
+		if err := s.conn.Connect(ctx); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for ch := range s.channels {
+			if err := s.conn.Join(ch); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 && !p.anyConnected() {
+		return fmt.Errorf("failed to connect any pooled connection: %w", errors.Join(errs...))
+	}
+
+	p.connected = true
+	return nil
+}
+
+// Disconnect closes every connection.
+func (p *Pool) Disconnect() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.connected = false
+
+	var errs []error
+	for _, s := range p.shards {
+		if err := s.conn.Disconnect(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Join joins a channel on the least loaded connection with room,
+// opening a new connection if all are full. Redundant channels are joined
+// the same way on a second connection.
+func (p *Pool) Join(channel string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel = normalizeChannel(channel)
+	for len(p.assigned[channel]) < p.copies(channel) {
+		s := p.leastLoaded(channel, nil)
+		if s == nil || len(s.channels) >= p.capacity {
+			s = p.openShard()
+			if p.connected {
+				if err := s.conn.Connect(p.ctx); err != nil {
+					return fmt.Errorf("failed to open connection for %s: %w", channel, err)
+				}
+			}
+			p.shards = append(p.shards, s)
+		}
+
+		if err := p.join(s, channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Part leaves a channel, then closes a connection if the remaining
+// channels fit on fewer connections.
+func (p *Pool) Part(channel string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel = normalizeChannel(channel)
+	for _, s := range p.assigned[channel] {
+		if err := p.part(s, channel); err != nil {
+			return err
+		}
+	}
+
+	return p.rebalance()
+}
+
+// IsConnected returns true if any pooled connection is connected.
+func (p *Pool) IsConnected() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.anyConnected()
+}
+
+// Channels returns every channel joined across the pool.
+func (p *Pool) Channels() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channels := make([]string, 0, len(p.assigned))
+	for ch := range p.assigned {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// JoinStatus returns a channel's join state from the connection it is
+// assigned to. Redundant channels report joined if either connection has
+// joined them. Channels assigned while the pool is disconnected are
+// pending. It returns false for channels not in the pool.
+func (p *Pool) JoinStatus(channel string) (JoinStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel = normalizeChannel(channel)
+	shards, ok := p.assigned[channel]
+	if !ok {
+		return JoinStatus{}, false
+	}
+
+	result := JoinStatus{Channel: channel, State: JoinStatePending}
+	for i, s := range shards {
+		conn, ok := s.conn.(joinQueueConn)
+		if !ok {
+			continue
+		}
+		status, ok := conn.JoinStatus(channel)
+		if !ok {
+			continue
+		}
+		if i == 0 || status.State == JoinStateJoined {
+			result = status
+		}
+		if status.State == JoinStateJoined {
+			break
+		}
+	}
+	return result, true
+}
+
+// IsRedundant reports whether a channel is joined on two connections.
+func (p *Pool) IsRedundant(channel string) bool {
+	return p.redundant[normalizeChannel(channel)]
+}
+
+// JoinQueueDepth returns the number of channels waiting to be joined
+// across all connections.
+func (p *Pool) JoinQueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	depth := 0
+	for _, s := range p.shards {
+		if conn, ok := s.conn.(joinQueueConn); ok {
+			depth += conn.JoinQueueDepth()
+		}
+	}
+	return depth
+}
+
+// Say sends a chat message on a connection that has joined channel.
+func (p *Pool) Say(channel, text string) error {
+	return p.chat(channel, func(conn chatConn) error { return conn.Say(channel, text) })
+}
+
+// Reply sends a chat message as a reply to parentMsgID on a connection
+// that has joined channel.
+func (p *Pool) Reply(channel, parentMsgID, text string) error {
+	return p.chat(channel, func(conn chatConn) error { return conn.Reply(channel, parentMsgID, text) })
+}
+
+// chat sends with the first of the channel's connections that has joined
+// it; for redundant channels the second is tried if the first hasn't.
+func (p *Pool) chat(channel string, send func(conn chatConn) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel = normalizeChannel(channel)
+	err := fmt.Errorf("%w: %s", ErrNotJoined, channel)
+	for _, s := range p.assigned[channel] {
+		conn, ok := s.conn.(chatConn)
+		if !ok {
+			continue
+		}
+		if err = send(conn); !errors.Is(err, ErrNotJoined) {
+			return err
+		}
+	}
+	return err
+}
+
+// Username returns the nick pooled connections log in with. It is empty
+// for anonymous pools, whose connections each pick their own.
+func (p *Pool) Username() string {
+	return p.cfg.Client.Username
+}
+
+// IsAnonymous returns true if the pool connects in anonymous mode.
+func (p *Pool) IsAnonymous() bool {
+	return p.cfg.Client.AuthMode == AuthModeAnonymous
+}
+
+// Shards describes each pooled connection and its channels.
+func (p *Pool) Shards() []ShardInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	infos := make([]ShardInfo, len(p.shards))
+	for i, s := range p.shards {
+		channels := make([]string, 0, len(s.channels))
+		for ch := range s.channels {
+			channels = append(channels, ch)
+		}
+		sort.Strings(channels)
+		infos[i] = ShardInfo{Index: i, Connected: s.conn.IsConnected(), Channels: channels}
+	}
+	return infos
+}
+
+// rebalance drains the least loaded connection into the others while the
+// channels would fit on one fewer connection. Each moved channel is
+// joined on its new connection before it is parted from the old one, so
+// no messages are missed. The caller must hold p.mu.
+func (p *Pool) rebalance() error {
+	for len(p.shards) > 1 {
+		// Redundant channels take a slot on each of their connections and
+		// need two connections to stay apart
+		slots, minShards := 0, 1
+		for ch, shards := range p.assigned {
+			slots += len(shards)
+			if p.redundant[ch] {
+				minShards = 2
+			}
+		}
+		needed := (slots + p.capacity - 1) / p.capacity
+		if len(p.shards) <= max(needed, minShards) {
+			return nil
+		}
+
+		// Drain the least loaded connection, preferring the newest on ties
+		// so long-lived connections stay up
+		victim := p.shards[len(p.shards)-1]
+		for _, s := range p.shards {
+			if len(s.channels) < len(victim.channels) {
+				victim = s
+			}
+		}
+		for ch := range victim.channels {
+			target := p.leastLoaded(ch, victim)
+			if target == nil {
+				return nil
+			}
+			if err := p.join(target, ch); err != nil {
+				return fmt.Errorf("failed to move %s: %w", ch, err)
+			}
+			if err := p.part(victim, ch); err != nil {
+				return fmt.Errorf("failed to move %s: %w", ch, err)
+			}
+		}
+
+		for i, s := range p.shards {
+			if s == victim {
+				p.shards = append(p.shards[:i], p.shards[i+1:]...)
+				break
+			}
+		}
+		if err := victim.conn.Disconnect(); err != nil {
+			return fmt.Errorf("failed to close drained connection: %w", err)
+		}
+	}
+	return nil
+}
+
+// leastLoaded returns the shard with the fewest channels that has not
+// joined channel, skipping except. Ties go to the earliest shard. The
+// caller must hold p.mu.
+func (p *Pool) leastLoaded(channel string, except *shard) *shard {
+	var best *shard
+	for _, s := range p.shards {
+		if s == except || s.channels[channel] {
+			continue
+		}
+		if best == nil || len(s.channels) < len(best.channels) {
+			best = s
+		}
+	}
+	return best
+}
+
+// join assigns channel to s, joining it if the pool is connected. The
+// caller must hold p.mu.
+func (p *Pool) join(s *shard, channel string) error {
+	if p.connected {
+		if err := s.conn.Join(channel); err != nil {
+			return err
+		}
+	}
+	s.channels[channel] = true
+	p.assigned[channel] = append(p.assigned[channel], s)
+	return nil
+}
+
+// part removes channel from s, parting it if the pool is connected. The
+// caller must hold p.mu.
+func (p *Pool) part(s *shard, channel string) error {
+	if p.connected {
+		if err := s.conn.Part(channel); err != nil {
+			return err
+		}
+	}
+	delete(s.channels, channel)
+
+	var rest []*shard
+	for _, other := range p.assigned[channel] {
+		if other != s {
+			rest = append(rest, other)
+		}
+	}
+	if len(rest) == 0 {
+		delete(p.assigned, channel)
+	} else {
+		p.assigned[channel] = rest
+	}
+	return nil
+}
+
+// copies returns how many connections join channel.
+func (p *Pool) copies(channel string) int {
+	if p.redundant[channel] {
+		return 2
+	}
+	return 1
+}
+
+// anyConnected reports whether any pooled connection is up. The caller
+// must hold p.mu.
+func (p *Pool) anyConnected() bool {
+	for _, s := range p.shards {
+		if s.conn.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// openShard creates an unconnected shard. The caller must hold p.mu.
+func (p *Pool) openShard() *shard {
+	return &shard{conn: p.newConn(p.cfg.Client), channels: make(map[string]bool)}
+}
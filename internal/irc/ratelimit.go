@@ -0,0 +1,90 @@
+package irc
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimit is at most Count events per Per, with bursts up to Count.
+type RateLimit struct {
+	Count int
+	Per   time.Duration
+}
+
+// RateLimiter is a token bucket. Clients keep one for JOINs and another
+// for chat messages; see DefaultJoinLimit and SendLimit. One limiter may be
+// shared by several connections on the same account, since Twitch counts
+// both per account rather than per connection.
+type RateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	burst  float64
+	rate   float64 // tokens per second
+	last   time.Time
+}
+
+// NewRateLimiter creates a full token bucket for limit.
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Count <= 0 || limit.Per <= 0 {
+		limit = AuthenticatedJoinLimit
+	}
+	return &RateLimiter{
+		tokens: float64(limit.Count),
+		burst:  float64(limit.Count),
+		rate:   float64(limit.Count) / limit.Per.Seconds(),
+		last:   time.Now(),
+	}
+}
+
+// Reserve takes a token and returns how long the caller must wait before
+// using it. Tokens taken ahead of time are paid back in order, so
+// concurrent callers are served first come, first served.
+func (l *RateLimiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Allow takes a token if one is available now. Otherwise it takes none
+// and returns how long until one will be.
+func (l *RateLimiter) Allow() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens < 1 {
+		return time.Duration((1 - l.tokens) / l.rate * float64(time.Second)), false
+	}
+	l.tokens--
+	return 0, true
+}
+
+// Wait takes a token and sleeps until it may be used. It returns false if
+// done is closed first.
+func (l *RateLimiter) Wait(done <-chan struct{}) bool {
+	d := l.Reserve()
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
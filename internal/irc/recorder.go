@@ -0,0 +1,362 @@
+package irc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRecordMaxBytes is the uncompressed size after which a
+	// recording file is rotated.
+	DefaultRecordMaxBytes = 64 << 20
+
+	// DefaultRecordMaxAge is how long a recording file is written to
+	// before it is rotated.
+	DefaultRecordMaxAge = time.Hour
+
+	// recordFlushInterval bounds how much recorded traffic a crash can
+	// lose to the compressor's buffer
+	recordFlushInterval = time.Second
+
+	recordFilePrefix = "irc-"
+	recordFileSuffix = ".log.gz"
+)
+
+// RecorderConfig holds IRC recorder configuration.
+type RecorderConfig struct {
+	Dir      string        // Directory recording files are written to
+	MaxBytes int64         // Uncompressed bytes per file; 0 means DefaultRecordMaxBytes
+	MaxAge   time.Duration // Time per file; 0 means DefaultRecordMaxAge
+
+	// OnError is called when a line can't be recorded. Optional.
+	OnError func(err error)
+}
+
+// Recorder writes every raw line read from the server, with the time it
+// was read, to gzip-compressed files in a directory, rotating them by size
+// and age. A line is stored as "<RFC 3339 time>\t<raw line>". One Recorder
+// can be shared by every connection in a pool.
+type Recorder struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	onError  func(err error)
+
+	mu        sync.Mutex
+	file      *os.File
+	gz        *gzip.Writer
+	written   int64
+	openedAt  time.Time
+	flushedAt time.Time
+	closed    bool
+}
+
+// NewRecorder creates a recorder writing to cfg.Dir, creating the directory
+// if needed. The first file is opened when the first line is recorded.
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("recorder directory is required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	maxBytes := cfg.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultRecordMaxBytes
+	}
+	maxAge := cfg.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultRecordMaxAge
+	}
+
+	return &Recorder{
+		dir:      cfg.Dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		onError:  cfg.OnError,
+	}, nil
+}
+
+// Record writes line as read at time at. Errors go to OnError; a recording
+// that fails must not stop ingestion.
+func (r *Recorder) Record(at time.Time, line string) {
+	if err := r.record(at, line); err != nil && r.onError != nil {
+		r.onError(err)
+	}
+}
+
+func (r *Recorder) record(at time.Time, line string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	if r.gz != nil && (r.written >= r.maxBytes || at.Sub(r.openedAt) >= r.maxAge) {
+		if err := r.closeFile(); err != nil {
+			return err
+		}
+	}
+	if r.gz == nil {
+		if err := r.openFile(at); err != nil {
+			return err
+		}
+	}
+
+	n, err := fmt.Fprintf(r.gz, "%s\t%s\n", at.UTC().Format(time.RFC3339Nano), line)
+	r.written += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to record line: %w", err)
+	}
+
+	if at.Sub(r.flushedAt) >= recordFlushInterval {
+		r.flushedAt = at
+		if err := r.gz.Flush(); err != nil {
+			return fmt.Errorf("failed to flush recording: %w", err)
+		}
+	}
+	return nil
+}
+
+// openFile starts a new file named after at. The caller must hold r.mu.
+func (r *Recorder) openFile(at time.Time) error {
+	name := recordFilePrefix + at.UTC().Format("20060102T150405.000000000Z") + recordFileSuffix
+	f, err := os.OpenFile(filepath.Join(r.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open recording file: %w", err)
+	}
+
+	r.file = f
+	r.gz = gzip.NewWriter(f)
+	r.written = 0
+	r.openedAt = at
+	r.flushedAt = at
+	return nil
+}
+
+// closeFile finishes the current file. The caller must hold r.mu.
+func (r *Recorder) closeFile() error {
+	gzErr := r.gz.Close()
+	fileErr := r.file.Close()
+	r.gz = nil
+	r.file = nil
+	if err := errors.Join(gzErr, fileErr); err != nil {
+		return fmt.Errorf("failed to close recording file: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the current file. Lines recorded afterwards are dropped.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.gz == nil {
+		return nil
+	}
+	return r.closeFile()
+}
+
+// RecordedLine is one line read back from a recording.
+type RecordedLine struct {
+	At   time.Time
+	Line string
+}
+
+// Recording reads recorded lines back, in order, from one file or every
+// recording file in a directory.
+type Recording struct {
+	paths   []string
+	file    *os.File
+	gz      *gzip.Reader
+	scanner *bufio.Scanner
+}
+
+// OpenRecording opens a recording file, or a directory whose recording
+// files are read oldest first.
+func OpenRecording(path string) (*Recording, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	if !info.IsDir() {
+		return &Recording{paths: []string{path}}, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(path, recordFilePrefix+"*"+recordFileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recording files: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no recording files in %s", path)
+	}
+	// File names are timestamps, so name order is time order
+	sort.Strings(paths)
+	return &Recording{paths: paths}, nil
+}
+
+// Next returns the next recorded line, or io.EOF after the last one. A file
+// cut short, such as the one still being recorded, ends at its last
+// complete line.
+func (r *Recording) Next() (RecordedLine, error) {
+	for {
+		if r.scanner == nil {
+			if len(r.paths) == 0 {
+				return RecordedLine{}, io.EOF
+			}
+			if err := r.openNext(); err != nil {
+				return RecordedLine{}, err
+			}
+			if r.scanner == nil {
+				continue
+			}
+		}
+
+		if r.scanner.Scan() {
+			text := r.scanner.Text()
+			stamp, line, ok := strings.Cut(text, "\t")
+			if !ok {
+				return RecordedLine{}, fmt.Errorf("malformed recorded line in %s: %q", r.file.Name(), text)
+			}
+			at, err := time.Parse(time.RFC3339Nano, stamp)
+			if err != nil {
+				return RecordedLine{}, fmt.Errorf("malformed recorded time in %s: %w", r.file.Name(), err)
+			}
+			return RecordedLine{At: at, Line: line}, nil
+		}
+
+		err := r.scanner.Err()
+		name := r.file.Name()
+		r.closeFile()
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return RecordedLine{}, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+}
+
+func (r *Recording) openNext() error {
+	path := r.paths[0]
+	r.paths = r.paths[1:]
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open recording file: %w", err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		// A file just opened for recording has nothing in it yet
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	r.file = f
+	r.gz = gz
+	r.scanner = bufio.NewScanner(gz)
+	// Twitch lines with tags run well past bufio's default token size
+	r.scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return nil
+}
+
+func (r *Recording) closeFile() {
+	r.gz.Close()
+	r.file.Close()
+	r.gz = nil
+	r.file = nil
+	r.scanner = nil
+}
+
+// Close closes the file being read.
+func (r *Recording) Close() error {
+	if r.scanner == nil {
+		return nil
+	}
+	r.closeFile()
+	return nil
+}
+
+// ReplayConfig controls how a recording is replayed.
+type ReplayConfig struct {
+	// Speed scales the recorded time between lines: 1 is real time, 10 is
+	// ten times as fast, and 0 replays as fast as possible.
+	Speed float64
+
+	// Wait is called before each line and blocks until its consumer can
+	// take another, e.g. an ingestion buffer with room. Optional.
+	Wait func(ctx context.Context) error
+}
+
+// Replay handles line as if it had just been read from the server,
+// delivering it to the client's handlers. Lines that act on a connection,
+// such as PING, RECONNECT or a rejected login, are delivered to handlers
+// but not acted on, so a client that never connected can replay traffic.
+func (c *Client) Replay(line string) {
+	c.ReplayAt(time.Now().UTC(), line)
+}
+
+// ReplayAt is like Replay for a line read at at, e.g. as recorded: what it
+// delivers is timed as when the line was originally received.
+func (c *Client) ReplayAt(at time.Time, line string) {
+	c.handleLine(nil, strings.TrimSpace(line), at.UTC())
+}
+
+// ReplayRecording replays every line of rec, paced by the time recorded
+// between lines and timed as when they were recorded. It returns how many
+// lines were replayed.
+func (c *Client) ReplayRecording(ctx context.Context, rec *Recording, cfg ReplayConfig) (int, error) {
+	var first time.Time
+	var start time.Time
+	replayed := 0
+
+	for {
+		rl, err := rec.Next()
+		if errors.Is(err, io.EOF) {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, err
+		}
+
+		if cfg.Speed > 0 {
+			if start.IsZero() {
+				first, start = rl.At, time.Now()
+			}
+			due := start.Add(time.Duration(float64(rl.At.Sub(first)) / cfg.Speed))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return replayed, ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+		if cfg.Wait != nil {
+			if err := cfg.Wait(ctx); err != nil {
+				return replayed, err
+			}
+		}
+
+		c.ReplayAt(rl.At, rl.Line)
+		replayed++
+	}
+}
@@ -0,0 +1,77 @@
+package irc
+
+import (
+	"strconv"
+	"time"
+)
+
+// RoomState represents a parsed ROOMSTATE command.
+//
+// Twitch sends a full ROOMSTATE after JOIN and partial ones (only the
+// changed tag) afterwards, so each setting is nil when not present.
+type RoomState struct {
+	Channel       string
+	RoomID        string
+	EmoteOnly     *bool
+	FollowersOnly *int // Minutes of following required; -1 means disabled
+	R9K           *bool
+	Slow          *int // Seconds between messages; 0 means disabled
+	SubsOnly      *bool
+	Tags          map[string]string
+	ReceivedAt    time.Time
+}
+
+// RoomStateHandler is called for each incoming ROOMSTATE.
+type RoomStateHandler func(rs RoomState)
+
+// ParseRoomState parses a raw ROOMSTATE line.
+// It returns nil if the line is not a well-formed ROOMSTATE.
+//
+// Example:
+//
+//	@emote-only=0;followers-only=-1;r9k=0;room-id=1;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #channel
+func ParseRoomState(line string) *RoomState {
+	return roomStateFromRaw(ParseRawMessage(line), time.Now().UTC())
+}
+
+func roomStateFromRaw(m *RawMessage, receivedAt time.Time) *RoomState {
+	// :tmi.twitch.tv ROOMSTATE #channel
+	if m == nil || m.Command != "ROOMSTATE" || m.Channel() == "" {
+		return nil
+	}
+
+	rs := &RoomState{
+		Channel:    m.Channel(),
+		RoomID:     m.Tags.RoomID(),
+		Tags:       m.Tags,
+		ReceivedAt: receivedAt,
+	}
+	rs.EmoteOnly = boolTag(rs.Tags, "emote-only")
+	rs.FollowersOnly = intTag(rs.Tags, "followers-only")
+	rs.R9K = boolTag(rs.Tags, "r9k")
+	rs.Slow = intTag(rs.Tags, "slow")
+	rs.SubsOnly = boolTag(rs.Tags, "subs-only")
+
+	return rs
+}
+
+func boolTag(tags map[string]string, key string) *bool {
+	v, ok := tags[key]
+	if !ok || v == "" {
+		return nil
+	}
+	b := v == "1"
+	return &b
+}
+
+func intTag(tags map[string]string, key string) *int {
+	v, ok := tags[key]
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
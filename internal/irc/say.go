@@ -0,0 +1,103 @@
+package irc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxMessageLength is the longest chat message Twitch accepts, in
+// characters.
+const MaxMessageLength = 500
+
+// SendLimit is Twitch's chat message limit for a regular account: 20
+// messages per 30 seconds across all channels. Moderators and the
+// broadcaster get more in their own channels, which this doesn't use.
+var SendLimit = RateLimit{Count: 20, Per: 30 * time.Second}
+
+// Send errors
+var (
+	// ErrReadOnly is returned when sending from an anonymous connection.
+	ErrReadOnly = errors.New("anonymous connections are read-only")
+	// ErrNotJoined is returned when sending to a channel that isn't joined.
+	ErrNotJoined = errors.New("channel not joined")
+	// ErrInvalidMessage is returned for an empty, multi-line or too long
+	// message, or a malformed reply parent id.
+	ErrInvalidMessage = errors.New("invalid chat message")
+)
+
+// RateLimitError is returned when a message would exceed SendLimit.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("chat rate limit reached, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Say sends a chat message to a joined channel. Messages beyond SendLimit
+// are refused with a *RateLimitError rather than queued.
+func (c *Client) Say(channel, text string) error {
+	return c.say(channel, "", text)
+}
+
+// Reply sends a chat message as a reply to the message whose id tag is
+// parentMsgID, so Twitch threads it under that message.
+func (c *Client) Reply(channel, parentMsgID, text string) error {
+	if parentMsgID == "" || strings.ContainsAny(parentMsgID, " ;\r\n") {
+		return fmt.Errorf("%w: bad reply parent id %q", ErrInvalidMessage, parentMsgID)
+	}
+	return c.say(channel, parentMsgID, text)
+}
+
+// Username returns the nick the client logs in with.
+func (c *Client) Username() string {
+	return c.username
+}
+
+func (c *Client) say(channel, parentMsgID, text string) error {
+	if c.authMode == AuthModeAnonymous {
+		return ErrReadOnly
+	}
+	if err := validateMessage(text); err != nil {
+		return err
+	}
+
+	channel = normalizeChannel(channel)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.connected {
+		return errors.New("not connected")
+	}
+	if c.joinStates[channel].State != JoinStateJoined {
+		return fmt.Errorf("%w: %s", ErrNotJoined, channel)
+	}
+	if wait, ok := c.sendLimiter.Allow(); !ok {
+		return &RateLimitError{RetryAfter: wait}
+	}
+
+	line := "PRIVMSG " + channel + " :" + text
+	if parentMsgID != "" {
+		line = "@reply-parent-msg-id=" + parentMsgID + " " + line
+	}
+	if err := c.send(line); err != nil {
+		return fmt.Errorf("failed to send message to %s: %w", channel, err)
+	}
+	return nil
+}
+
+// validateMessage checks that text can be sent as a single chat message.
+func validateMessage(text string) error {
+	switch {
+	case strings.TrimSpace(text) == "":
+		return fmt.Errorf("%w: empty message", ErrInvalidMessage)
+	case strings.ContainsAny(text, "\r\n"):
+		return fmt.Errorf("%w: message spans lines", ErrInvalidMessage)
+	case utf8.RuneCountInString(text) > MaxMessageLength:
+		return fmt.Errorf("%w: message longer than %d characters", ErrInvalidMessage, MaxMessageLength)
+	}
+	return nil
+}
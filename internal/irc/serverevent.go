@@ -0,0 +1,89 @@
+package irc
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrAuthFailed is returned when Twitch rejects the login. The client
+// stops reconnecting once it sees it.
+var ErrAuthFailed = errors.New("twitch IRC login authentication failed")
+
+// ServerEventKind classifies a ServerEvent.
+type ServerEventKind string
+
+const (
+	// ServerEventNotice is a NOTICE, e.g. a rate limit warning or a
+	// channel that can't be joined.
+	ServerEventNotice ServerEventKind = "notice"
+	// ServerEventReconnect is a RECONNECT: Twitch is about to restart the
+	// server and asks clients to reconnect.
+	ServerEventReconnect ServerEventKind = "reconnect"
+	// ServerEventAuthFailed is a NOTICE rejecting the login.
+	ServerEventAuthFailed ServerEventKind = "auth_failed"
+)
+
+// authFailureNotices are NOTICE texts Twitch sends when the login is
+// rejected. They carry no msg-id.
+var authFailureNotices = []string{
+	"Login authentication failed",
+	"Login unsuccessful",
+	"Improperly formatted auth",
+	"Invalid NICK",
+}
+
+// ServerEvent represents a parsed NOTICE or RECONNECT.
+type ServerEvent struct {
+	Kind       ServerEventKind
+	Channel    string // Empty for NOTICEs not tied to a channel and for RECONNECT
+	MsgID      string // msg-id tag of a NOTICE, e.g. "msg_channel_suspended"
+	Text       string
+	Tags       map[string]string
+	ReceivedAt time.Time
+}
+
+// ServerEventHandler is called for each incoming NOTICE and RECONNECT.
+type ServerEventHandler func(evt ServerEvent)
+
+// ParseServerEvent parses a raw NOTICE or RECONNECT line.
+// It returns nil for any other command.
+//
+// Example:
+//
+//	@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #channel :This channel has been suspended.
+//	:tmi.twitch.tv NOTICE * :Login authentication failed
+//	:tmi.twitch.tv RECONNECT
+func ParseServerEvent(line string) *ServerEvent {
+	return serverEventFromRaw(ParseRawMessage(line), time.Now().UTC())
+}
+
+func serverEventFromRaw(m *RawMessage, receivedAt time.Time) *ServerEvent {
+	if m == nil {
+		return nil
+	}
+
+	switch m.Command {
+	case "RECONNECT":
+		return &ServerEvent{Kind: ServerEventReconnect, Tags: m.Tags, ReceivedAt: receivedAt}
+	case "NOTICE":
+		evt := &ServerEvent{
+			Kind:       ServerEventNotice,
+			Channel:    m.Channel(),
+			MsgID:      m.Tags["msg-id"],
+			Text:       m.Param(1),
+			Tags:       m.Tags,
+			ReceivedAt: receivedAt,
+		}
+		if evt.MsgID == "" {
+			for _, text := range authFailureNotices {
+				if strings.HasPrefix(evt.Text, text) {
+					evt.Kind = ServerEventAuthFailed
+					break
+				}
+			}
+		}
+		return evt
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// overlapDedupSize caps the message keys remembered while two
+	// connections overlap.
+	overlapDedupSize = 10000
+
+	// overlapDrainTimeout bounds the wait for the replaced connection's
+	// read loop to finish, after which deduplication stops.
+	overlapDrainTimeout = 5 * time.Second
+)
+
+// session is one connection to Twitch IRC and its login.
+type session struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	registered chan error    // Outcome of the login; buffered
+	closed     chan struct{} // Closed when the read loop exits
+
+	// Channels confirmed while the session stands by to replace another
+	mu       sync.Mutex
+	joined   map[string]bool
+	joinWake chan struct{}
+}
+
+func newSession(conn net.Conn) *session {
+	return &session{
+		conn:       conn,
+		reader:     bufio.NewReader(conn),
+		registered: make(chan error, 1),
+		closed:     make(chan struct{}),
+		joined:     make(map[string]bool),
+		joinWake:   make(chan struct{}, 1),
+	}
+}
+
+// send writes a line. net.Conn allows concurrent writes, so it needs no
+// lock of its own.
+func (s *session) send(msg string) error {
+	_, err := s.conn.Write([]byte(msg + "\r\n"))
+	return err
+}
+
+// signalRegistered reports the login outcome to whoever waits for it.
+func (s *session) signalRegistered(err error) {
+	select {
+	case s.registered <- err:
+	default:
+	}
+}
+
+func (s *session) markJoined(channel string) {
+	s.mu.Lock()
+	s.joined[channel] = true
+	s.mu.Unlock()
+
+	select {
+	case s.joinWake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *session) isJoined(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.joined[channel]
+}
+
+func (s *session) joinedChannels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := make([]string, 0, len(s.joined))
+	for ch := range s.joined {
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+// dial connects to the IRC server and sends the login. The caller starts
+// its read loop and waits for the welcome.
+func (c *Client) dial(ctx context.Context) (*session, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IRC server %s: %w", c.server, err)
+	}
+	if c.useTLS {
+		host, _, err := net.SplitHostPort(c.server)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid IRC server address %s: %w", c.server, err)
+		}
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName: host,
+			MinVersion: tls.VersionTLS12,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to connect to IRC server %s (TLS): %w", c.server, err)
+		}
+		conn = tlsConn
+	}
+	sess := newSession(conn)
+
+	// Request capabilities (works for both auth modes)
+	if err := sess.send("CAP REQ :twitch.tv/tags twitch.tv/commands"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to request capabilities: %w", err)
+	}
+
+	// Authenticate based on mode
+	if c.authMode == AuthModeAuthenticated {
+		// Authenticated mode: send PASS with OAuth token, then NICK
+		if err := sess.send("PASS " + c.oauthToken); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to send password: %w", err)
+		}
+	}
+	// For both modes, send NICK (anonymous uses justinfan nick)
+	if err := sess.send("NICK " + c.username); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send nick: %w", err)
+	}
+
+	return sess, nil
+}
+
+// awaitWelcome waits for a session's login to be accepted or rejected.
+func (c *Client) awaitWelcome(ctx context.Context, sess *session) error {
+	timer := time.NewTimer(registrationTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-sess.registered:
+		return err
+	case <-sess.closed:
+		// An auth failure closes the connection after reporting it
+		select {
+		case err := <-sess.registered:
+			return err
+		default:
+		}
+		return errors.New("connection closed during login")
+	case <-timer.C:
+		return fmt.Errorf("no welcome from Twitch IRC within %s", registrationTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return errors.New("client disconnected")
+	}
+}
+
+// handleStandbyLine handles the login and join traffic of a standby
+// session. It returns false for chat lines, which are delivered like those
+// of the current session.
+func (c *Client) handleStandbyLine(sess *session, m *RawMessage) bool {
+	switch m.Command {
+	case "001":
+		sess.signalRegistered(nil)
+	case "PING":
+		sess.send("PONG :" + m.Param(0))
+	case "JOIN":
+		if strings.EqualFold(m.Nick(), c.username) {
+			sess.markJoined(m.Channel())
+		}
+	case "ROOMSTATE":
+		sess.markJoined(m.Channel())
+	case "NOTICE":
+		if evt := serverEventFromRaw(m, time.Now().UTC()); evt != nil && evt.Kind == ServerEventAuthFailed {
+			sess.signalRegistered(fmt.Errorf("%w: %s", ErrAuthFailed, evt.Text))
+		}
+	case "RECONNECT":
+	default:
+		return false
+	}
+	return true
+}
+
+// isOverlapDuplicate reports whether a chat line was already delivered by
+// the other connection while two overlap. Messages are keyed by their id
+// tag; moderation events and whispers, which have none, by the whole line.
+func (c *Client) isOverlapDuplicate(m *RawMessage, line string) bool {
+	seen := c.overlap.Load()
+	if seen == nil {
+		return false
+	}
+
+	var key string
+	switch m.Command {
+	case "PRIVMSG", "USERNOTICE":
+		key = m.Tags.MessageID()
+		if key == "" {
+			key = line
+		}
+	case "CLEARCHAT", "CLEARMSG", "WHISPER":
+		key = line
+	default:
+		return false
+	}
+	return !seen.add(key)
+}
+
+// replaceSession replaces the current session without a gap: a standby
+// session logs in and joins every channel, then takes over and the old
+// connection is closed. After a drop the old connection is already gone
+// and the standby takes over as soon as it is ready. If the standby fails
+// the client falls back to a plain reconnect.
+func (c *Client) replaceSession() {
+	defer c.wg.Done()
+
+	next, err := c.openStandby()
+
+	c.mu.Lock()
+	old := c.sess
+	c.standby = nil
+	if err != nil {
+		c.replacing = false
+		c.overlap.Store(nil)
+		if next != nil {
+			next.conn.Close()
+		}
+
+		if errors.Is(err, ErrAuthFailed) {
+			c.err = err
+			c.connected = false
+			c.mu.Unlock()
+			old.conn.Close()
+			return
+		}
+		if c.stopped() {
+			c.mu.Unlock()
+			return
+		}
+		c.beginReconnect()
+		c.reconnectDelay = 0
+		c.mu.Unlock()
+
+		old.conn.Close()
+		c.wg.Add(1)
+		go c.reconnect()
+		return
+	}
+
+	c.sess = next
+	c.connected = true
+	c.reconnecting = false
+	c.reconnectDelay = initialReconnectDelay
+	// Channels parted while the standby was joining are parted on it too
+	for _, ch := range next.joinedChannels() {
+		if !c.channels[ch] {
+			next.send("PART " + ch)
+		}
+	}
+	for ch := range c.channels {
+		if next.isJoined(ch) {
+			c.dequeueJoin(ch)
+			c.setJoinState(ch, JoinStateJoined, "")
+		} else {
+			c.enqueueJoin(ch)
+		}
+	}
+	c.mu.Unlock()
+
+	// Lines the old read loop already read are still deduplicated
+	old.conn.Close()
+	timer := time.NewTimer(overlapDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-old.closed:
+	case <-timer.C:
+	case <-c.done:
+	}
+
+	c.overlap.Store(nil)
+	c.mu.Lock()
+	c.replacing = false
+	c.mu.Unlock()
+}
+
+// openStandby logs in a standby session and joins every channel on it,
+// waiting up to the join timeout for the joins to be confirmed. Channels still
+// unconfirmed are joined again once it takes over.
+func (c *Client) openStandby() (*session, error) {
+	sess, err := c.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.standby = sess
+	c.overlap.Store(newRecentIDs())
+	channels := make([]string, 0, len(c.channels))
+	for ch := range c.channels {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	c.wg.Add(1)
+	go c.readLoop(sess)
+	c.mu.Unlock()
+
+	if err := c.awaitWelcome(context.Background(), sess); err != nil {
+		return sess, err
+	}
+
+	for _, ch := range channels {
+		if !c.joinLimiter.Wait(c.done) {
+			return sess, errors.New("client disconnected")
+		}
+		if err := sess.send("JOIN " + ch); err != nil {
+			return sess, fmt.Errorf("failed to join %s on standby connection: %w", ch, err)
+		}
+	}
+
+	timer := time.NewTimer(c.joinTimeout)
+	defer timer.Stop()
+	for len(sess.joinedChannels()) < len(channels) {
+		select {
+		case <-sess.joinWake:
+		case <-timer.C:
+			return sess, nil
+		case <-sess.closed:
+			return sess, errors.New("standby connection closed")
+		case <-c.done:
+			return sess, errors.New("client disconnected")
+		}
+	}
+	return sess, nil
+}
+
+// recentIDs remembers the most recent message keys seen on overlapping
+// connections.
+type recentIDs struct {
+	mu    sync.Mutex
+	seen  map[string]struct{}
+	order []string
+}
+
+func newRecentIDs() *recentIDs {
+	return &recentIDs{seen: make(map[string]struct{})}
+}
+
+// add records key and reports whether it is new. Past overlapDedupSize
+// keys the oldest is forgotten.
+func (r *recentIDs) add(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seen[key]; ok {
+		return false
+	}
+	r.seen[key] = struct{}{}
+	r.order = append(r.order, key)
+	if len(r.order) > overlapDedupSize {
+		delete(r.seen, r.order[0])
+		r.order = r.order[1:]
+	}
+	return true
+}
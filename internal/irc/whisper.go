@@ -0,0 +1,52 @@
+package irc
+
+import (
+	"strings"
+	"time"
+)
+
+// Whisper represents a parsed WHISPER: a private message to our account.
+// Only authenticated connections receive them.
+type Whisper struct {
+	From            string // login of the sender
+	FromDisplayName string
+	FromUserID      string
+	To              string // our login
+	Text            string
+	MessageID       string // message-id tag; counts up within a thread
+	ThreadID        string // thread-id tag, shared by both directions
+	Tags            map[string]string
+	ReceivedAt      time.Time
+}
+
+// WhisperHandler is called for each incoming WHISPER.
+type WhisperHandler func(w Whisper)
+
+// ParseWhisper parses a raw WHISPER line.
+// It returns nil if the line is not a well-formed WHISPER.
+//
+// Example:
+//
+//	@display-name=Friend;message-id=3;thread-id=11_22;user-id=22 :friend!friend@friend.tmi.twitch.tv WHISPER archiver :hey
+func ParseWhisper(line string) *Whisper {
+	return whisperFromRaw(ParseRawMessage(line), time.Now().UTC())
+}
+
+func whisperFromRaw(m *RawMessage, receivedAt time.Time) *Whisper {
+	// :sender!sender@sender.tmi.twitch.tv WHISPER recipient :message
+	if m == nil || m.Command != "WHISPER" || len(m.Params) < 2 || m.Nick() == "" {
+		return nil
+	}
+
+	return &Whisper{
+		From:            m.Nick(),
+		FromDisplayName: m.Tags.DisplayName(),
+		FromUserID:      m.Tags.UserID(),
+		To:              strings.ToLower(m.Param(0)),
+		Text:            m.Param(1),
+		MessageID:       m.Tags["message-id"],
+		ThreadID:        m.Tags["thread-id"],
+		Tags:            m.Tags,
+		ReceivedAt:      receivedAt,
+	}
+}
@@ -57,11 +57,18 @@ type Metrics struct {
 	ircConnections    int64
 	ircDisconnections int64
 	ircMessagesRecv   int64
+	ircJoins          int64
+	ircJoinFailures   int64
+	ircJoinLatencySum time.Duration
+	ircNotices        int64
+	ircReconnects     int64 // RECONNECT requests from Twitch
+	ircAuthFailures   int64
 
 	// Ingestion metrics
 	batchesProcessed  int64
 	messagesIngested  int64
 	droppedMessages   int64
+	duplicateMessages int64
 	totalBatchLatency time.Duration
 	batchCount        int64
 
@@ -161,6 +168,34 @@ func (m *Metrics) RecordIRCMessage() {
 	m.ircMessagesRecv++
 }
 
+// RecordIRCJoin records a channel join that was confirmed or failed, with
+// the time from queueing it to the outcome.
+func (m *Metrics) RecordIRCJoin(latency time.Duration, success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !success {
+		m.ircJoinFailures++
+		return
+	}
+	m.ircJoins++
+	m.ircJoinLatencySum += latency
+}
+
+// RecordIRCServerEvent records a NOTICE, RECONNECT or auth failure by
+// kind ("notice", "reconnect" or "auth_failed").
+func (m *Metrics) RecordIRCServerEvent(kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch kind {
+	case "reconnect":
+		m.ircReconnects++
+	case "auth_failed":
+		m.ircAuthFailures++
+	default:
+		m.ircNotices++
+	}
+}
+
 // RecordBatchSize records the size of a processed batch.
 func (m *Metrics) RecordBatchSize(size int) {
 	m.mu.Lock()
@@ -184,6 +219,13 @@ func (m *Metrics) RecordDroppedMessages(count int) {
 	m.droppedMessages += int64(count)
 }
 
+// RecordDuplicateMessages records messages dropped as duplicates.
+func (m *Metrics) RecordDuplicateMessages(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duplicateMessages += int64(count)
+}
+
 // RecordSearchQuery records a search query.
 func (m *Metrics) RecordSearchQuery(latency time.Duration) {
 	m.mu.Lock()
@@ -216,7 +258,10 @@ func (m *Metrics) Stats() MetricsSnapshot {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var avgBatchLatency, avgSearchLatency, avgHTTPLatency, avgStreamLatency time.Duration
+	var avgBatchLatency, avgSearchLatency, avgHTTPLatency, avgStreamLatency, avgJoinLatency time.Duration
+	if m.ircJoins > 0 {
+		avgJoinLatency = m.ircJoinLatencySum / time.Duration(m.ircJoins)
+	}
 	if m.batchCount > 0 {
 		avgBatchLatency = m.totalBatchLatency / time.Duration(m.batchCount)
 	}
@@ -234,9 +279,16 @@ func (m *Metrics) Stats() MetricsSnapshot {
 		IRCConnections:     m.ircConnections,
 		IRCDisconnections:  m.ircDisconnections,
 		IRCMessagesRecv:    m.ircMessagesRecv,
+		IRCJoins:           m.ircJoins,
+		IRCJoinFailures:    m.ircJoinFailures,
+		AvgIRCJoinLatency:  avgJoinLatency,
+		IRCNotices:         m.ircNotices,
+		IRCReconnects:      m.ircReconnects,
+		IRCAuthFailures:    m.ircAuthFailures,
 		BatchesProcessed:   m.batchesProcessed,
 		MessagesIngested:   m.messagesIngested,
 		DroppedMessages:    m.droppedMessages,
+		DuplicateMessages:  m.duplicateMessages,
 		AvgBatchLatency:    avgBatchLatency,
 		SearchQueries:      m.searchQueries,
 		AvgSearchLatency:   avgSearchLatency,
@@ -296,9 +348,16 @@ type MetricsSnapshot struct {
 	IRCConnections     int64
 	IRCDisconnections  int64
 	IRCMessagesRecv    int64
+	IRCJoins           int64
+	IRCJoinFailures    int64
+	AvgIRCJoinLatency  time.Duration
+	IRCNotices         int64
+	IRCReconnects      int64
+	IRCAuthFailures    int64
 	BatchesProcessed   int64
 	MessagesIngested   int64
 	DroppedMessages    int64
+	DuplicateMessages  int64
 	AvgBatchLatency    time.Duration
 	SearchQueries      int64
 	AvgSearchLatency   time.Duration
@@ -68,12 +68,23 @@ type OTelMetrics struct {
 	IRCConnections    metric.Int64Counter
 	IRCDisconnections metric.Int64Counter
 	IRCMessagesRecv   metric.Int64Counter
+	IRCJoins          metric.Int64Counter
+	IRCJoinLatency    metric.Float64Histogram
+	IRCJoinQueueDepth metric.Int64ObservableGauge
+	IRCServerEvents   metric.Int64Counter
 
 	// Ingestion metrics
-	BatchesProcessed metric.Int64Counter
-	MessagesIngested metric.Int64Counter
-	DroppedMessages  metric.Int64Counter
-	BatchLatency     metric.Float64Histogram
+	BatchesProcessed  metric.Int64Counter
+	MessagesIngested  metric.Int64Counter
+	DroppedMessages   metric.Int64Counter
+	DuplicateMessages metric.Int64Counter
+	BatchRetries      metric.Int64Counter
+	DeadLetters       metric.Int64Counter
+	BatchLatency      metric.Float64Histogram
+	IngestionLag      metric.Float64Histogram
+	SpoolRecords      metric.Int64ObservableGauge
+	SpoolBytes        metric.Int64ObservableGauge
+	SpoolAge          metric.Float64ObservableGauge
 
 	// Search metrics
 	SearchQueries metric.Int64Counter
@@ -260,6 +271,31 @@ func createOTelMetrics(meter metric.Meter) (*OTelMetrics, error) {
 		return nil, err
 	}
 
+	m.IRCJoins, err = meter.Int64Counter("goknut.irc.joins",
+		metric.WithDescription("Number of IRC joins confirmed or failed"),
+		metric.WithUnit("{join}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.IRCJoinLatency, err = meter.Float64Histogram("goknut.irc.join_latency",
+		metric.WithDescription("Time from queueing a channel join to the server confirming it"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(1, 10, 100, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.IRCServerEvents, err = meter.Int64Counter("goknut.irc.server_events",
+		metric.WithDescription("Number of NOTICE, RECONNECT and auth failure events from Twitch IRC"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ingestion metrics
 	m.BatchesProcessed, err = meter.Int64Counter("goknut.ingestion.batches_processed",
 		metric.WithDescription("Number of message batches processed"),
@@ -285,6 +321,30 @@ func createOTelMetrics(meter metric.Meter) (*OTelMetrics, error) {
 		return nil, err
 	}
 
+	m.DuplicateMessages, err = meter.Int64Counter("goknut.ingestion.duplicate_messages",
+		metric.WithDescription("Number of messages dropped as duplicates"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.BatchRetries, err = meter.Int64Counter("goknut.ingestion.batch_retries",
+		metric.WithDescription("Number of times a message batch the database refused was tried again"),
+		metric.WithUnit("{retry}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.DeadLetters, err = meter.Int64Counter("goknut.ingestion.dead_letters",
+		metric.WithDescription("Number of messages the database refused on their own and kept as dead letters"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	m.BatchLatency, err = meter.Float64Histogram("goknut.ingestion.batch_latency",
 		metric.WithDescription("Latency of batch processing"),
 		metric.WithUnit("ms"),
@@ -294,6 +354,15 @@ func createOTelMetrics(meter metric.Meter) (*OTelMetrics, error) {
 		return nil, err
 	}
 
+	m.IngestionLag, err = meter.Float64Histogram("goknut.ingestion.lag",
+		metric.WithDescription("Time between Twitch sending a message (tmi-sent-ts) and the archiver receiving it"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Search metrics
 	m.SearchQueries, err = meter.Int64Counter("goknut.search.queries",
 		metric.WithDescription("Number of search queries executed"),
@@ -470,6 +539,96 @@ func (p *OTelProvider) RegisterDatabaseCountCallbacks(provider DatabaseCountProv
 	return nil
 }
 
+// RegisterIRCJoinQueueCallback registers an observable gauge for the
+// number of channels waiting in the IRC join queue.
+func (p *OTelProvider) RegisterIRCJoinQueueCallback(depth func() int64) error {
+	if !p.metricsEnabled || p.otelMetrics == nil {
+		return nil
+	}
+
+	var err error
+	p.otelMetrics.IRCJoinQueueDepth, err = p.Meter.Int64ObservableGauge(
+		"goknut.irc.join_queue_depth",
+		metric.WithDescription("Number of channels waiting in the IRC join queue"),
+		metric.WithUnit("{channel}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create join_queue_depth gauge: %w", err)
+	}
+
+	_, err = p.Meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			o.ObserveInt64(p.otelMetrics.IRCJoinQueueDepth, depth())
+			return nil
+		},
+		p.otelMetrics.IRCJoinQueueDepth,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register join queue callback: %w", err)
+	}
+
+	return nil
+}
+
+// RegisterSpoolCallback registers observable gauges for the ingestion
+// spool: records and bytes waiting to be replayed, and the age of the
+// oldest. stats returns a zero oldestAt when the spool is empty.
+func (p *OTelProvider) RegisterSpoolCallback(stats func() (records, bytes int64, oldestAt time.Time)) error {
+	if !p.metricsEnabled || p.otelMetrics == nil {
+		return nil
+	}
+
+	var err error
+	p.otelMetrics.SpoolRecords, err = p.Meter.Int64ObservableGauge(
+		"goknut.ingestion.spool_records",
+		metric.WithDescription("Number of spooled batches and events waiting to be replayed"),
+		metric.WithUnit("{record}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create spool_records gauge: %w", err)
+	}
+
+	p.otelMetrics.SpoolBytes, err = p.Meter.Int64ObservableGauge(
+		"goknut.ingestion.spool_bytes",
+		metric.WithDescription("Bytes on disk waiting to be replayed from the spool"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create spool_bytes gauge: %w", err)
+	}
+
+	p.otelMetrics.SpoolAge, err = p.Meter.Float64ObservableGauge(
+		"goknut.ingestion.spool_age",
+		metric.WithDescription("Age of the oldest record waiting in the spool"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create spool_age gauge: %w", err)
+	}
+
+	_, err = p.Meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			records, bytes, oldestAt := stats()
+			o.ObserveInt64(p.otelMetrics.SpoolRecords, records)
+			o.ObserveInt64(p.otelMetrics.SpoolBytes, bytes)
+			age := 0.0
+			if !oldestAt.IsZero() {
+				age = time.Since(oldestAt).Seconds()
+			}
+			o.ObserveFloat64(p.otelMetrics.SpoolAge, age)
+			return nil
+		},
+		p.otelMetrics.SpoolRecords,
+		p.otelMetrics.SpoolBytes,
+		p.otelMetrics.SpoolAge,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register spool callback: %w", err)
+	}
+
+	return nil
+}
+
 // HTTPMiddleware returns an HTTP middleware that instruments requests with OTel.
 func (p *OTelProvider) HTTPMiddleware(next http.Handler) http.Handler {
 	return otelhttp.NewHandler(next, "goknut-http",
@@ -506,6 +665,33 @@ func (p *OTelProvider) RecordIRCMessage(ctx context.Context, channel string) {
 	}
 }
 
+// RecordIRCJoin records a channel join that was confirmed or failed, with
+// the time from queueing it to the outcome.
+func (p *OTelProvider) RecordIRCJoin(ctx context.Context, latencyMs float64, success bool) {
+	if p.otelMetrics != nil {
+		result := "joined"
+		if !success {
+			result = "failed"
+		}
+		p.otelMetrics.IRCJoins.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("result", result),
+		))
+		if success {
+			p.otelMetrics.IRCJoinLatency.Record(ctx, latencyMs)
+		}
+	}
+}
+
+// RecordIRCServerEvent records a NOTICE, RECONNECT or auth failure.
+func (p *OTelProvider) RecordIRCServerEvent(ctx context.Context, kind, msgID string) {
+	if p.otelMetrics != nil {
+		p.otelMetrics.IRCServerEvents.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("kind", kind),
+			attribute.String("msg_id", msgID),
+		))
+	}
+}
+
 // RecordBatch records batch processing metrics.
 func (p *OTelProvider) RecordBatch(ctx context.Context, size int, latencyMs float64) {
 	if p.otelMetrics != nil {
@@ -515,6 +701,14 @@ func (p *OTelProvider) RecordBatch(ctx context.Context, size int, latencyMs floa
 	}
 }
 
+// RecordIngestionLag records the delay between a message's tmi-sent-ts and
+// when it was received. Negative values from clock skew are recorded as 0.
+func (p *OTelProvider) RecordIngestionLag(ctx context.Context, lagMs float64) {
+	if p.otelMetrics != nil {
+		p.otelMetrics.IngestionLag.Record(ctx, max(lagMs, 0))
+	}
+}
+
 // RecordDroppedMessages records dropped messages.
 func (p *OTelProvider) RecordDroppedMessages(ctx context.Context, count int) {
 	if p.otelMetrics != nil {
@@ -522,6 +716,27 @@ func (p *OTelProvider) RecordDroppedMessages(ctx context.Context, count int) {
 	}
 }
 
+// RecordDuplicateMessages records messages dropped as duplicates.
+func (p *OTelProvider) RecordDuplicateMessages(ctx context.Context, count int) {
+	if p.otelMetrics != nil {
+		p.otelMetrics.DuplicateMessages.Add(ctx, int64(count))
+	}
+}
+
+// RecordBatchRetry records a message batch being tried again.
+func (p *OTelProvider) RecordBatchRetry(ctx context.Context) {
+	if p.otelMetrics != nil {
+		p.otelMetrics.BatchRetries.Add(ctx, 1)
+	}
+}
+
+// RecordDeadLetters records messages kept as dead letters.
+func (p *OTelProvider) RecordDeadLetters(ctx context.Context, count int) {
+	if p.otelMetrics != nil {
+		p.otelMetrics.DeadLetters.Add(ctx, int64(count))
+	}
+}
+
 // RecordSearchQuery records a search query.
 func (p *OTelProvider) RecordSearchQuery(ctx context.Context, searchType string, latencyMs float64) {
 	if p.otelMetrics != nil {
@@ -0,0 +1,236 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BitsTotal is the sum of cheers over a set of messages.
+type BitsTotal struct {
+	Bits     int64
+	Cheers   int64 // Messages carrying bits
+	Users    int64 // Distinct cheerers
+	Channels int64 // Distinct channels cheered in
+	LastAt   time.Time
+}
+
+// BitsCheerer is one user's cheering, for leaderboards.
+type BitsCheerer struct {
+	UserID      int64
+	Username    string
+	DisplayName string
+	Bits        int64
+	Cheers      int64
+	LastAt      time.Time
+}
+
+// BitsChannel is the cheering in one channel.
+type BitsChannel struct {
+	ChannelID   int64
+	ChannelName string
+	Bits        int64
+	Cheers      int64
+	LastAt      time.Time
+}
+
+// BitsDay is the cheering on one UTC day.
+type BitsDay struct {
+	Day    time.Time
+	Bits   int64
+	Cheers int64
+}
+
+// BitsFilter narrows bits aggregates. Nil fields are not filtered on.
+type BitsFilter struct {
+	ChannelID *int64
+	UserID    *int64
+	Since     *time.Time // Inclusive, on message sent_at
+	Until     *time.Time // Exclusive, on message sent_at
+	Limit     int
+}
+
+// BitsRepository provides cheer (bits) aggregates.
+type BitsRepository struct {
+	db Database
+}
+
+// NewBitsRepository creates a new bits repository.
+func NewBitsRepository(db Database) *BitsRepository {
+	return &BitsRepository{db: db}
+}
+
+// Totals returns the sum of bits matching the filter.
+func (r *BitsRepository) Totals(ctx context.Context, filter BitsFilter) (*BitsTotal, error) {
+	where, args, _ := r.conditions(filter)
+
+	query := `
+		SELECT COALESCE(SUM(m.bits), 0), COUNT(*), COUNT(DISTINCT m.user_id), COUNT(DISTINCT m.channel_id), MAX(m.sent_at)
+		FROM messages m
+		` + where
+
+	var total BitsTotal
+	var lastAt any
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&total.Bits, &total.Cheers, &total.Users, &total.Channels, &lastAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bits totals: %w", err)
+	}
+	total.LastAt = parseTimeValue(lastAt)
+
+	return &total, nil
+}
+
+// TopCheerers returns the users who cheered the most bits, most first.
+func (r *BitsRepository) TopCheerers(ctx context.Context, filter BitsFilter) ([]BitsCheerer, error) {
+	where, args, argIndex := r.conditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT m.user_id, MAX(u.username), MAX(u.display_name), SUM(m.bits) AS total, COUNT(*), MAX(m.sent_at)
+		FROM messages m
+		JOIN users u ON u.id = m.user_id
+		%s
+		GROUP BY m.user_id
+		ORDER BY total DESC, m.user_id ASC
+		LIMIT %s`, where, r.db.Placeholder(argIndex))
+	args = append(args, bitsLimit(filter.Limit))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top cheerers: %w", err)
+	}
+	defer rows.Close()
+
+	var cheerers []BitsCheerer
+	for rows.Next() {
+		var c BitsCheerer
+		var displayName *string
+		var lastAt any
+		if err := rows.Scan(&c.UserID, &c.Username, &displayName, &c.Bits, &c.Cheers, &lastAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cheerer: %w", err)
+		}
+		if displayName != nil {
+			c.DisplayName = *displayName
+		}
+		c.LastAt = parseTimeValue(lastAt)
+		cheerers = append(cheerers, c)
+	}
+
+	return cheerers, rows.Err()
+}
+
+// TopChannels returns the channels that received the most bits, most first.
+func (r *BitsRepository) TopChannels(ctx context.Context, filter BitsFilter) ([]BitsChannel, error) {
+	where, args, argIndex := r.conditions(filter)
+
+	query := fmt.Sprintf(`
+		SELECT m.channel_id, MAX(c.name), SUM(m.bits) AS total, COUNT(*), MAX(m.sent_at)
+		FROM messages m
+		JOIN channels c ON c.id = m.channel_id
+		%s
+		GROUP BY m.channel_id
+		ORDER BY total DESC, m.channel_id ASC
+		LIMIT %s`, where, r.db.Placeholder(argIndex))
+	args = append(args, bitsLimit(filter.Limit))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top bits channels: %w", err)
+	}
+	defer rows.Close()
+
+	var channels []BitsChannel
+	for rows.Next() {
+		var c BitsChannel
+		var lastAt any
+		if err := rows.Scan(&c.ChannelID, &c.ChannelName, &c.Bits, &c.Cheers, &lastAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bits channel: %w", err)
+		}
+		c.LastAt = parseTimeValue(lastAt)
+		channels = append(channels, c)
+	}
+
+	return channels, rows.Err()
+}
+
+// Daily returns bits per UTC day, most recent first. Days without cheers
+// are omitted.
+func (r *BitsRepository) Daily(ctx context.Context, filter BitsFilter) ([]BitsDay, error) {
+	where, args, argIndex := r.conditions(filter)
+
+	day := "date(m.sent_at)"
+	if r.db.DriverName() == "postgres" {
+		day = "to_char(m.sent_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS day, SUM(m.bits), COUNT(*)
+		FROM messages m
+		%s
+		GROUP BY day
+		ORDER BY day DESC
+		LIMIT %s`, day, where, r.db.Placeholder(argIndex))
+	args = append(args, bitsLimit(filter.Limit))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily bits: %w", err)
+	}
+	defer rows.Close()
+
+	var days []BitsDay
+	for rows.Next() {
+		var d BitsDay
+		var dayStr string
+		if err := rows.Scan(&dayStr, &d.Bits, &d.Cheers); err != nil {
+			return nil, fmt.Errorf("failed to scan daily bits: %w", err)
+		}
+		d.Day, _ = time.Parse("2006-01-02", dayStr)
+		days = append(days, d)
+	}
+
+	return days, rows.Err()
+}
+
+// conditions builds the WHERE clause for filter, always restricted to
+// messages that carry bits. It returns the next free placeholder index.
+func (r *BitsRepository) conditions(filter BitsFilter) (string, []any, int) {
+	conditions := []string{"m.bits > 0"}
+	var args []any
+	argIndex := 1
+
+	if filter.ChannelID != nil {
+		conditions = append(conditions, "m.channel_id = "+r.db.Placeholder(argIndex))
+		args = append(args, *filter.ChannelID)
+		argIndex++
+	}
+	if filter.UserID != nil {
+		conditions = append(conditions, "m.user_id = "+r.db.Placeholder(argIndex))
+		args = append(args, *filter.UserID)
+		argIndex++
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "m.sent_at >= "+r.db.Placeholder(argIndex))
+		args = append(args, timeArg(r.db, *filter.Since))
+		argIndex++
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "m.sent_at < "+r.db.Placeholder(argIndex))
+		args = append(args, timeArg(r.db, *filter.Until))
+		argIndex++
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args, argIndex
+}
+
+// bitsLimit clamps a leaderboard limit to 1-100, defaulting to 10.
+func bitsLimit(limit int) int {
+	if limit <= 0 {
+		return 10
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
@@ -0,0 +1,117 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ChannelRename is a detected broadcaster rename: two channel rows that
+// share a Twitch room-id. From is the older name, Into the newest.
+type ChannelRename struct {
+	RoomID string
+	From   Channel
+	Into   Channel
+}
+
+// ListRenames returns every channel whose room-id is shared with a newer
+// channel, paired with the newest channel for that room-id.
+func (r *ChannelRepository) ListRenames(ctx context.Context) ([]ChannelRename, error) {
+	query := `
+		SELECT ` + channelColumns + `
+		FROM ` + channelFrom + `
+		WHERE c.twitch_room_id IN (
+			SELECT twitch_room_id FROM channels
+			WHERE twitch_room_id IS NOT NULL
+			GROUP BY twitch_room_id
+			HAVING COUNT(*) > 1
+		)
+		ORDER BY c.twitch_room_id ASC, c.created_at DESC, c.id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel renames: %w", err)
+	}
+	defer rows.Close()
+
+	channels, err := r.scanChannels(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var renames []ChannelRename
+	var newest Channel
+	for _, ch := range channels {
+		if ch.TwitchRoomID != newest.TwitchRoomID {
+			newest = ch
+			continue
+		}
+		renames = append(renames, ChannelRename{RoomID: ch.TwitchRoomID, From: ch, Into: newest})
+	}
+
+	return renames, nil
+}
+
+// Merge moves all history recorded for channel fromID onto channel intoID
+// and deletes fromID. Messages, moderation events, notices and chat mode
+// history are moved; a profile link is moved only if intoID has none.
+// The target's message stats are recomputed and it inherits the room-id
+// if it has none.
+func (r *ChannelRepository) Merge(ctx context.Context, fromID, intoID int64) error {
+	if fromID == intoID {
+		return fmt.Errorf("cannot merge channel %d into itself", fromID)
+	}
+
+	p := r.db.Placeholder
+
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"messages", "moderation_events", "user_notices"} {
+			query := `UPDATE ` + table + ` SET channel_id = ` + p(1) + ` WHERE channel_id = ` + p(2)
+			if _, err := tx.ExecContext(ctx, query, intoID, fromID); err != nil {
+				return fmt.Errorf("failed to move %s: %w", table, err)
+			}
+		}
+
+		// Only one open chat mode row is allowed per channel; the target's wins
+		_, err := tx.ExecContext(ctx, `
+			UPDATE channel_states SET ended_at = `+r.db.NowFunc()+`
+			WHERE channel_id = `+p(1)+` AND ended_at IS NULL
+			  AND EXISTS (SELECT 1 FROM channel_states WHERE channel_id = `+p(2)+` AND ended_at IS NULL)`,
+			fromID, intoID)
+		if err != nil {
+			return fmt.Errorf("failed to close channel state: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE channel_states SET channel_id = `+p(1)+` WHERE channel_id = `+p(2), intoID, fromID); err != nil {
+			return fmt.Errorf("failed to move channel states: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE profile_channels SET channel_id = `+p(1)+`
+			WHERE channel_id = `+p(2)+`
+			  AND NOT EXISTS (SELECT 1 FROM profile_channels WHERE channel_id = `+p(3)+`)`,
+			intoID, fromID, intoID)
+		if err != nil {
+			return fmt.Errorf("failed to move profile link: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE channels
+			SET total_messages = (SELECT COUNT(*) FROM messages WHERE channel_id = `+p(1)+`),
+			    last_message_at = (SELECT MAX(sent_at) FROM messages WHERE channel_id = `+p(2)+`),
+			    twitch_room_id = COALESCE(twitch_room_id, (SELECT twitch_room_id FROM channels WHERE id = `+p(3)+`)),
+			    updated_at = `+r.db.NowFunc()+`
+			WHERE id = `+p(4),
+			intoID, intoID, fromID, intoID)
+		if err != nil {
+			return fmt.Errorf("failed to update merged channel: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM channels WHERE id = `+p(1), fromID); err != nil {
+			return fmt.Errorf("failed to delete merged channel: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -19,8 +19,19 @@ type Channel struct {
 	UpdatedAt             time.Time
 	LastMessageAt         *time.Time
 	TotalMessages         int64
+	TwitchRoomID          string        // Stable Twitch room-id, "" until first seen
+	State                 *ChannelState // Current ROOMSTATE modes, nil if none recorded
 }
 
+// channelColumns and channelFrom are shared by all channel selects so the
+// current chat state is joined in consistently.
+const channelColumns = `c.id, c.name, c.display_name, c.enabled, c.retain_history_on_delete,
+		       c.created_at, c.updated_at, c.last_message_at, c.total_messages, c.twitch_room_id,
+		       s.id, s.emote_only, s.followers_only_minutes, s.r9k, s.slow_seconds, s.subs_only, s.started_at`
+
+const channelFrom = `channels c
+		LEFT JOIN channel_states s ON s.channel_id = c.id AND s.ended_at IS NULL`
+
 // ChannelRepository provides CRUD operations for channels.
 type ChannelRepository struct {
 	db Database
@@ -34,10 +45,9 @@ func NewChannelRepository(db Database) *ChannelRepository {
 // List returns all channels with optional filtering.
 func (r *ChannelRepository) List(ctx context.Context) ([]Channel, error) {
 	query := `
-		SELECT id, name, display_name, enabled, retain_history_on_delete,
-		       created_at, updated_at, last_message_at, total_messages
-		FROM channels
-		ORDER BY name ASC
+		SELECT ` + channelColumns + `
+		FROM ` + channelFrom + `
+		ORDER BY c.name ASC
 	`
 
 	rows, err := r.db.QueryContext(ctx, query)
@@ -58,11 +68,10 @@ func (r *ChannelRepository) ListEnabled(ctx context.Context) ([]Channel, error)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, name, display_name, enabled, retain_history_on_delete,
-		       created_at, updated_at, last_message_at, total_messages
-		FROM channels
-		WHERE enabled = %s
-		ORDER BY name ASC
+		SELECT `+channelColumns+`
+		FROM `+channelFrom+`
+		WHERE c.enabled = %s
+		ORDER BY c.name ASC
 	`, enabledVal)
 
 	rows, err := r.db.QueryContext(ctx, query)
@@ -77,10 +86,9 @@ func (r *ChannelRepository) ListEnabled(ctx context.Context) ([]Channel, error)
 // GetByID returns a channel by ID.
 func (r *ChannelRepository) GetByID(ctx context.Context, id int64) (*Channel, error) {
 	query := `
-		SELECT id, name, display_name, enabled, retain_history_on_delete,
-		       created_at, updated_at, last_message_at, total_messages
-		FROM channels
-		WHERE id = ` + r.db.Placeholder(1)
+		SELECT ` + channelColumns + `
+		FROM ` + channelFrom + `
+		WHERE c.id = ` + r.db.Placeholder(1)
 
 	return r.scanChannel(r.db.QueryRowContext(ctx, query, id))
 }
@@ -88,10 +96,9 @@ func (r *ChannelRepository) GetByID(ctx context.Context, id int64) (*Channel, er
 // GetByName returns a channel by name.
 func (r *ChannelRepository) GetByName(ctx context.Context, name string) (*Channel, error) {
 	query := `
-		SELECT id, name, display_name, enabled, retain_history_on_delete,
-		       created_at, updated_at, last_message_at, total_messages
-		FROM channels
-		WHERE name = ` + r.db.Placeholder(1)
+		SELECT ` + channelColumns + `
+		FROM ` + channelFrom + `
+		WHERE c.name = ` + r.db.Placeholder(1)
 
 	return r.scanChannel(r.db.QueryRowContext(ctx, query, name))
 }
@@ -202,6 +209,36 @@ func (r *ChannelRepository) Delete(ctx context.Context, id int64, retainHistory
 	})
 }
 
+// SetRoomID records the channel's Twitch room-id.
+func (r *ChannelRepository) SetRoomID(ctx context.Context, id int64, roomID string) error {
+	p := r.db.Placeholder
+	query := `UPDATE channels SET twitch_room_id = ` + p(1) + ` WHERE id = ` + p(2)
+
+	if _, err := r.db.ExecContext(ctx, query, nullString(roomID), id); err != nil {
+		return fmt.Errorf("failed to set channel room id: %w", err)
+	}
+
+	return nil
+}
+
+// ListByRoomID returns all channels recorded with a room-id, oldest first.
+// More than one result means the broadcaster was archived under several names.
+func (r *ChannelRepository) ListByRoomID(ctx context.Context, roomID string) ([]Channel, error) {
+	query := `
+		SELECT ` + channelColumns + `
+		FROM ` + channelFrom + `
+		WHERE c.twitch_room_id = ` + r.db.Placeholder(1) + `
+		ORDER BY c.created_at ASC, c.id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channels by room id: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanChannels(rows)
+}
+
 // UpdateStats updates the channel message count and last message time.
 func (r *ChannelRepository) UpdateStats(ctx context.Context, id int64, totalMessages int64, lastMessageAt time.Time) error {
 	var query string
@@ -223,7 +260,7 @@ func (r *ChannelRepository) UpdateStats(ctx context.Context, id int64, totalMess
 		`
 	}
 
-	_, err := r.db.ExecContext(ctx, query, totalMessages, lastMessageAt.Format(time.RFC3339), id)
+	_, err := r.db.ExecContext(ctx, query, totalMessages, timeArg(r.db, lastMessageAt), id)
 	if err != nil {
 		return fmt.Errorf("failed to update channel stats: %w", err)
 	}
@@ -262,56 +299,71 @@ func (r *ChannelRepository) GetEnabledCount(ctx context.Context) (int64, error)
 
 // scanChannel scans a single channel row.
 func (r *ChannelRepository) scanChannel(row *sql.Row) (*Channel, error) {
-	var ch Channel
-	var createdAt, updatedAt any
-	var lastMessageAt any
-
-	err := row.Scan(
-		&ch.ID, &ch.Name, &ch.DisplayName, &ch.Enabled, &ch.RetainHistoryOnDelete,
-		&createdAt, &updatedAt, &lastMessageAt, &ch.TotalMessages,
-	)
+	ch, err := scanChannelRow(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan channel: %w", err)
 	}
-
-	ch.CreatedAt = parseTimeValue(createdAt)
-	ch.UpdatedAt = parseTimeValue(updatedAt)
-	if t := parseTimeValue(lastMessageAt); !t.IsZero() {
-		ch.LastMessageAt = &t
-	}
-
-	return &ch, nil
+	return ch, nil
 }
 
 // scanChannels scans multiple channel rows.
 func (r *ChannelRepository) scanChannels(rows *sql.Rows) ([]Channel, error) {
 	var channels []Channel
 	for rows.Next() {
-		var ch Channel
-		var createdAt, updatedAt any
-		var lastMessageAt any
-
-		err := rows.Scan(
-			&ch.ID, &ch.Name, &ch.DisplayName, &ch.Enabled, &ch.RetainHistoryOnDelete,
-			&createdAt, &updatedAt, &lastMessageAt, &ch.TotalMessages,
-		)
+		ch, err := scanChannelRow(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan channel: %w", err)
 		}
+		channels = append(channels, *ch)
+	}
 
-		ch.CreatedAt = parseTimeValue(createdAt)
-		ch.UpdatedAt = parseTimeValue(updatedAt)
-		if t := parseTimeValue(lastMessageAt); !t.IsZero() {
-			ch.LastMessageAt = &t
-		}
+	return channels, rows.Err()
+}
 
-		channels = append(channels, ch)
+// scanChannelRow scans the columns selected by channelColumns.
+func scanChannelRow(row interface{ Scan(...any) error }) (*Channel, error) {
+	var ch Channel
+	var createdAt, updatedAt any
+	var lastMessageAt any
+	var roomID sql.NullString
+	var stateID sql.NullInt64
+	var emoteOnly, r9k, subsOnly sql.NullBool
+	var followersOnly, slow sql.NullInt64
+	var stateStartedAt any
+
+	err := row.Scan(
+		&ch.ID, &ch.Name, &ch.DisplayName, &ch.Enabled, &ch.RetainHistoryOnDelete,
+		&createdAt, &updatedAt, &lastMessageAt, &ch.TotalMessages, &roomID,
+		&stateID, &emoteOnly, &followersOnly, &r9k, &slow, &subsOnly, &stateStartedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	return channels, rows.Err()
+	ch.CreatedAt = parseTimeValue(createdAt)
+	ch.UpdatedAt = parseTimeValue(updatedAt)
+	if t := parseTimeValue(lastMessageAt); !t.IsZero() {
+		ch.LastMessageAt = &t
+	}
+	ch.TwitchRoomID = roomID.String
+
+	if stateID.Valid {
+		ch.State = &ChannelState{
+			ID:                   stateID.Int64,
+			ChannelID:            ch.ID,
+			EmoteOnly:            boolPtr(emoteOnly),
+			FollowersOnlyMinutes: intPtr(followersOnly),
+			R9K:                  boolPtr(r9k),
+			SlowSeconds:          intPtr(slow),
+			SubsOnly:             boolPtr(subsOnly),
+			StartedAt:            parseTimeValue(stateStartedAt),
+		}
+	}
+
+	return &ch, nil
 }
 
 // parseTimeValue converts database time values to time.Time.
@@ -0,0 +1,262 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ChannelState is a time-ranged snapshot of a channel's chat modes as
+// reported by ROOMSTATE. A nil setting means it has not been observed yet.
+type ChannelState struct {
+	ID                   int64
+	ChannelID            int64
+	EmoteOnly            *bool
+	FollowersOnlyMinutes *int // -1 means followers-only is disabled
+	R9K                  *bool
+	SlowSeconds          *int // 0 means slow mode is disabled
+	SubsOnly             *bool
+	StartedAt            time.Time
+	EndedAt              *time.Time // nil for the current state
+}
+
+// ChannelStateUpdate is a (possibly partial) ROOMSTATE change.
+// Nil fields keep their previous value.
+type ChannelStateUpdate struct {
+	EmoteOnly            *bool
+	FollowersOnlyMinutes *int
+	R9K                  *bool
+	SlowSeconds          *int
+	SubsOnly             *bool
+}
+
+// ChannelStateRepository provides operations for channel state history.
+type ChannelStateRepository struct {
+	db Database
+}
+
+// NewChannelStateRepository creates a new channel state repository.
+func NewChannelStateRepository(db Database) *ChannelStateRepository {
+	return &ChannelStateRepository{db: db}
+}
+
+// Apply merges update into the channel's current state. If anything changed,
+// the current row is closed at `at` and a new current row is opened.
+// It returns the resulting current state and whether a new row was written.
+func (r *ChannelStateRepository) Apply(ctx context.Context, channelID int64, update ChannelStateUpdate, at time.Time) (*ChannelState, bool, error) {
+	p := r.db.Placeholder
+	var (
+		next    *ChannelState
+		changed bool
+	)
+
+	err := r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		current, err := scanChannelState(tx.QueryRowContext(ctx, `
+			SELECT id, channel_id, emote_only, followers_only_minutes, r9k, slow_seconds, subs_only,
+			       started_at, ended_at
+			FROM channel_states
+			WHERE channel_id = `+p(1)+` AND ended_at IS NULL`, channelID))
+		if err != nil {
+			return err
+		}
+
+		next = &ChannelState{ChannelID: channelID, StartedAt: at}
+		if current != nil {
+			next.EmoteOnly = current.EmoteOnly
+			next.FollowersOnlyMinutes = current.FollowersOnlyMinutes
+			next.R9K = current.R9K
+			next.SlowSeconds = current.SlowSeconds
+			next.SubsOnly = current.SubsOnly
+		}
+		if update.EmoteOnly != nil {
+			next.EmoteOnly = update.EmoteOnly
+		}
+		if update.FollowersOnlyMinutes != nil {
+			next.FollowersOnlyMinutes = update.FollowersOnlyMinutes
+		}
+		if update.R9K != nil {
+			next.R9K = update.R9K
+		}
+		if update.SlowSeconds != nil {
+			next.SlowSeconds = update.SlowSeconds
+		}
+		if update.SubsOnly != nil {
+			next.SubsOnly = update.SubsOnly
+		}
+
+		if current != nil && current.sameModes(next) {
+			next = current
+			return nil
+		}
+		changed = true
+
+		if current != nil {
+			_, err := tx.ExecContext(ctx,
+				`UPDATE channel_states SET ended_at = `+p(1)+` WHERE id = `+p(2),
+				timeArg(r.db, at), current.ID)
+			if err != nil {
+				return fmt.Errorf("failed to close channel state: %w", err)
+			}
+		}
+
+		insert := fmt.Sprintf(`
+			INSERT INTO channel_states
+				(channel_id, emote_only, followers_only_minutes, r9k, slow_seconds, subs_only, started_at)
+			VALUES (%s, %s, %s, %s, %s, %s, %s)`,
+			p(1), p(2), p(3), p(4), p(5), p(6), p(7))
+		args := []any{
+			channelID, nullBool(next.EmoteOnly), nullInt(next.FollowersOnlyMinutes), nullBool(next.R9K),
+			nullInt(next.SlowSeconds), nullBool(next.SubsOnly), timeArg(r.db, at),
+		}
+
+		if r.db.SupportsReturning() {
+			if err := tx.QueryRowContext(ctx, insert+` RETURNING id`, args...).Scan(&next.ID); err != nil {
+				return fmt.Errorf("failed to insert channel state: %w", err)
+			}
+			return nil
+		}
+
+		result, err := tx.ExecContext(ctx, insert, args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert channel state: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		next.ID = id
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return next, changed, nil
+}
+
+// GetCurrent returns the open state for a channel, or nil if none was recorded.
+func (r *ChannelStateRepository) GetCurrent(ctx context.Context, channelID int64) (*ChannelState, error) {
+	query := `
+		SELECT id, channel_id, emote_only, followers_only_minutes, r9k, slow_seconds, subs_only,
+		       started_at, ended_at
+		FROM channel_states
+		WHERE channel_id = ` + r.db.Placeholder(1) + ` AND ended_at IS NULL`
+
+	return scanChannelState(r.db.QueryRowContext(ctx, query, channelID))
+}
+
+// ListByChannel returns a channel's state history, newest first.
+func (r *ChannelStateRepository) ListByChannel(ctx context.Context, channelID int64, limit int) ([]ChannelState, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		SELECT id, channel_id, emote_only, followers_only_minutes, r9k, slow_seconds, subs_only,
+		       started_at, ended_at
+		FROM channel_states
+		WHERE channel_id = %s
+		ORDER BY started_at DESC, id DESC
+		LIMIT %s`, p(1), p(2))
+
+	rows, err := r.db.QueryContext(ctx, query, channelID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel states: %w", err)
+	}
+	defer rows.Close()
+
+	var states []ChannelState
+	for rows.Next() {
+		s, err := scanChannelState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, *s)
+	}
+
+	return states, rows.Err()
+}
+
+// sameModes reports whether two states have identical chat mode settings.
+func (s *ChannelState) sameModes(o *ChannelState) bool {
+	return equalPtr(s.EmoteOnly, o.EmoteOnly) &&
+		equalPtr(s.FollowersOnlyMinutes, o.FollowersOnlyMinutes) &&
+		equalPtr(s.R9K, o.R9K) &&
+		equalPtr(s.SlowSeconds, o.SlowSeconds) &&
+		equalPtr(s.SubsOnly, o.SubsOnly)
+}
+
+// scanChannelState scans a single channel_states row. It returns nil, nil
+// when the row does not exist.
+func scanChannelState(row interface{ Scan(...any) error }) (*ChannelState, error) {
+	var s ChannelState
+	var emoteOnly, r9k, subsOnly sql.NullBool
+	var followersOnly, slow sql.NullInt64
+	var startedAt, endedAt any
+
+	err := row.Scan(
+		&s.ID, &s.ChannelID, &emoteOnly, &followersOnly, &r9k, &slow, &subsOnly,
+		&startedAt, &endedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan channel state: %w", err)
+	}
+
+	s.EmoteOnly = boolPtr(emoteOnly)
+	s.FollowersOnlyMinutes = intPtr(followersOnly)
+	s.R9K = boolPtr(r9k)
+	s.SlowSeconds = intPtr(slow)
+	s.SubsOnly = boolPtr(subsOnly)
+	s.StartedAt = parseTimeValue(startedAt)
+	if t := parseTimeValue(endedAt); !t.IsZero() {
+		s.EndedAt = &t
+	}
+
+	return &s, nil
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
+
+func nullInt(n *int) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*n), Valid: true}
+}
+
+func boolPtr(b sql.NullBool) *bool {
+	if !b.Valid {
+		return nil
+	}
+	v := b.Bool
+	return &v
+}
+
+func intPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
@@ -192,3 +192,26 @@ func MapResultNotFound(result sql.Result) error {
 	}
 	return nil
 }
+
+// nullString returns a NULL value for empty strings.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// timeArg converts t into a query argument for the given database.
+// SQLite stores times as SQLiteTimestamp text, keeping the milliseconds of
+// tmi-sent-ts; Postgres accepts time.Time directly.
+func timeArg(db Database, t time.Time) any {
+	if db.SupportsReturning() {
+		return t
+	}
+	return FormatSQLiteTimestamp(t)
+}
+
+// nullTimeArg is like timeArg but stores NULL for the zero time.
+func nullTimeArg(db Database, t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return timeArg(db, t)
+}
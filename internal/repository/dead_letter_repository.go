@@ -0,0 +1,174 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DeadLetter is a message the database refused on its own, kept with the
+// error so it can be inspected and stored again.
+type DeadLetter struct {
+	ID            int64
+	ChannelName   string
+	Username      string
+	Text          string
+	Payload       string // the message as ingested, as JSON
+	Error         string // the latest error
+	Attempts      int
+	FirstFailedAt time.Time
+	LastFailedAt  time.Time
+}
+
+// DeadLetterRepository provides operations for dead letters.
+type DeadLetterRepository struct {
+	db Database
+}
+
+// NewDeadLetterRepository creates a new dead letter repository.
+func NewDeadLetterRepository(db Database) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db}
+}
+
+const deadLetterColumns = `id, channel_name, username, text, payload, error, attempts, first_failed_at, last_failed_at`
+
+// Create inserts a dead letter, failed now unless FirstFailedAt is set.
+func (r *DeadLetterRepository) Create(ctx context.Context, dl *DeadLetter) error {
+	if dl.FirstFailedAt.IsZero() {
+		dl.FirstFailedAt = time.Now().UTC()
+	}
+	if dl.LastFailedAt.IsZero() {
+		dl.LastFailedAt = dl.FirstFailedAt
+	}
+	if dl.Attempts <= 0 {
+		dl.Attempts = 1
+	}
+
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		INSERT INTO dead_letters
+			(channel_name, username, text, payload, error, attempts, first_failed_at, last_failed_at)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s)`,
+		p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8))
+	args := []any{
+		dl.ChannelName, dl.Username, dl.Text, dl.Payload, dl.Error, dl.Attempts,
+		timeArg(r.db, dl.FirstFailedAt), timeArg(r.db, dl.LastFailedAt),
+	}
+
+	if r.db.SupportsReturning() {
+		if err := r.db.QueryRowContext(ctx, query+` RETURNING id`, args...).Scan(&dl.ID); err != nil {
+			return MapSQLError(fmt.Errorf("failed to create dead letter: %w", err))
+		}
+		return nil
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return MapSQLError(fmt.Errorf("failed to create dead letter: %w", err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	dl.ID = id
+	return nil
+}
+
+// GetByID returns a dead letter, or nil if there is none with the id.
+func (r *DeadLetterRepository) GetByID(ctx context.Context, id int64) (*DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters WHERE id = ` + r.db.Placeholder(1)
+
+	dl, err := scanDeadLetter(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+	}
+	return dl, nil
+}
+
+// List returns dead letters oldest first, starting after the one with id
+// afterID.
+func (r *DeadLetterRepository) List(ctx context.Context, afterID int64, limit int) ([]DeadLetter, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		SELECT `+deadLetterColumns+`
+		FROM dead_letters
+		WHERE id > %s
+		ORDER BY id
+		LIMIT %s`, p(1), p(2))
+
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []DeadLetter
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, *dl)
+	}
+	return deadLetters, rows.Err()
+}
+
+// Count returns the number of dead letters.
+func (r *DeadLetterRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM dead_letters`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return count, nil
+}
+
+// RecordFailure records another failed attempt to store a dead letter.
+func (r *DeadLetterRepository) RecordFailure(ctx context.Context, id int64, errText string, at time.Time) error {
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		UPDATE dead_letters
+		SET error = %s, attempts = attempts + 1, last_failed_at = %s
+		WHERE id = %s`, p(1), p(2), p(3))
+
+	result, err := r.db.ExecContext(ctx, query, errText, timeArg(r.db, at), id)
+	if err != nil {
+		return MapSQLError(fmt.Errorf("failed to update dead letter: %w", err))
+	}
+	return MapResultNotFound(result)
+}
+
+// Delete deletes a dead letter.
+func (r *DeadLetterRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM dead_letters WHERE id = `+r.db.Placeholder(1), id)
+	if err != nil {
+		return MapSQLError(fmt.Errorf("failed to delete dead letter: %w", err))
+	}
+	return MapResultNotFound(result)
+}
+
+func scanDeadLetter(row interface{ Scan(...any) error }) (*DeadLetter, error) {
+	var dl DeadLetter
+	var firstFailedAt, lastFailedAt any
+	err := row.Scan(
+		&dl.ID, &dl.ChannelName, &dl.Username, &dl.Text, &dl.Payload, &dl.Error,
+		&dl.Attempts, &firstFailedAt, &lastFailedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	dl.FirstFailedAt = parseTimeValue(firstFailedAt)
+	dl.LastFailedAt = parseTimeValue(lastFailedAt)
+	return &dl, nil
+}
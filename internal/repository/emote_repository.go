@@ -0,0 +1,255 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+// MessageEmote is one occurrence of an emote in a message. Start and End
+// are inclusive rune offsets into the message text, as in the emotes tag.
+type MessageEmote struct {
+	MessageID int64
+	EmoteID   string
+	Name      string
+	Start     int
+	End       int
+}
+
+// EmoteStat is the usage of one emote over a set of messages.
+type EmoteStat struct {
+	EmoteID    string
+	Name       string
+	Uses       int64 // Occurrences, counting repeats within a message
+	Messages   int64
+	Users      int64
+	LastUsedAt time.Time
+}
+
+// EmoteStatsFilter narrows emote statistics. Nil fields are not filtered on.
+type EmoteStatsFilter struct {
+	ChannelID *int64
+	UserID    *int64
+	Since     *time.Time // Inclusive, on message sent_at
+	Until     *time.Time // Exclusive, on message sent_at
+	Limit     int
+}
+
+// EmoteRepository provides emote usage queries.
+type EmoteRepository struct {
+	db Database
+}
+
+// NewEmoteRepository creates a new emote repository.
+func NewEmoteRepository(db Database) *EmoteRepository {
+	return &EmoteRepository{db: db}
+}
+
+// ListByMessage returns the emotes in a message in text order.
+func (r *EmoteRepository) ListByMessage(ctx context.Context, messageID int64) ([]MessageEmote, error) {
+	query := `
+		SELECT message_id, emote_id, emote_name, start_pos, end_pos
+		FROM message_emotes
+		WHERE message_id = ` + r.db.Placeholder(1) + `
+		ORDER BY start_pos ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message emotes: %w", err)
+	}
+	defer rows.Close()
+
+	var emotes []MessageEmote
+	for rows.Next() {
+		var e MessageEmote
+		if err := rows.Scan(&e.MessageID, &e.EmoteID, &e.Name, &e.Start, &e.End); err != nil {
+			return nil, fmt.Errorf("failed to scan message emote: %w", err)
+		}
+		emotes = append(emotes, e)
+	}
+
+	return emotes, rows.Err()
+}
+
+// TopEmotes returns the most used emotes matching the filter, most used first.
+func (r *EmoteRepository) TopEmotes(ctx context.Context, filter EmoteStatsFilter) ([]EmoteStat, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	var conditions []string
+	var args []any
+	argIndex := 1
+
+	if filter.ChannelID != nil {
+		conditions = append(conditions, "m.channel_id = "+r.db.Placeholder(argIndex))
+		args = append(args, *filter.ChannelID)
+		argIndex++
+	}
+	if filter.UserID != nil {
+		conditions = append(conditions, "m.user_id = "+r.db.Placeholder(argIndex))
+		args = append(args, *filter.UserID)
+		argIndex++
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "m.sent_at >= "+r.db.Placeholder(argIndex))
+		args = append(args, timeArg(r.db, *filter.Since))
+		argIndex++
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "m.sent_at < "+r.db.Placeholder(argIndex))
+		args = append(args, timeArg(r.db, *filter.Until))
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT e.emote_id, MAX(e.emote_name), COUNT(*) AS uses,
+		       COUNT(DISTINCT e.message_id), COUNT(DISTINCT m.user_id), MAX(m.sent_at)
+		FROM message_emotes e
+		JOIN messages m ON m.id = e.message_id
+		%s
+		GROUP BY e.emote_id
+		ORDER BY uses DESC, e.emote_id ASC
+		LIMIT %s`, whereClause, r.db.Placeholder(argIndex))
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top emotes: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []EmoteStat
+	for rows.Next() {
+		var s EmoteStat
+		var lastUsed any
+		if err := rows.Scan(&s.EmoteID, &s.Name, &s.Uses, &s.Messages, &s.Users, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan emote stat: %w", err)
+		}
+		s.LastUsedAt = parseTimeValue(lastUsed)
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// execer is satisfied by both Database and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ParseMessageEmotes extracts each emote occurrence from the emotes tag,
+// named by the text it covers. Ranges that fall outside the text are skipped.
+func ParseMessageEmotes(tags map[string]string, text string) []MessageEmote {
+	var emotes []MessageEmote
+	for _, emote := range irc.Tags(tags).Emotes() {
+		for _, rng := range emote.Ranges {
+			name := rng.Text(text)
+			if name == "" {
+				continue
+			}
+			emotes = append(emotes, MessageEmote{
+				EmoteID: emote.ID,
+				Name:    name,
+				Start:   rng.Start,
+				End:     rng.End,
+			})
+		}
+	}
+	return emotes
+}
+
+// insertMessageEmotes writes the emote occurrences of a stored message.
+func insertMessageEmotes(ctx context.Context, p func(int) string, exec execer, messageID int64, emotes []MessageEmote) error {
+	if len(emotes) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO message_emotes (message_id, emote_id, emote_name, start_pos, end_pos)
+		VALUES (%s, %s, %s, %s, %s)`, p(1), p(2), p(3), p(4), p(5))
+
+	for _, e := range emotes {
+		if _, err := exec.ExecContext(ctx, query, messageID, e.EmoteID, e.Name, e.Start, e.End); err != nil {
+			return fmt.Errorf("failed to insert message emote: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// emoteBackfillPageSize is how many messages backfillMessageEmotes reads at
+// a time.
+const emoteBackfillPageSize = 500
+
+// backfillMessageEmotes adds the emote rows of messages stored before
+// message_emotes existed, parsed from their stored tags. Messages that
+// already have emote rows are left alone.
+func backfillMessageEmotes(ctx context.Context, tx *sql.Tx, p func(int) string) error {
+	query := `
+		SELECT m.id, m.text, m.tags
+		FROM messages m
+		WHERE m.id > ` + p(1) + `
+		  AND m.tags IS NOT NULL
+		  AND CAST(m.tags AS TEXT) LIKE '%"emotes"%'
+		  AND NOT EXISTS (SELECT 1 FROM message_emotes e WHERE e.message_id = m.id)
+		ORDER BY m.id ASC
+		LIMIT ` + p(2)
+
+	type storedMessage struct {
+		id   int64
+		text string
+		tags map[string]string
+	}
+
+	var afterID int64
+	for {
+		// Read a page before writing: a Postgres connection can't run
+		// statements while a result set is open
+		rows, err := tx.QueryContext(ctx, query, afterID, emoteBackfillPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to query messages to backfill emotes: %w", err)
+		}
+		var page []storedMessage
+		for rows.Next() {
+			var m storedMessage
+			var tagsJSON sql.NullString
+			if err := rows.Scan(&m.id, &m.text, &tagsJSON); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan message: %w", err)
+			}
+			// Unparseable tags have no emotes to recover
+			_ = json.Unmarshal([]byte(tagsJSON.String), &m.tags)
+			page = append(page, m)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read messages to backfill emotes: %w", err)
+		}
+
+		for _, m := range page {
+			if err := insertMessageEmotes(ctx, p, tx, m.id, ParseMessageEmotes(m.tags, m.text)); err != nil {
+				return err
+			}
+			afterID = m.id
+		}
+		if len(page) < emoteBackfillPageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// bulkChunkRows is how many rows one multi-row statement carries, well
+// within SQLite's limit on bound parameters.
+const bulkChunkRows = 500
+
+// messageInsertColumns are the columns written when a message is stored;
+// messageRow returns values in this order.
+var messageInsertColumns = []string{
+	"channel_id", "user_id", "text", "sent_at", "received_at", "tags", "twitch_message_id", "bits", "outbound",
+}
+
+// CreateBatch inserts multiple messages in a single transaction. Messages
+// whose TwitchMessageID is already stored, or earlier in the batch, are
+// skipped and left with ID 0.
+//
+// Rows are written in bulk, with COPY on Postgres and multi-row INSERTs on
+// SQLite, and channel and user counters are updated once per batch.
+// Duplicates are skipped by the database with ON CONFLICT, so concurrent
+// batches, such as spool replays and dead-letter redrives, may carry the
+// same message.
+func (r *MessageRepository) CreateBatch(ctx context.Context, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		batch := dedupeBatch(messages)
+
+		var err error
+		if r.db.DriverName() == "postgres" {
+			err = r.copyMessages(ctx, tx, batch)
+		} else {
+			err = r.insertMessages(ctx, tx, batch)
+		}
+		if err != nil {
+			return err
+		}
+
+		stored := make([]*Message, 0, len(batch))
+		for _, msg := range batch {
+			if msg.ID == 0 {
+				continue
+			}
+			if err := insertMessageEmotes(ctx, r.db.Placeholder, tx, msg.ID, msg.Emotes); err != nil {
+				return err
+			}
+			stored = append(stored, msg)
+		}
+		return addMessageStats(ctx, r.db, tx, stored)
+	})
+}
+
+// dedupeBatch returns the messages of a batch to insert, leaving out later
+// copies of a Twitch message id. Every message starts with ID 0.
+func dedupeBatch(messages []Message) []*Message {
+	seen := make(map[string]bool)
+	batch := make([]*Message, 0, len(messages))
+	for i := range messages {
+		msg := &messages[i]
+		msg.ID = 0
+		if id := msg.TwitchMessageID; id != "" {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		batch = append(batch, msg)
+	}
+	return batch
+}
+
+// insertMessages inserts messages on SQLite with multi-row INSERTs, setting
+// the ID of those stored. SQLite takes one writer at a time, so the rows of
+// one INSERT get ids in VALUES order, whatever order RETURNING gives them.
+func (r *MessageRepository) insertMessages(ctx context.Context, tx *sql.Tx, messages []*Message) error {
+	columns := strings.Join(messageInsertColumns, ", ")
+
+	for start := 0; start < len(messages); start += bulkChunkRows {
+		chunk := messages[start:min(start+bulkChunkRows, len(messages))]
+
+		values := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*len(messageInsertColumns))
+		for i, msg := range chunk {
+			row, err := r.messageRow(msg)
+			if err != nil {
+				return err
+			}
+			values[i] = "(" + placeholderList(r.db, len(args)+1, len(row)) + ")"
+			args = append(args, row...)
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			INSERT INTO messages (`+columns+`)
+			VALUES `+strings.Join(values, ", ")+`
+			ON CONFLICT (twitch_message_id) DO NOTHING
+			RETURNING id, twitch_message_id`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert messages: %w", err)
+		}
+		type insertedRow struct {
+			id       int64
+			twitchID sql.NullString
+		}
+		var inserted []insertedRow
+		for rows.Next() {
+			var row insertedRow
+			if err := rows.Scan(&row.id, &row.twitchID); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan message id: %w", err)
+			}
+			inserted = append(inserted, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to insert messages: %w", err)
+		}
+		slices.SortFunc(inserted, func(a, b insertedRow) int { return cmp.Compare(a.id, b.id) })
+
+		stored := make(map[string]bool, len(inserted))
+		for _, row := range inserted {
+			if row.twitchID.Valid {
+				stored[row.twitchID.String] = true
+			}
+		}
+		next := 0
+		for _, msg := range chunk {
+			if msg.TwitchMessageID != "" && !stored[msg.TwitchMessageID] {
+				continue
+			}
+			if next == len(inserted) || inserted[next].twitchID.String != msg.TwitchMessageID {
+				return fmt.Errorf("inserted message ids out of order")
+			}
+			msg.ID = inserted[next].id
+			next++
+		}
+	}
+	return nil
+}
+
+// copyMessages streams messages into a temporary table with COPY, then
+// moves them into messages, setting the ID of those stored. COPY can't skip
+// conflicts, and the INSERT can't tell which row an id it returns came
+// from, so the ids are taken from the sequence first.
+func (r *MessageRepository) copyMessages(ctx context.Context, tx *sql.Tx, messages []*Message) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT nextval(pg_get_serial_sequence('messages', 'id')) FROM generate_series(1, $1)`, len(messages))
+	if err != nil {
+		return fmt.Errorf("failed to reserve message ids: %w", err)
+	}
+	ids := make([]int64, 0, len(messages))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan message id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to reserve message ids: %w", err)
+	}
+	if len(ids) != len(messages) {
+		return fmt.Errorf("reserved %d message ids for %d messages", len(ids), len(messages))
+	}
+	// Ids follow arrival order, as they would inserting row by row
+	slices.Sort(ids)
+
+	columns := strings.Join(append([]string{"id"}, messageInsertColumns...), ", ")
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE messages_batch ON COMMIT DROP AS
+		SELECT `+columns+` FROM messages WITH NO DATA`); err != nil {
+		return fmt.Errorf("failed to create batch table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("messages_batch", append([]string{"id"}, messageInsertColumns...)...))
+	if err != nil {
+		return fmt.Errorf("failed to prepare copy: %w", err)
+	}
+	for i, msg := range messages {
+		row, err := r.messageRow(msg)
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, append([]any{ids[i]}, row...)...); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to copy message: %w", err)
+		}
+	}
+	// The rows are sent, and checked, when the copy ends
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to copy messages: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("failed to copy messages: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		INSERT INTO messages (`+columns+`)
+		SELECT `+columns+` FROM messages_batch ORDER BY id
+		ON CONFLICT (twitch_message_id) DO NOTHING
+		RETURNING id, twitch_message_id`)
+	if err != nil {
+		return fmt.Errorf("failed to insert messages: %w", err)
+	}
+	stored := make(map[int64]bool, len(messages))
+	for rows.Next() {
+		var id int64
+		var twitchID sql.NullString
+		if err := rows.Scan(&id, &twitchID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan message id: %w", err)
+		}
+		stored[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to insert messages: %w", err)
+	}
+
+	for i, msg := range messages {
+		if stored[ids[i]] {
+			msg.ID = ids[i]
+		}
+	}
+	return nil
+}
+
+// messageRow returns the values of msg for messageInsertColumns.
+func (r *MessageRepository) messageRow(msg *Message) ([]any, error) {
+	var tagsJSON sql.NullString
+	if len(msg.Tags) > 0 {
+		data, err := json.Marshal(msg.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		tagsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	return []any{
+		msg.ChannelID, msg.UserID, msg.Text, timeArg(r.db, msg.SentAt), nullTimeArg(r.db, msg.ReceivedAt),
+		tagsJSON, nullString(msg.TwitchMessageID), msg.Bits, msg.Outbound,
+	}, nil
+}
+
+// messageStats is what stored messages add to one channel's or user's
+// counters.
+type messageStats struct {
+	id     int64
+	count  int64
+	lastAt time.Time
+}
+
+// addMessageStats adds stored messages to the message counts and last
+// message times of their channels and users, with one UPDATE per table
+// however many messages there are.
+func addMessageStats(ctx context.Context, db Database, tx *sql.Tx, messages []*Message) error {
+	// Batches from other writers, and spool and dead-letter replays, may
+	// store older messages after newer ones; last times never go back
+	latest := func(column string) string {
+		if db.DriverName() == "postgres" {
+			return "GREATEST(" + column + ", v.last_at)"
+		}
+		// SQLite's MAX is NULL if any argument is
+		return "MAX(COALESCE(" + column + ", v.last_at), v.last_at)"
+	}
+
+	channels := aggregateMessageStats(messages, func(msg *Message) int64 { return msg.ChannelID })
+	if err := updateMessageStats(ctx, db, tx, `
+		UPDATE channels
+		SET total_messages = channels.total_messages + v.n,
+			last_message_at = `+latest("channels.last_message_at")+`,
+			updated_at = `+db.NowFunc()+`
+		FROM v
+		WHERE channels.id = v.id`, channels); err != nil {
+		return fmt.Errorf("failed to update channel stats: %w", err)
+	}
+
+	users := aggregateMessageStats(messages, func(msg *Message) int64 { return msg.UserID })
+	if err := updateMessageStats(ctx, db, tx, `
+		UPDATE users
+		SET total_messages = users.total_messages + v.n,
+			last_seen_at = `+latest("users.last_seen_at")+`
+		FROM v
+		WHERE users.id = v.id`, users); err != nil {
+		return fmt.Errorf("failed to update user stats: %w", err)
+	}
+	return nil
+}
+
+// aggregateMessageStats counts messages by the id key returns, with the
+// latest sent time of each, ordered by id so concurrent batches lock rows
+// in the same order.
+func aggregateMessageStats(messages []*Message, key func(*Message) int64) []messageStats {
+	byID := make(map[int64]*messageStats)
+	for _, msg := range messages {
+		id := key(msg)
+		stats, ok := byID[id]
+		if !ok {
+			stats = &messageStats{id: id}
+			byID[id] = stats
+		}
+		stats.count++
+		if msg.SentAt.After(stats.lastAt) {
+			stats.lastAt = msg.SentAt
+		}
+	}
+
+	all := make([]messageStats, 0, len(byID))
+	for _, stats := range byID {
+		all = append(all, *stats)
+	}
+	slices.SortFunc(all, func(a, b messageStats) int { return cmp.Compare(a.id, b.id) })
+	return all
+}
+
+// updateMessageStats runs update, which reads the stats from a table v of
+// (id, n, last_at), for every chunk of stats.
+func updateMessageStats(ctx context.Context, db Database, tx *sql.Tx, update string, stats []messageStats) error {
+	p := db.Placeholder
+	// Postgres can't tell the types of bare parameters in VALUES
+	row := "(%s, %s, %s)"
+	if db.DriverName() == "postgres" {
+		row = "(%s::bigint, %s::bigint, %s::timestamptz)"
+	}
+
+	for start := 0; start < len(stats); start += bulkChunkRows {
+		chunk := stats[start:min(start+bulkChunkRows, len(stats))]
+
+		values := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*3)
+		for i, s := range chunk {
+			n := len(args)
+			values[i] = fmt.Sprintf(row, p(n+1), p(n+2), p(n+3))
+			args = append(args, s.id, s.count, timeArg(db, s.lastAt))
+		}
+
+		query := `WITH v(id, n, last_at) AS (VALUES ` + strings.Join(values, ", ") + `)` + update
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeholderList returns n comma-separated placeholders, starting at
+// index first.
+func placeholderList(db Database, first, n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = db.Placeholder(first + i)
+	}
+	return strings.Join(placeholders, ", ")
+}
@@ -6,25 +6,39 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
 // Message represents a message in the database.
 type Message struct {
-	ID          int64
-	ChannelID   int64
-	UserID      int64
-	Text        string
-	SentAt      time.Time
-	Tags        map[string]string
-	Username    string // Joined from users table
-	DisplayName string // Joined from users table
-	ChannelName string // Joined from channels table
+	ID              int64
+	ChannelID       int64
+	UserID          int64
+	Text            string
+	SentAt          time.Time // Twitch server time (tmi-sent-ts), falling back to ReceivedAt
+	ReceivedAt      time.Time // When the archiver read the line; zero if unknown
+	Tags            map[string]string
+	TwitchMessageID string         // Twitch "id" tag, used to match CLEARMSG
+	DeletedAt       *time.Time     // Set when removed by a moderation event
+	Bits            int            // Bits cheered with the message; 0 if none
+	Outbound        bool           // Sent by the archiver's own account from the web UI
+	Emotes          []MessageEmote // Written on insert; not loaded by reads
+	Username        string         // Joined from users table
+	DisplayName     string         // Joined from users table
+	ChannelName     string         // Joined from channels table
 }
 
+// messageColumns is the column list shared by message queries; it must
+// stay in sync with scanMessage and scanMessages.
+const messageColumns = `m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.received_at, m.tags,
+		       m.twitch_message_id, m.deleted_at, m.bits, m.outbound,
+		       u.username, u.display_name, c.name as channel_name`
+
 // User represents a user in the database.
 type User struct {
 	ID            int64
+	TwitchUserID  string // Stable Twitch user-id; empty for users never seen with one
 	Username      string
 	DisplayName   string
 	FirstSeenAt   time.Time
@@ -32,6 +46,10 @@ type User struct {
 	TotalMessages int64
 }
 
+// userColumns is the column list shared by user queries; it must stay in
+// sync with scanUser.
+const userColumns = `id, username, display_name, first_seen_at, last_seen_at, total_messages, twitch_user_id`
+
 // MessageRepository provides operations for messages.
 type MessageRepository struct {
 	db Database
@@ -44,131 +62,44 @@ func NewMessageRepository(db Database) *MessageRepository {
 
 // Create inserts a new message into the database.
 func (r *MessageRepository) Create(ctx context.Context, msg *Message) error {
-	var tagsJSON sql.NullString
-	if len(msg.Tags) > 0 {
-		data, err := json.Marshal(msg.Tags)
-		if err != nil {
-			return fmt.Errorf("failed to marshal tags: %w", err)
-		}
-		tagsJSON = sql.NullString{String: string(data), Valid: true}
-	}
-
-	if r.db.SupportsReturning() {
-		// Postgres: use RETURNING
-		query := `
-			INSERT INTO messages (channel_id, user_id, text, sent_at, tags)
-			VALUES ($1, $2, $3, $4, $5)
-			RETURNING id
-		`
-		err := r.db.QueryRowContext(ctx, query,
-			msg.ChannelID, msg.UserID, msg.Text, msg.SentAt, tagsJSON,
-		).Scan(&msg.ID)
-		if err != nil {
-			return fmt.Errorf("failed to create message: %w", err)
-		}
-		return nil
-	}
-
-	// SQLite: use LastInsertId
-	query := `
-		INSERT INTO messages (channel_id, user_id, text, sent_at, tags)
-		VALUES (?, ?, ?, ?, ?)
-	`
-
-	result, err := r.db.ExecContext(ctx, query,
-		msg.ChannelID, msg.UserID, msg.Text, msg.SentAt.Format(time.RFC3339), tagsJSON,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create message: %w", err)
-	}
-
-	id, err := result.LastInsertId()
+	row, err := r.messageRow(msg)
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-	msg.ID = id
-
-	return nil
-}
-
-// CreateBatch inserts multiple messages in a single transaction.
-func (r *MessageRepository) CreateBatch(ctx context.Context, messages []Message) error {
-	if len(messages) == 0 {
-		return nil
+		return err
 	}
 
 	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
-		var query string
-		if r.db.SupportsReturning() {
-			query = `
-				INSERT INTO messages (channel_id, user_id, text, sent_at, tags)
-				VALUES ($1, $2, $3, $4, $5)
-				RETURNING id
-			`
-		} else {
-			query = `
-				INSERT INTO messages (channel_id, user_id, text, sent_at, tags)
-				VALUES (?, ?, ?, ?, ?)
-			`
-		}
+		query := `INSERT INTO messages (` + strings.Join(messageInsertColumns, ", ") + `)
+			VALUES (` + placeholderList(r.db, 1, len(row)) + `)`
 
-		stmt, err := tx.PrepareContext(ctx, query)
-		if err != nil {
-			return fmt.Errorf("failed to prepare statement: %w", err)
-		}
-		defer stmt.Close()
-
-		for i := range messages {
-			msg := &messages[i]
-
-			var tagsJSON sql.NullString
-			if len(msg.Tags) > 0 {
-				data, err := json.Marshal(msg.Tags)
-				if err != nil {
-					return fmt.Errorf("failed to marshal tags: %w", err)
-				}
-				tagsJSON = sql.NullString{String: string(data), Valid: true}
+		if r.db.SupportsReturning() {
+			// Postgres: use RETURNING
+			if err := tx.QueryRowContext(ctx, query+` RETURNING id`, row...).Scan(&msg.ID); err != nil {
+				return fmt.Errorf("failed to create message: %w", err)
 			}
-
-			var sentAtArg any
-			if r.db.SupportsReturning() {
-				sentAtArg = msg.SentAt
-			} else {
-				sentAtArg = msg.SentAt.Format(time.RFC3339)
+		} else {
+			// SQLite: use LastInsertId
+			result, err := tx.ExecContext(ctx, query, row...)
+			if err != nil {
+				return fmt.Errorf("failed to create message: %w", err)
 			}
-
-			if r.db.SupportsReturning() {
-				err := stmt.QueryRowContext(ctx,
-					msg.ChannelID, msg.UserID, msg.Text, sentAtArg, tagsJSON,
-				).Scan(&msg.ID)
-				if err != nil {
-					return fmt.Errorf("failed to insert message: %w", err)
-				}
-			} else {
-				result, err := stmt.ExecContext(ctx,
-					msg.ChannelID, msg.UserID, msg.Text, sentAtArg, tagsJSON,
-				)
-				if err != nil {
-					return fmt.Errorf("failed to insert message: %w", err)
-				}
-
-				id, err := result.LastInsertId()
-				if err != nil {
-					return fmt.Errorf("failed to get last insert id: %w", err)
-				}
-				msg.ID = id
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get last insert id: %w", err)
 			}
+			msg.ID = id
 		}
 
-		return nil
+		if err := insertMessageEmotes(ctx, r.db.Placeholder, tx, msg.ID, msg.Emotes); err != nil {
+			return err
+		}
+		return addMessageStats(ctx, r.db, tx, []*Message{msg})
 	})
 }
 
 // GetByID returns a message by ID.
 func (r *MessageRepository) GetByID(ctx context.Context, id int64) (*Message, error) {
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -187,8 +118,7 @@ func (r *MessageRepository) GetRecent(ctx context.Context, channelID int64, limi
 	}
 
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -228,8 +158,7 @@ func (r *MessageRepository) GetPaginated(ctx context.Context, channelID int64, p
 
 	// Get paginated results
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -261,8 +190,7 @@ func (r *MessageRepository) GetBeforeID(ctx context.Context, channelID, beforeID
 	}
 
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -289,8 +217,7 @@ func (r *MessageRepository) GetAfterID(ctx context.Context, channelID, afterID i
 	}
 
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -329,8 +256,7 @@ func (r *MessageRepository) GetRecentGlobal(ctx context.Context, limit int) ([]M
 	}
 
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -368,8 +294,7 @@ func (r *MessageRepository) GetGlobalAfterID(ctx context.Context, afterID int64,
 	}
 
 	query := `
-		SELECT m.id, m.channel_id, m.user_id, m.text, m.sent_at, m.tags,
-		       u.username, u.display_name, c.name as channel_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		JOIN users u ON m.user_id = u.id
 		JOIN channels c ON m.channel_id = c.id
@@ -400,12 +325,15 @@ func (r *MessageRepository) GetGlobalLatestID(ctx context.Context) (int64, error
 
 func (r *MessageRepository) scanMessage(row *sql.Row) (*Message, error) {
 	var msg Message
-	var sentAt any
+	var sentAt, receivedAt any
 	var tagsJSON sql.NullString
+	var twitchMessageID sql.NullString
+	var deletedAt any
 	var displayName sql.NullString
 
 	err := row.Scan(
-		&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Text, &sentAt, &tagsJSON,
+		&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Text, &sentAt, &receivedAt, &tagsJSON,
+		&twitchMessageID, &deletedAt, &msg.Bits, &msg.Outbound,
 		&msg.Username, &displayName, &msg.ChannelName,
 	)
 	if err == sql.ErrNoRows {
@@ -416,6 +344,11 @@ func (r *MessageRepository) scanMessage(row *sql.Row) (*Message, error) {
 	}
 
 	msg.SentAt = parseTimeValue(sentAt)
+	msg.ReceivedAt = parseTimeValue(receivedAt)
+	msg.TwitchMessageID = twitchMessageID.String
+	if t := parseTimeValue(deletedAt); !t.IsZero() {
+		msg.DeletedAt = &t
+	}
 	if displayName.Valid {
 		msg.DisplayName = displayName.String
 	}
@@ -435,12 +368,15 @@ func (r *MessageRepository) scanMessages(rows *sql.Rows) ([]Message, error) {
 
 	for rows.Next() {
 		var msg Message
-		var sentAt any
+		var sentAt, receivedAt any
 		var tagsJSON sql.NullString
+		var twitchMessageID sql.NullString
+		var deletedAt any
 		var displayName sql.NullString
 
 		err := rows.Scan(
-			&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Text, &sentAt, &tagsJSON,
+			&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Text, &sentAt, &receivedAt, &tagsJSON,
+			&twitchMessageID, &deletedAt, &msg.Bits, &msg.Outbound,
 			&msg.Username, &displayName, &msg.ChannelName,
 		)
 		if err != nil {
@@ -448,6 +384,11 @@ func (r *MessageRepository) scanMessages(rows *sql.Rows) ([]Message, error) {
 		}
 
 		msg.SentAt = parseTimeValue(sentAt)
+		msg.ReceivedAt = parseTimeValue(receivedAt)
+		msg.TwitchMessageID = twitchMessageID.String
+		if t := parseTimeValue(deletedAt); !t.IsZero() {
+			msg.DeletedAt = &t
+		}
 		if displayName.Valid {
 			msg.DisplayName = displayName.String
 		}
@@ -477,61 +418,50 @@ func NewUserRepository(db Database) *UserRepository {
 
 // GetByID returns a user by ID.
 func (r *UserRepository) GetByID(ctx context.Context, id int64) (*User, error) {
-	query := `
-		SELECT id, username, display_name, first_seen_at, last_seen_at, total_messages
-		FROM users
-		WHERE id = ` + r.db.Placeholder(1)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ` + r.db.Placeholder(1)
 
 	return r.scanUser(r.db.QueryRowContext(ctx, query, id))
 }
 
 // GetByUsername returns a user by username.
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
-	query := `
-		SELECT id, username, display_name, first_seen_at, last_seen_at, total_messages
-		FROM users
-		WHERE username = ` + r.db.Placeholder(1)
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ` + r.db.Placeholder(1)
 
 	return r.scanUser(r.db.QueryRowContext(ctx, query, username))
 }
 
-// Create creates a new user.
+// Create creates a new user and records their initial name in the history.
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
-	var displayName sql.NullString
-	if user.DisplayName != "" {
-		displayName = sql.NullString{String: user.DisplayName, Valid: true}
-	}
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		return r.createTx(ctx, tx, user)
+	})
+}
+
+func (r *UserRepository) createTx(ctx context.Context, tx *sql.Tx, user *User) error {
+	p := r.db.Placeholder
+	now := r.db.NowFunc()
+	query := fmt.Sprintf(`
+		INSERT INTO users (username, display_name, twitch_user_id, first_seen_at, last_seen_at)
+		VALUES (%s, %s, %s, %s, %s)`, p(1), p(2), p(3), now, now)
+	args := []any{user.Username, nullString(user.DisplayName), nullString(user.TwitchUserID)}
 
 	if r.db.SupportsReturning() {
-		query := `
-			INSERT INTO users (username, display_name, first_seen_at, last_seen_at)
-			VALUES ($1, $2, NOW(), NOW())
-			RETURNING id
-		`
-		err := r.db.QueryRowContext(ctx, query, user.Username, displayName).Scan(&user.ID)
+		if err := tx.QueryRowContext(ctx, query+` RETURNING id`, args...).Scan(&user.ID); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+	} else {
+		result, err := tx.ExecContext(ctx, query, args...)
 		if err != nil {
 			return fmt.Errorf("failed to create user: %w", err)
 		}
-		return nil
-	}
-
-	query := `
-		INSERT INTO users (username, display_name, first_seen_at, last_seen_at)
-		VALUES (?, ?, datetime('now'), datetime('now'))
-	`
-
-	result, err := r.db.ExecContext(ctx, query, user.Username, displayName)
-	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		user.ID = id
 	}
-	user.ID = id
 
-	return nil
+	return r.recordNameTx(ctx, tx, user)
 }
 
 // GetOrCreate returns an existing user or creates a new one.
@@ -580,10 +510,10 @@ func (r *UserRepository) GetCount(ctx context.Context) (int64, error) {
 func (r *UserRepository) scanUser(row *sql.Row) (*User, error) {
 	var user User
 	var firstSeen, lastSeen any
-	var displayName sql.NullString
+	var displayName, twitchUserID sql.NullString
 
 	err := row.Scan(
-		&user.ID, &user.Username, &displayName, &firstSeen, &lastSeen, &user.TotalMessages,
+		&user.ID, &user.Username, &displayName, &firstSeen, &lastSeen, &user.TotalMessages, &twitchUserID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -597,6 +527,7 @@ func (r *UserRepository) scanUser(row *sql.Row) (*User, error) {
 	if displayName.Valid {
 		user.DisplayName = displayName.String
 	}
+	user.TwitchUserID = twitchUserID.String
 
 	return &user, nil
 }
@@ -0,0 +1,135 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// migrationsTableSQL creates the table used to track applied migrations.
+// The statement is valid for both SQLite and Postgres.
+const migrationsTableSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)
+`
+
+// migration is a single numbered migration: a SQL file, or a Go step for
+// work SQL can't express.
+type migration struct {
+	version string
+	sql     string
+	run     func(ctx context.Context, tx *sql.Tx, placeholder func(int) string) error
+}
+
+// goMigrations are the Go steps, run in version order along with the SQL
+// files of either database.
+var goMigrations = []migration{
+	// Messages stored before 008 have their emotes only in the emotes tag,
+	// with rune offsets SQL can't slice the text by
+	{version: "015_message_emotes_backfill", run: backfillMessageEmotes},
+}
+
+// loadMigrations reads all *.sql files from dir in fsys and merges in the Go
+// steps, ordered by version. File names are expected to start with a
+// zero-padded sequence number (e.g. 001_init.sql, 002_moderation.sql).
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, migration{
+			version: strings.TrimSuffix(entry.Name(), ".sql"),
+			sql:     string(data),
+		})
+	}
+
+	migrations = append(migrations, goMigrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// applyMigrations runs every migration that has not yet been recorded in
+// schema_migrations. Each migration runs in its own transaction together
+// with its bookkeeping row, so a failed migration leaves no partial record.
+//
+// Migration 001 is written to be idempotent, which lets databases created
+// before schema_migrations existed adopt the table without manual steps.
+func applyMigrations(ctx context.Context, db *sql.DB, migrations []migration, placeholder func(int) string) error {
+	if _, err := db.ExecContext(ctx, migrationsTableSQL); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied := make(map[string]bool)
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	insertSQL := fmt.Sprintf(`INSERT INTO schema_migrations (version, applied_at) VALUES (%s, %s)`,
+		placeholder(1), placeholder(2))
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", m.version, err)
+		}
+		if m.sql != "" {
+			if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %s: %w", m.version, err)
+			}
+		}
+		if m.run != nil {
+			if err := m.run(ctx, tx, placeholder); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %s: %w", m.version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, insertSQL, m.version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %s: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", m.version, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+-- Migration 002: Moderation events
+-- Created: 2026-10-15
+-- Purpose: Archive CLEARCHAT/CLEARMSG events and soft-delete affected messages
+
+-- Moderation events table (timeouts, bans, chat clears, single-message deletes)
+CREATE TABLE IF NOT EXISTS moderation_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    user_id INTEGER,
+    target_username TEXT,
+    target_message_id TEXT,
+    duration_seconds INTEGER,
+    text TEXT,
+    tags TEXT,
+    occurred_at TEXT NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_events_channel ON moderation_events(channel_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_moderation_events_user ON moderation_events(user_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_moderation_events_target ON moderation_events(target_username);
+
+-- Messages are soft-deleted by moderation events rather than removed
+ALTER TABLE messages ADD COLUMN twitch_message_id TEXT;
+ALTER TABLE messages ADD COLUMN deleted_at TEXT;
+ALTER TABLE messages ADD COLUMN moderation_event_id INTEGER REFERENCES moderation_events(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_twitch_message_id ON messages(twitch_message_id);
@@ -0,0 +1,23 @@
+-- Migration 003: User notices
+-- Created: 2026-10-15
+-- Purpose: Archive USERNOTICE events (subs, resubs, gift subs, raids, announcements)
+
+CREATE TABLE IF NOT EXISTS user_notices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_id INTEGER NOT NULL,
+    user_id INTEGER,
+    kind TEXT NOT NULL,
+    username TEXT,
+    display_name TEXT,
+    system_message TEXT,
+    text TEXT,
+    params TEXT,
+    tags TEXT,
+    occurred_at TEXT NOT NULL DEFAULT (datetime('now')),
+    FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_notices_channel ON user_notices(channel_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_user_notices_channel_kind ON user_notices(channel_id, kind, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_user_notices_user ON user_notices(user_id);
@@ -0,0 +1,20 @@
+-- Migration 004: Channel states
+-- Created: 2026-10-15
+-- Purpose: Record ROOMSTATE chat mode changes as time-ranged rows per channel
+
+CREATE TABLE IF NOT EXISTS channel_states (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel_id INTEGER NOT NULL,
+    emote_only INTEGER,
+    followers_only_minutes INTEGER,
+    r9k INTEGER,
+    slow_seconds INTEGER,
+    subs_only INTEGER,
+    started_at TEXT NOT NULL DEFAULT (datetime('now')),
+    ended_at TEXT,
+    FOREIGN KEY (channel_id) REFERENCES channels(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_states_channel ON channel_states(channel_id, started_at);
+-- At most one open (current) state per channel
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_states_current ON channel_states(channel_id) WHERE ended_at IS NULL;
@@ -0,0 +1,9 @@
+-- Migration 005: Message receive time
+-- Created: 2026-10-15
+-- Purpose: Keep when the archiver read a message separately from sent_at,
+-- which now holds Twitch's tmi-sent-ts
+
+ALTER TABLE messages ADD COLUMN received_at TEXT;
+
+-- Before this migration sent_at was the receive time
+UPDATE messages SET received_at = sent_at WHERE received_at IS NULL;
@@ -0,0 +1,9 @@
+-- Migration 007: Channel room-id
+-- Created: 2026-10-15
+-- Purpose: Record the stable Twitch room-id on channels so a broadcaster
+-- rename (new name, same room-id) can be detected and merged
+
+ALTER TABLE channels ADD COLUMN twitch_room_id TEXT;
+
+-- Not unique: after a rename the old and new names share a room-id until merged
+CREATE INDEX IF NOT EXISTS idx_channels_twitch_room_id ON channels(twitch_room_id);
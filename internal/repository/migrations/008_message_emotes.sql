@@ -0,0 +1,18 @@
+-- Migration 008: Message emotes
+-- Created: 2026-10-15
+-- Purpose: Normalize the emotes tag into one row per emote occurrence for
+-- emote statistics and emote search
+
+CREATE TABLE IF NOT EXISTS message_emotes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL,
+    emote_id TEXT NOT NULL,
+    emote_name TEXT NOT NULL,
+    start_pos INTEGER NOT NULL,
+    end_pos INTEGER NOT NULL,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_emotes_message ON message_emotes(message_id);
+CREATE INDEX IF NOT EXISTS idx_message_emotes_emote ON message_emotes(emote_id);
+CREATE INDEX IF NOT EXISTS idx_message_emotes_name ON message_emotes(emote_name);
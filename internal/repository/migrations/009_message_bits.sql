@@ -0,0 +1,14 @@
+-- Migration 009: Message bits
+-- Created: 2026-10-15
+-- Purpose: Store the bits cheered with a message so cheers can be totalled
+-- per channel, per user and per day
+
+ALTER TABLE messages ADD COLUMN bits INTEGER NOT NULL DEFAULT 0;
+
+-- Backfill from the stored tags
+UPDATE messages
+SET bits = CAST(json_extract(tags, '$.bits') AS INTEGER)
+WHERE tags IS NOT NULL AND json_extract(tags, '$.bits') IS NOT NULL;
+
+-- Cheers are rare, so only index the messages that carry bits
+CREATE INDEX IF NOT EXISTS idx_messages_cheers ON messages(channel_id, sent_at) WHERE bits > 0;
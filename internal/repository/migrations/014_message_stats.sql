@@ -0,0 +1,7 @@
+-- Migration 014: Per-batch message stats
+-- Created: 2026-10-15
+-- Purpose: Message repositories now add stored messages to channel and
+-- user counters once per batch, so the per-row triggers go
+
+DROP TRIGGER IF EXISTS update_channel_stats;
+DROP TRIGGER IF EXISTS update_user_stats;
@@ -0,0 +1,28 @@
+-- Migration 002: Moderation events for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Archive CLEARCHAT/CLEARMSG events and soft-delete affected messages
+
+-- Moderation events table (timeouts, bans, chat clears, single-message deletes)
+CREATE TABLE IF NOT EXISTS moderation_events (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id BIGINT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    target_username TEXT,
+    target_message_id TEXT,
+    duration_seconds INTEGER,
+    text TEXT,
+    tags JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_events_channel ON moderation_events(channel_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_moderation_events_user ON moderation_events(user_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_moderation_events_target ON moderation_events(target_username);
+
+-- Messages are soft-deleted by moderation events rather than removed
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS twitch_message_id TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS moderation_event_id BIGINT REFERENCES moderation_events(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_twitch_message_id ON messages(twitch_message_id);
@@ -0,0 +1,21 @@
+-- Migration 003: User notices for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Archive USERNOTICE events (subs, resubs, gift subs, raids, announcements)
+
+CREATE TABLE IF NOT EXISTS user_notices (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id BIGINT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    kind TEXT NOT NULL,
+    username TEXT,
+    display_name TEXT,
+    system_message TEXT,
+    text TEXT,
+    params JSONB,
+    tags JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_notices_channel ON user_notices(channel_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_user_notices_channel_kind ON user_notices(channel_id, kind, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_user_notices_user ON user_notices(user_id);
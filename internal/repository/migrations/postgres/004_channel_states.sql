@@ -0,0 +1,19 @@
+-- Migration 004: Channel states for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Record ROOMSTATE chat mode changes as time-ranged rows per channel
+
+CREATE TABLE IF NOT EXISTS channel_states (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id BIGINT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    emote_only BOOLEAN,
+    followers_only_minutes INTEGER,
+    r9k BOOLEAN,
+    slow_seconds INTEGER,
+    subs_only BOOLEAN,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_states_channel ON channel_states(channel_id, started_at);
+-- At most one open (current) state per channel
+CREATE UNIQUE INDEX IF NOT EXISTS idx_channel_states_current ON channel_states(channel_id) WHERE ended_at IS NULL;
@@ -0,0 +1,23 @@
+-- Migration 006: User identity for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Key users by Twitch user-id and keep a history of login and
+-- display-name changes so renames don't split a user's history
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS twitch_user_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_twitch_user_id ON users(twitch_user_id) WHERE twitch_user_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS user_name_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    display_name TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_name_history_user ON user_name_history(user_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_user_name_history_username ON user_name_history(username, changed_at);
+
+-- Seed the history with every user's current name
+INSERT INTO user_name_history (user_id, username, display_name, changed_at)
+SELECT id, username, display_name, first_seen_at FROM users;
@@ -0,0 +1,17 @@
+-- Migration 008: Message emotes for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Normalize the emotes tag into one row per emote occurrence for
+-- emote statistics and emote search
+
+CREATE TABLE IF NOT EXISTS message_emotes (
+    id BIGSERIAL PRIMARY KEY,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    emote_id TEXT NOT NULL,
+    emote_name TEXT NOT NULL,
+    start_pos INTEGER NOT NULL,
+    end_pos INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_emotes_message ON message_emotes(message_id);
+CREATE INDEX IF NOT EXISTS idx_message_emotes_emote ON message_emotes(emote_id);
+CREATE INDEX IF NOT EXISTS idx_message_emotes_name ON message_emotes(emote_name);
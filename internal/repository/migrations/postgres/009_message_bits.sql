@@ -0,0 +1,14 @@
+-- Migration 009: Message bits for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Store the bits cheered with a message so cheers can be totalled
+-- per channel, per user and per day
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS bits INTEGER NOT NULL DEFAULT 0;
+
+-- Backfill from the stored tags
+UPDATE messages
+SET bits = (tags->>'bits')::INTEGER
+WHERE tags ? 'bits' AND tags->>'bits' ~ '^[0-9]+$';
+
+-- Cheers are rare, so only index the messages that carry bits
+CREATE INDEX IF NOT EXISTS idx_messages_cheers ON messages(channel_id, sent_at) WHERE bits > 0;
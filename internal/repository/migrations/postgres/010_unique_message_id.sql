@@ -0,0 +1,42 @@
+-- Migration 010: Unique Twitch message ids for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Store each Twitch message once, even when it is ingested twice
+-- by redundant IRC connections or again after a restart
+
+-- The stats triggers counted every stored copy, so take the extra copies
+-- back off the totals before removing them
+UPDATE channels
+SET total_messages = total_messages - (
+    SELECT COUNT(*) FROM messages m
+    WHERE m.channel_id = channels.id
+      AND m.id > (SELECT MIN(f.id) FROM messages f WHERE f.twitch_message_id = m.twitch_message_id)
+)
+WHERE id IN (
+    SELECT m.channel_id FROM messages m
+    WHERE m.id > (SELECT MIN(f.id) FROM messages f WHERE f.twitch_message_id = m.twitch_message_id)
+);
+
+UPDATE users
+SET total_messages = total_messages - (
+    SELECT COUNT(*) FROM messages m
+    WHERE m.user_id = users.id
+      AND m.id > (SELECT MIN(f.id) FROM messages f WHERE f.twitch_message_id = m.twitch_message_id)
+)
+WHERE id IN (
+    SELECT m.user_id FROM messages m
+    WHERE m.id > (SELECT MIN(f.id) FROM messages f WHERE f.twitch_message_id = m.twitch_message_id)
+);
+
+-- Keep the first copy of each message
+DELETE FROM message_emotes
+WHERE message_id IN (
+    SELECT m.id FROM messages m
+    WHERE m.id > (SELECT MIN(f.id) FROM messages f WHERE f.twitch_message_id = m.twitch_message_id)
+);
+
+DELETE FROM messages
+WHERE id > (SELECT MIN(f.id) FROM messages f WHERE f.twitch_message_id = messages.twitch_message_id);
+
+-- Messages without an id tag stay NULL, which the index allows any number of
+DROP INDEX IF EXISTS idx_messages_twitch_message_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_twitch_message_id_unique ON messages(twitch_message_id);
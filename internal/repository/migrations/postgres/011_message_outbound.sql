@@ -0,0 +1,7 @@
+-- Migration 011: Outbound messages for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Flag messages the archiver's own account sent from the web UI.
+-- They are stored when sent, since the sending connection gets no echo;
+-- echoes read by other connections are skipped (see services.SentEchoes)
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS outbound BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,23 @@
+-- Migration 012: Whispers for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Archive whispers to the authenticated account, kept apart from
+-- channel messages and public search
+
+CREATE TABLE IF NOT EXISTS whispers (
+    id BIGSERIAL PRIMARY KEY,
+    account TEXT NOT NULL,
+    counterpart TEXT NOT NULL,
+    counterpart_display_name TEXT,
+    counterpart_user_id TEXT,
+    thread_id TEXT,
+    twitch_message_id TEXT,
+    text TEXT NOT NULL,
+    tags JSONB,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_whispers_counterpart ON whispers(counterpart, received_at);
+CREATE INDEX IF NOT EXISTS idx_whispers_received_at ON whispers(received_at);
+
+-- Every connection on the account receives the whisper; store it once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_whispers_thread_message ON whispers(thread_id, twitch_message_id);
@@ -0,0 +1,18 @@
+-- Migration 013: Dead letters for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Keep messages the database refused on their own, with the error,
+-- so operators can inspect them and store them again
+
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    channel_name TEXT NOT NULL,
+    username TEXT NOT NULL,
+    text TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_first_failed_at ON dead_letters(first_failed_at);
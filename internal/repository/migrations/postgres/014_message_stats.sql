@@ -0,0 +1,9 @@
+-- Migration 014: Per-batch message stats for PostgreSQL
+-- Created: 2026-10-15
+-- Purpose: Message repositories now add stored messages to channel and
+-- user counters once per batch, so the per-row triggers go
+
+DROP TRIGGER IF EXISTS trigger_update_channel_stats ON messages;
+DROP TRIGGER IF EXISTS trigger_update_user_stats ON messages;
+DROP FUNCTION IF EXISTS update_channel_stats();
+DROP FUNCTION IF EXISTS update_user_stats();
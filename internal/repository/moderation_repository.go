@@ -0,0 +1,263 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Moderation actions stored in moderation_events.action.
+const (
+	ModerationActionTimeout = "timeout"
+	ModerationActionBan     = "ban"
+	ModerationActionClear   = "clear"
+	ModerationActionDelete  = "delete"
+)
+
+// ModerationEvent represents a CLEARCHAT/CLEARMSG event in the database.
+type ModerationEvent struct {
+	ID              int64
+	ChannelID       int64
+	Action          string
+	UserID          *int64 // Resolved from TargetUsername if the user is known
+	TargetUsername  string
+	TargetMessageID string
+	DurationSeconds int
+	Text            string
+	Tags            map[string]string
+	OccurredAt      time.Time
+	ChannelName     string // Joined from channels table
+	AffectedCount   int64  // Messages marked deleted by this event (not persisted)
+}
+
+// ModerationRepository provides operations for moderation events.
+type ModerationRepository struct {
+	db Database
+}
+
+// NewModerationRepository creates a new moderation repository.
+func NewModerationRepository(db Database) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+// Record stores a moderation event and soft-deletes the messages it affects
+// in a single transaction:
+//
+//   - delete: the message whose twitch_message_id matches TargetMessageID
+//   - timeout/ban: the target user's messages in the channel sent at or after since
+//   - clear: all messages in the channel sent at or after since
+//
+// Messages that are already deleted keep their original moderation event.
+func (r *ModerationRepository) Record(ctx context.Context, evt *ModerationEvent, since time.Time) error {
+	var tagsJSON sql.NullString
+	if len(evt.Tags) > 0 {
+		data, err := json.Marshal(evt.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		tagsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var duration sql.NullInt64
+	if evt.DurationSeconds > 0 {
+		duration = sql.NullInt64{Int64: int64(evt.DurationSeconds), Valid: true}
+	}
+
+	p := r.db.Placeholder
+
+	return r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Resolve the target user if we've seen them before, preferring the
+		// stable Twitch user-id over the login
+		evt.UserID = nil
+		if evt.TargetUsername != "" || evt.Tags["target-user-id"] != "" {
+			var userID int64
+			err := tx.QueryRowContext(ctx, `
+				SELECT id FROM users
+				WHERE twitch_user_id = `+p(1)+` OR username = `+p(2)+`
+				ORDER BY CASE WHEN twitch_user_id = `+p(3)+` THEN 0 ELSE 1 END
+				LIMIT 1`,
+				evt.Tags["target-user-id"], evt.TargetUsername, evt.Tags["target-user-id"]).Scan(&userID)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to resolve moderation target: %w", err)
+			}
+			if err == nil {
+				evt.UserID = &userID
+			}
+		}
+
+		var userID sql.NullInt64
+		if evt.UserID != nil {
+			userID = sql.NullInt64{Int64: *evt.UserID, Valid: true}
+		}
+
+		insert := fmt.Sprintf(`
+			INSERT INTO moderation_events
+				(channel_id, action, user_id, target_username, target_message_id,
+				 duration_seconds, text, tags, occurred_at)
+			VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)`,
+			p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9))
+		args := []any{
+			evt.ChannelID, evt.Action, userID, nullString(evt.TargetUsername), nullString(evt.TargetMessageID),
+			duration, nullString(evt.Text), tagsJSON, timeArg(r.db, evt.OccurredAt),
+		}
+
+		if r.db.SupportsReturning() {
+			if err := tx.QueryRowContext(ctx, insert+` RETURNING id`, args...).Scan(&evt.ID); err != nil {
+				return MapSQLError(fmt.Errorf("failed to create moderation event: %w", err))
+			}
+		} else {
+			result, err := tx.ExecContext(ctx, insert, args...)
+			if err != nil {
+				return MapSQLError(fmt.Errorf("failed to create moderation event: %w", err))
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get last insert id: %w", err)
+			}
+			evt.ID = id
+		}
+
+		var (
+			update     string
+			updateArgs []any
+		)
+		deletedAt := timeArg(r.db, evt.OccurredAt)
+		switch evt.Action {
+		case ModerationActionDelete:
+			if evt.TargetMessageID == "" {
+				return nil
+			}
+			update = fmt.Sprintf(`
+				UPDATE messages SET deleted_at = %s, moderation_event_id = %s
+				WHERE channel_id = %s AND twitch_message_id = %s AND deleted_at IS NULL`,
+				p(1), p(2), p(3), p(4))
+			updateArgs = []any{deletedAt, evt.ID, evt.ChannelID, evt.TargetMessageID}
+		case ModerationActionTimeout, ModerationActionBan:
+			if evt.UserID == nil {
+				return nil
+			}
+			update = fmt.Sprintf(`
+				UPDATE messages SET deleted_at = %s, moderation_event_id = %s
+				WHERE channel_id = %s AND user_id = %s AND sent_at >= %s AND deleted_at IS NULL`,
+				p(1), p(2), p(3), p(4), p(5))
+			updateArgs = []any{deletedAt, evt.ID, evt.ChannelID, *evt.UserID, timeArg(r.db, since)}
+		case ModerationActionClear:
+			update = fmt.Sprintf(`
+				UPDATE messages SET deleted_at = %s, moderation_event_id = %s
+				WHERE channel_id = %s AND sent_at >= %s AND deleted_at IS NULL`,
+				p(1), p(2), p(3), p(4))
+			updateArgs = []any{deletedAt, evt.ID, evt.ChannelID, timeArg(r.db, since)}
+		default:
+			return nil
+		}
+
+		result, err := tx.ExecContext(ctx, update, updateArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to mark messages deleted: %w", err)
+		}
+		evt.AffectedCount, _ = result.RowsAffected()
+		return nil
+	})
+}
+
+// ListByChannel returns the most recent moderation events for a channel.
+func (r *ModerationRepository) ListByChannel(ctx context.Context, channelID int64, limit int) ([]ModerationEvent, error) {
+	limit = clampModerationLimit(limit)
+
+	query := `
+		SELECT e.id, e.channel_id, e.action, e.user_id, e.target_username, e.target_message_id,
+		       e.duration_seconds, e.text, e.tags, e.occurred_at, c.name
+		FROM moderation_events e
+		JOIN channels c ON e.channel_id = c.id
+		WHERE e.channel_id = ` + r.db.Placeholder(1) + `
+		ORDER BY e.occurred_at DESC, e.id DESC
+		LIMIT ` + r.db.Placeholder(2)
+
+	rows, err := r.db.QueryContext(ctx, query, channelID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel moderation events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanModerationEvents(rows)
+}
+
+// ListByUsername returns the most recent moderation events targeting a user
+// across all channels, including events recorded under their previous names.
+func (r *ModerationRepository) ListByUsername(ctx context.Context, username string, limit int) ([]ModerationEvent, error) {
+	limit = clampModerationLimit(limit)
+
+	query := `
+		SELECT e.id, e.channel_id, e.action, e.user_id, e.target_username, e.target_message_id,
+		       e.duration_seconds, e.text, e.tags, e.occurred_at, c.name
+		FROM moderation_events e
+		JOIN channels c ON e.channel_id = c.id
+		WHERE e.target_username = ` + r.db.Placeholder(1) + `
+		   OR e.user_id = COALESCE(
+		       (SELECT id FROM users WHERE username = ` + r.db.Placeholder(2) + `),
+		       (SELECT user_id FROM user_name_history WHERE username = ` + r.db.Placeholder(3) + `
+		        ORDER BY changed_at DESC, id DESC LIMIT 1))
+		ORDER BY e.occurred_at DESC, e.id DESC
+		LIMIT ` + r.db.Placeholder(4)
+
+	rows, err := r.db.QueryContext(ctx, query, username, username, username, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user moderation events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanModerationEvents(rows)
+}
+
+func clampModerationLimit(limit int) int {
+	if limit <= 0 {
+		return 50
+	}
+	if limit > 200 {
+		return 200
+	}
+	return limit
+}
+
+func scanModerationEvents(rows *sql.Rows) ([]ModerationEvent, error) {
+	var events []ModerationEvent
+
+	for rows.Next() {
+		var evt ModerationEvent
+		var userID, duration sql.NullInt64
+		var targetUsername, targetMessageID, text, tagsJSON sql.NullString
+		var occurredAt any
+
+		err := rows.Scan(
+			&evt.ID, &evt.ChannelID, &evt.Action, &userID, &targetUsername, &targetMessageID,
+			&duration, &text, &tagsJSON, &occurredAt, &evt.ChannelName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan moderation event: %w", err)
+		}
+
+		if userID.Valid {
+			id := userID.Int64
+			evt.UserID = &id
+		}
+		evt.TargetUsername = targetUsername.String
+		evt.TargetMessageID = targetMessageID.String
+		evt.DurationSeconds = int(duration.Int64)
+		evt.Text = text.String
+		evt.OccurredAt = parseTimeValue(occurredAt)
+
+		if tagsJSON.Valid && tagsJSON.String != "" {
+			if err := json.Unmarshal([]byte(tagsJSON.String), &evt.Tags); err != nil {
+				// Non-fatal, just ignore tags
+				evt.Tags = nil
+			}
+		}
+
+		events = append(events, evt)
+	}
+
+	return events, rows.Err()
+}
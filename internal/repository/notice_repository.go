@@ -0,0 +1,197 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// UserNotice represents a USERNOTICE event (sub, resub, raid, ...) in the database.
+type UserNotice struct {
+	ID            int64
+	ChannelID     int64
+	UserID        *int64
+	Kind          string
+	Username      string
+	DisplayName   string
+	SystemMessage string
+	Text          string
+	Params        map[string]string
+	Tags          map[string]string
+	OccurredAt    time.Time
+	ChannelName   string // Joined from channels table
+}
+
+// NoticeRepository provides operations for user notices.
+type NoticeRepository struct {
+	db Database
+}
+
+// NewNoticeRepository creates a new notice repository.
+func NewNoticeRepository(db Database) *NoticeRepository {
+	return &NoticeRepository{db: db}
+}
+
+// Create inserts a new user notice.
+func (r *NoticeRepository) Create(ctx context.Context, n *UserNotice) error {
+	paramsJSON, err := marshalStringMap(n.Params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal params: %w", err)
+	}
+	tagsJSON, err := marshalStringMap(n.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	var userID sql.NullInt64
+	if n.UserID != nil {
+		userID = sql.NullInt64{Int64: *n.UserID, Valid: true}
+	}
+
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		INSERT INTO user_notices
+			(channel_id, user_id, kind, username, display_name, system_message, text, params, tags, occurred_at)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)`,
+		p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9), p(10))
+	args := []any{
+		n.ChannelID, userID, n.Kind, nullString(n.Username), nullString(n.DisplayName),
+		nullString(n.SystemMessage), nullString(n.Text), paramsJSON, tagsJSON, timeArg(r.db, n.OccurredAt),
+	}
+
+	if r.db.SupportsReturning() {
+		if err := r.db.QueryRowContext(ctx, query+` RETURNING id`, args...).Scan(&n.ID); err != nil {
+			return MapSQLError(fmt.Errorf("failed to create user notice: %w", err))
+		}
+		return nil
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return MapSQLError(fmt.Errorf("failed to create user notice: %w", err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	n.ID = id
+	return nil
+}
+
+// ListByChannel returns the most recent notices for a channel, optionally
+// filtered by kind (msg-id). An empty kind returns all notices.
+func (r *NoticeRepository) ListByChannel(ctx context.Context, channelID int64, kind string, limit int) ([]UserNotice, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	p := r.db.Placeholder
+	query := `
+		SELECT n.id, n.channel_id, n.user_id, n.kind, n.username, n.display_name,
+		       n.system_message, n.text, n.params, n.tags, n.occurred_at, c.name
+		FROM user_notices n
+		JOIN channels c ON n.channel_id = c.id
+		WHERE n.channel_id = ` + p(1)
+	args := []any{channelID}
+	if kind != "" {
+		query += ` AND n.kind = ` + p(2)
+		args = append(args, kind)
+	}
+	query += fmt.Sprintf(` ORDER BY n.occurred_at DESC, n.id DESC LIMIT %s`, p(len(args)+1))
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user notices: %w", err)
+	}
+	defer rows.Close()
+
+	var notices []UserNotice
+	for rows.Next() {
+		var n UserNotice
+		var userID sql.NullInt64
+		var username, displayName, systemMessage, text, paramsJSON, tagsJSON sql.NullString
+		var occurredAt any
+
+		err := rows.Scan(
+			&n.ID, &n.ChannelID, &userID, &n.Kind, &username, &displayName,
+			&systemMessage, &text, &paramsJSON, &tagsJSON, &occurredAt, &n.ChannelName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user notice: %w", err)
+		}
+
+		if userID.Valid {
+			id := userID.Int64
+			n.UserID = &id
+		}
+		n.Username = username.String
+		n.DisplayName = displayName.String
+		n.SystemMessage = systemMessage.String
+		n.Text = text.String
+		n.OccurredAt = parseTimeValue(occurredAt)
+		n.Params = unmarshalStringMap(paramsJSON)
+		n.Tags = unmarshalStringMap(tagsJSON)
+
+		notices = append(notices, n)
+	}
+
+	return notices, rows.Err()
+}
+
+// CountByKind returns the number of notices per kind for a channel.
+func (r *NoticeRepository) CountByKind(ctx context.Context, channelID int64) (map[string]int64, error) {
+	query := `
+		SELECT kind, COUNT(*)
+		FROM user_notices
+		WHERE channel_id = ` + r.db.Placeholder(1) + `
+		GROUP BY kind`
+
+	rows, err := r.db.QueryContext(ctx, query, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count user notices: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var kind string
+		var count int64
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan user notice count: %w", err)
+		}
+		counts[kind] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// marshalStringMap encodes m as JSON, returning NULL for empty maps.
+func marshalStringMap(m map[string]string) (sql.NullString, error) {
+	if len(m) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalStringMap decodes a JSON column, ignoring malformed values.
+func unmarshalStringMap(s sql.NullString) map[string]string {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s.String), &m); err != nil {
+		return nil
+	}
+	return m
+}
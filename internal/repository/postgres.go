@@ -4,7 +4,7 @@ package repository
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"fmt"
 	"sync"
 	"time"
@@ -12,8 +12,8 @@ import (
 	_ "github.com/lib/pq"
 )
 
-//go:embed migrations/postgres/001_init.sql
-var postgresInitSQL string
+//go:embed migrations/postgres/*.sql
+var postgresMigrationsFS embed.FS
 
 // PostgresDB wraps the PostgreSQL database connection with configuration and helpers.
 type PostgresDB struct {
@@ -63,8 +63,12 @@ func (db *PostgresDB) Migrate(ctx context.Context) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	_, err := db.ExecContext(ctx, postgresInitSQL)
+	migrations, err := loadMigrations(postgresMigrationsFS, "migrations/postgres")
 	if err != nil {
+		return err
+	}
+
+	if err := applyMigrations(ctx, db.DB, migrations, db.Placeholder); err != nil {
 		return fmt.Errorf("failed to run postgres migrations: %w", err)
 	}
 
@@ -220,10 +220,10 @@ func (r *ProfileRepository) UnlinkChannel(ctx context.Context, profileID, channe
 // ListLinkedChannels returns channels linked to a profile.
 func (r *ProfileRepository) ListLinkedChannels(ctx context.Context, profileID int64) ([]Channel, error) {
 	query := `
-		SELECT c.id, c.name, c.display_name, c.enabled, c.retain_history_on_delete,
-		       c.created_at, c.updated_at, c.last_message_at, c.total_messages
+		SELECT ` + channelColumns + `
 		FROM profile_channels pc
 		JOIN channels c ON pc.channel_id = c.id
+		LEFT JOIN channel_states s ON s.channel_id = c.id AND s.ended_at IS NULL
 		WHERE pc.profile_id = ` + r.db.Placeholder(1) + `
 		ORDER BY c.name ASC
 	`
@@ -234,24 +234,13 @@ func (r *ProfileRepository) ListLinkedChannels(ctx context.Context, profileID in
 	}
 	defer rows.Close()
 
-	// reuse scanChannels logic by duplicating minimal logic
 	var channels []Channel
 	for rows.Next() {
-		var ch Channel
-		var createdAt, updatedAt any
-		var lastMessageAt any
-		if err := rows.Scan(
-			&ch.ID, &ch.Name, &ch.DisplayName, &ch.Enabled, &ch.RetainHistoryOnDelete,
-			&createdAt, &updatedAt, &lastMessageAt, &ch.TotalMessages,
-		); err != nil {
+		ch, err := scanChannelRow(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan channel: %w", err)
 		}
-		ch.CreatedAt = parseTimeValue(createdAt)
-		ch.UpdatedAt = parseTimeValue(updatedAt)
-		if t := parseTimeValue(lastMessageAt); !t.IsZero() {
-			ch.LastMessageAt = &t
-		}
-		channels = append(channels, ch)
+		channels = append(channels, *ch)
 	}
 
 	return channels, rows.Err()
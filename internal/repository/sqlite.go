@@ -4,7 +4,7 @@ package repository
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"fmt"
 	"sync"
 	"time"
@@ -16,8 +16,17 @@ import (
 const (
 	// SQLiteDatetime is the format used by SQLite's datetime() function
 	SQLiteDatetime = "2006-01-02 15:04:05"
+
+	// SQLiteTimestamp is the format times are written in: RFC3339 in UTC
+	// with milliseconds, fixed width so the text sorts in time order.
+	SQLiteTimestamp = "2006-01-02T15:04:05.000Z07:00"
 )
 
+// FormatSQLiteTimestamp formats t as SQLiteTimestamp, in UTC.
+func FormatSQLiteTimestamp(t time.Time) string {
+	return t.UTC().Format(SQLiteTimestamp)
+}
+
 // ParseSQLiteDatetime parses a datetime string from SQLite.
 // It tries multiple formats since SQLite can store dates in various formats.
 func ParseSQLiteDatetime(s string) (time.Time, error) {
@@ -43,8 +52,8 @@ func ParseSQLiteDatetime(s string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse datetime: %s", s)
 }
 
-//go:embed migrations/001_init.sql
-var sqliteInitSQL string
+//go:embed migrations/*.sql
+var sqliteMigrationsFS embed.FS
 
 // SQLiteDB wraps the SQLite database connection with configuration and helpers.
 type SQLiteDB struct {
@@ -94,8 +103,12 @@ func (db *SQLiteDB) Migrate(ctx context.Context) error {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	_, err := db.ExecContext(ctx, sqliteInitSQL)
+	migrations, err := loadMigrations(sqliteMigrationsFS, "migrations")
 	if err != nil {
+		return err
+	}
+
+	if err := applyMigrations(ctx, db.DB, migrations, db.Placeholder); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
@@ -0,0 +1,190 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UserNameChange is a login/display name a user was seen with, starting at ChangedAt.
+type UserNameChange struct {
+	ID          int64
+	UserID      int64
+	Username    string
+	DisplayName string
+	ChangedAt   time.Time
+}
+
+// GetByTwitchID returns a user by their Twitch user-id.
+func (r *UserRepository) GetByTwitchID(ctx context.Context, twitchUserID string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE twitch_user_id = ` + r.db.Placeholder(1)
+
+	return r.scanUser(r.db.QueryRowContext(ctx, query, twitchUserID))
+}
+
+// GetOrCreateByTwitchID returns the user with the given Twitch user-id,
+// creating it if necessary. The user-id is the identity; the login and
+// display name are updated in place when they change and each change is
+// recorded in user_name_history.
+//
+// A user row without a user-id that already holds the login (created
+// before user-ids were tracked) is adopted. If the login is held by a
+// different user-id, that account has renamed away and its row gets a
+// placeholder login (name~user-id) until it is seen again.
+func (r *UserRepository) GetOrCreateByTwitchID(ctx context.Context, twitchUserID, username, displayName string) (*User, error) {
+	if twitchUserID == "" {
+		return r.GetOrCreate(ctx, username, displayName)
+	}
+
+	p := r.db.Placeholder
+	var user *User
+
+	err := r.db.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		user, err = r.scanUser(tx.QueryRowContext(ctx,
+			`SELECT `+userColumns+` FROM users WHERE twitch_user_id = `+p(1), twitchUserID))
+		if err != nil {
+			return err
+		}
+
+		if user == nil {
+			holder, err := r.scanUser(tx.QueryRowContext(ctx,
+				`SELECT `+userColumns+` FROM users WHERE username = `+p(1), username))
+			if err != nil {
+				return err
+			}
+
+			if holder == nil || holder.TwitchUserID != "" {
+				if holder != nil {
+					if err := r.releaseUsernameTx(ctx, tx, username, 0); err != nil {
+						return err
+					}
+				}
+				user = &User{TwitchUserID: twitchUserID, Username: username, DisplayName: displayName}
+				return r.createTx(ctx, tx, user)
+			}
+
+			// Adopt the legacy row for this login
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE users SET twitch_user_id = `+p(1)+` WHERE id = `+p(2), twitchUserID, holder.ID); err != nil {
+				return fmt.Errorf("failed to set twitch user id: %w", err)
+			}
+			user = holder
+			user.TwitchUserID = twitchUserID
+		}
+
+		renamed := username != "" && username != user.Username
+		displayChanged := displayName != "" && displayName != user.DisplayName
+		if !renamed && !displayChanged {
+			return nil
+		}
+
+		if renamed {
+			if err := r.releaseUsernameTx(ctx, tx, username, user.ID); err != nil {
+				return err
+			}
+			user.Username = username
+		}
+		if displayName != "" {
+			user.DisplayName = displayName
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users SET username = `+p(1)+`, display_name = `+p(2)+` WHERE id = `+p(3),
+			user.Username, nullString(user.DisplayName), user.ID); err != nil {
+			return fmt.Errorf("failed to update user name: %w", err)
+		}
+
+		return r.recordNameTx(ctx, tx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResolveUsername returns the user currently using username, or else the
+// user who most recently used it before renaming. It returns nil if the
+// name has never been seen.
+func (r *UserRepository) ResolveUsername(ctx context.Context, username string) (*User, error) {
+	user, err := r.GetByUsername(ctx, username)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	p := r.db.Placeholder
+	var userID int64
+	err = r.db.QueryRowContext(ctx, `
+		SELECT user_id FROM user_name_history
+		WHERE username = `+p(1)+`
+		ORDER BY changed_at DESC, id DESC
+		LIMIT 1`, username).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve username: %w", err)
+	}
+
+	return r.GetByID(ctx, userID)
+}
+
+// ListNameHistory returns the names a user has been seen with, newest first.
+func (r *UserRepository) ListNameHistory(ctx context.Context, userID int64) ([]UserNameChange, error) {
+	query := `
+		SELECT id, user_id, username, display_name, changed_at
+		FROM user_name_history
+		WHERE user_id = ` + r.db.Placeholder(1) + `
+		ORDER BY changed_at DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user name history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []UserNameChange
+	for rows.Next() {
+		var c UserNameChange
+		var displayName sql.NullString
+		var changedAt any
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Username, &displayName, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user name change: %w", err)
+		}
+		c.DisplayName = displayName.String
+		c.ChangedAt = parseTimeValue(changedAt)
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// recordNameTx appends the user's current login and display name to the history.
+func (r *UserRepository) recordNameTx(ctx context.Context, tx *sql.Tx, user *User) error {
+	p := r.db.Placeholder
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO user_name_history (user_id, username, display_name, changed_at)
+		VALUES (%s, %s, %s, %s)`, p(1), p(2), p(3), r.db.NowFunc()),
+		user.ID, user.Username, nullString(user.DisplayName))
+	if err != nil {
+		return fmt.Errorf("failed to record user name: %w", err)
+	}
+	return nil
+}
+
+// releaseUsernameTx frees a login held by another user (other than exceptID)
+// by giving that row a placeholder login.
+func (r *UserRepository) releaseUsernameTx(ctx context.Context, tx *sql.Tx, username string, exceptID int64) error {
+	p := r.db.Placeholder
+	_, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET username = username || '~' || COALESCE(twitch_user_id, CAST(id AS TEXT))
+		WHERE username = `+p(1)+` AND id <> `+p(2), username, exceptID)
+	if err != nil {
+		return fmt.Errorf("failed to release username: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Package repository provides database access for the Twitch Chat Archiver.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Whisper represents a whisper to the authenticated account in the database.
+// Whispers are private, so they are kept out of messages and search.
+type Whisper struct {
+	ID                     int64
+	Account                string // our login
+	Counterpart            string // login of the other side
+	CounterpartDisplayName string
+	CounterpartUserID      string
+	ThreadID               string
+	TwitchMessageID        string
+	Text                   string
+	Tags                   map[string]string
+	ReceivedAt             time.Time
+}
+
+// WhisperConversation summarizes the whispers exchanged with one counterpart.
+type WhisperConversation struct {
+	Counterpart            string
+	CounterpartDisplayName string
+	TotalWhispers          int64
+	LastText               string
+	LastAt                 time.Time
+}
+
+// WhisperRepository provides operations for whispers.
+type WhisperRepository struct {
+	db Database
+}
+
+// NewWhisperRepository creates a new whisper repository.
+func NewWhisperRepository(db Database) *WhisperRepository {
+	return &WhisperRepository{db: db}
+}
+
+// Create inserts a whisper. A whisper already stored, by thread and
+// message id, is skipped and left with ID 0.
+func (r *WhisperRepository) Create(ctx context.Context, w *Whisper) error {
+	tagsJSON, err := marshalStringMap(w.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		INSERT INTO whispers
+			(account, counterpart, counterpart_display_name, counterpart_user_id,
+			 thread_id, twitch_message_id, text, tags, received_at)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+		ON CONFLICT (thread_id, twitch_message_id) DO NOTHING`,
+		p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9))
+	args := []any{
+		w.Account, w.Counterpart, nullString(w.CounterpartDisplayName), nullString(w.CounterpartUserID),
+		nullString(w.ThreadID), nullString(w.TwitchMessageID), w.Text, tagsJSON, timeArg(r.db, w.ReceivedAt),
+	}
+
+	if r.db.SupportsReturning() {
+		err := r.db.QueryRowContext(ctx, query+` RETURNING id`, args...).Scan(&w.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.ID = 0
+			return nil
+		}
+		if err != nil {
+			return MapSQLError(fmt.Errorf("failed to create whisper: %w", err))
+		}
+		return nil
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return MapSQLError(fmt.Errorf("failed to create whisper: %w", err))
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		w.ID = 0
+		return nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	w.ID = id
+	return nil
+}
+
+// ListConversations returns one entry per counterpart, most recently
+// active first.
+func (r *WhisperRepository) ListConversations(ctx context.Context) ([]WhisperConversation, error) {
+	query := `
+		SELECT w.counterpart, w.counterpart_display_name, c.total, w.text, w.received_at
+		FROM whispers w
+		JOIN (
+			SELECT counterpart, COUNT(*) AS total, MAX(id) AS last_id
+			FROM whispers
+			GROUP BY counterpart
+		) c ON w.id = c.last_id
+		ORDER BY w.received_at DESC, w.id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query whisper conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var conversations []WhisperConversation
+	for rows.Next() {
+		var c WhisperConversation
+		var displayName sql.NullString
+		var lastAt any
+		if err := rows.Scan(&c.Counterpart, &displayName, &c.TotalWhispers, &c.LastText, &lastAt); err != nil {
+			return nil, fmt.Errorf("failed to scan whisper conversation: %w", err)
+		}
+		c.CounterpartDisplayName = displayName.String
+		c.LastAt = parseTimeValue(lastAt)
+		conversations = append(conversations, c)
+	}
+
+	return conversations, rows.Err()
+}
+
+// ListByCounterpart returns the most recent whispers with a counterpart,
+// oldest first.
+func (r *WhisperRepository) ListByCounterpart(ctx context.Context, counterpart string, limit int) ([]Whisper, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	p := r.db.Placeholder
+	query := fmt.Sprintf(`
+		SELECT id, account, counterpart, counterpart_display_name, counterpart_user_id,
+		       thread_id, twitch_message_id, text, tags, received_at
+		FROM whispers
+		WHERE counterpart = %s
+		ORDER BY received_at DESC, id DESC
+		LIMIT %s`, p(1), p(2))
+
+	rows, err := r.db.QueryContext(ctx, query, counterpart, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query whispers: %w", err)
+	}
+	defer rows.Close()
+
+	var whispers []Whisper
+	for rows.Next() {
+		var w Whisper
+		var displayName, userID, threadID, messageID, tagsJSON sql.NullString
+		var receivedAt any
+		err := rows.Scan(
+			&w.ID, &w.Account, &w.Counterpart, &displayName, &userID,
+			&threadID, &messageID, &w.Text, &tagsJSON, &receivedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan whisper: %w", err)
+		}
+		w.CounterpartDisplayName = displayName.String
+		w.CounterpartUserID = userID.String
+		w.ThreadID = threadID.String
+		w.TwitchMessageID = messageID.String
+		w.Tags = unmarshalStringMap(tagsJSON)
+		w.ReceivedAt = parseTimeValue(receivedAt)
+		whispers = append(whispers, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Newest were fetched first so the limit keeps the latest
+	for i, j := 0, len(whispers)-1; i < j; i, j = i+1, j-1 {
+		whispers[i], whispers[j] = whispers[j], whispers[i]
+	}
+	return whispers, nil
+}
+
+// DeleteOlderThan deletes whispers received before cutoff and returns how
+// many were deleted.
+func (r *WhisperRepository) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM whispers WHERE received_at < `+r.db.Placeholder(1),
+		timeArg(r.db, cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old whispers: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted whispers: %w", err)
+	}
+	return n, nil
+}
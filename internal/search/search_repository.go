@@ -44,6 +44,7 @@ type MessageSearchParams struct {
 	Query       string
 	ChannelName *string
 	Username    *string
+	Emote       *string // Emote ID or name; matches messages that use it
 	StartTime   *time.Time
 	EndTime     *time.Time
 	Page        int
@@ -87,10 +88,19 @@ type UserProfile struct {
 	ID            int64
 	Username      string
 	DisplayName   string
+	TwitchUserID  string
 	FirstSeenAt   time.Time
 	LastSeenAt    time.Time
 	TotalMessages int64
 	Channels      []UserChannelSummary
+	PreviousNames []UserNameChange
+}
+
+// UserNameChange is a name a user was previously known by.
+type UserNameChange struct {
+	Username    string
+	DisplayName string
+	ChangedAt   time.Time
 }
 
 // UserChannelSummary represents a channel in user profile.
@@ -306,14 +316,21 @@ func (r *SearchRepository) searchMessagesFTS(ctx context.Context, params Message
 		args = append(args, *params.Username)
 		argIndex++
 	}
+	if params.Emote != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM message_emotes e WHERE e.message_id = m.id AND (e.emote_id = %s OR e.emote_name = %s))",
+			r.ph(argIndex), r.ph(argIndex+1)))
+		args = append(args, *params.Emote, *params.Emote)
+		argIndex += 2
+	}
 	if params.StartTime != nil {
 		conditions = append(conditions, "m.sent_at >= "+r.ph(argIndex))
-		args = append(args, params.StartTime.Format(time.RFC3339))
+		args = append(args, repository.FormatSQLiteTimestamp(*params.StartTime))
 		argIndex++
 	}
 	if params.EndTime != nil {
 		conditions = append(conditions, "m.sent_at <= "+r.ph(argIndex))
-		args = append(args, params.EndTime.Format(time.RFC3339))
+		args = append(args, repository.FormatSQLiteTimestamp(*params.EndTime))
 		argIndex++
 	}
 
@@ -413,14 +430,21 @@ func (r *SearchRepository) searchMessagesLIKE(ctx context.Context, params Messag
 		args = append(args, *params.Username)
 		argIndex++
 	}
+	if params.Emote != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM message_emotes e WHERE e.message_id = m.id AND (e.emote_id = %s OR e.emote_name = %s))",
+			r.ph(argIndex), r.ph(argIndex+1)))
+		args = append(args, *params.Emote, *params.Emote)
+		argIndex += 2
+	}
 	if params.StartTime != nil {
 		conditions = append(conditions, "m.sent_at >= "+r.ph(argIndex))
-		args = append(args, params.StartTime.Format(time.RFC3339))
+		args = append(args, repository.FormatSQLiteTimestamp(*params.StartTime))
 		argIndex++
 	}
 	if params.EndTime != nil {
 		conditions = append(conditions, "m.sent_at <= "+r.ph(argIndex))
-		args = append(args, params.EndTime.Format(time.RFC3339))
+		args = append(args, repository.FormatSQLiteTimestamp(*params.EndTime))
 		argIndex++
 	}
 
@@ -503,7 +527,7 @@ func (r *SearchRepository) scanMessageResults(rows *sql.Rows, query string) ([]M
 func (r *SearchRepository) GetUserProfile(ctx context.Context, userID int64) (*UserProfile, error) {
 	// Get user basic info
 	userQuery := `
-		SELECT id, username, display_name, first_seen_at, last_seen_at, total_messages
+		SELECT id, username, display_name, first_seen_at, last_seen_at, total_messages, twitch_user_id
 		FROM users
 		WHERE id = ` + r.ph(1) + `
 	`
@@ -512,25 +536,37 @@ func (r *SearchRepository) GetUserProfile(ctx context.Context, userID int64) (*U
 }
 
 // GetUserProfileByUsername returns detailed user information by username.
+// A previous username resolves to the user who most recently held it.
 func (r *SearchRepository) GetUserProfileByUsername(ctx context.Context, username string) (*UserProfile, error) {
 	// Get user basic info
 	userQuery := `
-		SELECT id, username, display_name, first_seen_at, last_seen_at, total_messages
+		SELECT id, username, display_name, first_seen_at, last_seen_at, total_messages, twitch_user_id
 		FROM users
-		WHERE username = ` + r.ph(1) + `
+		WHERE id = ` + r.userIDForName(1) + `
 	`
 
-	return r.getUserProfile(ctx, userQuery, username)
+	return r.getUserProfile(ctx, userQuery, username, username)
+}
+
+// userIDForName returns a subquery resolving a username to a user ID: the
+// current holder of the name, else the user who most recently renamed away
+// from it. It consumes placeholders argIndex and argIndex+1, both bound to
+// the username.
+func (r *SearchRepository) userIDForName(argIndex int) string {
+	return `COALESCE(
+			(SELECT id FROM users WHERE username = ` + r.ph(argIndex) + `),
+			(SELECT user_id FROM user_name_history WHERE username = ` + r.ph(argIndex+1) + `
+			 ORDER BY changed_at DESC, id DESC LIMIT 1))`
 }
 
 // getUserProfile is a shared helper for fetching user profiles.
-func (r *SearchRepository) getUserProfile(ctx context.Context, userQuery string, arg any) (*UserProfile, error) {
+func (r *SearchRepository) getUserProfile(ctx context.Context, userQuery string, args ...any) (*UserProfile, error) {
 	var profile UserProfile
 	var firstSeen, lastSeen any
-	var displayName sql.NullString
+	var displayName, twitchUserID sql.NullString
 
-	err := r.db.QueryRowContext(ctx, userQuery, arg).Scan(
-		&profile.ID, &profile.Username, &displayName, &firstSeen, &lastSeen, &profile.TotalMessages,
+	err := r.db.QueryRowContext(ctx, userQuery, args...).Scan(
+		&profile.ID, &profile.Username, &displayName, &firstSeen, &lastSeen, &profile.TotalMessages, &twitchUserID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -544,6 +580,13 @@ func (r *SearchRepository) getUserProfile(ctx context.Context, userQuery string,
 	if displayName.Valid {
 		profile.DisplayName = displayName.String
 	}
+	profile.TwitchUserID = twitchUserID.String
+
+	previousNames, err := r.getPreviousNames(ctx, profile.ID, profile.Username)
+	if err != nil {
+		return nil, err
+	}
+	profile.PreviousNames = previousNames
 
 	// Get channel summaries using the profile.ID we just fetched
 	channelQuery := `
@@ -579,6 +622,45 @@ func (r *SearchRepository) getUserProfile(ctx context.Context, userQuery string,
 	return &profile, rows.Err()
 }
 
+// getPreviousNames returns the logins a user has used other than their
+// current one, each with the last time it was adopted, newest first.
+func (r *SearchRepository) getPreviousNames(ctx context.Context, userID int64, currentUsername string) ([]UserNameChange, error) {
+	query := `
+		SELECT username, display_name, changed_at
+		FROM user_name_history
+		WHERE user_id = ` + r.ph(1) + ` AND username <> ` + r.ph(2) + `
+		ORDER BY changed_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, currentUsername)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user name history: %w", err)
+	}
+	defer rows.Close()
+
+	var names []UserNameChange
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var n UserNameChange
+		var displayName sql.NullString
+		var changedAt any
+
+		if err := rows.Scan(&n.Username, &displayName, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user name change: %w", err)
+		}
+		if seen[n.Username] {
+			continue
+		}
+		seen[n.Username] = true
+
+		n.DisplayName = displayName.String
+		n.ChangedAt = parseTimeValue(changedAt)
+		names = append(names, n)
+	}
+
+	return names, rows.Err()
+}
+
 // GetUserMessages returns paginated messages for a user.
 func (r *SearchRepository) GetUserMessages(ctx context.Context, userID int64, channelID *int64, page, pageSize int) ([]MessageSearchResult, int, error) {
 	if page < 1 {
@@ -678,9 +760,9 @@ func (r *SearchRepository) GetUserMessagesByUsername(ctx context.Context, userna
 	var args []any
 	argIndex := 1
 
-	conditions = append(conditions, "u.username = "+r.ph(argIndex))
-	args = append(args, username)
-	argIndex++
+	conditions = append(conditions, "u.id = "+r.userIDForName(argIndex))
+	args = append(args, username, username)
+	argIndex += 2
 
 	if channelName != nil {
 		conditions = append(conditions, "c.name = "+r.ph(argIndex))
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/asabla/goknut/internal/irc"
 	"github.com/asabla/goknut/internal/observability"
 	"github.com/asabla/goknut/internal/repository"
 )
@@ -17,6 +18,7 @@ var (
 	ErrChannelNotFound      = errors.New("channel not found")
 	ErrChannelAlreadyExists = errors.New("channel already exists")
 	ErrInvalidChannelName   = errors.New("invalid channel name")
+	ErrChannelRoomMismatch  = errors.New("channels belong to different Twitch rooms")
 )
 
 // IRCController is the interface for IRC operations.
@@ -26,6 +28,12 @@ type IRCController interface {
 	IsConnected() bool
 }
 
+// JoinStatusReporter is implemented by IRC controllers that queue joins
+// and track each channel's join state, such as irc.Client and irc.Pool.
+type JoinStatusReporter interface {
+	JoinStatus(channel string) (irc.JoinStatus, bool)
+}
+
 // ChannelService manages channel lifecycle operations.
 type ChannelService struct {
 	repo    *repository.ChannelRepository
@@ -236,6 +244,63 @@ func (s *ChannelService) Delete(ctx context.Context, id int64, retainHistory boo
 	return nil
 }
 
+// ListRenames returns channels that share a Twitch room-id with a newer
+// channel, i.e. broadcaster renames that have not been merged yet.
+func (s *ChannelService) ListRenames(ctx context.Context) ([]repository.ChannelRename, error) {
+	return s.repo.ListRenames(ctx)
+}
+
+// Merge moves the history of channel fromID onto channel intoID and deletes
+// fromID, parting it first if it was joined. Channels with different known
+// room-ids are refused; a channel without a room-id (e.g. renamed before
+// room-ids were recorded) can be merged into any channel.
+func (s *ChannelService) Merge(ctx context.Context, fromID, intoID int64) (*repository.Channel, error) {
+	from, err := s.repo.GetByID(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	into, err := s.repo.GetByID(ctx, intoID)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || into == nil || fromID == intoID {
+		return nil, ErrChannelNotFound
+	}
+	if from.TwitchRoomID != "" && into.TwitchRoomID != "" && from.TwitchRoomID != into.TwitchRoomID {
+		return nil, ErrChannelRoomMismatch
+	}
+
+	if from.Enabled && s.irc != nil && s.irc.IsConnected() {
+		if err := s.irc.Part("#" + from.Name); err != nil {
+			s.logger.Error("failed to part channel before merge", "channel", from.Name, "error", err)
+		} else {
+			s.logger.IRC("left channel", "channel", from.Name)
+		}
+	}
+
+	if err := s.repo.Merge(ctx, fromID, intoID); err != nil {
+		return nil, err
+	}
+
+	merged, err := s.repo.GetByID(ctx, intoID)
+	if err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		return nil, ErrChannelNotFound
+	}
+
+	// Update cache
+	s.mu.Lock()
+	delete(s.channels, from.Name)
+	s.channels[merged.Name] = merged
+	s.mu.Unlock()
+
+	s.logger.Info("merged channel", "from", from.Name, "into", into.Name, "room_id", into.TwitchRoomID)
+
+	return merged, nil
+}
+
 // GetByName returns a channel by name from cache or database.
 func (s *ChannelService) GetByName(ctx context.Context, name string) (*repository.Channel, error) {
 	name = normalizeChannelName(name)
@@ -262,6 +327,16 @@ func (s *ChannelService) GetChannelID(ctx context.Context, name string) (int64,
 	return ch.ID, nil
 }
 
+// JoinStatus returns a channel's IRC join state. It returns false if the
+// channel hasn't been joined or the IRC controller doesn't track joins.
+func (s *ChannelService) JoinStatus(name string) (irc.JoinStatus, bool) {
+	reporter, ok := s.irc.(JoinStatusReporter)
+	if !ok {
+		return irc.JoinStatus{}, false
+	}
+	return reporter.JoinStatus("#" + normalizeChannelName(name))
+}
+
 func normalizeChannelName(name string) string {
 	name = strings.TrimSpace(strings.ToLower(name))
 	name = strings.TrimPrefix(name, "#")
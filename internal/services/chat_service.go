@@ -0,0 +1,164 @@
+// Package services provides business logic for the Twitch Chat Archiver.
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asabla/goknut/internal/observability"
+)
+
+// ErrChatUnavailable is returned when chat can't be sent: there is no IRC
+// connection, or it is anonymous and therefore read-only.
+var ErrChatUnavailable = errors.New("sending chat needs an authenticated Twitch connection")
+
+// ChatSender is the interface for sending chat, implemented by irc.Client
+// and irc.Pool.
+type ChatSender interface {
+	Say(channel, text string) error
+	Reply(channel, parentMsgID, text string) error
+	IsAnonymous() bool
+	Username() string
+}
+
+// SentMessage is a chat message sent from the web UI.
+type SentMessage struct {
+	Channel     string
+	Username    string
+	Text        string
+	ParentMsgID string // Twitch id of the message replied to; empty if not a reply
+	SentAt      time.Time
+}
+
+// ChatService sends chat messages as the archiver's own account.
+type ChatService struct {
+	sender ChatSender
+	onSent func(msg SentMessage)
+	logger *observability.Logger
+}
+
+// NewChatService creates a new chat service. onSent is called for every
+// message sent, so it can be archived: Twitch doesn't echo our own
+// messages back on the connection that sent them. Other connections do
+// see them; see SentEchoes.
+func NewChatService(sender ChatSender, onSent func(msg SentMessage), logger *observability.Logger) *ChatService {
+	return &ChatService{
+		sender: sender,
+		onSent: onSent,
+		logger: logger,
+	}
+}
+
+// CanSend reports whether chat can be sent at all.
+func (s *ChatService) CanSend() bool {
+	return s != nil && s.sender != nil && !s.sender.IsAnonymous()
+}
+
+// Send sends text to a channel, as a reply to parentMsgID if it is set.
+// Rate limit, validation and not-joined errors come from the irc package.
+func (s *ChatService) Send(ctx context.Context, channel, parentMsgID, text string) (*SentMessage, error) {
+	if !s.CanSend() {
+		return nil, ErrChatUnavailable
+	}
+
+	channel = normalizeChannelName(channel)
+	if channel == "" {
+		return nil, ErrInvalidChannelName
+	}
+
+	var err error
+	if parentMsgID != "" {
+		err = s.sender.Reply("#"+channel, parentMsgID, text)
+	} else {
+		err = s.sender.Say("#"+channel, text)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &SentMessage{
+		Channel:     channel,
+		Username:    s.sender.Username(),
+		Text:        text,
+		ParentMsgID: parentMsgID,
+		SentAt:      time.Now().UTC(),
+	}
+	if s.onSent != nil {
+		s.onSent(*msg)
+	}
+	s.logger.IRC("sent chat message",
+		"channel", channel,
+		"reply", parentMsgID != "",
+	)
+	return msg, nil
+}
+
+// echoWindow is how long a sent message waits for its echo.
+const echoWindow = 30 * time.Second
+
+// SentEchoes matches sent messages to their echoes: the same message read
+// back from chat by other connections on the account, e.g. ones joined for
+// redundancy. Sent messages are archived without a Twitch id, so the echo
+// would otherwise be archived a second time.
+type SentEchoes struct {
+	mu      sync.Mutex
+	pending []SentMessage
+	echoed  map[string]time.Time // Twitch ids of matched echoes, by when they were sent
+}
+
+// NewSentEchoes creates an empty SentEchoes.
+func NewSentEchoes() *SentEchoes {
+	return &SentEchoes{echoed: make(map[string]time.Time)}
+}
+
+// Sent records a sent message as awaiting its echo.
+func (e *SentEchoes) Sent(msg SentMessage) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prune(time.Now())
+	msg.Channel = normalizeChannelName(msg.Channel)
+	e.pending = append(e.pending, msg)
+}
+
+// IsEcho reports whether a chat message is the echo of a sent one. Each
+// sent message matches one echo, and further copies by the echo's id, so
+// the same text sent again from elsewhere on the account is still
+// archived.
+func (e *SentEchoes) IsEcho(channel, username, text, id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prune(time.Now())
+
+	if _, ok := e.echoed[id]; ok && id != "" {
+		return true
+	}
+	channel = normalizeChannelName(channel)
+	for i, msg := range e.pending {
+		if msg.Channel == channel && msg.Text == text && strings.EqualFold(msg.Username, username) {
+			e.pending = append(e.pending[:i], e.pending[i+1:]...)
+			if id != "" {
+				e.echoed[id] = msg.SentAt
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// prune forgets sent messages and echoes older than echoWindow. The
+// caller must hold e.mu.
+func (e *SentEchoes) prune(now time.Time) {
+	n := 0
+	for n < len(e.pending) && now.Sub(e.pending[n].SentAt) > echoWindow {
+		n++
+	}
+	e.pending = e.pending[n:]
+	for id, sentAt := range e.echoed {
+		if now.Sub(sentAt) > echoWindow {
+			delete(e.echoed, id)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package services provides business logic for the Twitch Chat Archiver.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// DeadLetterService errors
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrRedriveFailed      = errors.New("dead letter was refused again")
+)
+
+// DeadLetterRedriver stores a dead-lettered message again from its
+// payload, implemented by ingestion.Processor.
+type DeadLetterRedriver interface {
+	Redrive(ctx context.Context, payload string) error
+}
+
+// DeadLetterService lets operators inspect the messages the database
+// refused and store them again once the cause is fixed.
+type DeadLetterService struct {
+	repo     *repository.DeadLetterRepository
+	redriver DeadLetterRedriver
+	logger   *observability.Logger
+}
+
+// NewDeadLetterService creates a new dead letter service.
+func NewDeadLetterService(repo *repository.DeadLetterRepository, redriver DeadLetterRedriver, logger *observability.Logger) *DeadLetterService {
+	return &DeadLetterService{
+		repo:     repo,
+		redriver: redriver,
+		logger:   logger,
+	}
+}
+
+// List returns dead letters oldest first, starting after afterID.
+func (s *DeadLetterService) List(ctx context.Context, afterID int64, limit int) ([]repository.DeadLetter, error) {
+	return s.repo.List(ctx, afterID, limit)
+}
+
+// Count returns the number of dead letters.
+func (s *DeadLetterService) Count(ctx context.Context) (int64, error) {
+	return s.repo.Count(ctx)
+}
+
+// Redrive stores a dead letter again and deletes it. If it is refused
+// again the error is recorded on it and ErrRedriveFailed returned.
+func (s *DeadLetterService) Redrive(ctx context.Context, id int64) error {
+	dl, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if dl == nil {
+		return ErrDeadLetterNotFound
+	}
+	return s.redrive(ctx, dl)
+}
+
+// RedriveAll redrives every dead letter, oldest first, and returns how
+// many were stored and how many were refused again.
+func (s *DeadLetterService) RedriveAll(ctx context.Context) (stored, failed int, err error) {
+	var afterID int64
+	for {
+		deadLetters, err := s.repo.List(ctx, afterID, 100)
+		if err != nil {
+			return stored, failed, err
+		}
+		if len(deadLetters) == 0 {
+			return stored, failed, nil
+		}
+
+		for i := range deadLetters {
+			err := s.redrive(ctx, &deadLetters[i])
+			switch {
+			case err == nil:
+				stored++
+			case errors.Is(err, ErrRedriveFailed):
+				failed++
+			default:
+				return stored, failed, err
+			}
+		}
+		afterID = deadLetters[len(deadLetters)-1].ID
+	}
+}
+
+func (s *DeadLetterService) redrive(ctx context.Context, dl *repository.DeadLetter) error {
+	if err := s.redriver.Redrive(ctx, dl.Payload); err != nil {
+		if recordErr := s.repo.RecordFailure(ctx, dl.ID, err.Error(), time.Now().UTC()); recordErr != nil {
+			return recordErr
+		}
+		s.logger.Warn("dead letter refused again", "id", dl.ID, "error", err)
+		return fmt.Errorf("%w: %w", ErrRedriveFailed, err)
+	}
+
+	if err := s.repo.Delete(ctx, dl.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	s.logger.Info("redrove dead letter", "id", dl.ID, "channel", dl.ChannelName)
+	return nil
+}
+
+// Discard deletes a dead letter without storing it.
+func (s *DeadLetterService) Discard(ctx context.Context, id int64) error {
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrDeadLetterNotFound
+	}
+	return err
+}
@@ -195,6 +195,7 @@ func (s *SearchService) SearchMessages(ctx context.Context, req dto.SearchMessag
 		Query:       req.Query,
 		ChannelName: req.ChannelName,
 		Username:    req.Username,
+		Emote:       req.Emote,
 		StartTime:   req.StartTime,
 		EndTime:     req.EndTime,
 		Page:        req.Page,
@@ -226,6 +227,7 @@ func (s *SearchService) SearchMessages(ctx context.Context, req dto.SearchMessag
 		"query", req.Query,
 		"channel", req.ChannelName,
 		"username", req.Username,
+		"emote", req.Emote,
 		"results", len(messages),
 		"total", totalCount,
 		"latency_ms", time.Since(start).Milliseconds(),
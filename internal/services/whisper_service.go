@@ -0,0 +1,78 @@
+// Package services provides business logic for the Twitch Chat Archiver.
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// WhisperService reads archived whispers and enforces their retention,
+// which is separate from that of channel messages.
+type WhisperService struct {
+	repo      *repository.WhisperRepository
+	retention time.Duration
+	logger    *observability.Logger
+}
+
+// NewWhisperService creates a new whisper service. Whispers older than
+// retention are purged by RunRetention; zero keeps them forever.
+func NewWhisperService(repo *repository.WhisperRepository, retention time.Duration, logger *observability.Logger) *WhisperService {
+	return &WhisperService{
+		repo:      repo,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Retention returns how long whispers are kept; zero means forever.
+func (s *WhisperService) Retention() time.Duration {
+	return s.retention
+}
+
+// Conversations returns one entry per counterpart, most recently active first.
+func (s *WhisperService) Conversations(ctx context.Context) ([]repository.WhisperConversation, error) {
+	return s.repo.ListConversations(ctx)
+}
+
+// Conversation returns the latest whispers with counterpart, oldest first.
+func (s *WhisperService) Conversation(ctx context.Context, counterpart string, limit int) ([]repository.Whisper, error) {
+	return s.repo.ListByCounterpart(ctx, strings.ToLower(strings.TrimSpace(counterpart)), limit)
+}
+
+// Purge deletes whispers older than the retention period.
+func (s *WhisperService) Purge(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	return s.repo.DeleteOlderThan(ctx, time.Now().UTC().Add(-s.retention))
+}
+
+// RunRetention purges expired whispers now and then every interval until
+// ctx is done. It returns at once if whispers are kept forever.
+func (s *WhisperService) RunRetention(ctx context.Context, interval time.Duration) {
+	if s.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.Purge(ctx)
+		if err != nil {
+			s.logger.Error("failed to purge whispers", "error", err)
+		} else if n > 0 {
+			s.logger.Info("purged expired whispers", "count", n, "retention", s.retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
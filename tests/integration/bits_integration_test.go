@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func TestBitsAggregatesAndLeaderboard(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "alice", Text: "Cheer100 nice",
+			Tags: map[string]string{"id": "m1", "bits": "100"}, SentAt: day, ReceivedAt: day},
+		{ChannelName: "#modchannel", Username: "alice", Text: "Cheer50 again",
+			Tags: map[string]string{"id": "m2", "bits": "50"}, SentAt: day.Add(time.Hour), ReceivedAt: day.Add(time.Hour)},
+		{ChannelName: "#modchannel", Username: "bob", Text: "Cheer500 big",
+			Tags: map[string]string{"id": "m3", "bits": "500"}, SentAt: day.Add(24 * time.Hour), ReceivedAt: day.Add(24 * time.Hour)},
+		{ChannelName: "#modchannel", Username: "carol", Text: "just chatting",
+			Tags: map[string]string{"id": "m4", "bits": "lots"}, SentAt: day, ReceivedAt: day},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	messages, err := repository.NewMessageRepository(db).GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	stored := map[string]int{}
+	for _, m := range messages {
+		stored[m.TwitchMessageID] = m.Bits
+	}
+	if stored["m1"] != 100 || stored["m3"] != 500 || stored["m4"] != 0 {
+		t.Fatalf("unexpected stored bits: %v", stored)
+	}
+
+	bitsRepo := repository.NewBitsRepository(db)
+	total, err := bitsRepo.Totals(ctx, repository.BitsFilter{ChannelID: &channel.ID})
+	if err != nil {
+		t.Fatalf("Totals failed: %v", err)
+	}
+	if total.Bits != 650 || total.Cheers != 3 || total.Users != 2 || !total.LastAt.Equal(day.Add(24*time.Hour)) {
+		t.Errorf("unexpected channel totals: %+v", total)
+	}
+
+	cheerers, err := bitsRepo.TopCheerers(ctx, repository.BitsFilter{ChannelID: &channel.ID})
+	if err != nil {
+		t.Fatalf("TopCheerers failed: %v", err)
+	}
+	if len(cheerers) != 2 || cheerers[0].Username != "bob" || cheerers[0].Bits != 500 ||
+		cheerers[1].Username != "alice" || cheerers[1].Bits != 150 || cheerers[1].Cheers != 2 {
+		t.Fatalf("unexpected leaderboard: %+v", cheerers)
+	}
+
+	days, err := bitsRepo.Daily(ctx, repository.BitsFilter{ChannelID: &channel.ID})
+	if err != nil {
+		t.Fatalf("Daily failed: %v", err)
+	}
+	if len(days) != 2 || !days[0].Day.Equal(time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)) || days[0].Bits != 500 || days[1].Bits != 150 {
+		t.Fatalf("unexpected daily bits: %+v", days)
+	}
+
+	alice, err := repository.NewUserRepository(db).GetByUsername(ctx, "alice")
+	if err != nil || alice == nil {
+		t.Fatalf("GetByUsername failed: user=%v err=%v", alice, err)
+	}
+	channels, err := bitsRepo.TopChannels(ctx, repository.BitsFilter{UserID: &alice.ID})
+	if err != nil {
+		t.Fatalf("TopChannels failed: %v", err)
+	}
+	if len(channels) != 1 || channels[0].ChannelName != "modchannel" || channels[0].Bits != 150 {
+		t.Fatalf("unexpected channels for alice: %+v", channels)
+	}
+
+	since := day.Add(12 * time.Hour)
+	total, err = bitsRepo.Totals(ctx, repository.BitsFilter{ChannelID: &channel.ID, Since: &since})
+	if err != nil {
+		t.Fatalf("Totals failed: %v", err)
+	}
+	if total.Bits != 500 {
+		t.Errorf("expected only the later cheer after since, got %d bits", total.Bits)
+	}
+
+	handler := handlers.NewBitsHandler(repository.NewChannelRepository(db), repository.NewUserRepository(db),
+		bitsRepo, nil, observability.NewLogger("test"))
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/channels/modchannel/bits?window=all", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, `"rank":1,"name":"bob"`) || !strings.Contains(body, `"bits":650`) {
+		t.Errorf("unexpected bits response: %s", body)
+	}
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/services"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+func TestChannelRenameDetectAndMerge(t *testing.T) {
+	ctx, db, processor, oldChannel := setupModerationTest(t)
+	channelRepo := repository.NewChannelRepository(db)
+
+	newChannel := &repository.Channel{Name: "newmodchannel", DisplayName: "NewModChannel", Enabled: true}
+	if err := channelRepo.Create(ctx, newChannel); err != nil {
+		t.Fatalf("failed to create channel: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "viewer", Text: "old name",
+			Tags: map[string]string{"id": "m1", "room-id": "42"}, ReceivedAt: now},
+		{ChannelName: "#modchannel", Username: "viewer", Text: "old name again",
+			Tags: map[string]string{"id": "m2", "room-id": "42"}, ReceivedAt: now.Add(time.Second)},
+		{ChannelName: "#newmodchannel", Username: "viewer", Text: "new name",
+			Tags: map[string]string{"id": "m3", "room-id": "42"}, ReceivedAt: now.Add(2 * time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+	if err := processor.StoreModerationEvent(ctx, ingestion.ModerationEvent{
+		ChannelName: "#modchannel", Action: "ban", TargetUsername: "troll", ReceivedAt: now,
+	}); err != nil {
+		t.Fatalf("StoreModerationEvent failed: %v", err)
+	}
+
+	stored, err := channelRepo.GetByName(ctx, "modchannel")
+	if err != nil || stored == nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+	if stored.TwitchRoomID != "42" {
+		t.Fatalf("expected room-id 42 to be recorded, got %q", stored.TwitchRoomID)
+	}
+
+	renames, err := channelRepo.ListRenames(ctx)
+	if err != nil {
+		t.Fatalf("ListRenames failed: %v", err)
+	}
+	if len(renames) != 1 || renames[0].From.ID != oldChannel.ID || renames[0].Into.ID != newChannel.ID {
+		t.Fatalf("expected modchannel -> newmodchannel rename, got %+v", renames)
+	}
+
+	irc := fakes.NewFakeIRCClient()
+	service := services.NewChannelService(channelRepo, irc, observability.NewLogger("test"), nil)
+	handler := handlers.NewChannelHandler(service, nil, observability.NewLogger("test"))
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/channels/modchannel/merge",
+		strings.NewReader(url.Values{"into": {"newmodchannel"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("merge request failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, `"total_messages":3`) {
+		t.Errorf("expected merged channel to report 3 messages, got %s", body)
+	}
+
+	gone, err := channelRepo.GetByID(ctx, oldChannel.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if gone != nil {
+		t.Error("expected old channel to be removed after merge")
+	}
+
+	messages, err := repository.NewMessageRepository(db).GetRecent(ctx, newChannel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Errorf("expected 3 messages on the merged channel, got %d", len(messages))
+	}
+
+	events, err := repository.NewModerationRepository(db).ListByChannel(ctx, newChannel.ID, 10)
+	if err != nil {
+		t.Fatalf("ListByChannel failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected moderation event to move with the merge, got %d", len(events))
+	}
+
+	renames, err = channelRepo.ListRenames(ctx)
+	if err != nil {
+		t.Fatalf("ListRenames failed: %v", err)
+	}
+	if len(renames) != 0 {
+		t.Errorf("expected no renames after merge, got %d", len(renames))
+	}
+}
+
+func TestChannelMergeRefusesDifferentRooms(t *testing.T) {
+	ctx, db, _, oldChannel := setupModerationTest(t)
+	channelRepo := repository.NewChannelRepository(db)
+
+	other := &repository.Channel{Name: "otherchannel", DisplayName: "OtherChannel", Enabled: true}
+	if err := channelRepo.Create(ctx, other); err != nil {
+		t.Fatalf("failed to create channel: %v", err)
+	}
+	if err := channelRepo.SetRoomID(ctx, oldChannel.ID, "1"); err != nil {
+		t.Fatalf("SetRoomID failed: %v", err)
+	}
+	if err := channelRepo.SetRoomID(ctx, other.ID, "2"); err != nil {
+		t.Fatalf("SetRoomID failed: %v", err)
+	}
+
+	service := services.NewChannelService(channelRepo, nil, observability.NewLogger("test"), nil)
+	if _, err := service.Merge(ctx, oldChannel.ID, other.ID); err != services.ErrChannelRoomMismatch {
+		t.Fatalf("expected ErrChannelRoomMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func TestChannelStateTimeline(t *testing.T) {
+	ctx, db, _, channel := setupModerationTest(t)
+	channelRepo := repository.NewChannelRepository(db)
+	stateRepo := repository.NewChannelStateRepository(db)
+
+	processor := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		channelRepo,
+		ingestion.ProcessorConfig{RoomStateRepo: stateRepo},
+	)
+
+	off, on := false, true
+	followersOff, slowOff, slow30 := -1, 0, 30
+	start := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+
+	updates := []ingestion.RoomState{
+		// Full state sent after JOIN
+		{ChannelName: "#modchannel", EmoteOnly: &off, FollowersOnlyMinutes: &followersOff, R9K: &off, SlowSeconds: &slowOff, SubsOnly: &off, ReceivedAt: start},
+		// Slow mode enabled
+		{ChannelName: "#modchannel", SlowSeconds: &slow30, ReceivedAt: start.Add(10 * time.Minute)},
+		// Repeated update without changes is ignored
+		{ChannelName: "#modchannel", SlowSeconds: &slow30, ReceivedAt: start.Add(15 * time.Minute)},
+		// Emote-only enabled, slow mode kept
+		{ChannelName: "#modchannel", EmoteOnly: &on, ReceivedAt: start.Add(20 * time.Minute)},
+		// Unknown channels are ignored
+		{ChannelName: "#unknown", EmoteOnly: &on, ReceivedAt: start},
+	}
+	for _, u := range updates {
+		if err := processor.StoreRoomState(ctx, u); err != nil {
+			t.Fatalf("StoreRoomState failed: %v", err)
+		}
+	}
+
+	states, err := stateRepo.ListByChannel(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("ListByChannel failed: %v", err)
+	}
+	if len(states) != 3 {
+		t.Fatalf("expected 3 state rows, got %d", len(states))
+	}
+
+	current := states[0]
+	if current.EndedAt != nil {
+		t.Errorf("expected newest state to be open, ended at %v", current.EndedAt)
+	}
+	if current.EmoteOnly == nil || !*current.EmoteOnly || current.SlowSeconds == nil || *current.SlowSeconds != 30 {
+		t.Errorf("unexpected current state: %+v", current)
+	}
+	if states[1].EndedAt == nil || !states[1].EndedAt.Equal(start.Add(20*time.Minute)) {
+		t.Errorf("expected slow mode state to end when emote-only started, got %v", states[1].EndedAt)
+	}
+
+	ch, err := channelRepo.GetByName(ctx, "modchannel")
+	if err != nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+	if ch.State == nil || ch.State.ID != current.ID {
+		t.Fatalf("expected channel to carry current state %d, got %+v", current.ID, ch.State)
+	}
+
+	channels, err := channelRepo.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(channels) != 1 || channels[0].State == nil {
+		t.Fatalf("expected listed channel to carry current state, got %+v", channels)
+	}
+}
+
+func TestChannelStateHandler(t *testing.T) {
+	ctx, db, _, channel := setupModerationTest(t)
+	stateRepo := repository.NewChannelStateRepository(db)
+
+	subsOnly, followers := true, 10
+	at := time.Now().UTC().Truncate(time.Second)
+	if _, _, err := stateRepo.Apply(ctx, channel.ID, repository.ChannelStateUpdate{SubsOnly: &subsOnly}, at.Add(-time.Minute)); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if _, _, err := stateRepo.Apply(ctx, channel.ID, repository.ChannelStateUpdate{FollowersOnlyMinutes: &followers}, at); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	templates := templateFromRepoFiles(t,
+		"internal/http/templates/channels/states.html",
+		"internal/http/templates/partials/error.html",
+	)
+	handler := handlers.NewChannelStateHandler(
+		repository.NewChannelRepository(db),
+		stateRepo,
+		templates,
+		observability.NewLogger("test"),
+	)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/channels/modchannel/states", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Current *dto.ChannelState  `json:"current"`
+		States  []dto.ChannelState `json:"states"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.States) != 2 {
+		t.Fatalf("expected 2 states, got %d", len(body.States))
+	}
+	if body.Current == nil || body.Current.FollowersOnlyMinutes == nil || *body.Current.FollowersOnlyMinutes != 10 {
+		t.Fatalf("unexpected current state: %+v", body.Current)
+	}
+
+	htmlResp, err := http.Get(srv.URL + "/channels/modchannel/states")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer htmlResp.Body.Close()
+	html := readBody(t, htmlResp)
+	if !strings.Contains(html, "followers-only 10m") || !strings.Contains(html, "subs-only") || !strings.Contains(html, "current") {
+		t.Fatalf("expected state timeline in fragment, got: %s", html)
+	}
+
+	notFound, err := http.Get(srv.URL + "/channels/missing/states")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer notFound.Body.Close()
+	if notFound.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", notFound.StatusCode)
+	}
+}
@@ -0,0 +1,284 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/irc"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/services"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+// newChatClient returns an authenticated client pointed at server, joined
+// to alpha.
+func newChatClient(t *testing.T, server *fakes.FakeIRCServer, limit irc.RateLimit) *irc.Client {
+	t.Helper()
+	client := irc.NewClient(irc.ClientConfig{
+		AuthMode:    irc.AuthModeAuthenticated,
+		Username:    "archiver",
+		OAuthToken:  "oauth:secret",
+		Server:      server.Addr(),
+		DisableTLS:  true,
+		SendLimiter: irc.NewRateLimiter(limit),
+	})
+	t.Cleanup(func() { client.Disconnect() })
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "alpha") == irc.JoinStateJoined }) {
+		t.Fatal("expected alpha to be joined")
+	}
+	return client
+}
+
+// sentLines returns the PRIVMSG lines the server received on conn.
+func sentLines(conn *fakes.FakeIRCServerConn) []string {
+	var lines []string
+	for _, line := range conn.Lines() {
+		if strings.Contains(line, "PRIVMSG ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestClientSaysAndReplies(t *testing.T) {
+	server := newFakeIRCServer(t)
+	client := newChatClient(t, server, irc.SendLimit)
+
+	if err := client.Say("Alpha", "hello chat"); err != nil {
+		t.Fatalf("Say failed: %v", err)
+	}
+	if err := client.Reply("alpha", "parent-1", "hello you"); err != nil {
+		t.Fatalf("Reply failed: %v", err)
+	}
+
+	conn := server.Conns()[0]
+	if !server.WaitFor(2*time.Second, func() bool { return len(sentLines(conn)) == 2 }) {
+		t.Fatalf("expected two messages, got %v", sentLines(conn))
+	}
+	want := []string{
+		"PRIVMSG #alpha :hello chat",
+		"@reply-parent-msg-id=parent-1 PRIVMSG #alpha :hello you",
+	}
+	for i, line := range sentLines(conn) {
+		if line != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], line)
+		}
+	}
+}
+
+func TestClientRefusesMessagesItCannotSend(t *testing.T) {
+	server := newFakeIRCServer(t)
+	client := newChatClient(t, server, irc.RateLimit{Count: 2, Per: time.Minute})
+
+	tests := []struct {
+		name string
+		send func() error
+		want error
+	}{
+		{"empty", func() error { return client.Say("alpha", "  ") }, irc.ErrInvalidMessage},
+		{"multi-line", func() error { return client.Say("alpha", "one\r\nPRIVMSG #beta :two") }, irc.ErrInvalidMessage},
+		{"too long", func() error { return client.Say("alpha", strings.Repeat("x", irc.MaxMessageLength+1)) }, irc.ErrInvalidMessage},
+		{"bad parent id", func() error { return client.Reply("alpha", "a b", "hi") }, irc.ErrInvalidMessage},
+		{"not joined", func() error { return client.Say("beta", "hi") }, irc.ErrNotJoined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.send(); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Refused messages don't use up the limit
+	for range 2 {
+		if err := client.Say("alpha", "hi"); err != nil {
+			t.Fatalf("Say failed: %v", err)
+		}
+	}
+	var rateErr *irc.RateLimitError
+	if err := client.Say("alpha", "one too many"); !errors.As(err, &rateErr) || rateErr.RetryAfter <= 0 {
+		t.Fatalf("expected a rate limit error with a retry delay, got %v", err)
+	}
+
+	conn := server.Conns()[0]
+	time.Sleep(50 * time.Millisecond)
+	if got := len(sentLines(conn)); got != 2 {
+		t.Errorf("expected only the 2 allowed messages to be sent, got %v", sentLines(conn))
+	}
+
+	// JOINs have a bucket of their own
+	if err := client.Join("beta"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "beta") == irc.JoinStateJoined }) {
+		t.Errorf("expected beta to be joined with the chat limit used up, got %q", joinState(client, "beta"))
+	}
+}
+
+func TestAnonymousClientIsReadOnly(t *testing.T) {
+	server := newFakeIRCServer(t)
+	client := newServerClient(t, server, irc.ClientConfig{})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	if err := client.Say("alpha", "hello"); !errors.Is(err, irc.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+// fakeChatSender records messages instead of sending them.
+type fakeChatSender struct {
+	anonymous bool
+	err       error
+	sent      []string
+}
+
+func (f *fakeChatSender) Say(channel, text string) error {
+	return f.Reply(channel, "", text)
+}
+
+func (f *fakeChatSender) Reply(channel, parentMsgID, text string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, channel+" "+parentMsgID+" "+text)
+	return nil
+}
+
+func (f *fakeChatSender) IsAnonymous() bool { return f.anonymous }
+func (f *fakeChatSender) Username() string  { return "archiver" }
+
+func newChatServer(t *testing.T, db *repository.SQLiteDB, sender services.ChatSender, onSent func(services.SentMessage)) *httptest.Server {
+	t.Helper()
+	templates := templateFromRepoFiles(t,
+		"internal/http/templates/live/compose_status.html",
+		"internal/http/templates/partials/error.html",
+	)
+	handler := handlers.NewChannelViewHandler(
+		repository.NewChannelRepository(db),
+		repository.NewMessageRepository(db),
+		services.NewChatService(sender, onSent, observability.NewLogger("test")),
+		templates,
+		observability.NewLogger("test"),
+		nil,
+	)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func postChat(t *testing.T, srv *httptest.Server, form url.Values, headers map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest("POST", srv.URL+"/channels/modchannel/messages", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestSendChatStoresOutboundMessage(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	sender := &fakeChatSender{}
+	srv := newChatServer(t, db, sender, func(m services.SentMessage) {
+		err := processor.StoreBatch(ctx, []ingestion.Message{{
+			ChannelName: m.Channel,
+			Username:    m.Username,
+			Text:        m.Text,
+			Tags:        map[string]string{"reply-parent-msg-id": m.ParentMsgID},
+			SentAt:      m.SentAt,
+			ReceivedAt:  m.SentAt,
+			Outbound:    true,
+		}})
+		if err != nil {
+			t.Errorf("StoreBatch failed: %v", err)
+		}
+	})
+
+	resp := postChat(t, srv, url.Values{"text": {"hello chat"}, "reply_to": {"parent-1"}},
+		map[string]string{"Accept": "application/json"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	var sent dto.SentMessage
+	if err := json.NewDecoder(resp.Body).Decode(&sent); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if sent.Channel != "modchannel" || sent.ReplyTo != "parent-1" || sent.Username != "archiver" {
+		t.Errorf("unexpected sent message: %+v", sent)
+	}
+	if len(sender.sent) != 1 || sender.sent[0] != "#modchannel parent-1 hello chat" {
+		t.Errorf("expected a reply to be sent, got %v", sender.sent)
+	}
+
+	messages, err := repository.NewMessageRepository(db).GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	if len(messages) != 1 || !messages[0].Outbound || messages[0].Text != "hello chat" {
+		t.Fatalf("expected the sent message stored as outbound, got %+v", messages)
+	}
+}
+
+func TestSendChatErrors(t *testing.T) {
+	_, db, _, _ := setupModerationTest(t)
+
+	tests := []struct {
+		name       string
+		sender     *fakeChatSender
+		text       string
+		wantStatus int
+	}{
+		{"anonymous", &fakeChatSender{anonymous: true}, "hi", http.StatusForbidden},
+		{"invalid", &fakeChatSender{err: irc.ErrInvalidMessage}, "", http.StatusBadRequest},
+		{"not joined", &fakeChatSender{err: irc.ErrNotJoined}, "hi", http.StatusConflict},
+		{"rate limited", &fakeChatSender{err: &irc.RateLimitError{RetryAfter: 1500 * time.Millisecond}}, "hi", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newChatServer(t, db, tt.sender, nil)
+			resp := postChat(t, srv, url.Values{"text": {tt.text}}, map[string]string{"Accept": "application/json"})
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && resp.Header.Get("Retry-After") != "2" {
+				t.Errorf("expected Retry-After 2, got %q", resp.Header.Get("Retry-After"))
+			}
+		})
+	}
+
+	// HTMX gets the error as a fragment for the compose box
+	srv := newChatServer(t, db, &fakeChatSender{anonymous: true}, nil)
+	resp := postChat(t, srv, url.Values{"text": {"hi"}}, map[string]string{"HX-Request": "true"})
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "authenticated Twitch connection") {
+		t.Errorf("expected an error fragment, got %d %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,330 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/services"
+)
+
+// setupDeadLetterTest returns a processor that dead-letters into db and a
+// trigger that makes the database refuse any message with text "poison".
+func setupDeadLetterTest(t *testing.T) (context.Context, *repository.SQLiteDB, *ingestion.Processor, *repository.DeadLetterRepository) {
+	t.Helper()
+	ctx, db, _, _ := setupModerationTest(t)
+
+	deadLetterRepo := repository.NewDeadLetterRepository(db)
+	processor := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewChannelRepository(db),
+		ingestion.ProcessorConfig{DeadLetterRepo: deadLetterRepo},
+	)
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE TRIGGER reject_poison BEFORE INSERT ON messages
+		WHEN NEW.text = 'poison'
+		BEGIN SELECT RAISE(ABORT, 'poison message'); END`); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	return ctx, db, processor, deadLetterRepo
+}
+
+func countMessages(t *testing.T, ctx context.Context, db *repository.SQLiteDB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages`).Scan(&n); err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	return n
+}
+
+func TestPipelineDeadLettersPoisonMessages(t *testing.T) {
+	ctx, db, processor, deadLetterRepo := setupDeadLetterTest(t)
+
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:         10,
+		FlushTimeout:      20 * time.Millisecond,
+		FlushRetries:      2,
+		FlushRetryBackoff: 10 * time.Millisecond,
+	}, processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	defer pipeline.Stop()
+
+	// One bad message in a batch of ten
+	now := time.Now().UTC()
+	for i := range 10 {
+		text := fmt.Sprintf("hello %d", i)
+		if i == 6 {
+			text = "poison"
+		}
+		pipeline.Ingest(ingestion.Message{ChannelName: "#modchannel", Username: "viewer", Text: text, ReceivedAt: now})
+	}
+
+	if !waitUntil(3*time.Second, func() bool { return countMessages(t, ctx, db) == 9 }) {
+		t.Fatalf("expected the 9 good messages stored, got %d", countMessages(t, ctx, db))
+	}
+	deadLetters, err := deadLetterRepo.List(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected one dead letter, got %+v", deadLetters)
+	}
+	dl := deadLetters[0]
+	if dl.Text != "poison" || dl.ChannelName != "modchannel" || dl.Username != "viewer" || dl.Attempts != 1 {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	if !strings.Contains(dl.Error, "poison message") {
+		t.Errorf("expected the database error kept, got %q", dl.Error)
+	}
+	var payload ingestion.Message
+	if err := json.Unmarshal([]byte(dl.Payload), &payload); err != nil || payload.ChannelName != "#modchannel" {
+		t.Errorf("expected the message as ingested in the payload, got %q (%v)", dl.Payload, err)
+	}
+}
+
+// flakyStore refuses the first failures batches, then stores them, and
+// dead-letters into a slice unless deadLetterErr is set.
+type flakyStore struct {
+	mu            sync.Mutex
+	failures      int
+	calls         int
+	stored        []ingestion.Message
+	deadLetters   []ingestion.Message
+	deadLetterErr error
+}
+
+func (s *flakyStore) StoreBatch(ctx context.Context, messages []ingestion.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("database is locked")
+	}
+	s.stored = append(s.stored, messages...)
+	return nil
+}
+
+func (s *flakyStore) StoreDeadLetter(ctx context.Context, msg ingestion.Message, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deadLetterErr != nil {
+		return s.deadLetterErr
+	}
+	s.deadLetters = append(s.deadLetters, msg)
+	return nil
+}
+
+func (s *flakyStore) counts() (calls, stored, deadLetters int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls, len(s.stored), len(s.deadLetters)
+}
+
+func TestPipelineRetriesRefusedBatches(t *testing.T) {
+	tests := []struct {
+		name            string
+		store           *flakyStore
+		retries         int
+		wantStored      int
+		wantDeadLetters int
+		wantDropped     int
+	}{
+		// A transient error clears before the retries run out
+		{"transient", &flakyStore{failures: 2}, 3, 8, 0, 0},
+		// Retries run out; splitting stores the halves once it clears
+		{"split", &flakyStore{failures: 4}, 3, 8, 0, 0},
+		// The database is down: dead-lettering fails too, so nothing is
+		// dead-lettered and the batch is dropped without a spool
+		{"outage", &flakyStore{failures: 1000, deadLetterErr: errors.New("connection refused")}, 1, 0, 0, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			dropped := 0
+			metrics := &fakeMetrics{onDropped: func(n int) { mu.Lock(); dropped += n; mu.Unlock() }}
+
+			pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+				BatchSize:         8,
+				FlushTimeout:      time.Hour,
+				FlushRetries:      tt.retries,
+				FlushRetryBackoff: time.Millisecond,
+				Metrics:           metrics,
+			}, tt.store)
+			if err := pipeline.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start pipeline: %v", err)
+			}
+			ingestNumbered(pipeline, 8)
+
+			if !waitUntil(2*time.Second, func() bool {
+				_, stored, deadLetters := tt.store.counts()
+				mu.Lock()
+				defer mu.Unlock()
+				return stored+deadLetters+dropped == 8
+			}) {
+				t.Fatal("expected the batch to be stored, dead-lettered or dropped")
+			}
+			pipeline.Stop()
+
+			calls, stored, deadLetters := tt.store.counts()
+			if stored != tt.wantStored || deadLetters != tt.wantDeadLetters || dropped != tt.wantDropped {
+				t.Errorf("expected %d stored, %d dead letters and %d dropped, got %d, %d and %d",
+					tt.wantStored, tt.wantDeadLetters, tt.wantDropped, stored, deadLetters, dropped)
+			}
+			// An outage is given up on after a few halvings, not one
+			// attempt per message
+			if tt.name == "outage" && calls > 2+4 {
+				t.Errorf("expected the outage detected within a few calls, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestDeadLetterAdminRedrives(t *testing.T) {
+	ctx, db, processor, deadLetterRepo := setupDeadLetterTest(t)
+
+	// Two messages the database refused
+	for _, text := range []string{"poison", "also fine later"} {
+		msg := ingestion.Message{ChannelName: "#modchannel", Username: "viewer", Text: text, ReceivedAt: time.Now().UTC()}
+		if err := processor.StoreDeadLetter(ctx, msg, errors.New("poison message")); err != nil {
+			t.Fatalf("StoreDeadLetter failed: %v", err)
+		}
+	}
+
+	logger := observability.NewLogger("test")
+	handler := handlers.NewDeadLetterHandler(
+		services.NewDeadLetterService(deadLetterRepo, processor, logger),
+		templateFromRepoFiles(t, "internal/http/templates/partials/error.html"),
+		logger,
+	)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	do := func(method, path string) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+path, nil)
+		req.Header.Set("Accept", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	list := func() []dto.DeadLetter {
+		var body struct {
+			DeadLetters []dto.DeadLetter `json:"dead_letters"`
+			Total       int64            `json:"total"`
+		}
+		if err := json.NewDecoder(do("GET", "/admin/dead-letters").Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode dead letters: %v", err)
+		}
+		if int(body.Total) != len(body.DeadLetters) {
+			t.Errorf("expected total %d, got %d", len(body.DeadLetters), body.Total)
+		}
+		return body.DeadLetters
+	}
+	result := func(resp *http.Response) (redriven, failed int) {
+		var body struct {
+			Redriven int `json:"redriven"`
+			Failed   int `json:"failed"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode redrive result: %v", err)
+		}
+		return body.Redriven, body.Failed
+	}
+
+	deadLetters := list()
+	if len(deadLetters) != 2 || deadLetters[0].Text != "poison" {
+		t.Fatalf("expected both dead letters oldest first, got %+v", deadLetters)
+	}
+	poisonID := deadLetters[0].ID
+
+	// Still refused: the attempt is recorded and the dead letter kept
+	if redriven, failed := result(do("POST", fmt.Sprintf("/admin/dead-letters/%d/redrive", poisonID))); redriven != 0 || failed != 1 {
+		t.Errorf("expected the redrive refused, got %d redriven and %d failed", redriven, failed)
+	}
+	if dl, _ := deadLetterRepo.GetByID(ctx, poisonID); dl == nil || dl.Attempts != 2 {
+		t.Errorf("expected a second attempt recorded, got %+v", dl)
+	}
+
+	// Redriving all stores what the database takes now
+	if redriven, failed := result(do("POST", "/admin/dead-letters/redrive")); redriven != 1 || failed != 1 {
+		t.Errorf("expected 1 redriven and 1 failed, got %d and %d", redriven, failed)
+	}
+	if n := countMessages(t, ctx, db); n != 1 {
+		t.Errorf("expected the redriven message stored, got %d messages", n)
+	}
+
+	// Once the cause is fixed the last one goes through
+	if _, err := db.ExecContext(ctx, `DROP TRIGGER reject_poison`); err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+	if redriven, _ := result(do("POST", fmt.Sprintf("/admin/dead-letters/%d/redrive", poisonID))); redriven != 1 {
+		t.Error("expected the dead letter redriven")
+	}
+	if n := countMessages(t, ctx, db); n != 2 {
+		t.Errorf("expected both messages stored, got %d", n)
+	}
+	if deadLetters := list(); len(deadLetters) != 0 {
+		t.Errorf("expected no dead letters left, got %+v", deadLetters)
+	}
+
+	if resp := do("POST", fmt.Sprintf("/admin/dead-letters/%d/redrive", poisonID)); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a redriven dead letter, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeadLetterAdminDiscards(t *testing.T) {
+	ctx, _, processor, deadLetterRepo := setupDeadLetterTest(t)
+	msg := ingestion.Message{ChannelName: "#modchannel", Username: "viewer", Text: "poison", ReceivedAt: time.Now().UTC()}
+	if err := processor.StoreDeadLetter(ctx, msg, errors.New("poison message")); err != nil {
+		t.Fatalf("StoreDeadLetter failed: %v", err)
+	}
+	deadLetters, _ := deadLetterRepo.List(ctx, 0, 10)
+
+	logger := observability.NewLogger("test")
+	handler := handlers.NewDeadLetterHandler(
+		services.NewDeadLetterService(deadLetterRepo, processor, logger),
+		templateFromRepoFiles(t, "internal/http/templates/partials/error.html"),
+		logger,
+	)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// A form post redirects back to the list
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Post(fmt.Sprintf("%s/admin/dead-letters/%d/delete", srv.URL, deadLetters[0].ID), "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/admin/dead-letters" {
+		t.Errorf("expected a redirect to the list, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if n, _ := deadLetterRepo.Count(ctx); n != 0 {
+		t.Errorf("expected the dead letter discarded, got %d left", n)
+	}
+}
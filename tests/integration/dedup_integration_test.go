@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func TestPipelineDropsDuplicateMessages(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	var duplicates atomic.Int64
+	cfg := ingestion.DefaultPipelineConfig()
+	cfg.Metrics = &fakeMetrics{onDuplicate: func(n int) { duplicates.Add(int64(n)) }}
+	pipeline := ingestion.NewPipeline(cfg, processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	defer pipeline.Stop()
+
+	// The same messages arrive from two connections; one has no id tag
+	now := time.Now().UTC()
+	for range 2 {
+		for _, id := range []string{"msg-a", "msg-b", ""} {
+			tags := map[string]string{}
+			if id != "" {
+				tags["id"] = id
+			}
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: "#modchannel",
+				Username:    "viewer",
+				Text:        "hello " + id,
+				Tags:        tags,
+				ReceivedAt:  now,
+			})
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for duplicates.Load() < 2 || pipeline.BufferLen() > 0 || pipeline.BatchLen() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 duplicates to be dropped, got %d", duplicates.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+
+	var stored int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages WHERE channel_id = ?`, channel.ID).Scan(&stored); err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if stored != 4 {
+		t.Errorf("expected 4 messages stored (2 with ids, 2 without), got %d", stored)
+	}
+	if got := duplicates.Load(); got != 2 {
+		t.Errorf("expected 2 duplicates, got %d", got)
+	}
+}
+
+func TestStoreBatchSkipsStoredMessageIDs(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	now := time.Now().UTC()
+	msg := func(id string) ingestion.Message {
+		return ingestion.Message{
+			ChannelName: "#modchannel",
+			Username:    "viewer",
+			Text:        "hello " + id,
+			Tags:        map[string]string{"id": id},
+			ReceivedAt:  now,
+		}
+	}
+	if err := processor.StoreBatch(ctx, []ingestion.Message{msg("first")}); err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	// A fresh processor stands in for a restart: nothing is remembered in
+	// memory, so only the unique index keeps the message out
+	restarted := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewChannelRepository(db),
+		ingestion.ProcessorConfig{},
+	)
+	var stored []ingestion.StoredMessage
+	restarted.SetOnBatchStored(func(messages []ingestion.StoredMessage) {
+		stored = append(stored, messages...)
+	})
+	if err := restarted.StoreBatch(ctx, []ingestion.Message{msg("first"), msg("second")}); err != nil {
+		t.Fatalf("StoreBatch after restart failed: %v", err)
+	}
+
+	if len(stored) != 1 || stored[0].Text != "hello second" || stored[0].ID == 0 {
+		t.Errorf("expected only the new message to be reported stored, got %+v", stored)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages WHERE channel_id = ?`, channel.ID).Scan(&count); err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 messages stored, got %d", count)
+	}
+
+	updated, err := repository.NewChannelRepository(db).GetByID(ctx, channel.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if updated.TotalMessages != 2 {
+		t.Errorf("expected skipped message not to be counted, got total_messages=%d", updated.TotalMessages)
+	}
+}
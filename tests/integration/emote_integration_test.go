@@ -0,0 +1,156 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/search"
+)
+
+func TestEmoteExtractionAndStats(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "alice", Text: "Kappa Kappa hello",
+			Tags: map[string]string{"id": "m1", "emotes": "25:0-4,6-10"}, ReceivedAt: now},
+		{ChannelName: "#modchannel", Username: "bob", Text: "héllo LUL Kappa",
+			Tags: map[string]string{"id": "m2", "emotes": "425618:6-8/25:10-14"}, ReceivedAt: now.Add(time.Second)},
+		{ChannelName: "#modchannel", Username: "alice", Text: "ancient LUL",
+			Tags: map[string]string{"id": "m3", "emotes": "425618:8-10"}, ReceivedAt: now.Add(-48 * time.Hour)},
+		{ChannelName: "#modchannel", Username: "bob", Text: "no emotes here",
+			Tags: map[string]string{"id": "m4"}, ReceivedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	messages, err := repository.NewMessageRepository(db).GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	var secondID int64
+	for _, m := range messages {
+		if m.TwitchMessageID == "m2" {
+			secondID = m.ID
+		}
+	}
+
+	emoteRepo := repository.NewEmoteRepository(db)
+	emotes, err := emoteRepo.ListByMessage(ctx, secondID)
+	if err != nil {
+		t.Fatalf("ListByMessage failed: %v", err)
+	}
+	if len(emotes) != 2 || emotes[0].Name != "LUL" || emotes[1].Name != "Kappa" || emotes[1].Start != 10 {
+		t.Fatalf("expected LUL then Kappa using rune offsets, got %+v", emotes)
+	}
+
+	stats, err := emoteRepo.TopEmotes(ctx, repository.EmoteStatsFilter{ChannelID: &channel.ID})
+	if err != nil {
+		t.Fatalf("TopEmotes failed: %v", err)
+	}
+	if len(stats) != 2 || stats[0].EmoteID != "25" || stats[0].Uses != 3 || stats[0].Messages != 2 || stats[0].Users != 2 {
+		t.Fatalf("expected Kappa first with 3 uses in 2 messages by 2 users, got %+v", stats)
+	}
+
+	since := now.Add(-24 * time.Hour)
+	stats, err = emoteRepo.TopEmotes(ctx, repository.EmoteStatsFilter{ChannelID: &channel.ID, Since: &since})
+	if err != nil {
+		t.Fatalf("TopEmotes failed: %v", err)
+	}
+	if len(stats) != 2 || stats[1].EmoteID != "425618" || stats[1].Uses != 1 {
+		t.Fatalf("expected the old LUL to fall outside the window, got %+v", stats)
+	}
+
+	alice, err := repository.NewUserRepository(db).GetByUsername(ctx, "alice")
+	if err != nil || alice == nil {
+		t.Fatalf("GetByUsername failed: user=%v err=%v", alice, err)
+	}
+	stats, err = emoteRepo.TopEmotes(ctx, repository.EmoteStatsFilter{UserID: &alice.ID})
+	if err != nil {
+		t.Fatalf("TopEmotes failed: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Uses != 2 || stats[1].Uses != 1 {
+		t.Fatalf("expected alice's Kappa x2 and LUL x1, got %+v", stats)
+	}
+
+	for _, fts := range []bool{true, false} {
+		emote := "LUL"
+		results, total, err := search.NewSearchRepository(db, fts).SearchMessages(ctx, search.MessageSearchParams{Emote: &emote})
+		if err != nil {
+			t.Fatalf("SearchMessages (fts=%v) failed: %v", fts, err)
+		}
+		if total != 2 || len(results) != 2 {
+			t.Errorf("expected 2 messages using LUL (fts=%v), got %d", fts, total)
+		}
+	}
+
+	handler := handlers.NewEmoteHandler(repository.NewChannelRepository(db), repository.NewUserRepository(db),
+		emoteRepo, nil, observability.NewLogger("test"))
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/channels/modchannel/emotes?window=24h", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(body, `"window":"24h"`) || !strings.Contains(body, `"emote_id":"25","name":"Kappa","uses":3`) {
+		t.Errorf("unexpected emote stats response: %s", body)
+	}
+}
+
+func TestMigrateBackfillsEmotesOfStoredMessages(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "alice", Text: "héllo Kappa Kappa",
+			Tags: map[string]string{"id": "m1", "emotes": "25:6-10,12-16"}, ReceivedAt: now},
+		{ChannelName: "#modchannel", Username: "bob", Text: "LUL",
+			Tags: map[string]string{"id": "m2", "emotes": "425618:0-2"}, ReceivedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	// As stored before message_emotes existed: m1 has only its emotes tag
+	_, err = db.ExecContext(ctx, `
+		DELETE FROM message_emotes
+		WHERE message_id = (SELECT id FROM messages WHERE twitch_message_id = 'm1')`)
+	if err != nil {
+		t.Fatalf("failed to clear emotes: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = '015_message_emotes_backfill'`); err != nil {
+		t.Fatalf("failed to forget the backfill: %v", err)
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	stats, err := repository.NewEmoteRepository(db).TopEmotes(ctx, repository.EmoteStatsFilter{ChannelID: &channel.ID})
+	if err != nil {
+		t.Fatalf("TopEmotes failed: %v", err)
+	}
+	if len(stats) != 2 || stats[0].EmoteID != "25" || stats[0].Name != "Kappa" || stats[0].Uses != 2 {
+		t.Fatalf("expected the backfilled Kappa twice, got %+v", stats)
+	}
+	if stats[1].EmoteID != "425618" || stats[1].Uses != 1 {
+		t.Errorf("expected LUL left as stored, got %+v", stats[1])
+	}
+}
@@ -18,6 +18,7 @@ type FakeIRCClient struct {
 	authMode  irc.AuthMode
 	channels  map[string]bool
 	messages  []irc.Message
+	joins     map[string]irc.JoinStatus
 
 	onMessage       irc.MessageHandler
 	onChannelChange irc.ChannelChangeHandler
@@ -34,6 +35,7 @@ func NewFakeIRCClient() *FakeIRCClient {
 	return &FakeIRCClient{
 		channels: make(map[string]bool),
 		messages: make([]irc.Message, 0),
+		joins:    make(map[string]irc.JoinStatus),
 		authMode: irc.AuthModeAuthenticated,
 	}
 }
@@ -43,6 +45,7 @@ func NewFakeAnonymousIRCClient() *FakeIRCClient {
 	return &FakeIRCClient{
 		channels:  make(map[string]bool),
 		messages:  make([]irc.Message, 0),
+		joins:     make(map[string]irc.JoinStatus),
 		authMode:  irc.AuthModeAnonymous,
 		anonymous: true,
 	}
@@ -141,6 +144,42 @@ func (f *FakeIRCClient) Channels() []string {
 	return channels
 }
 
+// JoinStatus returns a channel's join state: joined for joined channels
+// unless overridden with SetJoinStatus.
+func (f *FakeIRCClient) JoinStatus(channel string) (irc.JoinStatus, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if status, ok := f.joins[channel]; ok {
+		return status, true
+	}
+	if f.channels[channel] {
+		return irc.JoinStatus{Channel: channel, State: irc.JoinStateJoined}, true
+	}
+	return irc.JoinStatus{}, false
+}
+
+// SetJoinStatus overrides the join state reported for a channel.
+func (f *FakeIRCClient) SetJoinStatus(status irc.JoinStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.joins[status.Channel] = status
+}
+
+// JoinQueueDepth returns the number of channels overridden as pending.
+func (f *FakeIRCClient) JoinQueueDepth() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	depth := 0
+	for _, status := range f.joins {
+		if status.State == irc.JoinStatePending {
+			depth++
+		}
+	}
+	return depth
+}
+
 // IsAnonymous returns true if the client is using anonymous mode.
 func (f *FakeIRCClient) IsAnonymous() bool {
 	f.mu.RLock()
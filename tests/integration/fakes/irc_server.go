@@ -0,0 +1,323 @@
+package fakes
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeIRCServer is an in-process plain-text IRC server that speaks enough
+// of Twitch's dialect to drive the real irc.Client: it welcomes every
+// login, echoes JOINs and answers PINGs. Tests push chat lines with Send.
+type FakeIRCServer struct {
+	listener net.Listener
+
+	mu         sync.Mutex
+	conns      []*FakeIRCServerConn
+	logins     int
+	authFail   string
+	joinNotice map[string]string // channel -> NOTICE msg-id refusing the JOIN
+	silent     map[string]bool   // channels whose JOINs get no reply
+	holdJoins  bool
+	held       []func() // JOIN replies held back by HoldJoins
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// FakeIRCServerConn is one client connection to a FakeIRCServer.
+type FakeIRCServerConn struct {
+	conn net.Conn
+
+	mu       sync.Mutex
+	nick     string
+	channels map[string]bool
+	lines    []string // Every line received, in order
+	closed   bool
+}
+
+// NewFakeIRCServer starts a fake IRC server on a free localhost port.
+func NewFakeIRCServer() (*FakeIRCServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s := &FakeIRCServer{
+		listener:   listener,
+		joinNotice: make(map[string]string),
+		silent:     make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return s, nil
+}
+
+// Addr returns the server address as host:port.
+func (s *FakeIRCServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and closes every connection.
+func (s *FakeIRCServer) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	close(s.done)
+	for _, c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// RejectLogins makes the server answer logins with an auth failure NOTICE
+// carrying text, as Twitch does for a bad token.
+func (s *FakeIRCServer) RejectLogins(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authFail = text
+}
+
+// RefuseJoin makes JOINs of channel fail with a NOTICE carrying msgID,
+// e.g. "msg_channel_suspended".
+func (s *FakeIRCServer) RefuseJoin(channel, msgID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.joinNotice[normalizeChannel(channel)] = msgID
+}
+
+// IgnoreJoin makes JOINs of channel go unanswered, though the channel's
+// chat is still sent.
+func (s *FakeIRCServer) IgnoreJoin(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silent[normalizeChannel(channel)] = true
+}
+
+// HoldJoins holds back JOIN replies until it is called with false, when
+// the held replies are sent. Held channels already receive chat.
+func (s *FakeIRCServer) HoldJoins(hold bool) {
+	s.mu.Lock()
+	s.holdJoins = hold
+	held := s.held
+	if !hold {
+		s.held = nil
+	}
+	s.mu.Unlock()
+
+	if !hold {
+		for _, reply := range held {
+			reply()
+		}
+	}
+}
+
+// Logins returns how many logins the server has welcomed.
+func (s *FakeIRCServer) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Conns returns every connection accepted so far, oldest first.
+func (s *FakeIRCServer) Conns() []*FakeIRCServerConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*FakeIRCServerConn(nil), s.conns...)
+}
+
+// OpenConns returns the connections still open, oldest first.
+func (s *FakeIRCServer) OpenConns() []*FakeIRCServerConn {
+	var open []*FakeIRCServerConn
+	for _, c := range s.Conns() {
+		if !c.Closed() {
+			open = append(open, c)
+		}
+	}
+	return open
+}
+
+// Send writes a line to every open connection that has joined channel.
+// An empty channel sends to every open connection.
+func (s *FakeIRCServer) Send(channel, line string) {
+	if channel != "" {
+		channel = normalizeChannel(channel)
+	}
+	for _, c := range s.OpenConns() {
+		if channel == "" || c.Joined(channel) {
+			c.Send(line)
+		}
+	}
+}
+
+// WaitFor polls cond until it returns true or timeout passes.
+func (s *FakeIRCServer) WaitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func (s *FakeIRCServer) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &FakeIRCServerConn{conn: conn, channels: make(map[string]bool)}
+		s.mu.Lock()
+		s.conns = append(s.conns, c)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *FakeIRCServer) serve(c *FakeIRCServerConn) {
+	defer s.wg.Done()
+	defer c.Close()
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		c.record(line)
+
+		command, arg, _ := strings.Cut(line, " ")
+		switch command {
+		case "NICK":
+			c.mu.Lock()
+			c.nick = arg
+			c.mu.Unlock()
+
+			s.mu.Lock()
+			authFail := s.authFail
+			if authFail == "" {
+				s.logins++
+			}
+			s.mu.Unlock()
+
+			if authFail != "" {
+				c.Send(":tmi.twitch.tv NOTICE * :" + authFail)
+				return
+			}
+			c.Send(":tmi.twitch.tv 001 " + arg + " :Welcome, GLHF!")
+		case "PING":
+			c.Send(":tmi.twitch.tv PONG tmi.twitch.tv " + arg)
+		case "JOIN":
+			s.handleJoin(c, normalizeChannel(arg))
+		case "PART":
+			channel := normalizeChannel(arg)
+			c.mu.Lock()
+			delete(c.channels, channel)
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (s *FakeIRCServer) handleJoin(c *FakeIRCServerConn, channel string) {
+	s.mu.Lock()
+	msgID := s.joinNotice[channel]
+	silent := s.silent[channel]
+	s.mu.Unlock()
+
+	if msgID != "" {
+		c.Send(fmt.Sprintf("@msg-id=%s :tmi.twitch.tv NOTICE %s :You cannot join %s.", msgID, channel, channel))
+		return
+	}
+
+	c.mu.Lock()
+	c.channels[channel] = true
+	nick := c.nick
+	c.mu.Unlock()
+	if silent {
+		return
+	}
+
+	reply := func() { c.Send(fmt.Sprintf(":%s!%s@%s.tmi.twitch.tv JOIN %s", nick, nick, nick, channel)) }
+	s.mu.Lock()
+	if s.holdJoins {
+		s.held = append(s.held, reply)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	reply()
+}
+
+// Send writes a line to the connection.
+func (c *FakeIRCServerConn) Send(line string) {
+	c.conn.Write([]byte(line + "\r\n"))
+}
+
+// Close closes the connection from the server side.
+func (c *FakeIRCServerConn) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.conn.Close()
+}
+
+// Closed reports whether the connection was closed by either side.
+func (c *FakeIRCServerConn) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Nick returns the nick the client logged in with.
+func (c *FakeIRCServerConn) Nick() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nick
+}
+
+// Joined reports whether the connection has joined channel.
+func (c *FakeIRCServerConn) Joined(channel string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.channels[normalizeChannel(channel)]
+}
+
+// Lines returns every line the client sent.
+func (c *FakeIRCServerConn) Lines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+func (c *FakeIRCServerConn) record(line string) {
+	c.mu.Lock()
+	c.lines = append(c.lines, line)
+	c.mu.Unlock()
+}
+
+func normalizeChannel(channel string) string {
+	channel = strings.ToLower(strings.TrimSpace(channel))
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	return channel
+}
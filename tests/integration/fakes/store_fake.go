@@ -28,12 +28,16 @@ func NewFakeMessageStore() *FakeMessageStore {
 
 // StoreBatch stores a batch of messages.
 func (f *FakeMessageStore) StoreBatch(ctx context.Context, messages []ingestion.Message) error {
-	if f.storeLatency > 0 {
-		time.Sleep(f.storeLatency)
+	f.mu.RLock()
+	latency, storeErr := f.storeLatency, f.storeError
+	f.mu.RUnlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
 	}
 
-	if f.storeError != nil {
-		return f.storeError
+	if storeErr != nil {
+		return storeErr
 	}
 
 	f.mu.Lock()
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
 	"github.com/asabla/goknut/internal/observability"
 )
 
@@ -17,7 +18,7 @@ func TestHomeDashboardSummaryFragment(t *testing.T) {
 	templates := templateFromRepoFiles(t,
 		"internal/http/templates/dashboard/home_summary.html",
 	)
-	h := handlers.NewHomeDashboardHandler(templates, logger, nil, nil, nil, "", 0)
+	h := handlers.NewHomeDashboardHandler(templates, logger, nil, nil, nil, nil, "", 0)
 	h.RegisterRoutes(mux)
 
 	srv := httptest.NewServer(mux)
@@ -44,3 +45,75 @@ func TestHomeDashboardSummaryFragment(t *testing.T) {
 		t.Fatalf("expected KPI labels in summary fragment")
 	}
 }
+
+// fixedOverflowStats reports the same overflow stats every time.
+type fixedOverflowStats ingestion.OverflowStats
+
+func (f fixedOverflowStats) OverflowStats() ingestion.OverflowStats {
+	return ingestion.OverflowStats(f)
+}
+
+func TestHomeDashboardSummaryShowsOverflow(t *testing.T) {
+	logger := observability.NewLogger("test")
+
+	mux := http.NewServeMux()
+	templates := templateFromRepoFiles(t,
+		"internal/http/templates/dashboard/home_summary.html",
+	)
+	stats := fixedOverflowStats{
+		Policy: "priority",
+		Channels: []ingestion.ChannelOverflow{
+			{Channel: "spammy", Dropped: 1234},
+			{Channel: "otherchannel", Spooled: 56},
+		},
+	}
+	h := handlers.NewHomeDashboardHandler(templates, logger, nil, nil, nil, stats, "", 0)
+	h.RegisterRoutes(mux)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/dashboard/home/summary")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body := readBody(t, resp)
+	for _, want := range []string{
+		`data-testid="dashboard-overflow"`,
+		"priority",
+		"#spammy",
+		"#otherchannel",
+		"1.2K dropped, 56 spooled",
+		">1234<",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in summary fragment, got body: %s", want, body)
+		}
+	}
+}
+
+func TestHomeDashboardSummaryHidesOverflowWithoutPipeline(t *testing.T) {
+	logger := observability.NewLogger("test")
+
+	mux := http.NewServeMux()
+	templates := templateFromRepoFiles(t,
+		"internal/http/templates/dashboard/home_summary.html",
+	)
+	h := handlers.NewHomeDashboardHandler(templates, logger, nil, nil, nil, nil, "", 0)
+	h.RegisterRoutes(mux)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/dashboard/home/summary")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if body := readBody(t, resp); strings.Contains(body, "dashboard-overflow") {
+		t.Errorf("expected no overflow section without a pipeline, got body: %s", body)
+	}
+}
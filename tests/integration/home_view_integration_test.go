@@ -26,7 +26,7 @@ func TestHomeView_DoesNotIncludeLiveFeedOrHomeSSE(t *testing.T) {
 	})
 
 	// Register dashboard routes; not required for assertions but matches real behavior.
-	dashboard := handlers.NewHomeDashboardHandler(templates, logger, nil, nil, nil, "", 0)
+	dashboard := handlers.NewHomeDashboardHandler(templates, logger, nil, nil, nil, nil, "", 0)
 	dashboard.RegisterRoutes(mux)
 
 	testServer := httptest.NewServer(mux)
@@ -0,0 +1,386 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+// newFakeIRCServer starts a fake IRC server that is closed with the test.
+func newFakeIRCServer(t *testing.T) *fakes.FakeIRCServer {
+	t.Helper()
+	server, err := fakes.NewFakeIRCServer()
+	if err != nil {
+		t.Fatalf("failed to start fake IRC server: %v", err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newServerClient returns a real anonymous client pointed at server. It is
+// disconnected with the test.
+func newServerClient(t *testing.T, server *fakes.FakeIRCServer, cfg irc.ClientConfig) *irc.Client {
+	t.Helper()
+	cfg.AuthMode = irc.AuthModeAnonymous
+	cfg.Server = server.Addr()
+	cfg.DisableTLS = true
+	client := irc.NewClient(cfg)
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+// messageCollector gathers messages delivered to OnMessage.
+type messageCollector struct {
+	mu       sync.Mutex
+	messages []irc.Message
+}
+
+func (c *messageCollector) add(msg irc.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, msg)
+}
+
+func (c *messageCollector) texts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	texts := make([]string, len(c.messages))
+	for i, msg := range c.messages {
+		texts[i] = msg.Text
+	}
+	return texts
+}
+
+func privmsg(channel, id, text string) string {
+	return fmt.Sprintf("@display-name=Viewer;id=%s;tmi-sent-ts=1700000000000 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #%s :%s",
+		id, channel, text)
+}
+
+func joinState(client *irc.Client, channel string) irc.JoinState {
+	status, _ := client.JoinStatus(channel)
+	return status.State
+}
+
+func TestClientJoinsAndReceivesMessagesFromServer(t *testing.T) {
+	server := newFakeIRCServer(t)
+
+	var dials atomic.Int32
+	var joins []irc.JoinResult
+	var joinsMu sync.Mutex
+	received := &messageCollector{}
+	client := newServerClient(t, server, irc.ClientConfig{
+		OnMessage: received.add,
+		OnJoin: func(result irc.JoinResult) {
+			joinsMu.Lock()
+			joins = append(joins, result)
+			joinsMu.Unlock()
+		},
+		Dialer: irc.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials.Add(1)
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		}),
+	})
+
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if dials.Load() != 1 {
+		t.Errorf("expected the custom dialer to be used once, got %d", dials.Load())
+	}
+	if err := client.Join("Alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "alpha") == irc.JoinStateJoined }) {
+		t.Fatalf("expected alpha to be joined, got %q", joinState(client, "alpha"))
+	}
+
+	lines := server.Conns()[0].Lines()
+	if !strings.HasPrefix(lines[0], "CAP REQ") || !strings.HasPrefix(lines[1], "NICK justinfan") {
+		t.Errorf("expected CAP REQ then NICK, got %v", lines)
+	}
+
+	server.Send("alpha", privmsg("alpha", "m1", "hello"))
+	server.Send("alpha", ":tmi.twitch.tv PING :tmi.twitch.tv")
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) == 1 }) {
+		t.Fatalf("expected one message, got %v", received.texts())
+	}
+	if !server.WaitFor(2*time.Second, func() bool {
+		lines := server.Conns()[0].Lines()
+		return lines[len(lines)-1] == "PONG :tmi.twitch.tv"
+	}) {
+		t.Errorf("expected PING to be answered, got %v", server.Conns()[0].Lines())
+	}
+
+	joinsMu.Lock()
+	defer joinsMu.Unlock()
+	if len(joins) != 1 || joins[0].Err != nil || joins[0].Attempts != 1 {
+		t.Errorf("expected one successful join on the first attempt, got %+v", joins)
+	}
+}
+
+func TestClientJoinRefusedByServer(t *testing.T) {
+	server := newFakeIRCServer(t)
+	server.RefuseJoin("gone", "msg_channel_suspended")
+
+	client := newServerClient(t, server, irc.ClientConfig{})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if err := client.Join("gone"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "gone") == irc.JoinStateFailed }) {
+		t.Fatalf("expected join to fail, got %q", joinState(client, "gone"))
+	}
+	status, _ := client.JoinStatus("gone")
+	if !strings.HasPrefix(status.Error, "msg_channel_suspended") {
+		t.Errorf("expected the NOTICE msg-id as the join error, got %q", status.Error)
+	}
+}
+
+func TestClientStopsOnRejectedLogin(t *testing.T) {
+	server := newFakeIRCServer(t)
+	server.RejectLogins("Login authentication failed")
+
+	client := newServerClient(t, server, irc.ClientConfig{})
+	err := client.Connect(context.Background())
+	if !errors.Is(err, irc.ErrAuthFailed) {
+		t.Fatalf("expected ErrAuthFailed, got %v", err)
+	}
+	if !errors.Is(client.Connect(context.Background()), irc.ErrAuthFailed) {
+		t.Error("expected a rejected client to refuse to connect again")
+	}
+	if got := len(server.Conns()); got != 1 {
+		t.Errorf("expected no login retries, got %d connections", got)
+	}
+}
+
+func TestClientReconnectsOnRequest(t *testing.T) {
+	server := newFakeIRCServer(t)
+	client := newServerClient(t, server, irc.ClientConfig{})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "alpha") == irc.JoinStateJoined }) {
+		t.Fatal("expected alpha to be joined")
+	}
+
+	server.Send("", ":tmi.twitch.tv RECONNECT")
+
+	if !server.WaitFor(3*time.Second, func() bool {
+		open := server.OpenConns()
+		return server.Logins() == 2 && len(open) == 1 && open[0].Joined("alpha") &&
+			joinState(client, "alpha") == irc.JoinStateJoined
+	}) {
+		t.Fatalf("expected a new connection with alpha rejoined, got %d logins", server.Logins())
+	}
+	if !client.IsConnected() {
+		t.Error("expected client to be connected after reconnecting")
+	}
+}
+
+func TestClientMakeBeforeBreakDeliversEachMessageOnce(t *testing.T) {
+	server := newFakeIRCServer(t)
+	received := &messageCollector{}
+	client := newServerClient(t, server, irc.ClientConfig{
+		OnMessage:       received.add,
+		MakeBeforeBreak: true,
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "alpha") == irc.JoinStateJoined }) {
+		t.Fatal("expected alpha to be joined")
+	}
+
+	// Hold the standby's JOIN so both connections receive chat for a while
+	server.HoldJoins(true)
+	server.Send("", ":tmi.twitch.tv RECONNECT")
+	if !server.WaitFor(2*time.Second, func() bool {
+		open := server.OpenConns()
+		return len(open) == 2 && open[1].Joined("alpha")
+	}) {
+		t.Fatal("expected a standby connection joining alpha")
+	}
+
+	server.Send("alpha", privmsg("alpha", "m1", "during overlap"))
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) >= 1 }) {
+		t.Fatal("expected the overlapping message to be delivered")
+	}
+
+	server.HoldJoins(false)
+	if !server.WaitFor(2*time.Second, func() bool { return len(server.OpenConns()) == 1 }) {
+		t.Fatal("expected the old connection to be closed after the handover")
+	}
+	server.Send("alpha", privmsg("alpha", "m2", "after handover"))
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) >= 2 }) {
+		t.Fatalf("expected the message after the handover, got %v", received.texts())
+	}
+
+	// Give any stray duplicate time to arrive
+	time.Sleep(50 * time.Millisecond)
+	if got := fmt.Sprint(received.texts()); got != "[during overlap after handover]" {
+		t.Errorf("expected each message once, got %s", got)
+	}
+	if server.Logins() != 2 || !client.IsConnected() || joinState(client, "alpha") != irc.JoinStateJoined {
+		t.Errorf("expected the client to stay connected and joined, got %d logins, state %q",
+			server.Logins(), joinState(client, "alpha"))
+	}
+}
+
+func TestClientMakeBeforeBreakDedupsByMessageID(t *testing.T) {
+	server := newFakeIRCServer(t)
+	received := &messageCollector{}
+	client := newServerClient(t, server, irc.ClientConfig{
+		OnMessage:       received.add,
+		MakeBeforeBreak: true,
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "alpha") == irc.JoinStateJoined }) {
+		t.Fatal("expected alpha to be joined")
+	}
+
+	server.HoldJoins(true)
+	server.Send("", ":tmi.twitch.tv RECONNECT")
+	if !server.WaitFor(2*time.Second, func() bool {
+		open := server.OpenConns()
+		return len(open) == 2 && open[1].Joined("alpha")
+	}) {
+		t.Fatal("expected a standby connection joining alpha")
+	}
+	conns := server.OpenConns()
+
+	// The same id is one message, however the lines differ
+	conns[0].Send(privmsg("alpha", "m1", "first copy"))
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) == 1 }) {
+		t.Fatal("expected the first copy to be delivered")
+	}
+	conns[1].Send(privmsg("alpha", "m1", "second copy"))
+
+	// Different ids are different messages, however the text matches
+	server.Send("alpha", privmsg("alpha", "m2", "same text"))
+	server.Send("alpha", privmsg("alpha", "m3", "same text"))
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) >= 3 }) {
+		t.Fatalf("expected both messages with the same text, got %v", received.texts())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got := fmt.Sprint(received.texts()); got != "[first copy same text same text]" {
+		t.Errorf("expected each id delivered once, got %s", got)
+	}
+}
+
+func TestClientMakeBeforeBreakHandsOverChannelChanges(t *testing.T) {
+	server := newFakeIRCServer(t)
+	client := newServerClient(t, server, irc.ClientConfig{MakeBeforeBreak: true})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	for _, ch := range []string{"alpha", "beta"} {
+		if err := client.Join(ch); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+	}
+	if !server.WaitFor(2*time.Second, func() bool {
+		return joinState(client, "alpha") == irc.JoinStateJoined && joinState(client, "beta") == irc.JoinStateJoined
+	}) {
+		t.Fatal("expected alpha and beta to be joined")
+	}
+
+	server.HoldJoins(true)
+	server.Send("", ":tmi.twitch.tv RECONNECT")
+	if !server.WaitFor(2*time.Second, func() bool {
+		open := server.OpenConns()
+		return len(open) == 2 && open[1].Joined("alpha") && open[1].Joined("beta")
+	}) {
+		t.Fatal("expected a standby connection joining alpha and beta")
+	}
+	standby := server.OpenConns()[1]
+
+	// Changed while the standby is joining
+	if err := client.Part("beta"); err != nil {
+		t.Fatalf("Part failed: %v", err)
+	}
+	if err := client.Join("gamma"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	server.HoldJoins(false)
+	if !server.WaitFor(2*time.Second, func() bool {
+		return len(server.OpenConns()) == 1 && standby.Joined("gamma") && !standby.Joined("beta")
+	}) {
+		t.Fatalf("expected the standby to take over with alpha and gamma, got %v", standby.Lines())
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "gamma") == irc.JoinStateJoined }) {
+		t.Fatalf("expected gamma to be joined, got %q", joinState(client, "gamma"))
+	}
+	channels := client.Channels()
+	sort.Strings(channels)
+	if got := fmt.Sprint(channels); got != "[#alpha #gamma]" {
+		t.Errorf("expected alpha and gamma, got %s", got)
+	}
+	if state := joinState(client, "alpha"); state != irc.JoinStateJoined {
+		t.Errorf("expected alpha to stay joined, got %q", state)
+	}
+}
+
+func TestClientMakeBeforeBreakReplacesDroppedConnection(t *testing.T) {
+	server := newFakeIRCServer(t)
+	received := &messageCollector{}
+	client := newServerClient(t, server, irc.ClientConfig{
+		OnMessage:       received.add,
+		MakeBeforeBreak: true,
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if !server.WaitFor(2*time.Second, func() bool { return joinState(client, "alpha") == irc.JoinStateJoined }) {
+		t.Fatal("expected alpha to be joined")
+	}
+
+	// The reconnect backoff starts at a second; the standby does not wait
+	dropped := time.Now()
+	server.Conns()[0].Close()
+	if !server.WaitFor(2*time.Second, func() bool {
+		return client.IsConnected() && joinState(client, "alpha") == irc.JoinStateJoined && server.Logins() == 2
+	}) {
+		t.Fatal("expected a new connection to take over and join alpha")
+	}
+	if took := time.Since(dropped); took > 500*time.Millisecond {
+		t.Errorf("expected the standby to take over without the backoff, took %v", took)
+	}
+
+	server.Send("alpha", privmsg("alpha", "m1", "after drop"))
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) == 1 }) {
+		t.Fatal("expected the message on the new connection")
+	}
+	if open := server.OpenConns(); len(open) != 1 {
+		t.Errorf("expected one open connection, got %d", len(open))
+	}
+}
@@ -0,0 +1,299 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/irc"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/services"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+// newFakePool returns a pool of fake connections with the given cap and
+// the connections it has created so far.
+func newFakePool(capacity int) (*irc.Pool, *[]*fakes.FakeIRCClient) {
+	var conns []*fakes.FakeIRCClient
+	pool := irc.NewPool(irc.PoolConfig{
+		ChannelsPerConnection: capacity,
+		NewConn: func(irc.ClientConfig) irc.PoolConn {
+			c := fakes.NewFakeIRCClient()
+			conns = append(conns, c)
+			return c
+		},
+	})
+	return pool, &conns
+}
+
+func shardSizes(pool *irc.Pool) []int {
+	var sizes []int
+	for _, s := range pool.Shards() {
+		sizes = append(sizes, len(s.Channels))
+	}
+	return sizes
+}
+
+func TestPoolShardsChannelsAcrossConnections(t *testing.T) {
+	pool, conns := newFakePool(2)
+	if err := pool.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	for i := range 5 {
+		if err := pool.Join(fmt.Sprintf("chan%d", i)); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+	}
+	if err := pool.Join("#CHAN0"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	if got := fmt.Sprint(shardSizes(pool)); got != "[2 2 1]" {
+		t.Fatalf("expected channels spread as [2 2 1], got %s", got)
+	}
+	if len(*conns) != 3 {
+		t.Fatalf("expected 3 connections, got %d", len(*conns))
+	}
+	for i, c := range *conns {
+		if !c.IsConnected() {
+			t.Errorf("connection %d not connected", i)
+		}
+	}
+	if len(pool.Channels()) != 5 {
+		t.Errorf("expected 5 channels, got %v", pool.Channels())
+	}
+
+	// Freeing a slot lets the lone channel on the third connection move
+	if err := pool.Part("chan0"); err != nil {
+		t.Fatalf("Part failed: %v", err)
+	}
+	if got := fmt.Sprint(shardSizes(pool)); got != "[2 2]" {
+		t.Fatalf("expected rebalance to [2 2], got %s", got)
+	}
+	drained := (*conns)[2]
+	if drained.IsConnected() || len(drained.Channels()) != 0 {
+		t.Errorf("expected drained connection to be closed and empty, got %v", drained.Channels())
+	}
+
+	for _, ch := range []string{"chan1", "chan2", "chan3", "chan4"} {
+		if err := pool.Part(ch); err != nil {
+			t.Fatalf("Part failed: %v", err)
+		}
+	}
+	if got := fmt.Sprint(shardSizes(pool)); got != "[0]" {
+		t.Errorf("expected one idle connection to remain, got %s", got)
+	}
+	if !pool.IsConnected() {
+		t.Error("expected pool to stay connected with no channels")
+	}
+}
+
+func TestPoolJoinsBeforeConnect(t *testing.T) {
+	pool, conns := newFakePool(1)
+	for _, ch := range []string{"a", "b"} {
+		if err := pool.Join(ch); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+	}
+	if pool.IsConnected() {
+		t.Fatal("expected pool not to be connected before Connect")
+	}
+	for _, c := range *conns {
+		if len(c.Channels()) != 0 {
+			t.Fatal("expected no JOIN to be sent before Connect")
+		}
+	}
+
+	if err := pool.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if len(*conns) != 2 || len((*conns)[0].Channels()) != 1 || len((*conns)[1].Channels()) != 1 {
+		t.Fatalf("expected one channel joined on each of 2 connections")
+	}
+
+	if err := pool.Disconnect(); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+	if pool.IsConnected() {
+		t.Error("expected pool to be disconnected")
+	}
+}
+
+func TestPoolConnectFailsOnlyWhenNoConnectionOpens(t *testing.T) {
+	pool := irc.NewPool(irc.PoolConfig{
+		NewConn: func(irc.ClientConfig) irc.PoolConn {
+			c := fakes.NewFakeIRCClient()
+			c.ConnectError = errors.New("refused")
+			return c
+		},
+	})
+	if err := pool.Connect(context.Background()); err == nil {
+		t.Fatal("expected Connect to fail when no connection opens")
+	}
+}
+
+func TestChannelServiceWithPool(t *testing.T) {
+	ctx, db, _, _ := setupModerationTest(t)
+	channelRepo := repository.NewChannelRepository(db)
+	for i := range 3 {
+		ch := &repository.Channel{Name: fmt.Sprintf("pooled%d", i), DisplayName: "Pooled", Enabled: true}
+		if err := channelRepo.Create(ctx, ch); err != nil {
+			t.Fatalf("failed to create channel: %v", err)
+		}
+	}
+
+	pool, _ := newFakePool(2)
+	if err := pool.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	service := services.NewChannelService(channelRepo, pool, observability.NewLogger("test"), nil)
+	if err := service.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// modchannel from setup plus the three created above
+	if got := len(pool.Channels()); got != 4 {
+		t.Fatalf("expected 4 channels joined, got %v", pool.Channels())
+	}
+	if got := len(pool.Shards()); got != 2 {
+		t.Errorf("expected 2 connections for 4 channels at 2 per connection, got %d", got)
+	}
+}
+
+func TestChannelListReportsJoinState(t *testing.T) {
+	ctx, db, _, _ := setupModerationTest(t)
+	channelRepo := repository.NewChannelRepository(db)
+	for _, name := range []string{"waiting", "asking", "broken", "off"} {
+		ch := &repository.Channel{Name: name, DisplayName: name, Enabled: name != "off"}
+		if err := channelRepo.Create(ctx, ch); err != nil {
+			t.Fatalf("failed to create channel: %v", err)
+		}
+	}
+
+	pool, conns := newFakePool(10)
+	if err := pool.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	service := services.NewChannelService(channelRepo, pool, observability.NewLogger("test"), nil)
+	if err := service.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	conn := (*conns)[0]
+	conn.SetJoinStatus(irc.JoinStatus{Channel: "#waiting", State: irc.JoinStatePending})
+	conn.SetJoinStatus(irc.JoinStatus{Channel: "#asking", State: irc.JoinStateRequested, Attempts: 2})
+	conn.SetJoinStatus(irc.JoinStatus{Channel: "#broken", State: irc.JoinStateFailed, Attempts: 1,
+		Error: "msg_channel_suspended: This channel has been suspended."})
+	if got := pool.JoinQueueDepth(); got != 1 {
+		t.Errorf("expected join queue depth 1, got %d", got)
+	}
+
+	handler := handlers.NewChannelHandler(service, nil, observability.NewLogger("test"))
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/channels", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var body struct {
+		Channels []dto.Channel `json:"channels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+
+	states := map[string]string{}
+	for _, ch := range body.Channels {
+		states[ch.Name] = ch.JoinState
+		if ch.Name == "broken" && ch.JoinError != "msg_channel_suspended: This channel has been suspended." {
+			t.Errorf("expected join error for broken, got %q", ch.JoinError)
+		}
+		if ch.Name == "asking" && ch.JoinAttempts != 2 {
+			t.Errorf("expected 2 join attempts for asking, got %d", ch.JoinAttempts)
+		}
+	}
+	want := map[string]string{"modchannel": "joined", "waiting": "pending", "asking": "requested", "broken": "failed", "off": ""}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("expected join states %v, got %v", want, states)
+	}
+}
+
+func TestPoolJoinsRedundantChannelsTwice(t *testing.T) {
+	var conns []*fakes.FakeIRCClient
+	pool := irc.NewPool(irc.PoolConfig{
+		ChannelsPerConnection: 2,
+		RedundantChannels:     []string{"#Important"},
+		NewConn: func(irc.ClientConfig) irc.PoolConn {
+			c := fakes.NewFakeIRCClient()
+			conns = append(conns, c)
+			return c
+		},
+	})
+	if err := pool.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	for _, ch := range []string{"important", "other"} {
+		if err := pool.Join(ch); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+	}
+	if !pool.IsRedundant("#important") || pool.IsRedundant("other") {
+		t.Error("expected only important to be redundant")
+	}
+
+	holding := 0
+	for _, c := range conns {
+		for _, ch := range c.Channels() {
+			if ch == "#important" {
+				holding++
+			}
+		}
+	}
+	if holding != 2 {
+		t.Fatalf("expected important joined on 2 connections, got %d", holding)
+	}
+	if got := fmt.Sprint(shardSizes(pool)); got != "[2 1]" {
+		t.Fatalf("expected channels spread as [2 1], got %s", got)
+	}
+
+	// A redundant channel keeps its two connections apart
+	if err := pool.Part("other"); err != nil {
+		t.Fatalf("Part failed: %v", err)
+	}
+	if got := fmt.Sprint(shardSizes(pool)); got != "[1 1]" {
+		t.Fatalf("expected [1 1] after part, got %s", got)
+	}
+
+	status, ok := pool.JoinStatus("important")
+	if !ok || status.State != irc.JoinStateJoined {
+		t.Errorf("expected important joined, got %+v", status)
+	}
+
+	if err := pool.Part("important"); err != nil {
+		t.Fatalf("Part failed: %v", err)
+	}
+	if len(pool.Channels()) != 0 {
+		t.Errorf("expected no channels, got %v", pool.Channels())
+	}
+	for i, c := range conns {
+		if len(c.Channels()) != 0 {
+			t.Errorf("connection %d still has %v", i, c.Channels())
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/irc"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func TestRecordedTrafficReplaysIntoArchive(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+	server := newFakeIRCServer(t)
+
+	// Record a live session
+	dir := t.TempDir()
+	recorder, err := irc.NewRecorder(irc.RecorderConfig{
+		Dir:     dir,
+		OnError: func(err error) { t.Errorf("failed to record: %v", err) },
+	})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	received := &messageCollector{}
+	client := newServerClient(t, server, irc.ClientConfig{OnMessage: received.add, Recorder: recorder})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	server.Send("", privmsg("modchannel", "m1", "first"))
+	server.Send("", ":tmi.twitch.tv PING :tmi.twitch.tv")
+	server.Send("", privmsg("modchannel", "m2", "second"))
+	if !server.WaitFor(2*time.Second, func() bool { return len(received.texts()) == 2 }) {
+		t.Fatalf("expected two live messages, got %v", received.texts())
+	}
+	client.Disconnect()
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Replay it, at full speed, into the archive
+	pipeline := ingestion.NewPipeline(ingestion.DefaultPipelineConfig(), processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	rec, err := irc.OpenRecording(dir)
+	if err != nil {
+		t.Fatalf("OpenRecording failed: %v", err)
+	}
+	defer rec.Close()
+
+	replayer := irc.NewClient(irc.ClientConfig{
+		AuthMode: irc.AuthModeAnonymous,
+		OnMessage: func(msg irc.Message) {
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: msg.Channel,
+				Username:    msg.Username,
+				DisplayName: msg.DisplayName,
+				Text:        msg.Text,
+				Tags:        msg.Tags,
+				SentAt:      msg.SentAt,
+				ReceivedAt:  msg.ReceivedAt,
+			})
+		},
+	})
+	n, err := replayer.ReplayRecording(ctx, rec, irc.ReplayConfig{})
+	if err != nil {
+		t.Fatalf("ReplayRecording failed: %v", err)
+	}
+	// The welcome and the rest of the login are recorded too
+	if n < 4 {
+		t.Errorf("expected the whole session replayed, got %d lines", n)
+	}
+
+	messageRepo := repository.NewMessageRepository(db)
+	deadline := time.Now().Add(2 * time.Second)
+	var messages []repository.Message
+	for len(messages) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("replayed messages were not stored, got %+v", messages)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if messages, err = messageRepo.GetRecent(ctx, channel.ID, 10); err != nil {
+			t.Fatalf("GetRecent failed: %v", err)
+		}
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+
+	texts := map[string]bool{}
+	for _, m := range messages {
+		texts[m.Text] = true
+	}
+	if !texts["first"] || !texts["second"] {
+		t.Errorf("expected both recorded messages archived, got %+v", messages)
+	}
+}
+
+func TestReplayedModerationDeletesRecordedMessages(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+
+	// Recorded two days ago; one CLEARCHAT lacks tmi-sent-ts, so only the
+	// recorded receive time places it
+	dir := t.TempDir()
+	recorder, err := irc.NewRecorder(irc.RecorderConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	recordedAt := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Millisecond)
+	lines := []struct {
+		at   time.Time
+		line string
+	}{
+		{recordedAt, fmt.Sprintf("@id=m1;tmi-sent-ts=%d :spammer!spammer@spammer.tmi.twitch.tv PRIVMSG #modchannel :spam",
+			recordedAt.Add(-100*time.Millisecond).UnixMilli())},
+		{recordedAt, fmt.Sprintf("@id=m2;tmi-sent-ts=%d :rude!rude@rude.tmi.twitch.tv PRIVMSG #modchannel :rude",
+			recordedAt.Add(-100*time.Millisecond).UnixMilli())},
+		{recordedAt.Add(time.Second), fmt.Sprintf("@room-id=1;target-user-id=2;tmi-sent-ts=%d :tmi.twitch.tv CLEARCHAT #modchannel :spammer",
+			recordedAt.Add(900*time.Millisecond).UnixMilli())},
+		{recordedAt.Add(2 * time.Second), "@ban-duration=600;room-id=1;target-user-id=3 :tmi.twitch.tv CLEARCHAT #modchannel :rude"},
+	}
+	for _, l := range lines {
+		recorder.Record(l.at, l.line)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	pipeline := ingestion.NewPipeline(ingestion.DefaultPipelineConfig(), processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	rec, err := irc.OpenRecording(dir)
+	if err != nil {
+		t.Fatalf("OpenRecording failed: %v", err)
+	}
+	defer rec.Close()
+
+	replayer := irc.NewClient(irc.ClientConfig{
+		AuthMode: irc.AuthModeAnonymous,
+		OnMessage: func(msg irc.Message) {
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: msg.Channel,
+				Username:    msg.Username,
+				Text:        msg.Text,
+				Tags:        msg.Tags,
+				SentAt:      msg.SentAt,
+				ReceivedAt:  msg.ReceivedAt,
+			})
+		},
+		OnModeration: func(evt irc.ModerationEvent) {
+			pipeline.IngestModeration(ingestion.ModerationEvent{
+				ChannelName:    evt.Channel,
+				Action:         string(evt.Action),
+				TargetUsername: evt.TargetUsername,
+				Duration:       evt.Duration,
+				Tags:           evt.Tags,
+				SentAt:         evt.SentAt,
+				ReceivedAt:     evt.ReceivedAt,
+			})
+		},
+	})
+	if _, err := replayer.ReplayRecording(ctx, rec, irc.ReplayConfig{}); err != nil {
+		t.Fatalf("ReplayRecording failed: %v", err)
+	}
+
+	moderationRepo := repository.NewModerationRepository(db)
+	if !waitUntil(2*time.Second, func() bool {
+		events, err := moderationRepo.ListByChannel(ctx, channel.ID, 10)
+		return err == nil && len(events) == 2
+	}) {
+		t.Fatal("expected both replayed moderation events stored")
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+
+	messages, err := repository.NewMessageRepository(db).GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 replayed messages, got %+v", messages)
+	}
+	for _, msg := range messages {
+		if msg.DeletedAt == nil {
+			t.Errorf("expected %s deleted by its replayed CLEARCHAT", msg.TwitchMessageID)
+		}
+		if !msg.ReceivedAt.Equal(recordedAt) {
+			t.Errorf("%s: expected ReceivedAt %v as recorded, got %v", msg.TwitchMessageID, recordedAt, msg.ReceivedAt)
+		}
+	}
+
+	events, err := moderationRepo.ListByChannel(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("ListByChannel failed: %v", err)
+	}
+	occurred := map[string]time.Time{}
+	for _, evt := range events {
+		occurred[evt.TargetUsername] = evt.OccurredAt
+	}
+	if want := recordedAt.Add(2 * time.Second); !occurred["rude"].Equal(want) {
+		t.Errorf("expected the timeout without tmi-sent-ts at its recorded time %v, got %v", want, occurred["rude"])
+	}
+}
@@ -803,6 +803,7 @@ type fakeMetrics struct {
 	onBatchSize    func(int)
 	onBatchLatency func(time.Duration)
 	onDropped      func(int)
+	onDuplicate    func(int)
 }
 
 func (f *fakeMetrics) RecordBatchSize(size int) {
@@ -822,3 +823,9 @@ func (f *fakeMetrics) RecordDroppedMessages(count int) {
 		f.onDropped(count)
 	}
 }
+
+func (f *fakeMetrics) RecordDuplicateMessages(count int) {
+	if f.onDuplicate != nil {
+		f.onDuplicate(count)
+	}
+}
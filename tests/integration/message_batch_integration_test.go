@@ -0,0 +1,375 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/repository"
+)
+
+type messageBatchFixture struct {
+	ctx      context.Context
+	db       *repository.SQLiteDB
+	messages *repository.MessageRepository
+	channels *repository.ChannelRepository
+	users    *repository.UserRepository
+	channel  [2]*repository.Channel
+	user     [2]*repository.User
+}
+
+func setupMessageBatchTest(tb testing.TB) *messageBatchFixture {
+	tb.Helper()
+	ctx := context.Background()
+
+	db, err := repository.Open(repository.DBConfig{Path: ":memory:", EnableFTS: true})
+	if err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if err := db.Migrate(ctx); err != nil {
+		tb.Fatalf("failed to migrate database: %v", err)
+	}
+
+	f := &messageBatchFixture{
+		ctx:      ctx,
+		db:       db,
+		messages: repository.NewMessageRepository(db),
+		channels: repository.NewChannelRepository(db),
+		users:    repository.NewUserRepository(db),
+	}
+	for i, name := range []string{"batchone", "batchtwo"} {
+		channel := &repository.Channel{Name: name, DisplayName: name, Enabled: true}
+		if err := f.channels.Create(ctx, channel); err != nil {
+			tb.Fatalf("failed to create channel: %v", err)
+		}
+		f.channel[i] = channel
+	}
+	for i, name := range []string{"chatterone", "chattertwo"} {
+		user, err := f.users.GetOrCreate(ctx, name, name)
+		if err != nil {
+			tb.Fatalf("failed to create user: %v", err)
+		}
+		f.user[i] = user
+	}
+	return f
+}
+
+func (f *messageBatchFixture) channelStats(t *testing.T, i int) *repository.Channel {
+	t.Helper()
+	channel, err := f.channels.GetByID(f.ctx, f.channel[i].ID)
+	if err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	return channel
+}
+
+func (f *messageBatchFixture) userStats(t *testing.T, i int) *repository.User {
+	t.Helper()
+	user, err := f.users.GetByID(f.ctx, f.user[i].ID)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	return user
+}
+
+func TestCreateBatchUpdatesCountersPerBatch(t *testing.T) {
+	f := setupMessageBatchTest(t)
+	// Later than the users were created, which counts as seeing them
+	base := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	messages := []repository.Message{
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "one", SentAt: base},
+		{ChannelID: f.channel[0].ID, UserID: f.user[1].ID, Text: "two", SentAt: base.Add(2 * time.Minute)},
+		// Out of order: the latest time wins, not the last message
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "three", SentAt: base.Add(time.Minute)},
+		{ChannelID: f.channel[1].ID, UserID: f.user[0].ID, Text: "four", SentAt: base.Add(3 * time.Minute),
+			Emotes: []repository.MessageEmote{{EmoteID: "25", Name: "Kappa", Start: 0, End: 3}}},
+	}
+	if err := f.messages.CreateBatch(f.ctx, messages); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	for _, msg := range messages {
+		stored, err := f.messages.GetByID(f.ctx, msg.ID)
+		if err != nil {
+			t.Fatalf("failed to get message %d: %v", msg.ID, err)
+		}
+		if stored.Text != msg.Text {
+			t.Errorf("message %d: expected text %q, got %q", msg.ID, msg.Text, stored.Text)
+		}
+	}
+	emotes, err := repository.NewEmoteRepository(f.db).ListByMessage(f.ctx, messages[3].ID)
+	if err != nil {
+		t.Fatalf("failed to list emotes: %v", err)
+	}
+	if len(emotes) != 1 || emotes[0].Name != "Kappa" {
+		t.Errorf("expected the Kappa emote on the last message, got %+v", emotes)
+	}
+
+	for i, want := range []struct {
+		total int64
+		last  time.Time
+	}{
+		{3, base.Add(2 * time.Minute)},
+		{1, base.Add(3 * time.Minute)},
+	} {
+		channel := f.channelStats(t, i)
+		if channel.TotalMessages != want.total {
+			t.Errorf("channel %s: expected %d messages, got %d", channel.Name, want.total, channel.TotalMessages)
+		}
+		if channel.LastMessageAt == nil || !channel.LastMessageAt.Equal(want.last) {
+			t.Errorf("channel %s: expected last message at %v, got %v", channel.Name, want.last, channel.LastMessageAt)
+		}
+	}
+
+	for i, want := range []struct {
+		total int64
+		last  time.Time
+	}{
+		{3, base.Add(3 * time.Minute)},
+		{1, base.Add(2 * time.Minute)},
+	} {
+		user := f.userStats(t, i)
+		if user.TotalMessages != want.total {
+			t.Errorf("user %s: expected %d messages, got %d", user.Username, want.total, user.TotalMessages)
+		}
+		if !user.LastSeenAt.Equal(want.last) {
+			t.Errorf("user %s: expected last seen at %v, got %v", user.Username, want.last, user.LastSeenAt)
+		}
+	}
+}
+
+func TestCreateBatchSkipsStoredMessages(t *testing.T) {
+	f := setupMessageBatchTest(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	first := []repository.Message{
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "stored", SentAt: now, TwitchMessageID: "stored-id"},
+	}
+	if err := f.messages.CreateBatch(f.ctx, first); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	second := []repository.Message{
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "stored again", SentAt: now, TwitchMessageID: "stored-id"},
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "new", SentAt: now, TwitchMessageID: "new-id"},
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "new again", SentAt: now, TwitchMessageID: "new-id"},
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "no id", SentAt: now},
+	}
+	if err := f.messages.CreateBatch(f.ctx, second); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	for i, stored := range []bool{false, true, false, true} {
+		if got := second[i].ID != 0; got != stored {
+			t.Errorf("message %q: expected stored=%v, got ID %d", second[i].Text, stored, second[i].ID)
+		}
+	}
+
+	total, err := f.messages.GetTotalCount(f.ctx)
+	if err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 messages, got %d", total)
+	}
+	if got := f.channelStats(t, 0).TotalMessages; got != 3 {
+		t.Errorf("expected the channel counter to skip duplicates and read 3, got %d", got)
+	}
+	if got := f.userStats(t, 0).TotalMessages; got != 3 {
+		t.Errorf("expected the user counter to skip duplicates and read 3, got %d", got)
+	}
+}
+
+func TestCreateBatchConcurrentDuplicates(t *testing.T) {
+	f := setupMessageBatchTest(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// A spool replay and a live writer storing the same message at once
+	const rounds = 20
+	for round := range rounds {
+		batches := make([][]repository.Message, 2)
+		for i := range batches {
+			batches[i] = []repository.Message{
+				{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "shared", SentAt: now, TwitchMessageID: fmt.Sprintf("shared-%d", round)},
+				{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "own", SentAt: now, TwitchMessageID: fmt.Sprintf("own-%d-%d", round, i)},
+			}
+		}
+
+		start := make(chan struct{})
+		errs := make(chan error, len(batches))
+		var wg sync.WaitGroup
+		for i := range batches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs <- f.messages.CreateBatch(f.ctx, batches[i])
+			}()
+		}
+		close(start)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("round %d: CreateBatch failed: %v", round, err)
+			}
+		}
+
+		if stored := batches[0][0].ID != 0; stored == (batches[1][0].ID != 0) {
+			t.Errorf("round %d: expected exactly one batch to store the shared message, got IDs %d and %d",
+				round, batches[0][0].ID, batches[1][0].ID)
+		}
+		for i := range batches {
+			if batches[i][1].ID == 0 {
+				t.Errorf("round %d: expected batch %d to store its own message", round, i)
+			}
+		}
+	}
+
+	total, err := f.messages.GetTotalCount(f.ctx)
+	if err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if total != 3*rounds {
+		t.Errorf("expected %d messages, got %d", 3*rounds, total)
+	}
+	if got := f.channelStats(t, 0).TotalMessages; got != 3*rounds {
+		t.Errorf("expected the channel counter to read %d, got %d", 3*rounds, got)
+	}
+}
+
+func TestCreateBatchKeepsLatestTimes(t *testing.T) {
+	f := setupMessageBatchTest(t)
+	newer := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	older := newer.Add(-2 * time.Hour)
+
+	// A spool replay storing an older message after a newer one
+	for _, sentAt := range []time.Time{newer, older} {
+		batch := []repository.Message{
+			{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "message", SentAt: sentAt},
+		}
+		if err := f.messages.CreateBatch(f.ctx, batch); err != nil {
+			t.Fatalf("CreateBatch failed: %v", err)
+		}
+	}
+
+	channel := f.channelStats(t, 0)
+	if channel.TotalMessages != 2 {
+		t.Errorf("expected 2 messages for the channel, got %d", channel.TotalMessages)
+	}
+	if channel.LastMessageAt == nil || !channel.LastMessageAt.Equal(newer) {
+		t.Errorf("expected the channel's last message to stay at %v, got %v", newer, channel.LastMessageAt)
+	}
+	user := f.userStats(t, 0)
+	if user.TotalMessages != 2 {
+		t.Errorf("expected 2 messages for the user, got %d", user.TotalMessages)
+	}
+	if !user.LastSeenAt.Equal(newer) {
+		t.Errorf("expected the user's last seen time to stay at %v, got %v", newer, user.LastSeenAt)
+	}
+}
+
+func TestCreateBatchSpansChunks(t *testing.T) {
+	f := setupMessageBatchTest(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// More rows than one multi-row INSERT carries
+	messages := make([]repository.Message, 1234)
+	for i := range messages {
+		messages[i] = repository.Message{
+			ChannelID:       f.channel[i%2].ID,
+			UserID:          f.user[0].ID,
+			Text:            fmt.Sprintf("message %d", i),
+			SentAt:          now,
+			TwitchMessageID: fmt.Sprintf("chunk-%d", i),
+		}
+	}
+	if err := f.messages.CreateBatch(f.ctx, messages); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	for _, i := range []int{0, 499, 500, 1000, 1233} {
+		stored, err := f.messages.GetByID(f.ctx, messages[i].ID)
+		if err != nil {
+			t.Fatalf("failed to get message %d: %v", messages[i].ID, err)
+		}
+		if stored.Text != messages[i].Text {
+			t.Errorf("message %d: expected text %q, got %q", messages[i].ID, messages[i].Text, stored.Text)
+		}
+	}
+	if got := f.channelStats(t, 0).TotalMessages + f.channelStats(t, 1).TotalMessages; got != 1234 {
+		t.Errorf("expected channel counters to add up to 1234, got %d", got)
+	}
+	if got := f.userStats(t, 0).TotalMessages; got != 1234 {
+		t.Errorf("expected 1234 messages for the user, got %d", got)
+	}
+}
+
+func TestCreateUpdatesCounters(t *testing.T) {
+	f := setupMessageBatchTest(t)
+	sentAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	msg := &repository.Message{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "single", SentAt: sentAt}
+	if err := f.messages.Create(f.ctx, msg); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if msg.ID == 0 {
+		t.Fatal("expected the message to get an ID")
+	}
+
+	channel := f.channelStats(t, 0)
+	if channel.TotalMessages != 1 || channel.LastMessageAt == nil || !channel.LastMessageAt.Equal(sentAt) {
+		t.Errorf("expected 1 message at %v for the channel, got %d at %v", sentAt, channel.TotalMessages, channel.LastMessageAt)
+	}
+	user := f.userStats(t, 0)
+	if user.TotalMessages != 1 || !user.LastSeenAt.Equal(sentAt) {
+		t.Errorf("expected 1 message at %v for the user, got %d at %v", sentAt, user.TotalMessages, user.LastSeenAt)
+	}
+}
+
+// BenchmarkMessageInserts compares storing messages one by one with Create
+// against the bulk path of CreateBatch.
+func BenchmarkMessageInserts(b *testing.B) {
+	for _, size := range []int{100, 1000} {
+		for _, mode := range []string{"row", "batch"} {
+			b.Run(fmt.Sprintf("%s/%d", mode, size), func(b *testing.B) {
+				f := setupMessageBatchTest(b)
+				now := time.Now().UTC()
+				seq := 0
+
+				b.ResetTimer()
+				for range b.N {
+					messages := make([]repository.Message, size)
+					for i := range messages {
+						seq++
+						messages[i] = repository.Message{
+							ChannelID:       f.channel[i%2].ID,
+							UserID:          f.user[i%2].ID,
+							Text:            "benchmark message",
+							SentAt:          now,
+							TwitchMessageID: fmt.Sprintf("bench-%d", seq),
+						}
+					}
+
+					if mode == "batch" {
+						if err := f.messages.CreateBatch(f.ctx, messages); err != nil {
+							b.Fatalf("CreateBatch failed: %v", err)
+						}
+						continue
+					}
+					for i := range messages {
+						if err := f.messages.Create(f.ctx, &messages[i]); err != nil {
+							b.Fatalf("Create failed: %v", err)
+						}
+					}
+				}
+				b.ReportMetric(float64(b.N*size)/b.Elapsed().Seconds(), "msgs/s")
+			})
+		}
+	}
+}
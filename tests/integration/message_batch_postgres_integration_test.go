@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/repository"
+)
+
+// postgresTestDSNEnv names the variable holding the DSN of a Postgres
+// database to run the Postgres tests against. They are skipped without it.
+const postgresTestDSNEnv = "GOKNUT_TEST_POSTGRES_DSN"
+
+// setupPostgresBatchTest is setupMessageBatchTest on Postgres, in a schema
+// of its own that is dropped afterwards.
+func setupPostgresBatchTest(t *testing.T) *messageBatchFixture {
+	t.Helper()
+	dsn := os.Getenv(postgresTestDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresTestDSNEnv)
+	}
+	ctx := context.Background()
+
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open postgres database: %v", err)
+	}
+	// One connection, so the search_path set below holds for every query
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	schema := fmt.Sprintf("goknut_test_%d", time.Now().UnixNano())
+	if _, err := sqlDB.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+	if _, err := sqlDB.ExecContext(ctx, "SET search_path TO "+schema); err != nil {
+		t.Fatalf("failed to set search_path: %v", err)
+	}
+
+	db := &repository.PostgresDB{DB: sqlDB}
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	f := &messageBatchFixture{
+		ctx:      ctx,
+		messages: repository.NewMessageRepository(db),
+		channels: repository.NewChannelRepository(db),
+		users:    repository.NewUserRepository(db),
+	}
+	for i, name := range []string{"batchone", "batchtwo"} {
+		channel := &repository.Channel{Name: name, DisplayName: name, Enabled: true}
+		if err := f.channels.Create(ctx, channel); err != nil {
+			t.Fatalf("failed to create channel: %v", err)
+		}
+		f.channel[i] = channel
+	}
+	for i, name := range []string{"chatterone", "chattertwo"} {
+		user, err := f.users.GetOrCreate(ctx, name, name)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		f.user[i] = user
+	}
+	return f
+}
+
+func TestPostgresCreateBatchSkipsDuplicates(t *testing.T) {
+	f := setupPostgresBatchTest(t)
+	// Later than the users were created, which counts as seeing them
+	now := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+
+	first := []repository.Message{
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "stored", SentAt: now, TwitchMessageID: "stored-id"},
+	}
+	if err := f.messages.CreateBatch(f.ctx, first); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	second := []repository.Message{
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "stored again", SentAt: now, TwitchMessageID: "stored-id"},
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "new", SentAt: now, TwitchMessageID: "new-id"},
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "new again", SentAt: now, TwitchMessageID: "new-id"},
+		{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "no id", SentAt: now},
+	}
+	if err := f.messages.CreateBatch(f.ctx, second); err != nil {
+		t.Fatalf("CreateBatch failed: %v", err)
+	}
+
+	for i, stored := range []bool{false, true, false, true} {
+		if got := second[i].ID != 0; got != stored {
+			t.Errorf("message %q: expected stored=%v, got ID %d", second[i].Text, stored, second[i].ID)
+		}
+		if !stored {
+			continue
+		}
+		msg, err := f.messages.GetByID(f.ctx, second[i].ID)
+		if err != nil {
+			t.Fatalf("failed to get message %d: %v", second[i].ID, err)
+		}
+		if msg.Text != second[i].Text {
+			t.Errorf("message %d: expected text %q, got %q", second[i].ID, second[i].Text, msg.Text)
+		}
+	}
+
+	total, err := f.messages.GetTotalCount(f.ctx)
+	if err != nil {
+		t.Fatalf("failed to count messages: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 messages, got %d", total)
+	}
+	if got := f.channelStats(t, 0).TotalMessages; got != 3 {
+		t.Errorf("expected the channel counter to skip duplicates and read 3, got %d", got)
+	}
+	if got := f.userStats(t, 0).TotalMessages; got != 3 {
+		t.Errorf("expected the user counter to skip duplicates and read 3, got %d", got)
+	}
+}
+
+func TestPostgresCreateBatchKeepsLatestTimes(t *testing.T) {
+	f := setupPostgresBatchTest(t)
+	newer := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+	older := newer.Add(-2 * time.Hour)
+
+	// A spool replay storing an older message after a newer one
+	for _, sentAt := range []time.Time{newer, older} {
+		batch := []repository.Message{
+			{ChannelID: f.channel[0].ID, UserID: f.user[0].ID, Text: "message", SentAt: sentAt},
+		}
+		if err := f.messages.CreateBatch(f.ctx, batch); err != nil {
+			t.Fatalf("CreateBatch failed: %v", err)
+		}
+	}
+
+	channel := f.channelStats(t, 0)
+	if channel.TotalMessages != 2 {
+		t.Errorf("expected 2 messages for the channel, got %d", channel.TotalMessages)
+	}
+	if channel.LastMessageAt == nil || !channel.LastMessageAt.Equal(newer) {
+		t.Errorf("expected the channel's last message to stay at %v, got %v", newer, channel.LastMessageAt)
+	}
+	user := f.userStats(t, 0)
+	if user.TotalMessages != 2 {
+		t.Errorf("expected 2 messages for the user, got %d", user.TotalMessages)
+	}
+	if !user.LastSeenAt.Equal(newer) {
+		t.Errorf("expected the user's last seen time to stay at %v, got %v", newer, user.LastSeenAt)
+	}
+}
@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func TestStoreBatchUsesTwitchSentTime(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+	messageRepo := repository.NewMessageRepository(db)
+
+	// tmi-sent-ts has milliseconds, which line messages up against VODs
+	receivedAt := time.Now().UTC().Truncate(time.Millisecond)
+	sentAt := receivedAt.Add(-45*time.Second - 123*time.Millisecond) // e.g. replayed after a reconnect
+
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "viewer", Text: "stamped", Tags: map[string]string{"id": "a"}, SentAt: sentAt, ReceivedAt: receivedAt},
+		{ChannelName: "#modchannel", Username: "viewer", Text: "unstamped", Tags: map[string]string{"id": "b"}, ReceivedAt: receivedAt},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	messages, err := messageRepo.GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+
+	byID := make(map[string]repository.Message)
+	for _, msg := range messages {
+		byID[msg.TwitchMessageID] = msg
+	}
+
+	stamped := byID["a"]
+	if !stamped.SentAt.Equal(sentAt) {
+		t.Errorf("stamped SentAt = %v, want tmi-sent-ts %v", stamped.SentAt, sentAt)
+	}
+	if got, want := stamped.SentAt.Nanosecond()/1e6, sentAt.Nanosecond()/1e6; got != want {
+		t.Errorf("stamped SentAt kept %d ms, want %d", got, want)
+	}
+	if !stamped.ReceivedAt.Equal(receivedAt) {
+		t.Errorf("stamped ReceivedAt = %v, want %v", stamped.ReceivedAt, receivedAt)
+	}
+
+	unstamped := byID["b"]
+	if !unstamped.SentAt.Equal(receivedAt) || !unstamped.ReceivedAt.Equal(receivedAt) {
+		t.Errorf("expected unstamped message to fall back to receive time, got sent=%v received=%v",
+			unstamped.SentAt, unstamped.ReceivedAt)
+	}
+
+	// Ordering follows Twitch's clock, not arrival order
+	if messages[0].TwitchMessageID != "b" {
+		t.Errorf("expected newest-by-sent_at message first, got %q", messages[0].TwitchMessageID)
+	}
+}
+
+func TestTimestampsSortWithMilliseconds(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+	messageRepo := repository.NewMessageRepository(db)
+
+	// Within one second, stored out of order
+	base := time.Now().UTC().Truncate(time.Second)
+	var batch []ingestion.Message
+	for i, ms := range []int{900, 50, 500} {
+		batch = append(batch, ingestion.Message{
+			ChannelName: "#modchannel",
+			Username:    "viewer",
+			Text:        "msg",
+			Tags:        map[string]string{"id": string(rune('a' + i))},
+			SentAt:      base.Add(time.Duration(ms) * time.Millisecond),
+			ReceivedAt:  base.Add(time.Second),
+		})
+	}
+	if err := processor.StoreBatch(ctx, batch); err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	messages, err := messageRepo.GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	var order string
+	for _, msg := range messages {
+		order += msg.TwitchMessageID
+	}
+	if order != "acb" {
+		t.Errorf("expected newest first by milliseconds (acb), got %q", order)
+	}
+}
@@ -0,0 +1,308 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func setupModerationTest(t *testing.T) (context.Context, *repository.SQLiteDB, *ingestion.Processor, *repository.Channel) {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := repository.Open(repository.DBConfig{Path: ":memory:", EnableFTS: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	channelRepo := repository.NewChannelRepository(db)
+	channel := &repository.Channel{Name: "modchannel", DisplayName: "ModChannel", Enabled: true}
+	if err := channelRepo.Create(ctx, channel); err != nil {
+		t.Fatalf("failed to create channel: %v", err)
+	}
+
+	processor := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		channelRepo,
+		ingestion.ProcessorConfig{
+			ModerationRepo:     repository.NewModerationRepository(db),
+			ModerationLookback: 5 * time.Minute,
+		},
+	)
+
+	return ctx, db, processor, channel
+}
+
+func TestMigrateIsRepeatable(t *testing.T) {
+	ctx, db, _, _ := setupModerationTest(t)
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("second migrate failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&count); err != nil {
+		t.Fatalf("failed to count migrations: %v", err)
+	}
+	if count < 2 {
+		t.Fatalf("expected at least 2 recorded migrations, got %d", count)
+	}
+}
+
+func TestModerationEventsMarkMessagesDeleted(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+	messageRepo := repository.NewMessageRepository(db)
+	moderationRepo := repository.NewModerationRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "spammer", Text: "old message", Tags: map[string]string{"id": "m0"}, ReceivedAt: now.Add(-time.Hour)},
+		{ChannelName: "#modchannel", Username: "spammer", Text: "spam 1", Tags: map[string]string{"id": "m1"}, ReceivedAt: now.Add(-time.Minute)},
+		{ChannelName: "#modchannel", Username: "spammer", Text: "spam 2", Tags: map[string]string{"id": "m2"}, ReceivedAt: now.Add(-30 * time.Second)},
+		{ChannelName: "#modchannel", Username: "regular", Text: "hello", Tags: map[string]string{"id": "m3"}, ReceivedAt: now.Add(-20 * time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	// Single message deletion by Twitch message ID
+	err = processor.StoreModerationEvent(ctx, ingestion.ModerationEvent{
+		ChannelName:     "#modchannel",
+		Action:          repository.ModerationActionDelete,
+		TargetUsername:  "regular",
+		TargetMessageID: "m3",
+		Text:            "hello",
+		ReceivedAt:      now,
+	})
+	if err != nil {
+		t.Fatalf("StoreModerationEvent(delete) failed: %v", err)
+	}
+
+	// Timeout removes the user's messages within the lookback window only
+	err = processor.StoreModerationEvent(ctx, ingestion.ModerationEvent{
+		ChannelName:    "#modchannel",
+		Action:         repository.ModerationActionTimeout,
+		TargetUsername: "Spammer",
+		Duration:       10 * time.Minute,
+		ReceivedAt:     now,
+	})
+	if err != nil {
+		t.Fatalf("StoreModerationEvent(timeout) failed: %v", err)
+	}
+
+	messages, err := messageRepo.GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("expected all 4 messages to be retained, got %d", len(messages))
+	}
+
+	deleted := make(map[string]bool)
+	for _, msg := range messages {
+		deleted[msg.TwitchMessageID] = msg.DeletedAt != nil
+	}
+	want := map[string]bool{"m0": false, "m1": true, "m2": true, "m3": true}
+	for id, wantDeleted := range want {
+		if deleted[id] != wantDeleted {
+			t.Errorf("message %s deleted = %v, want %v", id, deleted[id], wantDeleted)
+		}
+	}
+
+	events, err := moderationRepo.ListByChannel(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("ListByChannel failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 moderation events, got %d", len(events))
+	}
+
+	userEvents, err := moderationRepo.ListByUsername(ctx, "spammer", 10)
+	if err != nil {
+		t.Fatalf("ListByUsername failed: %v", err)
+	}
+	if len(userEvents) != 1 {
+		t.Fatalf("expected 1 event for spammer, got %d", len(userEvents))
+	}
+	if userEvents[0].Action != repository.ModerationActionTimeout || userEvents[0].DurationSeconds != 600 {
+		t.Errorf("unexpected event: %+v", userEvents[0])
+	}
+	if userEvents[0].UserID == nil {
+		t.Error("expected timeout to be linked to the known user")
+	}
+}
+
+func TestModerationLookbackUsesTwitchTime(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+	messageRepo := repository.NewMessageRepository(db)
+	moderationRepo := repository.NewModerationRepository(db)
+
+	// Everything reaches the database an hour late, e.g. from the spool
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	bannedAt := now.Add(-time.Hour)
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "spammer", Text: "before lookback", Tags: map[string]string{"id": "m0"}, SentAt: bannedAt.Add(-10 * time.Minute), ReceivedAt: now},
+		{ChannelName: "#modchannel", Username: "spammer", Text: "spam", Tags: map[string]string{"id": "m1"}, SentAt: bannedAt.Add(-time.Minute), ReceivedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	err = processor.StoreModerationEvent(ctx, ingestion.ModerationEvent{
+		ChannelName:    "#modchannel",
+		Action:         repository.ModerationActionBan,
+		TargetUsername: "spammer",
+		SentAt:         bannedAt,
+		ReceivedAt:     now,
+	})
+	if err != nil {
+		t.Fatalf("StoreModerationEvent failed: %v", err)
+	}
+
+	messages, err := messageRepo.GetRecent(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecent failed: %v", err)
+	}
+	deleted := make(map[string]bool)
+	for _, msg := range messages {
+		deleted[msg.TwitchMessageID] = msg.DeletedAt != nil
+	}
+	if deleted["m0"] || !deleted["m1"] {
+		t.Errorf("expected only the message within the lookback of the ban deleted, got %v", deleted)
+	}
+
+	events, err := moderationRepo.ListByChannel(ctx, channel.ID, 10)
+	if err != nil {
+		t.Fatalf("ListByChannel failed: %v", err)
+	}
+	if len(events) != 1 || !events[0].OccurredAt.Equal(bannedAt) {
+		t.Errorf("expected the ban to occur at its tmi-sent-ts %v, got %+v", bannedAt, events)
+	}
+}
+
+func TestModerationPipelineOrdersEventsAfterMessages(t *testing.T) {
+	ctx, db, processor, channel := setupModerationTest(t)
+	messageRepo := repository.NewMessageRepository(db)
+
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    100,
+		FlushTimeout: time.Hour, // only flush on moderation events or Stop
+	}, processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+
+	now := time.Now().UTC()
+	pipeline.Ingest(ingestion.Message{ChannelName: "#modchannel", Username: "banned", Text: "bye", ReceivedAt: now})
+	pipeline.IngestModeration(ingestion.ModerationEvent{
+		ChannelName:    "#modchannel",
+		Action:         repository.ModerationActionBan,
+		TargetUsername: "banned",
+		ReceivedAt:     now.Add(time.Second),
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		messages, err := messageRepo.GetRecent(ctx, channel.ID, 10)
+		if err != nil {
+			t.Fatalf("GetRecent failed: %v", err)
+		}
+		if len(messages) == 1 && messages[0].DeletedAt != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("message was not stored and marked deleted before the ban: %+v", messages)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+}
+
+func TestModerationHandler(t *testing.T) {
+	ctx, db, processor, _ := setupModerationTest(t)
+
+	err := processor.StoreModerationEvent(ctx, ingestion.ModerationEvent{
+		ChannelName:    "#modchannel",
+		Action:         repository.ModerationActionTimeout,
+		TargetUsername: "someone",
+		Duration:       time.Minute,
+		ReceivedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("StoreModerationEvent failed: %v", err)
+	}
+
+	templates := templateFromRepoFiles(t,
+		"internal/http/templates/moderation/list.html",
+		"internal/http/templates/partials/error.html",
+	)
+	handler := handlers.NewModerationHandler(
+		repository.NewChannelRepository(db),
+		repository.NewModerationRepository(db),
+		templates,
+		observability.NewLogger("test"),
+	)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/channels/modchannel/moderation", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Events []dto.ModerationEvent `json:"events"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Events) != 1 || body.Events[0].TargetUsername != "someone" || body.Events[0].DurationSeconds != 60 {
+		t.Fatalf("unexpected events: %+v", body.Events)
+	}
+
+	htmlResp, err := http.Get(srv.URL + "/users/someone/moderation")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer htmlResp.Body.Close()
+	html := readBody(t, htmlResp)
+	if !strings.Contains(html, "timeout 60s") || !strings.Contains(html, "#modchannel") {
+		t.Fatalf("expected timeout entry in fragment, got: %s", html)
+	}
+
+	notFound, err := http.Get(srv.URL + "/channels/missing/moderation")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer notFound.Body.Close()
+	if notFound.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", notFound.StatusCode)
+	}
+}
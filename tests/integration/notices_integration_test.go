@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+)
+
+func TestUserNoticesStoredAndFiltered(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := repository.Open(repository.DBConfig{Path: ":memory:", EnableFTS: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	channelRepo := repository.NewChannelRepository(db)
+	channel := &repository.Channel{Name: "noticechannel", DisplayName: "NoticeChannel", Enabled: true}
+	if err := channelRepo.Create(ctx, channel); err != nil {
+		t.Fatalf("failed to create channel: %v", err)
+	}
+
+	noticeRepo := repository.NewNoticeRepository(db)
+	processor := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		channelRepo,
+		ingestion.ProcessorConfig{NoticeRepo: noticeRepo},
+	)
+
+	pipeline := ingestion.NewPipeline(ingestion.DefaultPipelineConfig(), processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+
+	now := time.Now().UTC()
+	notices := []ingestion.Notice{
+		{ChannelName: "#noticechannel", Kind: "sub", Username: "alice", SystemMsg: "alice subscribed", ReceivedAt: now},
+		{ChannelName: "#noticechannel", Kind: "resub", Username: "bob", Text: "6 months!", Params: map[string]string{"cumulative-months": "6"}, ReceivedAt: now},
+		{ChannelName: "#noticechannel", Kind: "raid", Username: "raider", Params: map[string]string{"viewerCount": "42"}, ReceivedAt: now},
+		{ChannelName: "#noticechannel", Kind: "sub", Username: "carol", ReceivedAt: now},
+		{ChannelName: "#unknownchannel", Kind: "sub", Username: "dave", ReceivedAt: now},
+	}
+	for _, n := range notices {
+		pipeline.IngestNotice(n)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		counts, err := noticeRepo.CountByKind(ctx, channel.ID)
+		if err != nil {
+			t.Fatalf("CountByKind failed: %v", err)
+		}
+		if counts["sub"] == 2 && counts["resub"] == 1 && counts["raid"] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("notices were not stored, counts: %v", counts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+
+	templates := templateFromRepoFiles(t,
+		"internal/http/templates/notices/list.html",
+		"internal/http/templates/partials/error.html",
+	)
+	handler := handlers.NewNoticeHandler(channelRepo, noticeRepo, templates, observability.NewLogger("test"))
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/channels/noticechannel/notices?kind=raid", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Notices []dto.Notice               `json:"notices"`
+		Kinds   []handlers.NoticeKindCount `json:"kinds"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(body.Notices) != 1 || body.Notices[0].Username != "raider" || body.Notices[0].Params["viewerCount"] != "42" {
+		t.Fatalf("unexpected notices: %+v", body.Notices)
+	}
+	if len(body.Kinds) != 3 || body.Kinds[0].Kind != "sub" || body.Kinds[0].Count != 2 {
+		t.Fatalf("unexpected kind counts: %+v", body.Kinds)
+	}
+
+	htmlResp, err := http.Get(srv.URL + "/channels/noticechannel/notices")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer htmlResp.Body.Close()
+	html := readBody(t, htmlResp)
+	for _, want := range []string{"alice subscribed", "6 months!", "?kind=raid"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected fragment to contain %q", want)
+		}
+	}
+}
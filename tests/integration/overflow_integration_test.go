@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+// overflowTestPipeline starts a pipeline with a small buffer and policy.
+func overflowTestPipeline(t *testing.T, store ingestion.MessageStore, policy ingestion.OverflowPolicy, metrics ingestion.Metrics) *ingestion.Pipeline {
+	t.Helper()
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    5,
+		FlushTimeout: 20 * time.Millisecond,
+		BufferSize:   10,
+		Metrics:      metrics,
+		Overflow:     policy,
+	}, store)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	t.Cleanup(func() { pipeline.Stop() })
+	return pipeline
+}
+
+func ingestToChannel(pipeline *ingestion.Pipeline, channel string, count int) {
+	for range count {
+		pipeline.Ingest(ingestion.Message{
+			ChannelName: channel,
+			Username:    "viewer",
+			Text:        "hello",
+			ReceivedAt:  time.Now().UTC(),
+		})
+	}
+}
+
+func TestPipelineDropPolicyCountsDropsPerChannel(t *testing.T) {
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreLatency(100 * time.Millisecond)
+
+	var dropped atomic.Int64
+	metrics := &fakeMetrics{onDropped: func(n int) { dropped.Add(int64(n)) }}
+	pipeline := overflowTestPipeline(t, store, nil, metrics)
+
+	ingestToChannel(pipeline, "#busychannel", 100)
+
+	stats := pipeline.OverflowStats()
+	if stats.Policy != "drop" {
+		t.Errorf("expected the drop policy by default, got %q", stats.Policy)
+	}
+	if len(stats.Channels) != 1 || stats.Channels[0].Channel != "busychannel" {
+		t.Fatalf("expected overflow for busychannel only, got %+v", stats.Channels)
+	}
+	if got := stats.Channels[0].Dropped; got == 0 || got != dropped.Load() {
+		t.Errorf("expected the channel's drops to match the %d counted, got %d", dropped.Load(), got)
+	}
+}
+
+func TestPipelineBlockPolicyWaitsForRoom(t *testing.T) {
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreLatency(20 * time.Millisecond)
+
+	var dropped atomic.Int64
+	metrics := &fakeMetrics{onDropped: func(n int) { dropped.Add(int64(n)) }}
+	pipeline := overflowTestPipeline(t, store, ingestion.BlockPolicy{Timeout: 2 * time.Second}, metrics)
+
+	// Far more than the buffer holds; Ingest waits rather than drops
+	ingestToChannel(pipeline, "#busychannel", 100)
+
+	if !waitUntil(5*time.Second, func() bool { return len(store.GetMessages()) == 100 }) {
+		t.Fatalf("expected every message stored, got %d", len(store.GetMessages()))
+	}
+	if n := dropped.Load(); n != 0 {
+		t.Errorf("expected no messages dropped, got %d", n)
+	}
+	stats := pipeline.OverflowStats()
+	if stats.Policy != "block" || len(stats.Channels) != 0 {
+		t.Errorf("expected the block policy without overflow, got %+v", stats)
+	}
+}
+
+func TestPipelineBlockPolicyGivesUpAfterTimeout(t *testing.T) {
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreLatency(time.Second)
+
+	pipeline := overflowTestPipeline(t, store, ingestion.BlockPolicy{Timeout: 10 * time.Millisecond}, nil)
+
+	start := time.Now()
+	ingestToChannel(pipeline, "#busychannel", 30)
+
+	// The wait is bounded, so a stuck store can't stall the caller for long
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected Ingest to give up waiting, took %v", elapsed)
+	}
+	stats := pipeline.OverflowStats()
+	if len(stats.Channels) != 1 || stats.Channels[0].Dropped == 0 {
+		t.Errorf("expected drops once the wait times out, got %+v", stats.Channels)
+	}
+}
+
+func TestPipelinePriorityPolicyShedsLowPriorityChannels(t *testing.T) {
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreLatency(50 * time.Millisecond)
+
+	policy := ingestion.PriorityPolicy{
+		Priorities: map[string]ingestion.Priority{
+			"bigchannel": ingestion.PriorityHigh,
+			"spammy":     ingestion.PriorityLow,
+		},
+		Wait: 2 * time.Second,
+	}
+	pipeline := overflowTestPipeline(t, store, policy, nil)
+
+	// Interleaved, so both channels see the buffer fill up
+	for range 30 {
+		ingestToChannel(pipeline, "#spammy", 1)
+		ingestToChannel(pipeline, "#bigchannel", 1)
+	}
+
+	if !waitUntil(5*time.Second, func() bool {
+		return countChannelMessages(store, "#bigchannel") == 30
+	}) {
+		t.Fatalf("expected every high priority message stored, got %d", countChannelMessages(store, "#bigchannel"))
+	}
+
+	stats := pipeline.OverflowStats()
+	if stats.Policy != "priority" {
+		t.Errorf("expected the priority policy, got %q", stats.Policy)
+	}
+	if len(stats.Channels) != 1 || stats.Channels[0].Channel != "spammy" || stats.Channels[0].Dropped == 0 {
+		t.Fatalf("expected only spammy shed, got %+v", stats.Channels)
+	}
+	if got, want := countChannelMessages(store, "#spammy"), 30-int(stats.Channels[0].Dropped); got != want {
+		t.Errorf("expected %d spammy messages stored, got %d", want, got)
+	}
+}
+
+func countChannelMessages(store *fakes.FakeMessageStore, channel string) int {
+	count := 0
+	for _, msg := range store.GetMessages() {
+		if msg.ChannelName == channel {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,352 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+// concurrencyStore records the most StoreBatch calls in flight at once,
+// and for each moderation event how many messages of its channel were
+// stored before it.
+type concurrencyStore struct {
+	*fakes.FakeMessageStore
+
+	inFlight    atomic.Int64
+	maxInFlight atomic.Int64
+
+	mu           sync.Mutex
+	storedBefore map[string]int // By moderation event target
+}
+
+func newConcurrencyStore(latency time.Duration) *concurrencyStore {
+	store := &concurrencyStore{
+		FakeMessageStore: fakes.NewFakeMessageStore(),
+		storedBefore:     map[string]int{},
+	}
+	store.SetStoreLatency(latency)
+	return store
+}
+
+func (s *concurrencyStore) StoreBatch(ctx context.Context, messages []ingestion.Message) error {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		most := s.maxInFlight.Load()
+		if n <= most || s.maxInFlight.CompareAndSwap(most, n) {
+			break
+		}
+	}
+	return s.FakeMessageStore.StoreBatch(ctx, messages)
+}
+
+func (s *concurrencyStore) StoreModerationEvent(ctx context.Context, evt ingestion.ModerationEvent) error {
+	stored := countChannelMessages(s.FakeMessageStore, evt.ChannelName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storedBefore[evt.TargetUsername] = stored
+	return nil
+}
+
+func parallelTestPipeline(t *testing.T, store ingestion.MessageStore, writers int) *ingestion.Pipeline {
+	t.Helper()
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    10,
+		FlushTimeout: 20 * time.Millisecond,
+		BufferSize:   10000,
+		Writers:      writers,
+	}, store)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	t.Cleanup(func() { pipeline.Stop() })
+	return pipeline
+}
+
+func TestPipelineParallelWritersKeepChannelOrder(t *testing.T) {
+	const channels, perChannel = 8, 100
+	store := newConcurrencyStore(5 * time.Millisecond)
+	pipeline := parallelTestPipeline(t, store, 4)
+
+	// Interleaved across channels, numbered within each
+	for i := range perChannel {
+		for c := range channels {
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: fmt.Sprintf("#channel%d", c),
+				Username:    "viewer",
+				Text:        strconv.Itoa(i),
+				ReceivedAt:  time.Now().UTC(),
+			})
+		}
+	}
+
+	if !waitUntil(10*time.Second, func() bool { return len(store.GetMessages()) == channels*perChannel }) {
+		t.Fatalf("expected %d messages stored, got %d", channels*perChannel, len(store.GetMessages()))
+	}
+
+	next := map[string]int{}
+	for _, msg := range store.GetMessages() {
+		if want := strconv.Itoa(next[msg.ChannelName]); msg.Text != want {
+			t.Fatalf("%s: expected message %s next, got %s", msg.ChannelName, want, msg.Text)
+		}
+		next[msg.ChannelName]++
+	}
+	if n := store.maxInFlight.Load(); n < 2 {
+		t.Errorf("expected batches written concurrently, at most %d were", n)
+	}
+}
+
+func TestPipelineParallelWritersStoreEventsAfterEarlierMessages(t *testing.T) {
+	store := newConcurrencyStore(5 * time.Millisecond)
+	pipeline := parallelTestPipeline(t, store, 4)
+
+	// Each channel's ban must see that channel's messages before it,
+	// whatever the other writers are doing
+	for c := range 6 {
+		channel := fmt.Sprintf("#channel%d", c)
+		for range 5 + c {
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: channel,
+				Username:    "viewer",
+				Text:        "hello",
+				ReceivedAt:  time.Now().UTC(),
+			})
+		}
+		pipeline.IngestModeration(ingestion.ModerationEvent{
+			ChannelName:    channel,
+			Action:         "ban",
+			TargetUsername: channel,
+			ReceivedAt:     time.Now().UTC(),
+		})
+	}
+
+	if !waitUntil(5*time.Second, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.storedBefore) == 6
+	}) {
+		t.Fatal("expected every moderation event stored")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for c := range 6 {
+		channel := fmt.Sprintf("#channel%d", c)
+		if got, want := store.storedBefore[channel], 5+c; got != want {
+			t.Errorf("%s: expected the ban after %d messages, got %d", channel, want, got)
+		}
+	}
+}
+
+func TestPipelineParallelWritersSpoolQueuedAtStop(t *testing.T) {
+	spool := openTestSpool(t, t.TempDir())
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreLatency(200 * time.Millisecond)
+
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    5,
+		FlushTimeout: time.Hour,
+		BufferSize:   1000,
+		Writers:      3,
+		Spool:        spool,
+	}, store)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	for c := range 3 {
+		for range 40 {
+			pipeline.Ingest(ingestion.Message{
+				ChannelName: fmt.Sprintf("#channel%d", c),
+				Username:    "viewer",
+				Text:        "hello",
+				ReceivedAt:  time.Now().UTC(),
+			})
+		}
+	}
+	// Stop while batches are being written
+	if !waitUntil(time.Second, func() bool { return pipeline.BufferLen()+pipeline.BatchLen() < 120 }) {
+		t.Fatal("expected the writers to start on their batches")
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+	stored := len(store.GetMessages())
+	if stored == 0 {
+		t.Fatal("expected the batches in flight at stop stored")
+	}
+
+	// Nothing is lost: the rest is in the spool
+	replayed := fakes.NewFakeMessageStore()
+	spoolTestPipeline(t, replayed, spool, 100, nil)
+	if !waitUntil(5*time.Second, func() bool { return spool.Len() == 0 }) {
+		t.Fatalf("expected the spool replayed, %d records left", spool.Len())
+	}
+	if total := stored + len(replayed.GetMessages()); total != 120 {
+		t.Errorf("expected 120 messages stored or spooled, got %d", total)
+	}
+}
+
+// gatedStore holds up storing any batch with a message of channel until
+// the gate is opened.
+type gatedStore struct {
+	*concurrencyStore
+	channel string
+	gate    chan struct{}
+	held    atomic.Bool // Set once a batch waits at the gate
+}
+
+func (s *gatedStore) StoreBatch(ctx context.Context, messages []ingestion.Message) error {
+	for _, msg := range messages {
+		if msg.ChannelName == s.channel {
+			s.held.Store(true)
+			<-s.gate
+			break
+		}
+	}
+	return s.concurrencyStore.StoreBatch(ctx, messages)
+}
+
+func TestPipelineParallelWritersReplaySpoolPerChannel(t *testing.T) {
+	dir := t.TempDir()
+
+	// The database is down for the first run
+	spool := openTestSpool(t, dir)
+	failing := fakes.NewFakeMessageStore()
+	failing.SetStoreError(errors.New("connection refused"))
+	first := spoolTestPipeline(t, failing, spool, 100, nil)
+	for range 20 {
+		first.Ingest(ingestion.Message{
+			ChannelName: "#replaychannel",
+			Username:    "viewer",
+			Text:        "spooled",
+			ReceivedAt:  time.Now().UTC(),
+		})
+	}
+	if !waitUntil(2*time.Second, func() bool { return spool.Len() == 2 }) {
+		t.Fatalf("expected 2 batches spooled, got %d", spool.Len())
+	}
+	if err := first.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+
+	// The next run's replay is slow
+	store := &gatedStore{
+		concurrencyStore: newConcurrencyStore(0),
+		channel:          "#replaychannel",
+		gate:             make(chan struct{}),
+	}
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    10,
+		FlushTimeout: 20 * time.Millisecond,
+		BufferSize:   1000,
+		Writers:      4,
+		Spool:        spool,
+	}, store)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	t.Cleanup(func() { pipeline.Stop() })
+	// Opened before the pipeline stops, should the test fail first
+	release := sync.OnceFunc(func() { close(store.gate) })
+	t.Cleanup(release)
+	if !waitUntil(2*time.Second, store.held.Load) {
+		t.Fatal("expected the replay to start")
+	}
+
+	// A live ban in the replayed channel, and messages in others
+	pipeline.IngestModeration(ingestion.ModerationEvent{
+		ChannelName:    "#replaychannel",
+		Action:         "ban",
+		TargetUsername: "#replaychannel",
+		ReceivedAt:     time.Now().UTC(),
+	})
+	for c := range 8 {
+		pipeline.Ingest(ingestion.Message{
+			ChannelName: fmt.Sprintf("#livechannel%d", c),
+			Username:    "viewer",
+			Text:        "live",
+			ReceivedAt:  time.Now().UTC(),
+		})
+	}
+
+	// Other writers' channels are stored while the replay is held up
+	if !waitUntil(2*time.Second, func() bool { return len(store.GetMessages()) > 0 }) {
+		t.Fatal("expected live messages stored during the replay")
+	}
+	for _, msg := range store.GetMessages() {
+		if msg.ChannelName == "#replaychannel" {
+			t.Fatalf("expected no replayed message stored before the gate opened")
+		}
+	}
+
+	release()
+	if !waitUntil(5*time.Second, func() bool { return spool.Len() == 0 && len(store.GetMessages()) == 28 }) {
+		t.Fatalf("expected the spool replayed and 28 messages stored, got %d records and %d messages",
+			spool.Len(), len(store.GetMessages()))
+	}
+	if !waitUntil(time.Second, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.storedBefore) == 1
+	}) {
+		t.Fatal("expected the ban stored")
+	}
+
+	// The ban waited for the replay of its channel
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if got := store.storedBefore["#replaychannel"]; got != 20 {
+		t.Errorf("expected the ban after the 20 replayed messages, got %d", got)
+	}
+}
+
+func TestPipelineParallelWritersBufferBoundsBacklog(t *testing.T) {
+	const bufferSize, batchSize = 20, 5
+	store := &gatedStore{
+		concurrencyStore: newConcurrencyStore(0),
+		channel:          "#stuckchannel",
+		gate:             make(chan struct{}),
+	}
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    batchSize,
+		FlushTimeout: time.Hour,
+		BufferSize:   bufferSize,
+		Writers:      2,
+	}, store)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	t.Cleanup(func() { pipeline.Stop() })
+	release := sync.OnceFunc(func() { close(store.gate) })
+	t.Cleanup(release)
+
+	for i := range 200 {
+		pipeline.Ingest(ingestion.Message{
+			ChannelName: "#stuckchannel",
+			Username:    "viewer",
+			Text:        strconv.Itoa(i),
+			ReceivedAt:  time.Now().UTC(),
+		})
+		// Let the dispatcher keep up, so only a full buffer drops
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	// Beyond the buffer, the stuck writer holds only the batch it is
+	// writing, the next one handed off, and the item being dispatched
+	var dropped int64
+	for _, ch := range pipeline.OverflowStats().Channels {
+		dropped += ch.Dropped
+	}
+	if held, most := 200-dropped, int64(bufferSize+2*batchSize+1); held > most {
+		t.Errorf("expected at most %d items held while the writer is stuck, got %d", most, held)
+	}
+}
@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+)
+
+// latencyStore stands in for a database that takes a fixed round trip per
+// batch and keeps up with several at once, as Postgres does.
+type latencyStore struct {
+	latency time.Duration
+	stored  atomic.Int64
+}
+
+func (s *latencyStore) StoreBatch(ctx context.Context, messages []ingestion.Message) error {
+	time.Sleep(s.latency)
+	s.stored.Add(int64(len(messages)))
+	return nil
+}
+
+// BenchmarkPipelineWriters measures message throughput from Ingest to the
+// store by number of writers, with messages spread over 32 channels.
+func BenchmarkPipelineWriters(b *testing.B) {
+	channels := make([]string, 32)
+	for i := range channels {
+		channels[i] = fmt.Sprintf("#channel%d", i)
+	}
+
+	for _, writers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("writers=%d", writers), func(b *testing.B) {
+			store := &latencyStore{latency: 2 * time.Millisecond}
+			pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+				BatchSize:    100,
+				FlushTimeout: 10 * time.Millisecond,
+				BufferSize:   10000,
+				Writers:      writers,
+				// Measure the writers, not how much the buffer drops
+				Overflow: ingestion.BlockPolicy{Timeout: time.Minute},
+			}, store)
+			if err := pipeline.Start(context.Background()); err != nil {
+				b.Fatalf("failed to start pipeline: %v", err)
+			}
+			defer pipeline.Stop()
+
+			receivedAt := time.Now().UTC()
+			b.ResetTimer()
+			for i := range b.N {
+				pipeline.Ingest(ingestion.Message{
+					ChannelName: channels[i%len(channels)],
+					Username:    "viewer",
+					Text:        "hello",
+					ReceivedAt:  receivedAt,
+				})
+			}
+			for store.stored.Load() < int64(b.N) {
+				time.Sleep(time.Millisecond)
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+		})
+	}
+}
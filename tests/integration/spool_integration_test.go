@@ -0,0 +1,206 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/tests/integration/fakes"
+)
+
+// openTestSpool opens a spool in dir and closes it when the test ends.
+func openTestSpool(t *testing.T, dir string) *ingestion.Spool {
+	t.Helper()
+	spool, err := ingestion.OpenSpool(ingestion.SpoolConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenSpool failed: %v", err)
+	}
+	t.Cleanup(func() { spool.Close() })
+	return spool
+}
+
+// spoolTestPipeline starts a pipeline that spools to spool.
+func spoolTestPipeline(t *testing.T, store ingestion.MessageStore, spool *ingestion.Spool, bufferSize int, metrics ingestion.Metrics) *ingestion.Pipeline {
+	t.Helper()
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    10,
+		FlushTimeout: 20 * time.Millisecond,
+		BufferSize:   bufferSize,
+		Metrics:      metrics,
+		Spool:        spool,
+	}, store)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	t.Cleanup(func() { pipeline.Stop() })
+	return pipeline
+}
+
+// ingestNumbered ingests count messages with texts "0", "1", ...
+func ingestNumbered(pipeline *ingestion.Pipeline, count int) {
+	now := time.Now().UTC()
+	for i := range count {
+		pipeline.Ingest(ingestion.Message{
+			ChannelName: "#spoolchannel",
+			Username:    "viewer",
+			Text:        fmt.Sprint(i),
+			ReceivedAt:  now,
+		})
+	}
+}
+
+// waitUntil polls cond until it holds or the timeout passes.
+func waitUntil(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func TestPipelineSpoolsFailedBatchesAndReplaysInOrder(t *testing.T) {
+	spool := openTestSpool(t, t.TempDir())
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreError(errors.New("database is locked"))
+
+	pipeline := spoolTestPipeline(t, store, spool, 100, nil)
+	ingestNumbered(pipeline, 25)
+
+	// Every failed batch lands in the spool
+	if !waitUntil(2*time.Second, func() bool {
+		return pipeline.BufferLen() == 0 && pipeline.BatchLen() == 0 && spool.Stats().Records == 3
+	}) {
+		t.Fatalf("expected 3 batches spooled, got %+v", spool.Stats())
+	}
+	stats := spool.Stats()
+	if stats.Bytes <= 0 || stats.Segments != 1 {
+		t.Errorf("expected the batches in one segment on disk, got %+v", stats)
+	}
+	if stats.OldestAt.IsZero() || time.Since(stats.OldestAt) > time.Minute {
+		t.Errorf("expected the oldest record's spool time, got %v", stats.OldestAt)
+	}
+
+	// The database recovers and the spool is replayed oldest first
+	store.SetStoreError(nil)
+	if !waitUntil(5*time.Second, func() bool { return len(store.GetMessages()) == 25 }) {
+		t.Fatalf("expected 25 messages replayed, got %d", len(store.GetMessages()))
+	}
+	for i, msg := range store.GetMessages() {
+		if msg.Text != fmt.Sprint(i) {
+			t.Fatalf("message %d: expected %q, got %q", i, fmt.Sprint(i), msg.Text)
+		}
+	}
+	if stats := spool.Stats(); stats.Records != 0 || stats.Bytes != 0 || !stats.OldestAt.IsZero() {
+		t.Errorf("expected an empty spool after replay, got %+v", stats)
+	}
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	// The database is down for the whole first run
+	spool, err := ingestion.OpenSpool(ingestion.SpoolConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenSpool failed: %v", err)
+	}
+	failing := fakes.NewFakeMessageStore()
+	failing.SetStoreError(errors.New("connection refused"))
+	pipeline := ingestion.NewPipeline(ingestion.PipelineConfig{
+		BatchSize:    10,
+		FlushTimeout: 20 * time.Millisecond,
+		BufferSize:   100,
+		Spool:        spool,
+	}, failing)
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+	ingestNumbered(pipeline, 15)
+	if !waitUntil(2*time.Second, func() bool { return spool.Len() == 2 }) {
+		t.Fatalf("expected 2 batches spooled, got %d", spool.Len())
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+	if err := spool.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// The next run finds the spool and replays it
+	spool = openTestSpool(t, dir)
+	if n := spool.Len(); n != 2 {
+		t.Fatalf("expected 2 records after reopening, got %d", n)
+	}
+	store := fakes.NewFakeMessageStore()
+	spoolTestPipeline(t, store, spool, 100, nil)
+	if !waitUntil(3*time.Second, func() bool { return len(store.GetMessages()) == 15 }) {
+		t.Fatalf("expected 15 messages replayed, got %d", len(store.GetMessages()))
+	}
+	if err := spool.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// What was replayed is not replayed again
+	if n := openTestSpool(t, dir).Len(); n != 0 {
+		t.Errorf("expected an empty spool after the replay, got %d records", n)
+	}
+}
+
+func TestPipelineSpoolsOverflow(t *testing.T) {
+	spool := openTestSpool(t, t.TempDir())
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreLatency(50 * time.Millisecond)
+
+	var dropped atomic.Int64
+	metrics := &fakeMetrics{onDropped: func(n int) { dropped.Add(int64(n)) }}
+	pipeline := spoolTestPipeline(t, store, spool, 5, metrics)
+
+	// Far more than the buffer holds while the store is slow
+	ingestNumbered(pipeline, 100)
+	if spool.Len() == 0 {
+		t.Fatal("expected the overflow to be spooled")
+	}
+
+	if !waitUntil(10*time.Second, func() bool { return len(store.GetMessages()) == 100 }) {
+		t.Fatalf("expected every message stored eventually, got %d", len(store.GetMessages()))
+	}
+	if n := dropped.Load(); n != 0 {
+		t.Errorf("expected no messages dropped, got %d", n)
+	}
+	seen := map[string]bool{}
+	for _, msg := range store.GetMessages() {
+		seen[msg.Text] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("expected 100 distinct messages, got %d", len(seen))
+	}
+}
+
+func TestSpoolRejectsRecordsPastMaxBytes(t *testing.T) {
+	spool, err := ingestion.OpenSpool(ingestion.SpoolConfig{Dir: t.TempDir(), MaxBytes: 1024})
+	if err != nil {
+		t.Fatalf("OpenSpool failed: %v", err)
+	}
+	defer spool.Close()
+	store := fakes.NewFakeMessageStore()
+	store.SetStoreError(errors.New("database is locked"))
+
+	var dropped atomic.Int64
+	metrics := &fakeMetrics{onDropped: func(n int) { dropped.Add(int64(n)) }}
+	pipeline := spoolTestPipeline(t, store, spool, 100, metrics)
+	ingestNumbered(pipeline, 50)
+
+	// What doesn't fit is dropped and counted, and the spool stays in bounds
+	if !waitUntil(2*time.Second, func() bool { return dropped.Load() > 0 && pipeline.BatchLen() == 0 }) {
+		t.Fatalf("expected messages dropped once the spool is full, got %d", dropped.Load())
+	}
+	if stats := spool.Stats(); stats.Bytes > 1024 {
+		t.Errorf("expected the spool within 1024 bytes, got %+v", stats)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"runtime"
 	"testing"
 	"time"
+
+	"github.com/asabla/goknut/internal/http/render"
 )
 
 func templateFromRepoFiles(t *testing.T, relPaths ...string) *template.Template {
@@ -55,6 +57,9 @@ func templateFromRepoFiles(t *testing.T, relPaths ...string) *template.Template
 			return template.HTML(s)
 		},
 	}
+	for name, fn := range render.NewChat("", "").FuncMap() {
+		funcs[name] = fn
+	}
 
 	// Test binary CWD is the package directory, so derive repo root
 	// from this file location.
@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/search"
+)
+
+func TestRenameKeepsUserHistory(t *testing.T) {
+	ctx, db, processor, _ := setupModerationTest(t)
+	userRepo := repository.NewUserRepository(db)
+
+	now := time.Now().UTC()
+	err := processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "oldname", DisplayName: "OldName", Text: "before",
+			Tags: map[string]string{"id": "m1", "user-id": "1234"}, ReceivedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+	err = processor.StoreBatch(ctx, []ingestion.Message{
+		{ChannelName: "#modchannel", Username: "newname", DisplayName: "NewName", Text: "after",
+			Tags: map[string]string{"id": "m2", "user-id": "1234"}, ReceivedAt: now.Add(time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	count, err := userRepo.GetCount(ctx)
+	if err != nil {
+		t.Fatalf("GetCount failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected rename to keep a single user, got %d users", count)
+	}
+
+	user, err := userRepo.GetByTwitchID(ctx, "1234")
+	if err != nil || user == nil {
+		t.Fatalf("GetByTwitchID failed: user=%v err=%v", user, err)
+	}
+	if user.Username != "newname" || user.DisplayName != "NewName" {
+		t.Errorf("expected current name newname/NewName, got %s/%s", user.Username, user.DisplayName)
+	}
+	if user.TotalMessages != 2 {
+		t.Errorf("expected 2 messages on the user, got %d", user.TotalMessages)
+	}
+
+	history, err := userRepo.ListNameHistory(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListNameHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Username != "newname" || history[1].Username != "oldname" {
+		t.Fatalf("unexpected name history: %+v", history)
+	}
+
+	resolved, err := userRepo.ResolveUsername(ctx, "oldname")
+	if err != nil || resolved == nil {
+		t.Fatalf("ResolveUsername failed: user=%v err=%v", resolved, err)
+	}
+	if resolved.ID != user.ID {
+		t.Errorf("expected old name to resolve to user %d, got %d", user.ID, resolved.ID)
+	}
+
+	searchRepo := search.NewSearchRepository(db, true)
+	profile, err := searchRepo.GetUserProfileByUsername(ctx, "oldname")
+	if err != nil || profile == nil {
+		t.Fatalf("GetUserProfileByUsername failed: profile=%v err=%v", profile, err)
+	}
+	if profile.ID != user.ID || profile.Username != "newname" {
+		t.Errorf("expected profile for newname, got %d/%s", profile.ID, profile.Username)
+	}
+	if len(profile.PreviousNames) != 1 || profile.PreviousNames[0].Username != "oldname" {
+		t.Errorf("expected oldname as previous name, got %+v", profile.PreviousNames)
+	}
+
+	messages, total, err := searchRepo.GetUserMessagesByUsername(ctx, "oldname", nil, 1, 20)
+	if err != nil {
+		t.Fatalf("GetUserMessagesByUsername failed: %v", err)
+	}
+	if total != 2 || len(messages) != 2 {
+		t.Errorf("expected both messages under the old name, got %d", total)
+	}
+}
+
+func TestNameReuseByAnotherAccount(t *testing.T) {
+	ctx, db, _, _ := setupModerationTest(t)
+	userRepo := repository.NewUserRepository(db)
+
+	// A user seen before user-ids were tracked is adopted by the first id
+	legacy, err := userRepo.GetOrCreate(ctx, "shared", "Shared")
+	if err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+	first, err := userRepo.GetOrCreateByTwitchID(ctx, "1", "shared", "Shared")
+	if err != nil {
+		t.Fatalf("GetOrCreateByTwitchID failed: %v", err)
+	}
+	if first.ID != legacy.ID || first.TwitchUserID != "1" {
+		t.Fatalf("expected legacy user %d to be adopted, got %+v", legacy.ID, first)
+	}
+
+	// First account renames away, a second account takes the old login
+	if _, err := userRepo.GetOrCreateByTwitchID(ctx, "1", "moved", ""); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	second, err := userRepo.GetOrCreateByTwitchID(ctx, "2", "shared", "")
+	if err != nil {
+		t.Fatalf("GetOrCreateByTwitchID failed: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("expected a new user for a different user-id")
+	}
+
+	resolved, err := userRepo.ResolveUsername(ctx, "shared")
+	if err != nil || resolved == nil || resolved.ID != second.ID {
+		t.Fatalf("expected shared to resolve to its current holder, got %+v err=%v", resolved, err)
+	}
+
+	// The login is taken back while the other account still holds it in our db
+	if _, err := userRepo.GetOrCreateByTwitchID(ctx, "1", "shared", ""); err != nil {
+		t.Fatalf("rename back failed: %v", err)
+	}
+	released, err := userRepo.GetByID(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if released.Username != "shared~2" {
+		t.Errorf("expected displaced user to get a placeholder login, got %q", released.Username)
+	}
+}
@@ -0,0 +1,239 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/http/dto"
+	"github.com/asabla/goknut/internal/http/handlers"
+	"github.com/asabla/goknut/internal/ingestion"
+	"github.com/asabla/goknut/internal/irc"
+	"github.com/asabla/goknut/internal/observability"
+	"github.com/asabla/goknut/internal/repository"
+	"github.com/asabla/goknut/internal/services"
+)
+
+func setupWhisperTest(t *testing.T) (context.Context, *repository.SQLiteDB, *repository.WhisperRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := repository.Open(repository.DBConfig{Path: ":memory:", EnableFTS: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return ctx, db, repository.NewWhisperRepository(db)
+}
+
+func TestClientDeliversWhispers(t *testing.T) {
+	server := newFakeIRCServer(t)
+
+	var mu sync.Mutex
+	var whispers []irc.Whisper
+	client := irc.NewClient(irc.ClientConfig{
+		AuthMode:   irc.AuthModeAuthenticated,
+		Username:   "archiver",
+		OAuthToken: "oauth:secret",
+		Server:     server.Addr(),
+		DisableTLS: true,
+		OnWhisper: func(w irc.Whisper) {
+			mu.Lock()
+			whispers = append(whispers, w)
+			mu.Unlock()
+		},
+	})
+	t.Cleanup(func() { client.Disconnect() })
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	server.Send("", "@display-name=Friend;message-id=1;thread-id=11_22;user-id=22 :friend!friend@friend.tmi.twitch.tv WHISPER archiver :psst")
+	if !server.WaitFor(2*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(whispers) == 1
+	}) {
+		t.Fatal("expected the whisper to be delivered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if w := whispers[0]; w.From != "friend" || w.Text != "psst" || w.ThreadID != "11_22" {
+		t.Errorf("unexpected whisper: %+v", w)
+	}
+}
+
+func TestWhispersStoredApartFromMessages(t *testing.T) {
+	ctx, db, whisperRepo := setupWhisperTest(t)
+
+	processor := ingestion.NewProcessor(
+		repository.NewMessageRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewChannelRepository(db),
+		ingestion.ProcessorConfig{WhisperRepo: whisperRepo},
+	)
+	pipeline := ingestion.NewPipeline(ingestion.DefaultPipelineConfig(), processor)
+	if err := pipeline.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+
+	now := time.Now().UTC()
+	whisper := func(from, id, text string, at time.Time) ingestion.Whisper {
+		return ingestion.Whisper{
+			From: from, FromDisplayName: from, To: "archiver", Text: text,
+			MessageID: id, ThreadID: "11_" + from, ReceivedAt: at,
+		}
+	}
+	// Two connections on the account both receive the first whisper
+	pipeline.IngestWhisper(whisper("friend", "1", "hi", now.Add(-2*time.Minute)))
+	pipeline.IngestWhisper(whisper("friend", "1", "hi", now.Add(-2*time.Minute)))
+	pipeline.IngestWhisper(whisper("stranger", "1", "hello?", now.Add(-time.Minute)))
+	pipeline.IngestWhisper(whisper("friend", "2", "you there?", now))
+
+	deadline := time.Now().Add(2 * time.Second)
+	var conversations []repository.WhisperConversation
+	for pipeline.BufferLen() > 0 || len(conversations) != 2 || conversations[0].TotalWhispers != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("whispers were not stored, got %+v", conversations)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var err error
+		if conversations, err = whisperRepo.ListConversations(ctx); err != nil {
+			t.Fatalf("ListConversations failed: %v", err)
+		}
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop pipeline: %v", err)
+	}
+	if c := conversations[0]; c.Counterpart != "friend" || c.TotalWhispers != 2 || c.LastText != "you there?" {
+		t.Errorf("expected friend's conversation first, got %+v", c)
+	}
+
+	// A whisper stored before a restart is skipped
+	again := &repository.Whisper{Account: "archiver", Counterpart: "friend", ThreadID: "11_friend", TwitchMessageID: "1", Text: "hi", ReceivedAt: now}
+	if err := whisperRepo.Create(ctx, again); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if again.ID != 0 {
+		t.Errorf("expected the stored whisper to be skipped, got id %d", again.ID)
+	}
+
+	// Whispers never reach messages (which search reads) or users
+	for _, table := range []string{"messages", "users"} {
+		var count int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&count); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("expected no %s from whispers, got %d", table, count)
+		}
+	}
+}
+
+func TestWhisperRetentionPurgesOldWhispers(t *testing.T) {
+	ctx, _, whisperRepo := setupWhisperTest(t)
+
+	now := time.Now().UTC()
+	for i, age := range []time.Duration{40 * 24 * time.Hour, 31 * 24 * time.Hour, time.Hour} {
+		w := &repository.Whisper{
+			Account: "archiver", Counterpart: "friend", Text: "whisper",
+			ThreadID: "11_22", TwitchMessageID: string(rune('a' + i)), ReceivedAt: now.Add(-age),
+		}
+		if err := whisperRepo.Create(ctx, w); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	forever := services.NewWhisperService(whisperRepo, 0, observability.NewLogger("test"))
+	if n, err := forever.Purge(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no purge without retention, got %d, %v", n, err)
+	}
+
+	service := services.NewWhisperService(whisperRepo, 30*24*time.Hour, observability.NewLogger("test"))
+	n, err := service.Purge(ctx)
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 expired whispers purged, got %d", n)
+	}
+	left, err := service.Conversation(ctx, "Friend", 10)
+	if err != nil {
+		t.Fatalf("Conversation failed: %v", err)
+	}
+	if len(left) != 1 {
+		t.Errorf("expected 1 whisper left, got %d", len(left))
+	}
+}
+
+func TestWhisperHandler(t *testing.T) {
+	ctx, _, whisperRepo := setupWhisperTest(t)
+
+	now := time.Now().UTC()
+	for i, text := range []string{"first", "second"} {
+		w := &repository.Whisper{
+			Account: "archiver", Counterpart: "friend", CounterpartDisplayName: "Friend", Text: text,
+			ThreadID: "11_22", TwitchMessageID: string(rune('1' + i)), ReceivedAt: now.Add(time.Duration(i) * time.Second),
+		}
+		if err := whisperRepo.Create(ctx, w); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	templates := templateFromRepoFiles(t, "internal/http/templates/partials/error.html")
+	handler := handlers.NewWhisperHandler(
+		services.NewWhisperService(whisperRepo, 30*24*time.Hour, observability.NewLogger("test")),
+		templates,
+		observability.NewLogger("test"),
+	)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		req.Header.Set("Accept", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	var list struct {
+		Conversations []dto.WhisperConversation `json:"conversations"`
+		RetentionDays int                       `json:"retention_days"`
+	}
+	if err := json.NewDecoder(get("/whispers").Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode conversations: %v", err)
+	}
+	if len(list.Conversations) != 1 || list.Conversations[0].TotalWhispers != 2 || list.RetentionDays != 30 {
+		t.Errorf("unexpected conversations: %+v", list)
+	}
+
+	var thread struct {
+		Whispers []dto.Whisper `json:"whispers"`
+	}
+	if err := json.NewDecoder(get("/whispers/Friend").Body).Decode(&thread); err != nil {
+		t.Fatalf("failed to decode whispers: %v", err)
+	}
+	if len(thread.Whispers) != 2 || thread.Whispers[0].Text != "first" || thread.Whispers[1].Text != "second" {
+		t.Errorf("expected both whispers oldest first, got %+v", thread.Whispers)
+	}
+
+	if resp := get("/whispers/nobody"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown counterpart, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/services"
+)
+
+func TestSentEchoesMatchesEachSentMessageOnce(t *testing.T) {
+	echoes := services.NewSentEchoes()
+	echoes.Sent(services.SentMessage{Channel: "alpha", Username: "archiver", Text: "hello chat", SentAt: time.Now()})
+
+	tests := []struct {
+		name     string
+		channel  string
+		username string
+		text     string
+		id       string
+		want     bool
+	}{
+		{"other channel", "#beta", "archiver", "hello chat", "m1", false},
+		{"other user", "#alpha", "viewer", "hello chat", "m2", false},
+		{"other text", "#alpha", "archiver", "hello", "m3", false},
+		{"echo", "#alpha", "Archiver", "hello chat", "m4", true},
+		{"echo on another connection", "#alpha", "archiver", "hello chat", "m4", true},
+		{"same text sent elsewhere", "#alpha", "archiver", "hello chat", "m5", false},
+	}
+	for _, tt := range tests {
+		if got := echoes.IsEcho(tt.channel, tt.username, tt.text, tt.id); got != tt.want {
+			t.Errorf("%s: expected IsEcho %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSentEchoesForgetsOldMessages(t *testing.T) {
+	echoes := services.NewSentEchoes()
+	echoes.Sent(services.SentMessage{Channel: "alpha", Username: "archiver", Text: "long ago", SentAt: time.Now().Add(-time.Hour)})
+	echoes.Sent(services.SentMessage{Channel: "alpha", Username: "archiver", Text: "just now", SentAt: time.Now()})
+
+	if echoes.IsEcho("#alpha", "archiver", "long ago", "m1") {
+		t.Error("expected a message sent an hour ago not to match")
+	}
+	if !echoes.IsEcho("#alpha", "archiver", "just now", "m2") {
+		t.Error("expected the recent message to match its echo")
+	}
+}
@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/asabla/goknut/internal/http/render"
+)
+
+func TestChatText(t *testing.T) {
+	cdn := render.NewChat("https://cdn.example/{id}/1.0", "")
+	offline := render.NewChat("", "")
+
+	tests := []struct {
+		name      string
+		chat      *render.Chat
+		text      string
+		tags      map[string]string
+		highlight []string
+		want      string
+	}{
+		{
+			name: "no emotes is escaped",
+			chat: cdn,
+			text: "<b>hi</b>",
+			want: "&lt;b&gt;hi&lt;/b&gt;",
+		},
+		{
+			name: "emote image",
+			chat: cdn,
+			text: "hi Kappa",
+			tags: map[string]string{"emotes": "25:3-7"},
+			want: `hi <img class="chat-emote" src="https://cdn.example/25/1.0" alt="Kappa" title="Kappa">`,
+		},
+		{
+			name: "offline text fallback",
+			chat: offline,
+			text: "Kappa hi",
+			tags: map[string]string{"emotes": "25:0-4"},
+			want: `<span class="chat-emote-text" title="Kappa">Kappa</span> hi`,
+		},
+		{
+			name: "rune offsets",
+			chat: offline,
+			text: "é LUL",
+			tags: map[string]string{"emotes": "425618:2-4"},
+			want: `é <span class="chat-emote-text" title="LUL">LUL</span>`,
+		},
+		{
+			name:      "highlight skips emotes",
+			chat:      offline,
+			text:      "Kappa kappa",
+			tags:      map[string]string{"emotes": "25:0-4"},
+			highlight: []string{"kappa"},
+			want:      `<span class="chat-emote-text" title="Kappa">Kappa</span> <mark>kappa</mark>`,
+		},
+		{
+			name: "out of range and overlapping ranges are ignored",
+			chat: offline,
+			text: "Kappa",
+			tags: map[string]string{"emotes": "25:0-4,2-3/1:3-20"},
+			want: `<span class="chat-emote-text" title="Kappa">Kappa</span>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.chat.Text(tt.text, tt.tags, tt.highlight...)); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatBadges(t *testing.T) {
+	tags := map[string]string{"badges": "moderator/1,subscriber/12"}
+
+	offline := string(render.NewChat("", "").Badges(tags))
+	want := `<span class="chat-badge-text" title="moderator/1">MOD</span>` +
+		`<span class="chat-badge-text" title="subscriber/12">SUB</span>`
+	if offline != want {
+		t.Errorf("Badges() offline = %q, want %q", offline, want)
+	}
+
+	images := string(render.NewChat("", "https://cdn.example/{name}/{version}").Badges(tags))
+	want = `<img class="chat-badge" src="https://cdn.example/moderator/1" alt="moderator/1" title="moderator/1">` +
+		`<img class="chat-badge" src="https://cdn.example/subscriber/12" alt="subscriber/12" title="subscriber/12">`
+	if images != want {
+		t.Errorf("Badges() images = %q, want %q", images, want)
+	}
+
+	if got := render.NewChat("", "").Badges(nil); got != "" {
+		t.Errorf("expected no badges for nil tags, got %q", got)
+	}
+}
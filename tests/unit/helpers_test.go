@@ -243,6 +243,92 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "priority overflow policy with channel priorities",
+			cfg: &config.Config{
+				DBDriver:          config.DBDriverSQLite,
+				DBPath:            "./test.db",
+				HTTPAddr:          ":8080",
+				TwitchAuthMode:    config.AuthModeAnonymous,
+				BatchSize:         100,
+				FlushTimeout:      100,
+				BufferSize:        10000,
+				OverflowPolicy:    config.OverflowPriority,
+				OverflowWait:      250,
+				ChannelPriorities: []string{"bigchannel=high", "#spammy=low"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative writers",
+			cfg: &config.Config{
+				DBDriver:       config.DBDriverSQLite,
+				DBPath:         "./test.db",
+				HTTPAddr:       ":8080",
+				TwitchAuthMode: config.AuthModeAnonymous,
+				BatchSize:      100,
+				FlushTimeout:   100,
+				BufferSize:     10000,
+				Writers:        -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown overflow policy",
+			cfg: &config.Config{
+				DBDriver:       config.DBDriverSQLite,
+				DBPath:         "./test.db",
+				HTTPAddr:       ":8080",
+				TwitchAuthMode: config.AuthModeAnonymous,
+				BatchSize:      100,
+				FlushTimeout:   100,
+				BufferSize:     10000,
+				OverflowPolicy: "shed",
+			},
+			wantErr: true,
+		},
+		{
+			name: "block overflow policy requires a wait",
+			cfg: &config.Config{
+				DBDriver:       config.DBDriverSQLite,
+				DBPath:         "./test.db",
+				HTTPAddr:       ":8080",
+				TwitchAuthMode: config.AuthModeAnonymous,
+				BatchSize:      100,
+				FlushTimeout:   100,
+				BufferSize:     10000,
+				OverflowPolicy: config.OverflowBlock,
+			},
+			wantErr: true,
+		},
+		{
+			name: "channel priority without a level",
+			cfg: &config.Config{
+				DBDriver:          config.DBDriverSQLite,
+				DBPath:            "./test.db",
+				HTTPAddr:          ":8080",
+				TwitchAuthMode:    config.AuthModeAnonymous,
+				BatchSize:         100,
+				FlushTimeout:      100,
+				BufferSize:        10000,
+				ChannelPriorities: []string{"bigchannel"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "channel priority with an unknown level",
+			cfg: &config.Config{
+				DBDriver:          config.DBDriverSQLite,
+				DBPath:            "./test.db",
+				HTTPAddr:          ":8080",
+				TwitchAuthMode:    config.AuthModeAnonymous,
+				BatchSize:         100,
+				FlushTimeout:      100,
+				BufferSize:        10000,
+				ChannelPriorities: []string{"bigchannel=urgent"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
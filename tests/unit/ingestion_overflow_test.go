@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/ingestion"
+)
+
+func TestOverflowPolicyAdmit(t *testing.T) {
+	priority := ingestion.PriorityPolicy{
+		Priorities: map[string]ingestion.Priority{
+			"bigchannel": ingestion.PriorityHigh,
+			"spammy":     ingestion.PriorityLow,
+		},
+		Wait: 200 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name     string
+		policy   ingestion.OverflowPolicy
+		channel  string
+		queued   int
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{"drop never waits", ingestion.DropPolicy{}, "#anychannel", 100, 0, true},
+		{"block waits on a full buffer", ingestion.BlockPolicy{Timeout: time.Second}, "#anychannel", 100, time.Second, true},
+		{"low priority queued below half", priority, "#spammy", 49, 0, true},
+		{"low priority shed from half", priority, "#spammy", 50, 0, false},
+		{"normal priority queued below 90%", priority, "#otherchannel", 89, 0, true},
+		{"normal priority shed from 90%", priority, "#otherchannel", 90, 0, false},
+		{"whispers are normal priority", priority, "", 90, 0, false},
+		{"high priority waits on a full buffer", priority, "#BigChannel", 100, 200 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := tt.policy.Admit(tt.channel, tt.queued, 100)
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Errorf("Admit(%q, %d, 100) = (%v, %v), want (%v, %v)",
+					tt.channel, tt.queued, wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, name := range []string{"low", "normal", "high"} {
+		priority, err := ingestion.ParsePriority(name)
+		if err != nil {
+			t.Fatalf("ParsePriority(%q) failed: %v", name, err)
+		}
+		if priority.String() != name {
+			t.Errorf("ParsePriority(%q) = %v", name, priority)
+		}
+	}
+	if _, err := ingestion.ParsePriority("urgent"); err == nil {
+		t.Error("expected an error for an unknown priority")
+	}
+}
@@ -0,0 +1,292 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+const joinTestNick = "justinfan123"
+
+// scriptedConn is the server end of a client connection. It welcomes the
+// login and answers PINGs; everything else is up to the test.
+type scriptedConn struct {
+	conn  net.Conn
+	lines chan string
+}
+
+func (c *scriptedConn) readLoop() {
+	defer close(c.lines)
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "NICK "):
+			c.send(":tmi.twitch.tv 001 " + joinTestNick + " :Welcome, GLHF!")
+		case strings.HasPrefix(line, "PING "):
+			c.send(":tmi.twitch.tv PONG tmi.twitch.tv")
+		}
+		c.lines <- line
+	}
+}
+
+func (c *scriptedConn) send(line string) {
+	c.conn.Write([]byte(line + "\r\n"))
+}
+
+// expect returns the next line from the client starting with prefix,
+// skipping others.
+func (c *scriptedConn) expect(t *testing.T, prefix string) string {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				t.Fatalf("connection closed waiting for %q", prefix)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q", prefix)
+		}
+	}
+}
+
+// refute fails if the client sends a line starting with prefix within d.
+func (c *scriptedConn) refute(t *testing.T, prefix string, d time.Duration) {
+	t.Helper()
+	timeout := time.After(d)
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return
+			}
+			if strings.HasPrefix(line, prefix) {
+				t.Fatalf("expected no %q, got %q", prefix, line)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// joinTestClient is an anonymous client whose connections are served by
+// the test.
+type joinTestClient struct {
+	*irc.Client
+	conns chan *scriptedConn
+
+	mu      sync.Mutex
+	results []irc.JoinResult
+}
+
+func newJoinTestClient(t *testing.T, joinTimeout time.Duration) (*joinTestClient, *scriptedConn) {
+	t.Helper()
+	jc := &joinTestClient{conns: make(chan *scriptedConn, 4)}
+	jc.Client = irc.NewClient(irc.ClientConfig{
+		AuthMode:    irc.AuthModeAnonymous,
+		Username:    joinTestNick,
+		DisableTLS:  true,
+		JoinTimeout: joinTimeout,
+		OnJoin: func(result irc.JoinResult) {
+			jc.mu.Lock()
+			defer jc.mu.Unlock()
+			jc.results = append(jc.results, result)
+		},
+		Dialer: irc.DialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			client, server := net.Pipe()
+			conn := &scriptedConn{conn: server, lines: make(chan string, 100)}
+			go conn.readLoop()
+			jc.conns <- conn
+			return client, nil
+		}),
+	})
+	t.Cleanup(func() { jc.Disconnect() })
+
+	if err := jc.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	return jc, jc.nextConn(t)
+}
+
+func (jc *joinTestClient) nextConn(t *testing.T) *scriptedConn {
+	t.Helper()
+	select {
+	case conn := <-jc.conns:
+		return conn
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a connection")
+		return nil
+	}
+}
+
+func (jc *joinTestClient) joinResults() []irc.JoinResult {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	return append([]irc.JoinResult(nil), jc.results...)
+}
+
+// waitForJoinState waits for channel to reach state and returns its status.
+func waitForJoinState(t *testing.T, client *joinTestClient, channel string, state irc.JoinState) irc.JoinStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, _ := client.JoinStatus(channel)
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be %s, got %q", channel, state, status.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJoinQueueConfirmsJoin(t *testing.T) {
+	tests := []struct {
+		name    string
+		confirm string
+	}{
+		{
+			name:    "JOIN echo",
+			confirm: ":" + joinTestNick + "!" + joinTestNick + "@" + joinTestNick + ".tmi.twitch.tv JOIN #alpha",
+		},
+		{
+			name:    "ROOMSTATE",
+			confirm: "@emote-only=0;room-id=12345678;slow=0 :tmi.twitch.tv ROOMSTATE #alpha",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, conn := newJoinTestClient(t, time.Hour)
+			if err := client.Join("Alpha"); err != nil {
+				t.Fatalf("Join failed: %v", err)
+			}
+			conn.expect(t, "JOIN #alpha")
+			waitForJoinState(t, client, "alpha", irc.JoinStateRequested)
+
+			conn.send(tt.confirm)
+			status := waitForJoinState(t, client, "alpha", irc.JoinStateJoined)
+			if status.Attempts != 1 || status.Error != "" {
+				t.Errorf("expected joined on the first attempt, got %+v", status)
+			}
+			if depth := client.JoinQueueDepth(); depth != 0 {
+				t.Errorf("expected an empty join queue, got %d", depth)
+			}
+			results := client.joinResults()
+			if len(results) != 1 || results[0].Channel != "#alpha" || results[0].Err != nil {
+				t.Errorf("expected one successful join reported, got %+v", results)
+			}
+		})
+	}
+}
+
+func TestJoinQueueFailsOnNotice(t *testing.T) {
+	for _, msgID := range []string{"msg_channel_suspended", "msg_banned"} {
+		t.Run(msgID, func(t *testing.T) {
+			client, conn := newJoinTestClient(t, time.Hour)
+			if err := client.Join("alpha"); err != nil {
+				t.Fatalf("Join failed: %v", err)
+			}
+			conn.expect(t, "JOIN #alpha")
+
+			conn.send("@msg-id=" + msgID + " :tmi.twitch.tv NOTICE #alpha :You cannot join #alpha.")
+			status := waitForJoinState(t, client, "alpha", irc.JoinStateFailed)
+			if !strings.HasPrefix(status.Error, msgID+":") {
+				t.Errorf("expected the msg-id as the error, got %q", status.Error)
+			}
+			results := client.joinResults()
+			if len(results) != 1 || results[0].Err == nil {
+				t.Errorf("expected one failed join reported, got %+v", results)
+			}
+
+			// Refused channels aren't retried until the next connection
+			conn.refute(t, "JOIN", 50*time.Millisecond)
+		})
+	}
+}
+
+func TestJoinQueueRequeuesOnTimeout(t *testing.T) {
+	client, conn := newJoinTestClient(t, 50*time.Millisecond)
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	// Unconfirmed, the JOIN is sent again
+	conn.expect(t, "JOIN #alpha")
+	conn.expect(t, "JOIN #alpha")
+	conn.send("@room-id=12345678 :tmi.twitch.tv ROOMSTATE #alpha")
+
+	status := waitForJoinState(t, client, "alpha", irc.JoinStateJoined)
+	if status.Attempts != 2 {
+		t.Errorf("expected joined on the second attempt, got %d", status.Attempts)
+	}
+	results := client.joinResults()
+	if len(results) != 1 || results[0].Attempts != 2 || results[0].Err != nil {
+		t.Errorf("expected one successful join after 2 attempts reported, got %+v", results)
+	}
+}
+
+func TestJoinQueueFailsAfterMaxAttempts(t *testing.T) {
+	client, conn := newJoinTestClient(t, 20*time.Millisecond)
+	if err := client.Join("alpha"); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+
+	for range 3 {
+		conn.expect(t, "JOIN #alpha")
+	}
+	status := waitForJoinState(t, client, "alpha", irc.JoinStateFailed)
+	if status.Attempts != 3 || !strings.Contains(status.Error, "no confirmation after 3 attempts") {
+		t.Errorf("expected failure after 3 attempts, got %+v", status)
+	}
+	conn.refute(t, "JOIN", 100*time.Millisecond)
+
+	results := client.joinResults()
+	if len(results) != 1 || results[0].Err == nil || results[0].Attempts != 3 {
+		t.Errorf("expected one failed join after 3 attempts reported, got %+v", results)
+	}
+}
+
+func TestJoinQueueRetriesFailedChannelsOnReconnect(t *testing.T) {
+	client, conn := newJoinTestClient(t, time.Hour)
+	for _, ch := range []string{"alpha", "beta"} {
+		if err := client.Join(ch); err != nil {
+			t.Fatalf("Join failed: %v", err)
+		}
+	}
+	conn.expect(t, "JOIN #alpha")
+	conn.send("@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #alpha :This channel has been suspended.")
+	conn.expect(t, "JOIN #beta")
+	conn.send("@room-id=2 :tmi.twitch.tv ROOMSTATE #beta")
+	waitForJoinState(t, client, "alpha", irc.JoinStateFailed)
+	waitForJoinState(t, client, "beta", irc.JoinStateJoined)
+
+	// Reconnects right away, joining every channel afresh
+	conn.send(":tmi.twitch.tv RECONNECT")
+	next := client.nextConn(t)
+	next.expect(t, "JOIN #alpha")
+	next.send("@room-id=1 :tmi.twitch.tv ROOMSTATE #alpha")
+	next.expect(t, "JOIN #beta")
+	next.send("@room-id=2 :tmi.twitch.tv ROOMSTATE #beta")
+
+	status := waitForJoinState(t, client, "alpha", irc.JoinStateJoined)
+	if status.Attempts != 1 || status.Error != "" {
+		t.Errorf("expected the retried channel joined on a fresh first attempt, got %+v", status)
+	}
+	waitForJoinState(t, client, "beta", irc.JoinStateJoined)
+}
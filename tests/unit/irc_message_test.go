@@ -0,0 +1,266 @@
+package unit
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+// loadIRCFixtures returns the raw lines in testdata/irc_lines.txt.
+func loadIRCFixtures(tb testing.TB) []string {
+	tb.Helper()
+
+	f, err := os.Open("testdata/irc_lines.txt")
+	if err != nil {
+		tb.Fatalf("failed to open fixtures: %v", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		tb.Fatalf("failed to read fixtures: %v", err)
+	}
+	return lines
+}
+
+func TestParseRawMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantNil     bool
+		wantTags    irc.Tags
+		wantPrefix  string
+		wantCommand string
+		wantParams  []string
+		wantNick    string
+	}{
+		{
+			name:        "ping",
+			line:        "PING :tmi.twitch.tv",
+			wantTags:    irc.Tags{},
+			wantCommand: "PING",
+			wantParams:  []string{"tmi.twitch.tv"},
+		},
+		{
+			name:        "privmsg with escaped tags",
+			line:        `@display-name=Some\sUser;flags=;system-msg=a\:b\\c\r\n;trailing=x\ :user!user@user.tmi.twitch.tv PRIVMSG #Chan :hello  there`,
+			wantTags:    irc.Tags{"display-name": "Some User", "flags": "", "system-msg": "a;b\\c\r\n", "trailing": "x"},
+			wantPrefix:  "user!user@user.tmi.twitch.tv",
+			wantCommand: "PRIVMSG",
+			wantParams:  []string{"#Chan", "hello  there"},
+			wantNick:    "user",
+		},
+		{
+			name:        "key without value and duplicate key",
+			line:        "@a;b=1;b=2 :tmi.twitch.tv ROOMSTATE #chan",
+			wantTags:    irc.Tags{"a": "", "b": "2"},
+			wantPrefix:  "tmi.twitch.tv",
+			wantCommand: "ROOMSTATE",
+			wantParams:  []string{"#chan"},
+		},
+		{
+			name:        "middle params and empty trailing",
+			line:        ":tmi.twitch.tv CAP * ACK :",
+			wantTags:    irc.Tags{},
+			wantPrefix:  "tmi.twitch.tv",
+			wantCommand: "CAP",
+			wantParams:  []string{"*", "ACK", ""},
+		},
+		{
+			name:        "extra spaces between components",
+			line:        ":tmi.twitch.tv   CLEARCHAT   #chan   :target",
+			wantTags:    irc.Tags{},
+			wantPrefix:  "tmi.twitch.tv",
+			wantCommand: "CLEARCHAT",
+			wantParams:  []string{"#chan", "target"},
+		},
+		{
+			name:    "tags only",
+			line:    "@a=b",
+			wantNil: true,
+		},
+		{
+			name:    "empty line",
+			line:    "",
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := irc.ParseRawMessage(tt.line)
+			if tt.wantNil {
+				if msg != nil {
+					t.Fatalf("expected nil, got %+v", msg)
+				}
+				return
+			}
+			if msg == nil {
+				t.Fatal("expected message, got nil")
+			}
+			if !reflect.DeepEqual(msg.Tags, tt.wantTags) {
+				t.Errorf("Tags = %q, want %q", msg.Tags, tt.wantTags)
+			}
+			if msg.Prefix != tt.wantPrefix {
+				t.Errorf("Prefix = %q, want %q", msg.Prefix, tt.wantPrefix)
+			}
+			if msg.Command != tt.wantCommand {
+				t.Errorf("Command = %q, want %q", msg.Command, tt.wantCommand)
+			}
+			if !reflect.DeepEqual(msg.Params, tt.wantParams) {
+				t.Errorf("Params = %q, want %q", msg.Params, tt.wantParams)
+			}
+			if msg.Nick() != tt.wantNick {
+				t.Errorf("Nick() = %q, want %q", msg.Nick(), tt.wantNick)
+			}
+		})
+	}
+}
+
+func TestParseMessageUnescapesTags(t *testing.T) {
+	msg := irc.ParseMessage(`@display-name=Some\sUser;id=abc :someuser!someuser@someuser.tmi.twitch.tv PRIVMSG #SomeChannel :hi :)`)
+	if msg == nil {
+		t.Fatal("expected message, got nil")
+	}
+	if msg.Channel != "#somechannel" || msg.Username != "someuser" || msg.Text != "hi :)" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if msg.DisplayName != "Some User" || msg.Tags["display-name"] != "Some User" {
+		t.Errorf("expected unescaped display name, got %q / %q", msg.DisplayName, msg.Tags["display-name"])
+	}
+
+	if !msg.SentAt.IsZero() {
+		t.Errorf("expected zero SentAt without tmi-sent-ts, got %v", msg.SentAt)
+	}
+
+	stamped := irc.ParseMessage(`@tmi-sent-ts=1507246572675 :someuser!someuser@someuser.tmi.twitch.tv PRIVMSG #chan :hi`)
+	if stamped == nil || !stamped.SentAt.Equal(time.UnixMilli(1507246572675)) {
+		t.Errorf("expected SentAt from tmi-sent-ts, got %+v", stamped)
+	}
+
+	if irc.ParseMessage(":tmi.twitch.tv CLEARCHAT #chan :user") != nil {
+		t.Error("expected nil for non-PRIVMSG line")
+	}
+}
+
+func TestTagAccessors(t *testing.T) {
+	tags := irc.Tags{
+		"badges":      "broadcaster/1,subscriber/12",
+		"badge-info":  "subscriber/14",
+		"emotes":      "25:0-4,12-16/1902:6-10/bad:x-1",
+		"color":       "#1E90FF",
+		"user-id":     "1337",
+		"room-id":     "42",
+		"tmi-sent-ts": "1507246572675",
+		"bits":        "100",
+	}
+
+	wantBadges := []irc.Badge{{Name: "broadcaster", Version: "1"}, {Name: "subscriber", Version: "12"}}
+	if got := tags.Badges(); !reflect.DeepEqual(got, wantBadges) {
+		t.Errorf("Badges() = %+v, want %+v", got, wantBadges)
+	}
+	if got := tags.BadgeInfo(); len(got) != 1 || got[0].Version != "14" {
+		t.Errorf("BadgeInfo() = %+v", got)
+	}
+
+	wantEmotes := []irc.Emote{
+		{ID: "25", Ranges: []irc.EmoteRange{{Start: 0, End: 4}, {Start: 12, End: 16}}},
+		{ID: "1902", Ranges: []irc.EmoteRange{{Start: 6, End: 10}}},
+	}
+	if got := tags.Emotes(); !reflect.DeepEqual(got, wantEmotes) {
+		t.Errorf("Emotes() = %+v, want %+v", got, wantEmotes)
+	}
+
+	if tags.Color() != "#1E90FF" || tags.UserID() != "1337" || tags.RoomID() != "42" || tags.Bits() != 100 {
+		t.Errorf("unexpected scalar accessors: color=%q user=%q room=%q bits=%d",
+			tags.Color(), tags.UserID(), tags.RoomID(), tags.Bits())
+	}
+
+	sentAt, ok := tags.SentAt()
+	if !ok || !sentAt.Equal(time.UnixMilli(1507246572675)) {
+		t.Errorf("SentAt() = %v, %v", sentAt, ok)
+	}
+
+	empty := irc.Tags{"bits": "lots", "tmi-sent-ts": "soon"}
+	if empty.Badges() != nil || empty.Emotes() != nil || empty.Bits() != 0 {
+		t.Error("expected zero values for missing or malformed tags")
+	}
+	if _, ok := empty.SentAt(); ok {
+		t.Error("expected SentAt to fail for malformed tmi-sent-ts")
+	}
+}
+
+func TestParseRawMessageFixtures(t *testing.T) {
+	for _, line := range loadIRCFixtures(t) {
+		msg := irc.ParseRawMessage(line)
+		if msg == nil {
+			t.Errorf("failed to parse fixture: %s", line)
+			continue
+		}
+		if again := irc.ParseRawMessage(msg.String()); !reflect.DeepEqual(again, msg) {
+			t.Errorf("round trip mismatch for %q:\n got  %+v\n want %+v", line, again, msg)
+		}
+	}
+}
+
+// FuzzParseRawMessage checks that parsing never panics and that any parsed
+// message survives a serialize/parse round trip unchanged.
+func FuzzParseRawMessage(f *testing.F) {
+	for _, line := range loadIRCFixtures(f) {
+		f.Add(line)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		msg := irc.ParseRawMessage(line)
+		if msg == nil {
+			return
+		}
+		again := irc.ParseRawMessage(msg.String())
+		if !reflect.DeepEqual(again, msg) {
+			t.Fatalf("round trip mismatch for %q via %q:\n got  %+v\n want %+v", line, msg.String(), again, msg)
+		}
+
+		// Typed accessors must tolerate arbitrary tag values
+		msg.Tags.Badges()
+		msg.Tags.Emotes()
+		msg.Tags.SentAt()
+		msg.Tags.Bits()
+		irc.ParseMessage(line)
+		irc.ParseNotice(line)
+		irc.ParseModerationEvent(line)
+		irc.ParseRoomState(line)
+	})
+}
+
+func TestEmoteRangeText(t *testing.T) {
+	tests := []struct {
+		text string
+		rng  irc.EmoteRange
+		want string
+	}{
+		{"Kappa hi", irc.EmoteRange{Start: 0, End: 4}, "Kappa"},
+		{"héllo LUL", irc.EmoteRange{Start: 6, End: 8}, "LUL"},
+		{"🙂 Kappa", irc.EmoteRange{Start: 2, End: 6}, "Kappa"},
+		{"short", irc.EmoteRange{Start: 3, End: 9}, ""},
+		{"short", irc.EmoteRange{Start: 3, End: 1}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rng.Text(tt.text); got != tt.want {
+			t.Errorf("%+v.Text(%q) = %q, want %q", tt.rng, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+func TestParseModerationEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		wantNil      bool
+		wantAction   irc.ModerationAction
+		wantChannel  string
+		wantTarget   string
+		wantMsgID    string
+		wantDuration time.Duration
+		wantText     string
+		wantSentAt   time.Time
+	}{
+		{
+			name:         "timeout",
+			line:         "@ban-duration=600;room-id=12345;target-user-id=67890;tmi-sent-ts=1700000000000 :tmi.twitch.tv CLEARCHAT #SomeChannel :BadUser",
+			wantAction:   irc.ModerationTimeout,
+			wantChannel:  "#somechannel",
+			wantTarget:   "baduser",
+			wantDuration: 10 * time.Minute,
+			wantSentAt:   time.UnixMilli(1700000000000).UTC(),
+		},
+		{
+			name:        "permanent ban",
+			line:        "@room-id=12345;target-user-id=67890;tmi-sent-ts=1700000000000 :tmi.twitch.tv CLEARCHAT #somechannel :baduser",
+			wantAction:  irc.ModerationBan,
+			wantChannel: "#somechannel",
+			wantTarget:  "baduser",
+		},
+		{
+			name:        "chat cleared",
+			line:        "@room-id=12345;tmi-sent-ts=1700000000000 :tmi.twitch.tv CLEARCHAT #somechannel",
+			wantAction:  irc.ModerationClear,
+			wantChannel: "#somechannel",
+		},
+		{
+			name:        "single message deleted",
+			line:        "@login=baduser;room-id=;target-msg-id=abc-123;tmi-sent-ts=1700000000000 :tmi.twitch.tv CLEARMSG #somechannel :spam spam spam",
+			wantAction:  irc.ModerationDelete,
+			wantChannel: "#somechannel",
+			wantTarget:  "baduser",
+			wantMsgID:   "abc-123",
+			wantText:    "spam spam spam",
+			wantSentAt:  time.UnixMilli(1700000000000).UTC(),
+		},
+		{
+			name:    "clearmsg without target id",
+			line:    "@login=baduser :tmi.twitch.tv CLEARMSG #somechannel :spam",
+			wantNil: true,
+		},
+		{
+			name:    "missing channel",
+			line:    ":tmi.twitch.tv CLEARCHAT",
+			wantNil: true,
+		},
+		{
+			name:    "not a moderation command",
+			line:    ":user!user@user.tmi.twitch.tv PRIVMSG #somechannel :hello",
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := irc.ParseModerationEvent(tt.line)
+			if tt.wantNil {
+				if evt != nil {
+					t.Fatalf("expected nil, got %+v", evt)
+				}
+				return
+			}
+			if evt == nil {
+				t.Fatal("expected event, got nil")
+			}
+			if evt.Action != tt.wantAction {
+				t.Errorf("action = %q, want %q", evt.Action, tt.wantAction)
+			}
+			if evt.Channel != tt.wantChannel {
+				t.Errorf("channel = %q, want %q", evt.Channel, tt.wantChannel)
+			}
+			if evt.TargetUsername != tt.wantTarget {
+				t.Errorf("target = %q, want %q", evt.TargetUsername, tt.wantTarget)
+			}
+			if evt.TargetMessageID != tt.wantMsgID {
+				t.Errorf("target message id = %q, want %q", evt.TargetMessageID, tt.wantMsgID)
+			}
+			if evt.Duration != tt.wantDuration {
+				t.Errorf("duration = %v, want %v", evt.Duration, tt.wantDuration)
+			}
+			if evt.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", evt.Text, tt.wantText)
+			}
+			if !tt.wantSentAt.IsZero() && !evt.SentAt.Equal(tt.wantSentAt) {
+				t.Errorf("sent at = %v, want %v", evt.SentAt, tt.wantSentAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+func TestParseNotice(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantNil     bool
+		wantKind    string
+		wantUser    string
+		wantSystem  string
+		wantText    string
+		wantParams  map[string]string
+		wantChannel string
+	}{
+		{
+			name:        "resub with message",
+			line:        `@badge-info=subscriber/6;display-name=Ronni;login=ronni;msg-id=resub;msg-param-cumulative-months=6;msg-param-sub-plan=Prime;system-msg=ronni\shas\ssubscribed\sfor\s6\smonths! :tmi.twitch.tv USERNOTICE #Dallas :Great stream -- keep it up!`,
+			wantKind:    irc.NoticeResub,
+			wantUser:    "ronni",
+			wantSystem:  "ronni has subscribed for 6 months!",
+			wantText:    "Great stream -- keep it up!",
+			wantParams:  map[string]string{"cumulative-months": "6", "sub-plan": "Prime"},
+			wantChannel: "#dallas",
+		},
+		{
+			name:        "raid without message",
+			line:        `@display-name=TestChannel;login=testchannel;msg-id=raid;msg-param-displayName=TestChannel;msg-param-viewerCount=15;system-msg=15\sraiders\sfrom\sTestChannel\shave\sjoined! :tmi.twitch.tv USERNOTICE #othertestchannel`,
+			wantKind:    irc.NoticeRaid,
+			wantUser:    "testchannel",
+			wantSystem:  "15 raiders from TestChannel have joined!",
+			wantParams:  map[string]string{"displayName": "TestChannel", "viewerCount": "15"},
+			wantChannel: "#othertestchannel",
+		},
+		{
+			name:        "gift sub escaped semicolon",
+			line:        `@login=gifter;msg-id=subgift;msg-param-recipient-user-name=lucky;system-msg=a\:b\\c :tmi.twitch.tv USERNOTICE #chan`,
+			wantKind:    irc.NoticeSubGift,
+			wantUser:    "gifter",
+			wantSystem:  `a;b\c`,
+			wantParams:  map[string]string{"recipient-user-name": "lucky"},
+			wantChannel: "#chan",
+		},
+		{
+			name:    "missing msg-id",
+			line:    `@login=someone :tmi.twitch.tv USERNOTICE #chan`,
+			wantNil: true,
+		},
+		{
+			name:    "not a usernotice",
+			line:    `:tmi.twitch.tv CLEARCHAT #chan :someone`,
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := irc.ParseNotice(tt.line)
+			if tt.wantNil {
+				if n != nil {
+					t.Fatalf("expected nil, got %+v", n)
+				}
+				return
+			}
+			if n == nil {
+				t.Fatal("expected notice, got nil")
+			}
+			if n.Kind != tt.wantKind {
+				t.Errorf("kind = %q, want %q", n.Kind, tt.wantKind)
+			}
+			if n.Channel != tt.wantChannel {
+				t.Errorf("channel = %q, want %q", n.Channel, tt.wantChannel)
+			}
+			if n.Username != tt.wantUser {
+				t.Errorf("username = %q, want %q", n.Username, tt.wantUser)
+			}
+			if n.SystemMsg != tt.wantSystem {
+				t.Errorf("system msg = %q, want %q", n.SystemMsg, tt.wantSystem)
+			}
+			if n.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", n.Text, tt.wantText)
+			}
+			if len(n.Params) != len(tt.wantParams) {
+				t.Errorf("params = %v, want %v", n.Params, tt.wantParams)
+			}
+			for k, v := range tt.wantParams {
+				if n.Params[k] != v {
+					t.Errorf("param %s = %q, want %q", k, n.Params[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+func TestRateLimiterBurstThenRate(t *testing.T) {
+	limiter := irc.NewRateLimiter(irc.RateLimit{Count: 3, Per: 3 * time.Second})
+
+	for i := range 3 {
+		if d := limiter.Reserve(); d != 0 {
+			t.Fatalf("join %d: expected no wait within burst, got %v", i, d)
+		}
+	}
+
+	// Tokens come back at one per second; queued joins wait in order
+	for i, want := range []time.Duration{time.Second, 2 * time.Second} {
+		d := limiter.Reserve()
+		if d < want-50*time.Millisecond || d > want {
+			t.Errorf("join %d: expected wait of about %v, got %v", i+3, want, d)
+		}
+	}
+}
+
+func TestRateLimiterAllowRefusesWithoutTakingTokens(t *testing.T) {
+	limiter := irc.NewRateLimiter(irc.RateLimit{Count: 2, Per: 2 * time.Second})
+
+	for i := range 2 {
+		if _, ok := limiter.Allow(); !ok {
+			t.Fatalf("message %d: expected to be allowed within burst", i)
+		}
+	}
+
+	// Refusals leave the bucket alone, so the wait doesn't grow
+	for range 3 {
+		wait, ok := limiter.Allow()
+		if ok {
+			t.Fatal("expected message beyond burst to be refused")
+		}
+		if wait < 950*time.Millisecond || wait > time.Second {
+			t.Errorf("expected retry in about 1s, got %v", wait)
+		}
+	}
+}
+
+func TestRateLimiterWaitStopsOnDone(t *testing.T) {
+	limiter := irc.NewRateLimiter(irc.RateLimit{Count: 1, Per: time.Hour})
+	done := make(chan struct{})
+
+	if !limiter.Wait(done) {
+		t.Fatal("expected first join to go through")
+	}
+	close(done)
+	if limiter.Wait(done) {
+		t.Error("expected Wait to give up once done is closed")
+	}
+}
+
+func TestDefaultJoinLimit(t *testing.T) {
+	auth := irc.DefaultJoinLimit(irc.AuthModeAuthenticated)
+	anon := irc.DefaultJoinLimit(irc.AuthModeAnonymous)
+
+	if auth != irc.AuthenticatedJoinLimit || anon != irc.AnonymousJoinLimit {
+		t.Fatalf("unexpected defaults: authenticated=%+v anonymous=%+v", auth, anon)
+	}
+	if anon.Count >= auth.Count {
+		t.Errorf("expected anonymous limit below authenticated, got %d >= %d", anon.Count, auth.Count)
+	}
+}
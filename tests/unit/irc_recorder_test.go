@@ -0,0 +1,256 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+// readRecording returns every line in the recording at path.
+func readRecording(t *testing.T, path string) []irc.RecordedLine {
+	t.Helper()
+	rec, err := irc.OpenRecording(path)
+	if err != nil {
+		t.Fatalf("OpenRecording failed: %v", err)
+	}
+	defer rec.Close()
+
+	var lines []irc.RecordedLine
+	for {
+		line, err := rec.Next()
+		if errors.Is(err, io.EOF) {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := irc.NewRecorder(irc.RecorderConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	start := time.Date(2026, 10, 15, 12, 0, 0, 123456789, time.UTC)
+	want := []string{
+		":tmi.twitch.tv 001 justinfan1 :Welcome, GLHF!",
+		"@id=a;tmi-sent-ts=1700000000000 :user!user@user.tmi.twitch.tv PRIVMSG #alpha :hello\tthere",
+		"PING :tmi.twitch.tv",
+	}
+	for i, line := range want {
+		recorder.Record(start.Add(time.Duration(i)*time.Second), line)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := readRecording(t, dir)
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d", len(want), len(lines))
+	}
+	for i, line := range lines {
+		if line.Line != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], line.Line)
+		}
+		if at := start.Add(time.Duration(i) * time.Second); !line.At.Equal(at) {
+			t.Errorf("line %d: expected time %v, got %v", i, at, line.At)
+		}
+	}
+}
+
+func TestRecorderRotates(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       irc.RecorderConfig
+		step      time.Duration
+		wantFiles int
+	}{
+		{"by size", irc.RecorderConfig{MaxBytes: 100}, time.Millisecond, 5},
+		{"by age", irc.RecorderConfig{MaxAge: time.Minute}, 30 * time.Second, 5},
+		{"neither", irc.RecorderConfig{}, time.Second, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.cfg.Dir = dir
+			recorder, err := irc.NewRecorder(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewRecorder failed: %v", err)
+			}
+
+			// Each line is about 80 bytes with its timestamp
+			start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+			for i := range 10 {
+				recorder.Record(start.Add(time.Duration(i)*tt.step), ":user!user@user.tmi.twitch.tv PRIVMSG #alpha :hi")
+			}
+			if err := recorder.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*.log.gz"))
+			if len(files) != tt.wantFiles {
+				t.Errorf("expected %d files, got %d", tt.wantFiles, len(files))
+			}
+			// Reading the directory back gets every line in order
+			lines := readRecording(t, dir)
+			if len(lines) != 10 {
+				t.Fatalf("expected 10 lines, got %d", len(lines))
+			}
+			for i := 1; i < len(lines); i++ {
+				if lines[i].At.Before(lines[i-1].At) {
+					t.Errorf("line %d is out of order", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordingEndsAtLastCompleteLine(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := irc.NewRecorder(irc.RecorderConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	// Lines a second apart are flushed as they go, so a recording still
+	// being written can be read
+	start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		recorder.Record(start.Add(time.Duration(i)*time.Second), "PING :tmi.twitch.tv")
+	}
+	if lines := readRecording(t, dir); len(lines) < 2 {
+		t.Errorf("expected the flushed lines while recording, got %d", len(lines))
+	}
+	recorder.Close()
+
+	// A new, still empty file is skipped
+	if err := os.WriteFile(filepath.Join(dir, "irc-29991231T000000.000000000Z.log.gz"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write empty file: %v", err)
+	}
+	if lines := readRecording(t, dir); len(lines) != 3 {
+		t.Errorf("expected 3 lines, got %d", len(lines))
+	}
+}
+
+func TestOpenRecordingRequiresFiles(t *testing.T) {
+	if _, err := irc.OpenRecording(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without recordings")
+	}
+	if _, err := irc.OpenRecording(filepath.Join(t.TempDir(), "missing.log.gz")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestClientReplayDeliversWithoutConnection(t *testing.T) {
+	var messages []irc.Message
+	var events []irc.ServerEvent
+	var whispers []irc.Whisper
+	client := irc.NewClient(irc.ClientConfig{
+		AuthMode:      irc.AuthModeAnonymous,
+		OnMessage:     func(msg irc.Message) { messages = append(messages, msg) },
+		OnServerEvent: func(evt irc.ServerEvent) { events = append(events, evt) },
+		OnWhisper:     func(w irc.Whisper) { whispers = append(whispers, w) },
+	})
+
+	// Lines that act on a connection must not need one
+	for _, line := range []string{
+		":tmi.twitch.tv 001 justinfan1 :Welcome, GLHF!",
+		"PING :tmi.twitch.tv",
+		":tmi.twitch.tv RECONNECT",
+		":tmi.twitch.tv NOTICE * :Login authentication failed",
+		"@id=a;tmi-sent-ts=1700000000000 :user!user@user.tmi.twitch.tv PRIVMSG #alpha :hello\r\n",
+		"@message-id=1;thread-id=1_2 :friend!friend@friend.tmi.twitch.tv WHISPER archiver :psst",
+	} {
+		client.Replay(line)
+	}
+
+	if len(messages) != 1 || messages[0].Channel != "#alpha" || messages[0].Text != "hello" {
+		t.Errorf("expected the PRIVMSG delivered, got %+v", messages)
+	}
+	if len(whispers) != 1 || whispers[0].Text != "psst" {
+		t.Errorf("expected the WHISPER delivered, got %+v", whispers)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected the RECONNECT and auth NOTICE reported, got %+v", events)
+	}
+	if err := client.Err(); err != nil {
+		t.Errorf("expected a replayed auth failure not to stop the client, got %v", err)
+	}
+}
+
+func TestClientReplayRecordingPacing(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := irc.NewRecorder(irc.RecorderConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	// Two seconds of traffic
+	start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		recorder.Record(start.Add(time.Duration(i)*500*time.Millisecond), ":user!user@user.tmi.twitch.tv PRIVMSG #alpha :hi")
+	}
+	recorder.Close()
+
+	tests := []struct {
+		name    string
+		speed   float64
+		atLeast time.Duration
+		within  time.Duration
+	}{
+		{"max speed", 0, 0, time.Second},
+		{"20x", 20, 100 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivered, waits := 0, 0
+			client := irc.NewClient(irc.ClientConfig{
+				AuthMode:  irc.AuthModeAnonymous,
+				OnMessage: func(irc.Message) { delivered++ },
+			})
+			rec, err := irc.OpenRecording(dir)
+			if err != nil {
+				t.Fatalf("OpenRecording failed: %v", err)
+			}
+			defer rec.Close()
+
+			began := time.Now()
+			n, err := client.ReplayRecording(context.Background(), rec, irc.ReplayConfig{
+				Speed: tt.speed,
+				Wait:  func(context.Context) error { waits++; return nil },
+			})
+			elapsed := time.Since(began)
+			if err != nil {
+				t.Fatalf("ReplayRecording failed: %v", err)
+			}
+			if n != 5 || delivered != 5 || waits != 5 {
+				t.Errorf("expected 5 lines replayed, delivered and waited for, got %d, %d, %d", n, delivered, waits)
+			}
+			if elapsed < tt.atLeast || elapsed > tt.within {
+				t.Errorf("expected replay to take between %v and %v, took %v", tt.atLeast, tt.within, elapsed)
+			}
+		})
+	}
+
+	// A cancelled replay stops between lines
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec, err := irc.OpenRecording(dir)
+	if err != nil {
+		t.Fatalf("OpenRecording failed: %v", err)
+	}
+	defer rec.Close()
+	client := irc.NewClient(irc.ClientConfig{AuthMode: irc.AuthModeAnonymous})
+	if n, err := client.ReplayRecording(ctx, rec, irc.ReplayConfig{Speed: 1}); !errors.Is(err, context.Canceled) || n != 0 {
+		t.Errorf("expected a cancelled replay, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+func TestParseRoomState(t *testing.T) {
+	intp := func(n int) *int { return &n }
+	boolp := func(b bool) *bool { return &b }
+
+	tests := []struct {
+		name          string
+		line          string
+		wantNil       bool
+		wantChannel   string
+		wantRoomID    string
+		wantEmoteOnly *bool
+		wantFollowers *int
+		wantR9K       *bool
+		wantSlow      *int
+		wantSubsOnly  *bool
+	}{
+		{
+			name:          "full state after join",
+			line:          `@emote-only=0;followers-only=-1;r9k=0;room-id=12345678;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #Bar`,
+			wantChannel:   "#bar",
+			wantRoomID:    "12345678",
+			wantEmoteOnly: boolp(false),
+			wantFollowers: intp(-1),
+			wantR9K:       boolp(false),
+			wantSlow:      intp(0),
+			wantSubsOnly:  boolp(false),
+		},
+		{
+			name:        "partial slow mode update",
+			line:        `@room-id=12345678;slow=10 :tmi.twitch.tv ROOMSTATE #bar`,
+			wantChannel: "#bar",
+			wantRoomID:  "12345678",
+			wantSlow:    intp(10),
+		},
+		{
+			name:          "followers only and emote only enabled",
+			line:          `@emote-only=1;followers-only=30;room-id=1 :tmi.twitch.tv ROOMSTATE #bar`,
+			wantChannel:   "#bar",
+			wantRoomID:    "1",
+			wantEmoteOnly: boolp(true),
+			wantFollowers: intp(30),
+		},
+		{
+			name:    "not a roomstate",
+			line:    `@room-id=1 :tmi.twitch.tv USERSTATE #bar`,
+			wantNil: true,
+		},
+		{
+			name:    "missing channel",
+			line:    `@room-id=1 :tmi.twitch.tv ROOMSTATE`,
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := irc.ParseRoomState(tt.line)
+			if tt.wantNil {
+				if rs != nil {
+					t.Fatalf("expected nil, got %+v", rs)
+				}
+				return
+			}
+			if rs == nil {
+				t.Fatal("expected room state, got nil")
+			}
+			if rs.Channel != tt.wantChannel {
+				t.Errorf("Channel = %q, want %q", rs.Channel, tt.wantChannel)
+			}
+			if rs.RoomID != tt.wantRoomID {
+				t.Errorf("RoomID = %q, want %q", rs.RoomID, tt.wantRoomID)
+			}
+			checkBoolPtr(t, "EmoteOnly", rs.EmoteOnly, tt.wantEmoteOnly)
+			checkIntPtr(t, "FollowersOnly", rs.FollowersOnly, tt.wantFollowers)
+			checkBoolPtr(t, "R9K", rs.R9K, tt.wantR9K)
+			checkIntPtr(t, "Slow", rs.Slow, tt.wantSlow)
+			checkBoolPtr(t, "SubsOnly", rs.SubsOnly, tt.wantSubsOnly)
+		})
+	}
+}
+
+func checkBoolPtr(t *testing.T, field string, got, want *bool) {
+	t.Helper()
+	if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+		t.Errorf("%s = %v, want %v", field, fmtPtr(got), fmtPtr(want))
+	}
+}
+
+func checkIntPtr(t *testing.T, field string, got, want *int) {
+	t.Helper()
+	if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+		t.Errorf("%s = %v, want %v", field, fmtPtr(got), fmtPtr(want))
+	}
+}
+
+func fmtPtr[T any](p *T) any {
+	if p == nil {
+		return "<nil>"
+	}
+	return *p
+}
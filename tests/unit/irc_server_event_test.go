@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+func TestParseServerEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		wantNil     bool
+		wantKind    irc.ServerEventKind
+		wantChannel string
+		wantMsgID   string
+		wantText    string
+	}{
+		{
+			name:        "channel suspended",
+			line:        `@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #Gone :This channel has been suspended.`,
+			wantKind:    irc.ServerEventNotice,
+			wantChannel: "#gone",
+			wantMsgID:   "msg_channel_suspended",
+			wantText:    "This channel has been suspended.",
+		},
+		{
+			name:     "login authentication failed",
+			line:     `:tmi.twitch.tv NOTICE * :Login authentication failed`,
+			wantKind: irc.ServerEventAuthFailed,
+			wantText: "Login authentication failed",
+		},
+		{
+			name:     "improperly formatted auth",
+			line:     `:tmi.twitch.tv NOTICE * :Improperly formatted auth`,
+			wantKind: irc.ServerEventAuthFailed,
+			wantText: "Improperly formatted auth",
+		},
+		{
+			name:        "rate limit warning is a plain notice",
+			line:        `@msg-id=msg_ratelimit :tmi.twitch.tv NOTICE #bar :Your message was not sent because you are sending messages too quickly.`,
+			wantKind:    irc.ServerEventNotice,
+			wantChannel: "#bar",
+			wantMsgID:   "msg_ratelimit",
+			wantText:    "Your message was not sent because you are sending messages too quickly.",
+		},
+		{
+			name:     "reconnect",
+			line:     `:tmi.twitch.tv RECONNECT`,
+			wantKind: irc.ServerEventReconnect,
+		},
+		{
+			name:    "not a server event",
+			line:    `:tmi.twitch.tv ROOMSTATE #bar`,
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := irc.ParseServerEvent(tt.line)
+			if tt.wantNil {
+				if evt != nil {
+					t.Fatalf("expected nil, got %+v", evt)
+				}
+				return
+			}
+			if evt == nil {
+				t.Fatal("expected event, got nil")
+			}
+			if evt.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", evt.Kind, tt.wantKind)
+			}
+			if evt.Channel != tt.wantChannel {
+				t.Errorf("Channel = %q, want %q", evt.Channel, tt.wantChannel)
+			}
+			if evt.MsgID != tt.wantMsgID {
+				t.Errorf("MsgID = %q, want %q", evt.MsgID, tt.wantMsgID)
+			}
+			if evt.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", evt.Text, tt.wantText)
+			}
+		})
+	}
+}
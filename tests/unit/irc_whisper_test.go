@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/asabla/goknut/internal/irc"
+)
+
+func TestParseWhisper(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantNil  bool
+		wantFrom string
+		wantTo   string
+		wantText string
+		wantID   string
+		wantTh   string
+	}{
+		{
+			name:     "whisper with tags",
+			line:     `@badges=;color=#1E90FF;display-name=Friend;emotes=;message-id=3;thread-id=11_22;turbo=0;user-id=22;user-type= :friend!friend@friend.tmi.twitch.tv WHISPER Archiver :hey there`,
+			wantFrom: "friend",
+			wantTo:   "archiver",
+			wantText: "hey there",
+			wantID:   "3",
+			wantTh:   "11_22",
+		},
+		{
+			name:     "without tags",
+			line:     `:Friend!friend@friend.tmi.twitch.tv WHISPER archiver :hi`,
+			wantFrom: "friend",
+			wantTo:   "archiver",
+			wantText: "hi",
+		},
+		{
+			name:    "server prefix",
+			line:    `:tmi.twitch.tv WHISPER archiver :hi`,
+			wantNil: true,
+		},
+		{
+			name:    "missing text",
+			line:    `:friend!friend@friend.tmi.twitch.tv WHISPER archiver`,
+			wantNil: true,
+		},
+		{
+			name:    "not a whisper",
+			line:    `:friend!friend@friend.tmi.twitch.tv PRIVMSG #archiver :hi`,
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := irc.ParseWhisper(tt.line)
+			if tt.wantNil {
+				if w != nil {
+					t.Fatalf("expected nil, got %+v", w)
+				}
+				return
+			}
+			if w == nil {
+				t.Fatal("expected whisper, got nil")
+			}
+			if w.From != tt.wantFrom || w.To != tt.wantTo || w.Text != tt.wantText {
+				t.Errorf("expected %s -> %s %q, got %s -> %s %q", tt.wantFrom, tt.wantTo, tt.wantText, w.From, w.To, w.Text)
+			}
+			if w.MessageID != tt.wantID || w.ThreadID != tt.wantTh {
+				t.Errorf("expected message %q in thread %q, got %q in %q", tt.wantID, tt.wantTh, w.MessageID, w.ThreadID)
+			}
+		})
+	}
+}